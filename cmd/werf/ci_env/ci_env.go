@@ -60,6 +60,7 @@ Currently supported only GitLab (gitlab) and GitHub (github) CI systems`,
 	common.SetupGitWorkTree(&commonCmdData, cmd)
 	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
 	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
 	common.SetupEnvironment(&commonCmdData, cmd)
 
 	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
@@ -0,0 +1,72 @@
+package cleanup
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/cleaning"
+	"github.com/werf/werf/pkg/werf/global_warnings"
+)
+
+var applyCommonCmdData common.CmdData
+
+func NewApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "apply PLAN",
+		DisableFlagsInUseLine: true,
+		Short:                 "Execute the project images cleanup plan",
+		Long: common.GetLongCommandDescription(`Execute the cleanup plan saved by the werf cleanup plan command.
+
+Only the deletions listed in the plan are performed. Before the deletion the plan is re-validated: the stages which are used in Kubernetes, listed in the allow lists, built or used since the plan creation (or within the --keep-stages-built-within-last-n-hours limit) or related to the image metadata that is not deleted by the plan, as well as their relatives, are skipped.`),
+		Example: `  $ werf cleanup apply --repo registry.mydomain.com/myproject/werf plan.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defer global_warnings.PrintGlobalWarnings(common.BackgroundContext())
+
+			if err := common.ProcessLogOptions(&applyCommonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+			common.LogVersion()
+
+			if err := common.ValidateArgumentCount(1, args, cmd); err != nil {
+				return err
+			}
+
+			return common.LogRunningTime(func() error {
+				return runApply(args[0])
+			})
+		},
+	}
+
+	setupCleanupFlags(&applyCommonCmdData, cmd)
+	common.SetupDryRun(&applyCommonCmdData, cmd)
+	common.SetupKeepStagesBuiltWithinLastNHours(&applyCommonCmdData, cmd)
+	common.SetupTriggerRegistryGC(&applyCommonCmdData, cmd)
+
+	return cmd
+}
+
+func runApply(planPath string) error {
+	ctx := common.BackgroundContext()
+
+	plan, err := cleaning.ReadCleanupPlan(planPath)
+	if err != nil {
+		return err
+	}
+
+	c, err := initCleanup(ctx, &applyCommonCmdData)
+	if err != nil {
+		return err
+	}
+	defer c.Terminate()
+
+	if plan.IsEmpty() {
+		logboek.Default().LogLnHighlight("Nothing to delete: the cleanup plan is empty")
+		return nil
+	}
+
+	logboek.LogOptionalLn()
+	return cleaning.ApplyCleanupPlan(c.Ctx, c.ProjectName, c.StorageManager, plan, c.Options)
+}
@@ -1,6 +1,7 @@
 package cleanup
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -32,6 +33,12 @@ func NewCmd() *cobra.Command {
 
 The command works according to special rules called cleanup policies, which the user defines in werf.yaml (https://werf.io/documentation/reference/werf_yaml.html#configuring-cleanup-policies).
 
+The images used in Kubernetes clusters are kept (unless --without-kube specified), as well as the images provided by the cleanup.allowList sources of werf.yaml: helm releases revisions, custom resources, published bundles and image lists.
+
+The cleanup.storageQuota directive of werf.yaml bounds the stages storage: the least recently used stages are deleted until the repo fits the maxSize quota and each image has at most maxStagesPerImage stages. The stages retained by the cleanup policies are not deleted by the quota unless overrideKeepPolicies is set.
+
+The stages are deleted with the bulk deletion API of the container registry if available (ECR, Harbor with --repo-harbor-username/password, GitLab with --repo-gitlab-token and --repo-gitlab-api-url for the instances other than gitlab.com). GitLab deletes the tags asynchronously, such tags are reported as scheduled for deletion. The deleted images still occupy the registry space until the registry garbage collection, the --trigger-registry-gc option schedules it after the cleanup if the registry API allows it (Harbor).
+
 It is safe to run this command periodically (daily is enough) by automated cleanup job in parallel with other werf commands such as build, converge and host cleanup.`),
 		Example: `  $ werf cleanup --repo registry.mydomain.com/myproject/werf`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -47,106 +54,143 @@ It is safe to run this command periodically (daily is enough) by automated clean
 		},
 	}
 
-	common.SetupDir(&commonCmdData, cmd)
-	common.SetupGitWorkTree(&commonCmdData, cmd)
-	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
-	common.SetupConfigPath(&commonCmdData, cmd)
-	common.SetupEnvironment(&commonCmdData, cmd)
+	cmd.AddCommand(
+		NewPlanCmd(),
+		NewApplyCmd(),
+	)
 
-	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+	setupCleanupFlags(&commonCmdData, cmd)
+	common.SetupDryRun(&commonCmdData, cmd)
+	common.SetupKeepStagesBuiltWithinLastNHours(&commonCmdData, cmd)
+	common.SetupTriggerRegistryGC(&commonCmdData, cmd)
 
-	common.SetupTmpDir(&commonCmdData, cmd)
-	common.SetupHomeDir(&commonCmdData, cmd)
+	return cmd
+}
 
-	common.SetupSecondaryStagesStorageOptions(&commonCmdData, cmd)
-	common.SetupStagesStorageOptions(&commonCmdData, cmd)
-	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultCleanupParallelTasksLimit)
+func runCleanup() error {
+	ctx := common.BackgroundContext()
 
-	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and delete images from the specified repo")
-	common.SetupInsecureRegistry(&commonCmdData, cmd)
-	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	c, err := initCleanup(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+	defer c.Terminate()
 
-	common.SetupScanContextNamespaceOnly(&commonCmdData, cmd)
-	common.SetupDryRun(&commonCmdData, cmd)
+	logboek.LogOptionalLn()
+	if err := cleaning.Cleanup(c.Ctx, c.ProjectName, c.StorageManager, c.StorageLockManager, c.Options); err != nil {
+		return err
+	}
 
-	common.SetupLogOptions(&commonCmdData, cmd)
-	common.SetupLogProjectDir(&commonCmdData, cmd)
+	return nil
+}
 
-	common.SetupSynchronization(&commonCmdData, cmd)
-	common.SetupKubeConfig(&commonCmdData, cmd)
-	common.SetupKubeConfigBase64(&commonCmdData, cmd)
-	common.SetupKubeContext(&commonCmdData, cmd)
-	common.SetupWithoutKube(&commonCmdData, cmd)
-	common.SetupKeepStagesBuiltWithinLastNHours(&commonCmdData, cmd)
+func setupCleanupFlags(cmdData *common.CmdData, cmd *cobra.Command) {
+	common.SetupDir(cmdData, cmd)
+	common.SetupGitWorkTree(cmdData, cmd)
+	common.SetupConfigTemplatesDir(cmdData, cmd)
+	common.SetupConfigPath(cmdData, cmd)
+	common.SetupConfigParams(cmdData, cmd)
+	common.SetupEnvironment(cmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(cmdData, cmd)
+
+	common.SetupTmpDir(cmdData, cmd)
+	common.SetupHomeDir(cmdData, cmd)
+
+	common.SetupSecondaryStagesStorageOptions(cmdData, cmd)
+	common.SetupStagesStorageOptions(cmdData, cmd)
+	common.SetupParallelOptions(cmdData, cmd, common.DefaultCleanupParallelTasksLimit)
+
+	common.SetupDockerConfig(cmdData, cmd, "Command needs granted permissions to read, pull and delete images from the specified repo")
+	common.SetupInsecureRegistry(cmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(cmdData, cmd)
+
+	common.SetupScanContextNamespaceOnly(cmdData, cmd)
+
+	common.SetupLogOptions(cmdData, cmd)
+	common.SetupLogProjectDir(cmdData, cmd)
+
+	common.SetupSynchronization(cmdData, cmd)
+	common.SetupKubeConfig(cmdData, cmd)
+	common.SetupKubeConfigBase64(cmdData, cmd)
+	common.SetupKubeContext(cmdData, cmd)
+	common.SetupWithoutKube(cmdData, cmd)
 
-	return cmd
 }
 
-func runCleanup() error {
+type cleanupCommand struct {
+	Ctx                context.Context
+	ProjectName        string
+	StorageManager     *manager.StorageManager
+	StorageLockManager storage.LockManager
+	Options            cleaning.CleanupOptions
+
+	projectTmpDir string
+}
+
+func (c *cleanupCommand) Terminate() {
+	tmp_manager.ReleaseProjectDir(c.projectTmpDir)
+}
+
+// initCleanup performs the initialization shared by the cleanup commands
+func initCleanup(ctx context.Context, cmdData *common.CmdData) (*cleanupCommand, error) {
 	tmp_manager.AutoGCEnabled = true
-	ctx := common.BackgroundContext()
 
-	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
-		return fmt.Errorf("initialization error: %s", err)
+	if err := werf.Init(*cmdData.TmpDir, *cmdData.HomeDir); err != nil {
+		return nil, fmt.Errorf("initialization error: %s", err)
 	}
 
-	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
-		return err
+	if err := common.InitGiterminismInspector(cmdData); err != nil {
+		return nil, err
 	}
 
 	if err := git_repo.Init(); err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
-		return err
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *cmdData.LogVerbose || *cmdData.LogDebug}); err != nil {
+		return nil, err
 	}
 
 	if err := image.Init(); err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
-		return err
+	if err := common.DockerRegistryInit(cmdData); err != nil {
+		return nil, err
 	}
 
-	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
-		return err
+	if err := docker.Init(ctx, *cmdData.DockerConfig, *cmdData.LogVerbose, *cmdData.LogDebug); err != nil {
+		return nil, err
 	}
 
 	ctxWithDockerCli, err := docker.NewContext(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	ctx = ctxWithDockerCli
 
-	common.SetupOndemandKubeInitializer(*commonCmdData.KubeContext, *commonCmdData.KubeConfig, *commonCmdData.KubeConfigBase64)
+	common.SetupOndemandKubeInitializer(*cmdData.KubeContext, *cmdData.KubeConfig, *cmdData.KubeConfigBase64)
 	if err := common.GetOndemandKubeInitializer().Init(ctx); err != nil {
-		return err
+		return nil, err
 	}
 
-	giterminismManager, err := common.GetGiterminismManager(&commonCmdData)
+	giterminismManager, err := common.GetGiterminismManager(cmdData)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	common.ProcessLogProjectDir(&commonCmdData, giterminismManager.ProjectDir())
-
-	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
-	if err != nil {
-		return fmt.Errorf("getting project tmp dir failed: %s", err)
-	}
-	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+	common.ProcessLogProjectDir(cmdData, giterminismManager.ProjectDir())
 
-	werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
+	werfConfig, err := common.GetRequiredWerfConfig(ctx, cmdData, giterminismManager, common.GetWerfConfigOptions(cmdData, true))
 	if err != nil {
-		return fmt.Errorf("unable to load werf config: %s", err)
+		return nil, fmt.Errorf("unable to load werf config: %s", err)
 	}
 
 	if !werfConfig.Meta.GitWorktree.GetForceShallowClone() && !werfConfig.Meta.GitWorktree.GetAllowFetchingOriginBranchesAndTags() {
 		isShallow, err := giterminismManager.LocalGitRepo().IsShallowClone()
 		if err != nil {
-			return fmt.Errorf("check shallow clone failed: %s", err)
+			return nil, fmt.Errorf("check shallow clone failed: %s", err)
 		}
 
 		if isShallow {
@@ -154,13 +198,13 @@ func runCleanup() error {
 			logboek.Warn().LogLn("It is recommended to enable automatic fetch of origin git branches and tags during cleanup process with the gitWorktree.allowFetchOriginBranchesAndTags=true werf.yaml directive (which is enabled by default, http://werf.io/documentation/reference/werf_yaml.html#git-worktree).")
 			logboek.Warn().LogLn("If you still want to use shallow clone, add gitWorktree.forceShallowClone=true directive into werf.yaml (http://werf.io/documentation/reference/werf_yaml.html#git-worktree).")
 
-			return fmt.Errorf("git shallow clone is not allowed")
+			return nil, fmt.Errorf("git shallow clone is not allowed")
 		}
 	}
 
 	if werfConfig.Meta.GitWorktree.GetAllowFetchingOriginBranchesAndTags() {
 		if err := giterminismManager.LocalGitRepo().SyncWithOrigin(ctx); err != nil {
-			return fmt.Errorf("synchronization failed: %s", err)
+			return nil, fmt.Errorf("synchronization failed: %s", err)
 		}
 	}
 
@@ -168,61 +212,76 @@ func runCleanup() error {
 
 	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
 
-	stagesStorageAddress := common.GetOptionalStagesStorageAddress(&commonCmdData)
-	stagesStorage, err := common.GetStagesStorage(stagesStorageAddress, containerRuntime, &commonCmdData)
+	stagesStorageAddress := common.GetOptionalStagesStorageAddress(cmdData)
+	stagesStorage, err := common.GetStagesStorage(stagesStorageAddress, containerRuntime, cmdData)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
+	synchronization, err := common.GetSynchronization(ctx, cmdData, projectName, stagesStorage)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	stagesStorageCache, err := common.GetStagesStorageCache(synchronization)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	secondaryStagesStorageList, err := common.GetSecondaryStagesStorageList(stagesStorage, containerRuntime, &commonCmdData)
+	secondaryStagesStorageList, err := common.GetSecondaryStagesStorageList(stagesStorage, containerRuntime, cmdData)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	storageManager := manager.NewStorageManager(projectName, stagesStorage, secondaryStagesStorageList, storageLockManager, stagesStorageCache)
 
-	if stagesStorage.Address() != storage.LocalStorageAddress && *commonCmdData.Parallel {
-		storageManager.StagesStorageManager.EnableParallel(int(*commonCmdData.ParallelTasksLimit))
+	if stagesStorage.Address() != storage.LocalStorageAddress && *cmdData.Parallel {
+		storageManager.StagesStorageManager.EnableParallel(int(*cmdData.ParallelTasksLimit))
 	}
 
 	imagesNames, err := common.GetManagedImagesNames(ctx, projectName, stagesStorage, werfConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	logboek.Debug().LogF("Managed images names: %v\n", imagesNames)
 
-	kubernetesContextClients, err := common.GetKubernetesContextClients(&commonCmdData)
+	allowListSources, err := common.GetCleanupAllowListSources(ctx, cmdData, werfConfig.Meta.Cleanup.AllowList, giterminismManager.ProjectDir(), stagesStorage)
 	if err != nil {
-		return fmt.Errorf("unable to get Kubernetes clusters connections: %s", err)
+		return nil, err
 	}
 
-	cleanupOptions := cleaning.CleanupOptions{
-		ImageNameList:                           imagesNames,
-		LocalGit:                                giterminismManager.LocalGitRepo(),
-		KubernetesContextClients:                kubernetesContextClients,
-		KubernetesNamespaceRestrictionByContext: common.GetKubernetesNamespaceRestrictionByContext(&commonCmdData, kubernetesContextClients),
-		WithoutKube:                             *commonCmdData.WithoutKube,
-		GitHistoryBasedCleanupOptions:           werfConfig.Meta.Cleanup,
-		KeepStagesBuiltWithinLastNHours:         *commonCmdData.KeepStagesBuiltWithinLastNHours,
-		DryRun:                                  *commonCmdData.DryRun,
+	options := cleaning.CleanupOptions{
+		ImageNameList:                 imagesNames,
+		LocalGit:                      giterminismManager.LocalGitRepo(),
+		AllowListSources:              allowListSources,
+		GitHistoryBasedCleanupOptions: werfConfig.Meta.Cleanup,
 	}
 
-	logboek.LogOptionalLn()
-	if err := cleaning.Cleanup(ctx, projectName, storageManager, storageLockManager, cleanupOptions); err != nil {
-		return err
+	if cmdData.KeepStagesBuiltWithinLastNHours != nil {
+		options.KeepStagesBuiltWithinLastNHours = *cmdData.KeepStagesBuiltWithinLastNHours
 	}
 
-	return nil
+	if cmdData.DryRun != nil {
+		options.DryRun = *cmdData.DryRun
+	}
+
+	if cmdData.TriggerRegistryGC != nil {
+		options.TriggerRegistryGC = *cmdData.TriggerRegistryGC
+	}
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+
+	return &cleanupCommand{
+		Ctx:                ctx,
+		ProjectName:        projectName,
+		StorageManager:     storageManager,
+		StorageLockManager: storageLockManager,
+		Options:            options,
+		projectTmpDir:      projectTmpDir,
+	}, nil
 }
@@ -0,0 +1,85 @@
+package cleanup
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/cleaning"
+	"github.com/werf/werf/pkg/werf/global_warnings"
+)
+
+var planCmdData struct {
+	Out string
+}
+
+var planCommonCmdData common.CmdData
+
+func NewPlanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "plan",
+		DisableFlagsInUseLine: true,
+		Short:                 "Save the project images cleanup plan",
+		Long: common.GetLongCommandDescription(`Compute the project images cleanup without deleting anything and save the plan into the file.
+
+The plan lists every stage, image metadata and import metadata which would be deleted, the reason of each deletion and the stages kept by the allow lists, the keep policies and the age.
+
+The reviewed plan is executed by the werf cleanup apply command.`),
+		Example: `  $ werf cleanup plan --repo registry.mydomain.com/myproject/werf --out plan.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defer global_warnings.PrintGlobalWarnings(common.BackgroundContext())
+
+			if err := common.ProcessLogOptions(&planCommonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+			common.LogVersion()
+
+			if planCmdData.Out == "" {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("--out PATH required")
+			}
+
+			return common.LogRunningTime(runPlan)
+		},
+	}
+
+	setupCleanupFlags(&planCommonCmdData, cmd)
+	common.SetupKeepStagesBuiltWithinLastNHours(&planCommonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&planCmdData.Out, "out", "", "", "Path to save the cleanup plan (required)")
+
+	return cmd
+}
+
+func runPlan() error {
+	ctx := common.BackgroundContext()
+
+	c, err := initCleanup(ctx, &planCommonCmdData)
+	if err != nil {
+		return err
+	}
+	defer c.Terminate()
+
+	logboek.LogOptionalLn()
+	plan, err := cleaning.PlanCleanup(c.Ctx, c.ProjectName, c.StorageManager, c.Options)
+	if err != nil {
+		return err
+	}
+
+	if err := plan.WriteFile(planCmdData.Out); err != nil {
+		return err
+	}
+
+	logboek.LogOptionalLn()
+	logboek.Default().LogFHighlight("Cleanup plan saved into %s\n", planCmdData.Out)
+	logboek.Default().LogF("  stages to delete: %d\n", len(plan.Stages))
+	logboek.Default().LogF("  image metadata to delete: %d\n", len(plan.ImageMetadata))
+	logboek.Default().LogF("  import metadata to delete: %d\n", len(plan.ImportMetadata))
+	logboek.Default().LogF("  kept stages: %d\n", len(plan.KeptStages))
+
+	return nil
+}
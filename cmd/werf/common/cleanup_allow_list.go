@@ -0,0 +1,81 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	cmd_helm "helm.sh/helm/v3/cmd/helm"
+	"helm.sh/helm/v3/pkg/action"
+
+	"github.com/werf/werf/pkg/cleaning/allow_list"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/storage"
+)
+
+// GetCleanupAllowListSources returns the sources of the images that must be kept by the cleanup:
+// the workloads of the Kubernetes clusters (unless --without-kube specified) and the sources configured in the werf.yaml cleanup.allowList section
+func GetCleanupAllowListSources(ctx context.Context, cmdData *CmdData, allowList config.MetaCleanupAllowList, projectDir string, stagesStorage storage.StagesStorage) ([]allow_list.Source, error) {
+	var sources []allow_list.Source
+
+	if !*cmdData.WithoutKube {
+		kubernetesContextClients, err := GetKubernetesContextClients(cmdData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get Kubernetes clusters connections: %s", err)
+		}
+
+		namespaceRestrictionByContext := GetKubernetesNamespaceRestrictionByContext(cmdData, kubernetesContextClients)
+		for _, contextClient := range kubernetesContextClients {
+			namespace := namespaceRestrictionByContext[contextClient.ContextName]
+			sources = append(sources, allow_list.NewKubernetesSource(contextClient.ContextName, contextClient.Client, namespace))
+
+			if allowList.HelmReleases {
+				sources = append(sources, allow_list.NewHelmReleasesSource(contextClient.ContextName, contextClient.Client, namespace))
+			}
+
+			for _, customResource := range allowList.CustomResources {
+				sources = append(sources, &allow_list.CustomResourcesSource{
+					ContextName: contextClient.ContextName,
+					Client:      contextClient.Client,
+					Namespace:   namespace,
+					Group:       customResource.Group,
+					Version:     customResource.Version,
+					Resource:    customResource.Resource,
+					ImagePaths:  customResource.ImagePaths,
+				})
+			}
+		}
+	}
+
+	for _, list := range allowList.Lists {
+		location := list
+		if !strings.HasPrefix(list, "http://") && !strings.HasPrefix(list, "https://") && !filepath.IsAbs(list) {
+			location = filepath.Join(projectDir, list)
+		}
+
+		sources = append(sources, allow_list.NewListSource(location))
+	}
+
+	if len(allowList.Bundles) != 0 {
+		repoStagesStorage, ok := stagesStorage.(*storage.RepoStagesStorage)
+		if !ok {
+			return nil, fmt.Errorf("cleanup.allowList.bundles requires the repo (--repo) where the bundles are published")
+		}
+
+		actionConfig := new(action.Configuration)
+		if err := helm.InitActionConfig(ctx, nil, "", cmd_helm.Settings, actionConfig, helm.InitActionConfigOptions{}); err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, &allow_list.BundlesSource{
+			RepoAddress:    repoStagesStorage.RepoAddress,
+			TagRegexps:     allowList.Bundles,
+			DockerRegistry: repoStagesStorage.DockerRegistry,
+			ActionConfig:   actionConfig,
+		})
+	}
+
+	return sources, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -25,8 +26,10 @@ import (
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/giterminism_inspector"
 	"github.com/werf/werf/pkg/giterminism_manager"
+	"github.com/werf/werf/pkg/giterminism_manager/report"
 	"github.com/werf/werf/pkg/logging"
 	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/tracing"
 	"github.com/werf/werf/pkg/util"
 	"github.com/werf/werf/pkg/werf"
 )
@@ -37,6 +40,7 @@ type CmdData struct {
 	Dir                *string
 	ConfigPath         *string
 	ConfigTemplatesDir *string
+	ConfigParams       *[]string
 	TmpDir             *string
 	HomeDir            *string
 	SSHKeys            *[]string
@@ -79,6 +83,7 @@ type CmdData struct {
 	DryRun                          *bool
 	KeepStagesBuiltWithinLastNHours *uint64
 	WithoutKube                     *bool
+	TriggerRegistryGC               *bool
 
 	LooseGiterminism *bool
 	Dev              *bool
@@ -89,6 +94,10 @@ type CmdData struct {
 
 	Follow *bool
 
+	BuildkitAddress  *string
+	ContainerRuntime *string
+	LayersCacheRepo  *string
+
 	LogDebug         *bool
 	LogPretty        *bool
 	LogVerbose       *bool
@@ -146,6 +155,15 @@ func SetupConfigPath(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().StringVarP(cmdData.ConfigPath, "config", "", os.Getenv("WERF_CONFIG"), `Use custom configuration file (default $WERF_CONFIG or werf.yaml in working directory)`)
 }
 
+func SetupConfigParams(cmdData *CmdData, cmd *cobra.Command) {
+	configParams := predefinedValuesByEnvNamePrefix("WERF_CONFIG_PARAM")
+
+	cmdData.ConfigParams = &configParams
+	cmd.Flags().StringArrayVarP(cmdData.ConfigParams, "config-param", "", configParams, `Set the werf config parameter declared in the meta section (can specify multiple: --config-param key1=val1 --config-param key2=val2).
+The parameter overrides the default and the value from werf-params.yaml and werf-params.<ENV>.yaml files.
+Also, can be defined with $WERF_CONFIG_PARAM_* (e.g. $WERF_CONFIG_PARAM_1=key1=val1, $WERF_CONFIG_PARAM_2=key2=val2)`)
+}
+
 func SetupConfigTemplatesDir(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.ConfigTemplatesDir = new(string)
 	cmd.Flags().StringVarP(cmdData.ConfigTemplatesDir, "config-templates-dir", "", os.Getenv("WERF_CONFIG_TEMPLATES_DIR"), `Custom configuration templates directory (default $WERF_CONFIG_TEMPLATES_DIR or .werf in working directory)`)
@@ -228,6 +246,11 @@ func SetupWithoutKube(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.WithoutKube, "without-kube", "", GetBoolEnvironmentDefaultFalse("WERF_WITHOUT_KUBE"), "Do not skip deployed Kubernetes images (default $WERF_WITHOUT_KUBE)")
 }
 
+func SetupTriggerRegistryGC(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.TriggerRegistryGC = new(bool)
+	cmd.Flags().BoolVarP(cmdData.TriggerRegistryGC, "trigger-registry-gc", "", GetBoolEnvironmentDefaultFalse("WERF_TRIGGER_REGISTRY_GC"), "Trigger the garbage collection of the container registry after the cleanup to free the space of the deleted images, the registry API should allow it (default $WERF_TRIGGER_REGISTRY_GC)")
+}
+
 func SetupKeepStagesBuiltWithinLastNHours(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.KeepStagesBuiltWithinLastNHours = new(uint64)
 
@@ -243,7 +266,7 @@ func SetupKeepStagesBuiltWithinLastNHours(cmdData *CmdData, cmd *cobra.Command)
 		defaultValue = 2
 	}
 
-	cmd.Flags().Uint64VarP(cmdData.KeepStagesBuiltWithinLastNHours, "keep-stages-built-within-last-n-hours", "", defaultValue, "Keep stages that were built within last hours (default $WERF_KEEP_STAGES_BUILT_WITHIN_LAST_N_HOURS or 2)")
+	cmd.Flags().Uint64VarP(cmdData.KeepStagesBuiltWithinLastNHours, "keep-stages-built-within-last-n-hours", "", defaultValue, "Keep stages that were built or used by werf within last hours (default $WERF_KEEP_STAGES_BUILT_WITHIN_LAST_N_HOURS or 2)")
 }
 
 func predefinedValuesByEnvNamePrefix(envNamePrefix string, envNamePrefixesToExcept ...string) []string {
@@ -332,6 +355,8 @@ func SetupCommonRepoData(cmdData *CmdData, cmd *cobra.Command) {
 	SetupDockerHubPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-docker-hub-password", []string{"WERF_REPO_DOCKER_HUB_PASSWORD"})
 	SetupDockerHubTokenForRepoData(cmdData.CommonRepoData, cmd, "repo-docker-hub-token", []string{"WERF_REPO_DOCKER_HUB_TOKEN"})
 	SetupGithubTokenForRepoData(cmdData.CommonRepoData, cmd, "repo-github-token", []string{"WERF_REPO_GITHUB_TOKEN"})
+	SetupGitLabTokenForRepoData(cmdData.CommonRepoData, cmd, "repo-gitlab-token", []string{"WERF_REPO_GITLAB_TOKEN"})
+	SetupGitLabApiURLForRepoData(cmdData.CommonRepoData, cmd, "repo-gitlab-api-url", []string{"WERF_REPO_GITLAB_API_URL"})
 	SetupHarborUsernameForRepoData(cmdData.CommonRepoData, cmd, "repo-harbor-username", []string{"WERF_REPO_HARBOR_USERNAME"})
 	SetupHarborPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-harbor-password", []string{"WERF_REPO_HARBOR_PASSWORD"})
 	SetupQuayTokenForRepoData(cmdData.CommonRepoData, cmd, "repo-quay-token", []string{"WERF_REPO_QUAY_TOKEN"})
@@ -352,7 +377,7 @@ func SetupStagesStorageOptions(cmdData *CmdData, cmd *cobra.Command) {
 
 func setupStagesStorage(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.StagesStorage = new(string)
-	cmd.Flags().StringVarP(cmdData.StagesStorage, "repo", "", os.Getenv("WERF_REPO"), fmt.Sprintf("Docker Repo to store stages (default $WERF_REPO)"))
+	cmd.Flags().StringVarP(cmdData.StagesStorage, "repo", "", os.Getenv("WERF_REPO"), fmt.Sprintf("Docker Repo to store stages or %sPATH to store stages in the OCI image layout directory (default $WERF_REPO)", storage.OCILayoutStorageAddressPrefix))
 }
 
 func SetupStatusProgressPeriod(cmdData *CmdData, cmd *cobra.Command) {
@@ -736,6 +761,36 @@ func SetupFollow(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.Follow, "follow", "", GetBoolEnvironmentDefaultFalse("WERF_FOLLOW"), "Follow git HEAD and run command for each new commit (default $WERF_FOLLOW)")
 }
 
+func SetupBuildkitAddress(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.BuildkitAddress = new(string)
+	cmd.Flags().StringVarP(cmdData.BuildkitAddress, "buildkit-addr", "", os.Getenv("WERF_BUILDKIT_ADDR"), `Build dockerfile images with the buildkitd daemon available by the specified address (e.g. unix:///run/buildkit/buildkitd.sock) instead of the docker server legacy builder.
+The built image is loaded into the container runtime ($WERF_BUILDKIT_ADDR by default)`)
+}
+
+func SetupLayersCacheRepo(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.LayersCacheRepo = new(string)
+	cmd.Flags().StringVarP(cmdData.LayersCacheRepo, "layers-cache-repo", "", os.Getenv("WERF_LAYERS_CACHE_REPO"), `Docker Repo to store stapel stages layers addressed by the stage dependencies and the parent image layer.
+Stage image is restored from the single cached layer when the parent image is available locally ($WERF_LAYERS_CACHE_REPO by default)`)
+}
+
+func SetupContainerRuntime(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.ContainerRuntime = new(string)
+	cmd.Flags().StringVarP(cmdData.ContainerRuntime, "container-runtime", "", os.Getenv("WERF_CONTAINER_RUNTIME"), `Container runtime to build and keep images: docker-server or localhost (default docker-server or $WERF_CONTAINER_RUNTIME).
+The localhost runtime does not require docker server: images are kept in the OCI image layout in the werf local cache dir and stapel instructions are run by the rootless executor in the user namespaces (linux only).
+Only the root user is mapped into the container run by the unprivileged user: changing the files ownership to other users is not supported, werf should be run as root to build such stages`)
+}
+
+func GetContainerRuntime(cmdData *CmdData) (container_runtime.ContainerRuntime, error) {
+	switch *cmdData.ContainerRuntime {
+	case "", "docker-server":
+		return &container_runtime.LocalDockerServerRuntime{}, nil
+	case "localhost":
+		return container_runtime.NewLocalHostRuntime(filepath.Join(werf.GetLocalCacheDir(), "localhost_runtime", "images"))
+	default:
+		return nil, fmt.Errorf("bad --container-runtime=%q: docker-server or localhost expected", *cmdData.ContainerRuntime)
+	}
+}
+
 func allStagesNames() []string {
 	var stageNames []string
 	for _, stageName := range stage.AllStages {
@@ -860,6 +915,8 @@ func GetStagesStorage(stagesStorageAddress string, containerRuntime container_ru
 					DockerHubPassword:     *cmdData.CommonRepoData.DockerHubPassword,
 					DockerHubToken:        *cmdData.CommonRepoData.DockerHubToken,
 					GitHubToken:           *cmdData.CommonRepoData.GitHubToken,
+					GitLabToken:           *cmdData.CommonRepoData.GitLabToken,
+					GitLabApiURL:          *cmdData.CommonRepoData.GitLabApiURL,
 					HarborUsername:        *cmdData.CommonRepoData.HarborUsername,
 					HarborPassword:        *cmdData.CommonRepoData.HarborPassword,
 					QuayToken:             *cmdData.CommonRepoData.QuayToken,
@@ -871,7 +928,8 @@ func GetStagesStorage(stagesStorageAddress string, containerRuntime container_ru
 
 func GetSecondaryStagesStorageList(stagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, cmdData *CmdData) ([]storage.StagesStorage, error) {
 	var res []storage.StagesStorage
-	if stagesStorage.Address() != storage.LocalStorageAddress {
+	// local stages storage is available only with the docker server runtime
+	if _, isLocalDockerServerRuntime := containerRuntime.(*container_runtime.LocalDockerServerRuntime); isLocalDockerServerRuntime && stagesStorage.Address() != storage.LocalStorageAddress {
 		localStagesStorage, err := storage.NewStagesStorage(storage.LocalStorageAddress, containerRuntime, storage.StagesStorageOptions{})
 		if err != nil {
 			return nil, fmt.Errorf("unable to create local secondary stages storage: %s", err)
@@ -961,19 +1019,42 @@ func GetCustomWerfConfigTemplatesDirRelPath(giterminismManager giterminism_manag
 }
 
 func GetWerfConfigOptions(cmdData *CmdData, LogRenderedFilePath bool) config.WerfConfigOptions {
+	var params []string
+	if cmdData.ConfigParams != nil {
+		params = *cmdData.ConfigParams
+	}
+
 	return config.WerfConfigOptions{
 		LogRenderedFilePath: LogRenderedFilePath,
 		Env:                 *cmdData.Environment,
+		Params:              params,
 	}
 }
 
 func GetGiterminismManager(cmdData *CmdData) (giterminism_manager.Interface, error) {
+	return GetGiterminismManagerForRevision(cmdData, "")
+}
+
+// GetGiterminismManagerForRevision returns giterminism manager which reads werf config and build context files
+// from the specified revision of the project git repo instead of the work tree HEAD (dev mode is not used in this case)
+func GetGiterminismManagerForRevision(cmdData *CmdData, revision string) (giterminism_manager.Interface, error) {
+	return getGiterminismManager(cmdData, revision, nil)
+}
+
+// GetGiterminismManagerWithReport returns giterminism manager which registers giterminism violations in the report instead of failing
+func GetGiterminismManagerWithReport(cmdData *CmdData, rep *report.Report) (giterminism_manager.Interface, error) {
+	return getGiterminismManager(cmdData, "", rep)
+}
+
+func getGiterminismManager(cmdData *CmdData, revision string, rep *report.Report) (giterminism_manager.Interface, error) {
 	gitWorkTree, err := GetGitWorkTree(cmdData)
 	if err != nil {
 		return nil, err
 	}
 
-	localGitRepo, err := git_repo.OpenLocalRepo("own", gitWorkTree, git_repo.OpenLocalRepoOptions{Dev: *cmdData.Dev})
+	dev := *cmdData.Dev && revision == ""
+
+	localGitRepo, err := git_repo.OpenLocalRepo("own", gitWorkTree, git_repo.OpenLocalRepoOptions{Dev: dev, Revision: revision})
 	if err != nil {
 		return nil, err
 	}
@@ -990,7 +1071,8 @@ func GetGiterminismManager(cmdData *CmdData) (giterminism_manager.Interface, err
 
 	return giterminism_manager.NewManager(BackgroundContext(), projectDir, localGitRepo, headCommit, giterminism_manager.NewManagerOptions{
 		LooseGiterminism: *cmdData.LooseGiterminism,
-		Dev:              *cmdData.Dev,
+		Dev:              dev,
+		Report:           rep,
 	})
 }
 
@@ -1298,9 +1380,19 @@ func TerminateWithError(errMsg string, exitCode int) {
 
 	logboek.Streams().DisableLineWrapping()
 	logboek.Error().LogLn(msg)
+
+	ShutdownTracing()
+
 	os.Exit(exitCode)
 }
 
+// ShutdownTracing exports remaining spans if tracing is enabled by WERF_TRACING_* environment variables
+func ShutdownTracing() {
+	if err := tracing.Shutdown(context.Background()); err != nil {
+		logboek.Warn().LogF("WARNING: %s\n", err)
+	}
+}
+
 func SetupVirtualMerge(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.VirtualMerge = new(bool)
 	cmd.Flags().BoolVarP(cmdData.VirtualMerge, "virtual-merge", "", GetBoolEnvironmentDefaultFalse("WERF_VIRTUAL_MERGE"), "Enable virtual/ephemeral merge commit mode when building current application state ($WERF_VIRTUAL_MERGE by default)")
@@ -16,6 +16,8 @@ func GetConveyorOptions(commonCmdData *CmdData) build.ConveyorOptions {
 			VirtualMergeFromCommit: *commonCmdData.VirtualMergeFromCommit,
 			VirtualMergeIntoCommit: *commonCmdData.VirtualMergeIntoCommit,
 		},
+		BuildkitAddress: *commonCmdData.BuildkitAddress,
+		LayersCacheRepo: *commonCmdData.LayersCacheRepo,
 	}
 }
 
@@ -19,6 +19,8 @@ type RepoData struct {
 	DockerHubPassword *string
 	DockerHubToken    *string
 	GitHubToken       *string
+	GitLabToken       *string
+	GitLabApiURL      *string
 	HarborUsername    *string
 	HarborPassword    *string
 	QuayToken         *string
@@ -43,6 +45,12 @@ func MergeRepoData(repoDataArr ...*RepoData) *RepoData {
 		if res.GitHubToken == nil || *res.GitHubToken == "" {
 			res.GitHubToken = repoData.GitHubToken
 		}
+		if res.GitLabToken == nil || *res.GitLabToken == "" {
+			res.GitLabToken = repoData.GitLabToken
+		}
+		if res.GitLabApiURL == nil || *res.GitLabApiURL == "" {
+			res.GitLabApiURL = repoData.GitLabApiURL
+		}
 		if res.HarborUsername == nil || *res.HarborUsername == "" {
 			res.HarborUsername = repoData.HarborUsername
 		}
@@ -153,6 +161,42 @@ func SetupGithubTokenForRepoData(repoData *RepoData, cmd *cobra.Command, paramNa
 	)
 }
 
+func SetupGitLabTokenForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("GitLab token to remove tags with the GitLab bulk tags deletion API (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("GitLab token for %s to remove tags with the GitLab bulk tags deletion API (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.GitLabToken = new(string)
+	cmd.Flags().StringVarP(
+		repoData.GitLabToken,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+}
+
+func SetupGitLabApiURLForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("GitLab API URL to remove tags with the GitLab bulk tags deletion API, e.g. https://gitlab.example.com/api/v4 or $CI_API_V4_URL in GitLab CI/CD, required for the GitLab instances other than gitlab.com (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("GitLab API URL for %s to remove tags with the GitLab bulk tags deletion API, e.g. https://gitlab.example.com/api/v4 or $CI_API_V4_URL in GitLab CI/CD, required for the GitLab instances other than gitlab.com (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.GitLabApiURL = new(string)
+	cmd.Flags().StringVarP(
+		repoData.GitLabApiURL,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+}
+
 func SetupHarborUsernameForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
 	var usage string
 	if repoData.IsCommon {
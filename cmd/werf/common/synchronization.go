@@ -140,7 +140,7 @@ func GetSynchronization(ctx context.Context, cmdData *CmdData, projectName strin
 	}
 
 	if *cmdData.Synchronization == "" {
-		if stagesStorage.Address() == storage.LocalStorageAddress {
+		if stagesStorage.Address() == storage.LocalStorageAddress || storage.IsOCILayoutStorageAddress(stagesStorage.Address()) {
 			return &SynchronizationParams{SynchronizationType: LocalSynchronization, Address: storage.LocalStorageAddress}, nil
 		} else {
 			return getHttpParamsFunc("https://synchronization.werf.io", stagesStorage)
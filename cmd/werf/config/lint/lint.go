@@ -0,0 +1,112 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "lint",
+		DisableFlagsInUseLine: true,
+		Short:                 "Lint werf.yaml",
+		Long: common.GetLongCommandDescription(`Lint werf.yaml.
+
+Collect errors of all werf.yaml documents and best-practice warnings. Each issue is printed with the line of the rendered werf.yaml, the command exits with non-zero code if there are errors.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+				return fmt.Errorf("initialization error: %s", err)
+			}
+
+			if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+				return err
+			}
+
+			if err := git_repo.Init(); err != nil {
+				return err
+			}
+
+			if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+				return err
+			}
+
+			giterminismManager, err := common.GetGiterminismManager(&commonCmdData)
+			if err != nil {
+				return err
+			}
+
+			configOpts := common.GetWerfConfigOptions(&commonCmdData, false)
+
+			customWerfConfigRelPath, err := common.GetCustomWerfConfigRelPath(giterminismManager, &commonCmdData)
+			if err != nil {
+				return err
+			}
+
+			customWerfConfigTemplatesDirRelPath, err := common.GetCustomWerfConfigTemplatesDirRelPath(giterminismManager, &commonCmdData)
+			if err != nil {
+				return err
+			}
+
+			result, err := config.LintWerfConfig(common.BackgroundContext(), customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath, giterminismManager, configOpts)
+			if err != nil {
+				return err
+			}
+
+			configName := customWerfConfigRelPath
+			if configName == "" {
+				configName = "werf.yaml"
+			}
+
+			var errorsNumber, warningsNumber int
+			for _, issue := range result.Issues {
+				if issue.Severity == config.LintSeverityError {
+					errorsNumber++
+				} else {
+					warningsNumber++
+				}
+
+				if issue.Line != 0 {
+					fmt.Printf("%s:%d: %s: %s\n", configName, issue.Line, issue.Severity, issue.Message)
+				} else {
+					fmt.Printf("%s: %s: %s\n", configName, issue.Severity, issue.Message)
+				}
+			}
+
+			if errorsNumber != 0 {
+				return fmt.Errorf("%d error(s) and %d warning(s) found", errorsNumber, warningsNumber)
+			}
+
+			return nil
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
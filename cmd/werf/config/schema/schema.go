@@ -0,0 +1,33 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
+)
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "schema",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print JSON Schema of werf.yaml",
+		Long: common.GetLongCommandDescription(`Print JSON Schema of werf.yaml.
+
+The schema is generated from the werf.yaml types and can be used by IDE for autocompletion and validation (e.g. with yaml-language-server).`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := config.GetWerfConfigJSONSchemaData()
+			if err != nil {
+				return fmt.Errorf("unable to generate werf.yaml schema: %s", err)
+			}
+
+			fmt.Println(string(data))
+
+			return nil
+		},
+	}
+
+	return cmd
+}
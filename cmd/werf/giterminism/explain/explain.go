@@ -0,0 +1,251 @@
+package explain
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/pkg/fileutils"
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/giterminism_manager"
+	"github.com/werf/werf/pkg/giterminism_manager/file_reader"
+	"github.com/werf/werf/pkg/giterminism_manager/report"
+	"github.com/werf/werf/pkg/path_matcher"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+var cmdData struct {
+	Fix bool
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "explain",
+		DisableFlagsInUseLine: true,
+		Short:                 "Explain giterminism violations of the project",
+		Long: common.GetLongCommandDescription(fmt.Sprintf(`Explain giterminism violations of the project.
+
+Run all giterminism checks for the werf config rendering, stapel images, Dockerfile contexts and helm chart files without failing on the first violation. Each violation is printed with the %s rule that allows it, the command exits with non-zero code if there are violations.
+
+With --fix option the minimal set of the rules is added to the %s (the violations which cannot be allowed by the config must be fixed manually).`, file_reader.GiterminismConfigName, file_reader.GiterminismConfigName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+				return fmt.Errorf("initialization error: %s", err)
+			}
+
+			if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+				return err
+			}
+
+			if err := git_repo.Init(); err != nil {
+				return err
+			}
+
+			if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+				return err
+			}
+
+			return runExplain()
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&cmdData.Fix, "fix", "", false, fmt.Sprintf("Add the rules allowing the violations to the %s", file_reader.GiterminismConfigName))
+
+	return cmd
+}
+
+func runExplain() error {
+	ctx := common.BackgroundContext()
+
+	rep := report.NewReport()
+	giterminismManager, err := common.GetGiterminismManagerWithReport(&commonCmdData, rep)
+	if err != nil {
+		return err
+	}
+
+	if err := inspectProject(ctx, giterminismManager); err != nil {
+		return err
+	}
+
+	violations := rep.Violations()
+	if len(violations) == 0 {
+		fmt.Println("No giterminism violations found")
+		return nil
+	}
+
+	var notAllowedNumber int
+	for _, v := range violations {
+		fmt.Printf("- %s\n", v.Message)
+		if v.Rule != nil {
+			fmt.Printf("  allowed by %s: %s\n", file_reader.GiterminismConfigName, v.Rule)
+		} else {
+			notAllowedNumber++
+			fmt.Printf("  cannot be allowed by %s: commit the changes\n", file_reader.GiterminismConfigName)
+		}
+	}
+
+	if !cmdData.Fix {
+		return fmt.Errorf("%d giterminism violation(s) found", len(violations))
+	}
+
+	configPath := filepath.Join(giterminismManager.ProjectDir(), file_reader.GiterminismConfigName)
+	if err := patchGiterminismConfig(configPath, rep.Rules()); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%s updated: %d rule(s) added\n", configPath, len(rep.Rules()))
+
+	if notAllowedNumber != 0 {
+		return fmt.Errorf("%d giterminism violation(s) cannot be allowed by %s", notAllowedNumber, file_reader.GiterminismConfigName)
+	}
+
+	return nil
+}
+
+// inspectProject runs the giterminism checks which are performed during werf config rendering, build and deploy
+func inspectProject(ctx context.Context, giterminismManager giterminism_manager.Interface) error {
+	customWerfConfigRelPath, err := common.GetCustomWerfConfigRelPath(giterminismManager, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	customWerfConfigTemplatesDirRelPath, err := common.GetCustomWerfConfigTemplatesDirRelPath(giterminismManager, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	werfConfig, err := config.GetWerfConfig(ctx, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	var stapelImages []*config.StapelImageBase
+	for _, img := range werfConfig.StapelImages {
+		stapelImages = append(stapelImages, img.ImageBaseConfig())
+	}
+	for _, img := range werfConfig.Artifacts {
+		stapelImages = append(stapelImages, img.ImageBaseConfig())
+	}
+
+	for _, img := range stapelImages {
+		if img.Git == nil {
+			continue
+		}
+
+		for _, gitLocal := range img.Git.Local {
+			if err := giterminismManager.Inspector().InspectBuildContextFiles(ctx, path_matcher.NewGitMappingPathMatcher(gitLocal.GitMappingAdd(), gitLocal.GitMappingIncludePaths(), gitLocal.GitMappingExcludePath(), true)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, img := range werfConfig.ImagesFromDockerfile {
+		if err := inspectDockerfileImage(ctx, giterminismManager, img); err != nil {
+			return fmt.Errorf("image %q: %s", img.Name, err)
+		}
+	}
+
+	helmChartDir, err := common.GetHelmChartDir(werfConfig, giterminismManager)
+	if err != nil {
+		return err
+	}
+
+	if exist, err := util.DirExists(filepath.Join(giterminismManager.ProjectDir(), helmChartDir)); err != nil {
+		return err
+	} else if !exist {
+		return nil
+	}
+
+	if _, err := giterminismManager.FileReader().LoadChartDir(ctx, helmChartDir); err != nil {
+		return fmt.Errorf("unable to load chart %q: %s", helmChartDir, err)
+	}
+
+	return nil
+}
+
+// inspectDockerfileImage checks the Dockerfile, .dockerignore and the whole build context except contextAddFiles
+func inspectDockerfileImage(ctx context.Context, giterminismManager giterminism_manager.Interface, img *config.ImageFromDockerfile) error {
+	if _, err := giterminismManager.FileReader().ReadDockerfile(ctx, filepath.Join(img.Context, img.Dockerfile)); err != nil {
+		return err
+	}
+
+	var dockerignorePatterns []string
+	relDockerignorePath := filepath.Join(img.Context, ".dockerignore")
+	if exist, err := giterminismManager.FileReader().IsDockerignoreExistAnywhere(ctx, relDockerignorePath); err != nil {
+		return err
+	} else if exist {
+		dockerignoreData, err := giterminismManager.FileReader().ReadDockerignore(ctx, relDockerignorePath)
+		if err != nil {
+			return err
+		}
+
+		dockerignorePatterns, err = dockerignore.ReadAll(bytes.NewReader(dockerignoreData))
+		if err != nil {
+			return fmt.Errorf("unable to read .dockerignore file: %s", err)
+		}
+	}
+
+	dockerignorePatternMatcher, err := fileutils.NewPatternMatcher(dockerignorePatterns)
+	if err != nil {
+		return err
+	}
+
+	var contextAddFiles []string
+	for _, contextAddFile := range img.ContextAddFile {
+		contextAddFiles = append(contextAddFiles, filepath.Join(img.Context, contextAddFile))
+	}
+
+	return giterminismManager.Inspector().InspectBuildContextFiles(ctx, path_matcher.NewMultiPathMatcher(
+		path_matcher.NewDockerfileIgnorePathMatcher(img.Context, dockerignorePatternMatcher, false),
+		path_matcher.NewGitMappingPathMatcher("", []string{}, contextAddFiles, true),
+	))
+}
+
+func patchGiterminismConfig(configPath string, rules []report.Rule) error {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read %s: %s", configPath, err)
+	}
+
+	newData, err := report.PatchGiterminismConfig(data, rules)
+	if err != nil {
+		return fmt.Errorf("unable to patch %s: %s", configPath, err)
+	}
+
+	if err := ioutil.WriteFile(configPath, newData, 0644); err != nil {
+		return fmt.Errorf("unable to write %s: %s", configPath, err)
+	}
+
+	return nil
+}
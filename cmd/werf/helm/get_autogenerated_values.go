@@ -10,7 +10,6 @@ import (
 
 	"github.com/werf/werf/cmd/werf/common"
 	"github.com/werf/werf/pkg/build"
-	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/deploy/helm/chart_extender"
 	"github.com/werf/werf/pkg/docker"
 	"github.com/werf/werf/pkg/git_repo"
@@ -50,6 +49,7 @@ These values includes project name, docker images ids and other`),
 	common.SetupGitWorkTree(&getAutogeneratedValuedCmdData, cmd)
 	common.SetupConfigTemplatesDir(&getAutogeneratedValuedCmdData, cmd)
 	common.SetupConfigPath(&getAutogeneratedValuedCmdData, cmd)
+	common.SetupConfigParams(&getAutogeneratedValuedCmdData, cmd)
 	common.SetupEnvironment(&getAutogeneratedValuedCmdData, cmd)
 
 	common.SetupGiterminismInspectorOptions(&getAutogeneratedValuedCmdData, cmd)
@@ -69,6 +69,10 @@ These values includes project name, docker images ids and other`),
 	common.SetupVirtualMergeFromCommit(&getAutogeneratedValuedCmdData, cmd)
 	common.SetupVirtualMergeIntoCommit(&getAutogeneratedValuedCmdData, cmd)
 
+	common.SetupBuildkitAddress(&getAutogeneratedValuedCmdData, cmd)
+	common.SetupLayersCacheRepo(&getAutogeneratedValuedCmdData, cmd)
+	common.SetupContainerRuntime(&getAutogeneratedValuedCmdData, cmd)
+
 	common.SetupNamespace(&getAutogeneratedValuedCmdData, cmd)
 
 	common.SetupDockerConfig(&getAutogeneratedValuedCmdData, cmd, "Command needs granted permissions to read and pull images from the specified repo")
@@ -165,7 +169,10 @@ func runGetServiceValues() error {
 		if err != nil {
 			return fmt.Errorf("%s (use --stub-tags option to get service values without real tags)", err)
 		}
-		containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+		containerRuntime, err := common.GetContainerRuntime(&getAutogeneratedValuedCmdData)
+		if err != nil {
+			return err
+		}
 		stagesStorage, err := common.GetStagesStorage(stagesStorageAddress, containerRuntime, &getAutogeneratedValuedCmdData)
 		if err != nil {
 			return err
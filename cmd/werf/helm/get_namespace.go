@@ -32,6 +32,7 @@ func NewGetNamespaceCmd() *cobra.Command {
 	common.SetupGitWorkTree(&getNamespaceCmdData, cmd)
 	common.SetupConfigTemplatesDir(&getNamespaceCmdData, cmd)
 	common.SetupConfigPath(&getNamespaceCmdData, cmd)
+	common.SetupConfigParams(&getNamespaceCmdData, cmd)
 	common.SetupEnvironment(&getNamespaceCmdData, cmd)
 
 	common.SetupGiterminismInspectorOptions(&getNamespaceCmdData, cmd)
@@ -32,6 +32,7 @@ func NewGetReleaseCmd() *cobra.Command {
 	common.SetupGitWorkTree(&getReleaseCmdData, cmd)
 	common.SetupConfigTemplatesDir(&getReleaseCmdData, cmd)
 	common.SetupConfigPath(&getReleaseCmdData, cmd)
+	common.SetupConfigParams(&getReleaseCmdData, cmd)
 	common.SetupEnvironment(&getReleaseCmdData, cmd)
 
 	common.SetupGiterminismInspectorOptions(&getReleaseCmdData, cmd)
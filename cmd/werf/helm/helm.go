@@ -12,12 +12,15 @@ import (
 
 	helm_secret_decrypt "github.com/werf/werf/cmd/werf/helm/secret/decrypt"
 	helm_secret_encrypt "github.com/werf/werf/cmd/werf/helm/secret/encrypt"
+	helm_secret_encrypt_secret_key "github.com/werf/werf/cmd/werf/helm/secret/encrypt_secret_key"
 	helm_secret_file_decrypt "github.com/werf/werf/cmd/werf/helm/secret/file/decrypt"
 	helm_secret_file_edit "github.com/werf/werf/cmd/werf/helm/secret/file/edit"
 	helm_secret_file_encrypt "github.com/werf/werf/cmd/werf/helm/secret/file/encrypt"
 	helm_secret_generate_secret_key "github.com/werf/werf/cmd/werf/helm/secret/generate_secret_key"
+	helm_secret_migrate "github.com/werf/werf/cmd/werf/helm/secret/migrate"
 	helm_secret_rotate_secret_key "github.com/werf/werf/cmd/werf/helm/secret/rotate_secret_key"
 	helm_secret_values_decrypt "github.com/werf/werf/cmd/werf/helm/secret/values/decrypt"
+	helm_secret_values_diff "github.com/werf/werf/cmd/werf/helm/secret/values/diff"
 	helm_secret_values_edit "github.com/werf/werf/cmd/werf/helm/secret/values/edit"
 	helm_secret_values_encrypt "github.com/werf/werf/cmd/werf/helm/secret/values/encrypt"
 
@@ -200,15 +203,18 @@ func secretCmd() *cobra.Command {
 		helm_secret_values_encrypt.NewCmd(),
 		helm_secret_values_decrypt.NewCmd(),
 		helm_secret_values_edit.NewCmd(),
+		helm_secret_values_diff.NewCmd(),
 	)
 
 	cmd.AddCommand(
 		fileCmd,
 		valuesCmd,
 		helm_secret_generate_secret_key.NewCmd(),
+		helm_secret_encrypt_secret_key.NewCmd(),
 		helm_secret_encrypt.NewCmd(),
 		helm_secret_decrypt.NewCmd(),
 		helm_secret_rotate_secret_key.NewCmd(),
+		helm_secret_migrate.NewCmd(),
 	)
 
 	return cmd
@@ -18,6 +18,7 @@ type GenerateOptions struct {
 	FilePath       string
 	OutputFilePath string
 	Values         bool
+	WithKeyNames   bool
 }
 
 func ReadFileData(filePath string) ([]byte, error) {
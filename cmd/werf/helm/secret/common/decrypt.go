@@ -69,7 +69,7 @@ func secretDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, optio
 	encodedData = bytes.TrimSpace(encodedData)
 
 	if options.Values {
-		data, err = encoder.DecryptYamlData(encodedData)
+		data, err = encoder.DecryptYamlDataWithKeyNames(encodedData)
 		if err != nil {
 			return err
 		}
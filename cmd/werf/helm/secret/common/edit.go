@@ -69,7 +69,7 @@ func SecretEdit(ctx context.Context, m *secrets_manager.SecretsManager, filePath
 
 		var newEncodedData []byte
 		if values {
-			newEncodedData, err = encoder.EncryptYamlData(newData)
+			newEncodedData, err = encoder.EncryptYamlDataWithKeyNames(newData)
 			if err != nil {
 				return err
 			}
@@ -139,7 +139,7 @@ func readEditedFile(filePath string, values bool, encoder *secret.YamlEncoder) (
 		encodedData = bytes.TrimSpace(encodedData)
 
 		if values {
-			data, err = encoder.DecryptYamlData(encodedData)
+			data, err = encoder.DecryptYamlDataWithKeyNames(encodedData)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -307,7 +307,8 @@ func mergeYamlEncodedData(d, eD, newD, newED interface{}) (interface{}, error) {
 		dMapItem := d.(yaml.MapItem)
 		eDMapItem := eD.(yaml.MapItem)
 
-		resultMapItem.Key = newDMapItem.Key
+		// the decrypted data key can contain the secret key name
+		resultMapItem.Key = newEDMapItem.Key
 
 		resultValue, err := mergeYamlEncodedData(dMapItem.Value, eDMapItem.Value, newDMapItem.Value, newEDMapItem.Value)
 		if err != nil {
@@ -317,6 +318,27 @@ func mergeYamlEncodedData(d, eD, newD, newED interface{}) (interface{}, error) {
 		resultMapItem.Value = resultValue
 
 		return resultMapItem, nil
+	case []interface{}:
+		newDSlice := newD.([]interface{})
+		dSlice := d.([]interface{})
+		resultSlice := make([]interface{}, len(newDSlice))
+
+		for ind := range newDSlice {
+			newEDElm := newED.([]interface{})[ind]
+			if ind >= len(dSlice) {
+				resultSlice[ind] = newEDElm
+				continue
+			}
+
+			result, err := mergeYamlEncodedData(dSlice[ind], eD.([]interface{})[ind], newDSlice[ind], newEDElm)
+			if err != nil {
+				return nil, err
+			}
+
+			resultSlice[ind] = result
+		}
+
+		return resultSlice, nil
 	default:
 		if !reflect.DeepEqual(d, newD) {
 			return newED, nil
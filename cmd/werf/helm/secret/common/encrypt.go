@@ -23,11 +23,12 @@ func SecretFileEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, f
 	return secretEncrypt(ctx, m, options)
 }
 
-func SecretValuesEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, filePath, outputFilePath string) error {
+func SecretValuesEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, filePath, outputFilePath string, withKeyNames bool) error {
 	options := &GenerateOptions{
 		FilePath:       filePath,
 		OutputFilePath: outputFilePath,
 		Values:         true,
+		WithKeyNames:   withKeyNames,
 	}
 
 	return secretEncrypt(ctx, m, options)
@@ -63,7 +64,12 @@ func secretEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, optio
 		return ExpectedFilePathOrPipeError()
 	}
 
-	if options.Values {
+	if options.Values && options.WithKeyNames {
+		encodedData, err = encoder.EncryptYamlDataWithKeyNames(data)
+		if err != nil {
+			return err
+		}
+	} else if options.Values {
 		encodedData, err = encoder.EncryptYamlData(data)
 		if err != nil {
 			return err
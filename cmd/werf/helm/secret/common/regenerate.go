@@ -0,0 +1,154 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/util"
+)
+
+// RegenerateSecretFiles regenerates and rewrites the chart secret files:
+// * raw secret files in the secret dir (skipped if regenerateFileFunc is nil);
+// * default secret values yaml file secret-values.yaml;
+// * additional secret values yaml files specified with secretValuesPaths.
+// The file is not rewritten if the regenerate function returns nil data.
+func RegenerateSecretFiles(helmChartDir string, secretValuesPaths []string, regenerateFileFunc, regenerateValuesFunc func([]byte) ([]byte, error)) error {
+	var secretFilesPaths []string
+	regeneratedFilesData := map[string][]byte{}
+	secretFilesData := map[string][]byte{}
+	secretValuesFilesData := map[string][]byte{}
+
+	isHelmChartDirExist, err := util.FileExists(helmChartDir)
+	if err != nil {
+		return err
+	}
+
+	if isHelmChartDirExist {
+		defaultSecretValuesPath := filepath.Join(helmChartDir, "secret-values.yaml")
+		isDefaultSecretValuesExist, err := util.FileExists(defaultSecretValuesPath)
+		if err != nil {
+			return err
+		}
+
+		if isDefaultSecretValuesExist {
+			secretValuesPaths = append(secretValuesPaths, defaultSecretValuesPath)
+		}
+
+		secretDirectory := filepath.Join(helmChartDir, "secret")
+		isSecretDirectoryExist, err := util.FileExists(secretDirectory)
+		if err != nil {
+			return err
+		}
+
+		if isSecretDirectoryExist && regenerateFileFunc != nil {
+			err = filepath.Walk(secretDirectory,
+				func(path string, info os.FileInfo, err error) error {
+					if err != nil {
+						return err
+					}
+
+					fileInfo, err := os.Stat(path)
+					if err != nil {
+						return err
+					}
+
+					if !fileInfo.IsDir() {
+						secretFilesPaths = append(secretFilesPaths, path)
+					}
+
+					return nil
+				})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	secretFilesData, err = readFilesToDecode(secretFilesPaths, pwd)
+	if err != nil {
+		return err
+	}
+
+	secretValuesFilesData, err = readFilesToDecode(secretValuesPaths, pwd)
+	if err != nil {
+		return err
+	}
+
+	if err := regenerateSecrets(secretFilesData, regeneratedFilesData, regenerateFileFunc); err != nil {
+		return err
+	}
+
+	if err := regenerateSecrets(secretValuesFilesData, regeneratedFilesData, regenerateValuesFunc); err != nil {
+		return err
+	}
+
+	for filePath, fileData := range regeneratedFilesData {
+		err := logboek.LogProcess(fmt.Sprintf("Saving file %q", filePath)).DoError(func() error {
+			fileData = append(bytes.TrimSpace(fileData), []byte("\n")...)
+			return ioutil.WriteFile(filePath, fileData, 0644)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func regenerateSecrets(filesData, regeneratedFilesData map[string][]byte, regenerateFunc func([]byte) ([]byte, error)) error {
+	for filePath, fileData := range filesData {
+		err := logboek.LogProcess(fmt.Sprintf("Regenerating file %q", filePath)).
+			DoError(func() error {
+				resultData, err := regenerateFunc(fileData)
+				if err != nil {
+					return err
+				}
+
+				if resultData == nil {
+					return nil
+				}
+
+				regeneratedFilesData[filePath] = resultData
+
+				return nil
+			})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readFilesToDecode(filePaths []string, pwd string) (map[string][]byte, error) {
+	filesData := map[string][]byte{}
+	for _, filePath := range filePaths {
+		fileData, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		if filepath.IsAbs(filePath) {
+			filePath, err = filepath.Rel(pwd, filePath)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		filesData[filePath] = bytes.TrimSpace(fileData)
+	}
+
+	return filesData, nil
+}
@@ -0,0 +1,52 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/true_git"
+)
+
+// GetSecretsManager returns the secrets manager with the secret key provider configured in the project werf.yaml,
+// the static secret key is used outside of the git work tree or if there is no werf.yaml
+func GetSecretsManager(ctx context.Context, cmdData *common.CmdData, workingDir string) (*secrets_manager.SecretsManager, error) {
+	secretKeyConfig, err := getProjectSecretKeyConfig(ctx, cmdData)
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets_manager.NewSecretsManager(workingDir, secrets_manager.SecretsManagerOptions{SecretKey: secretKeyConfig}), nil
+}
+
+func getProjectSecretKeyConfig(ctx context.Context, cmdData *common.CmdData) (config.MetaSecretKey, error) {
+	if _, err := common.GetGitWorkTree(cmdData); err != nil {
+		if *cmdData.GitWorkTree != "" {
+			return config.MetaSecretKey{}, err
+		}
+
+		return config.MetaSecretKey{}, nil
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *cmdData.LogVerbose || *cmdData.LogDebug}); err != nil {
+		return config.MetaSecretKey{}, err
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(cmdData)
+	if err != nil {
+		return config.MetaSecretKey{}, err
+	}
+
+	werfConfig, err := common.GetOptionalWerfConfig(ctx, cmdData, giterminismManager, common.GetWerfConfigOptions(cmdData, false))
+	if err != nil {
+		return config.MetaSecretKey{}, fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	if werfConfig == nil {
+		return config.MetaSecretKey{}, nil
+	}
+
+	return werfConfig.Meta.SecretKey, nil
+}
@@ -56,6 +56,12 @@ Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file`),
 	common.SetupTmpDir(&commonCmdData, cmd)
 	common.SetupHomeDir(&commonCmdData, cmd)
 
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
 	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
@@ -80,7 +86,12 @@ func runSecretDecrypt(ctx context.Context) error {
 
 	workingDir := common.GetWorkingDir(&commonCmdData)
 
-	return secretDecrypt(ctx, secrets_manager.NewSecretsManager(workingDir, secrets_manager.SecretsManagerOptions{}))
+	secretsManager, err := secret_common.GetSecretsManager(ctx, &commonCmdData, workingDir)
+	if err != nil {
+		return err
+	}
+
+	return secretDecrypt(ctx, secretsManager)
 }
 
 func secretDecrypt(ctx context.Context, m *secrets_manager.SecretsManager) error {
@@ -0,0 +1,170 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var cmdData struct {
+	Generate      bool
+	SecretKeyName string
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "encrypt-secret-key",
+		DisableFlagsInUseLine: true,
+		Short:                 "Encrypt secret key for the recipients of the secret key provider",
+		Long: common.GetLongCommandDescription(`Encrypt secret key for the recipients of the secret key provider configured with secretKey directive in werf.yaml and save it into the encrypted key file.
+
+The secret key is taken from the $WERF_SECRET_KEY or .werf_secret_key file, otherwise the existing encrypted key file is decrypted, so the command should be run again to re-encrypt the secret key after the recipients are changed.
+The new secret key can be generated with --generate option.
+The named secret key configured with secretKey.keys directive in werf.yaml is encrypted with --secret-key-name option, the current named key is taken from the $WERF_SECRET_KEY_<NAME> or .werf_secret_key_<name> file.
+
+The age provider requires the identity in $WERF_SECRET_KEY_AGE_IDENTITY or ~/.werf/age_identities file ($WERF_SECRET_KEY_AGE_IDENTITY_FILE) to decrypt the key.
+The pgp provider uses the exported keyrings ~/.gnupg/pubring.gpg and ~/.gnupg/secring.gpg ($WERF_SECRET_KEY_PGP_PUBLIC_KEYRING, $WERF_SECRET_KEY_PGP_SECRET_KEYRING), the private key passphrase can be specified in $WERF_SECRET_KEY_PGP_PASSPHRASE.
+The exec provider runs the helper command with $WERF_SECRET_KEY_ACTION=encrypt and the secret key in stdin.`),
+		Example: `  # Generate the secret key and encrypt it for the recipients
+  $ werf helm secret encrypt-secret-key --generate
+
+  # Re-encrypt the secret key after the new recipient is added to werf.yaml
+  $ werf helm secret encrypt-secret-key
+
+  # Generate the production secret key configured with secretKey.keys.production directive
+  $ werf helm secret encrypt-secret-key --secret-key-name production --generate`,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runEncryptSecretKey(common.BackgroundContext())
+		},
+	}
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&cmdData.Generate, "generate", "", false, "Generate new secret key instead of using the current one")
+	cmd.Flags().StringVarP(&cmdData.SecretKeyName, "secret-key-name", "", "", "Encrypt the named secret key configured with secretKey.keys directive in werf.yaml instead of the default one")
+
+	return cmd
+}
+
+func runEncryptSecretKey(ctx context.Context) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := git_repo.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	secretsManager := secrets_manager.NewSecretsManager(giterminismManager.ProjectDir(), secrets_manager.SecretsManagerOptions{SecretKey: werfConfig.Meta.SecretKey})
+
+	var provider secrets_manager.SecretKeyProvider
+	if cmdData.SecretKeyName != "" {
+		provider, err = secretsManager.GetNamedSecretKeyProvider(cmdData.SecretKeyName)
+	} else {
+		provider, err = secretsManager.GetSecretKeyProvider()
+	}
+	if err != nil {
+		return err
+	}
+
+	envelopeProvider, ok := provider.(secrets_manager.EnvelopeSecretKeyProvider)
+	if !ok {
+		return fmt.Errorf("the %s secret key provider does not store encrypted secret key: configure age, pgp or exec provider with secretKey directive in werf.yaml", provider)
+	}
+
+	var key []byte
+	if cmdData.Generate {
+		key, err = secrets_manager.GenerateSecretKey()
+	} else {
+		key, err = getCurrentSecretKey(ctx, giterminismManager.ProjectDir(), cmdData.SecretKeyName, envelopeProvider)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := secret.NewAesEncoder(key); err != nil {
+		return fmt.Errorf("check encryption key: %s", err)
+	}
+
+	if err := envelopeProvider.EncryptSecretKey(ctx, key); err != nil {
+		return fmt.Errorf("unable to encrypt secret key: %s", err)
+	}
+
+	logboek.Context(ctx).Default().LogFDetails("Secret key encrypted by the %s secret key provider and saved into %s\n", provider, envelopeProvider.EncryptedKeyFilePath())
+
+	return nil
+}
+
+func getCurrentSecretKey(ctx context.Context, projectDir, secretKeyName string, provider secrets_manager.EnvelopeSecretKeyProvider) ([]byte, error) {
+	if secretKeyName != "" {
+		if key, err := secrets_manager.GetRequiredNamedSecretKey(projectDir, secretKeyName); err == nil {
+			return key, nil
+		}
+	} else if key, err := secrets_manager.GetRequiredSecretKey(projectDir); err == nil {
+		return key, nil
+	}
+
+	if provider.EncryptedKeyFilePath() != "" {
+		exist, err := util.FileExists(provider.EncryptedKeyFilePath())
+		if err != nil {
+			return nil, err
+		}
+
+		if exist {
+			return provider.GetSecretKey(ctx)
+		}
+	}
+
+	return nil, fmt.Errorf("secret key not found: specify the current secret key in $WERF_SECRET_KEY or .werf_secret_key file ($WERF_SECRET_KEY_<NAME> or .werf_secret_key_<name> file for the named key) or generate the new one with --generate option")
+}
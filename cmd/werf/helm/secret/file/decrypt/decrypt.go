@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/werf/werf/pkg/deploy/secrets_manager"
-
 	"github.com/spf13/cobra"
 
 	"github.com/werf/werf/cmd/werf/common"
@@ -65,6 +63,12 @@ Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file`),
 	common.SetupTmpDir(&commonCmdData, cmd)
 	common.SetupHomeDir(&commonCmdData, cmd)
 
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
 	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
@@ -89,5 +93,10 @@ func runSecretDecrypt(ctx context.Context, filePath string) error {
 
 	workingDir := common.GetWorkingDir(&commonCmdData)
 
-	return secret_common.SecretFileDecrypt(ctx, secrets_manager.NewSecretsManager(workingDir, secrets_manager.SecretsManagerOptions{}), filePath, CmdData.OutputFilePath)
+	secretsManager, err := secret_common.GetSecretsManager(ctx, &commonCmdData, workingDir)
+	if err != nil {
+		return err
+	}
+
+	return secret_common.SecretFileDecrypt(ctx, secretsManager, filePath, CmdData.OutputFilePath)
 }
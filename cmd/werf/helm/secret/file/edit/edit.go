@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/werf/werf/pkg/deploy/secrets_manager"
-
 	"github.com/spf13/cobra"
 
 	"github.com/werf/werf/cmd/werf/common"
@@ -46,6 +44,12 @@ Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file`),
 	common.SetupTmpDir(&commonCmdData, cmd)
 	common.SetupHomeDir(&commonCmdData, cmd)
 
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
 	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
@@ -68,5 +72,10 @@ func runSecretEdit(ctx context.Context, filePath string) error {
 
 	workingDir := common.GetWorkingDir(&commonCmdData)
 
-	return secret_common.SecretEdit(ctx, secrets_manager.NewSecretsManager(workingDir, secrets_manager.SecretsManagerOptions{}), filePath, false)
+	secretsManager, err := secret_common.GetSecretsManager(ctx, &commonCmdData, workingDir)
+	if err != nil {
+		return err
+	}
+
+	return secret_common.SecretEdit(ctx, secretsManager, filePath, false)
 }
@@ -0,0 +1,140 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	secret_common "github.com/werf/werf/cmd/werf/helm/secret/common"
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "migrate [EXTRA_SECRET_VALUES_FILE_PATH...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Migrate secret files to the authenticated secret format",
+		Long: common.GetLongCommandDescription(`Migrate secret files from the legacy unauthenticated secret format (AES-CBC) to the versioned authenticated one (AES-GCM).
+
+Command will re-encrypt the secrets in the legacy format with the same secret key and rewrite files:
+* standard raw secret files in the .helm/secret folder;
+* standard secret values yaml file .helm/secret-values.yaml;
+* additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params.
+
+The secrets already in the versioned format are left untouched, as well as the secret values encrypted with the named keys which are not available.
+
+The versioned format detects the modification of each secret value, but the values are not bound to their paths in the file,
+so the encrypted values moved or swapped within the file or between the files encrypted with the same key are not detected.
+
+Pay attention, the secrets in the versioned format cannot be decrypted by the previous werf versions, thus all werf installations working with the project should be updated before migration.
+By default werf encrypts new secrets in the legacy format, after migration the versioned format should be enabled with secretKey.useVersionedFormat directive in werf.yaml and the legacy format can be disabled with secretKey.rejectLegacyFormat directive.`),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runMigrate(common.BackgroundContext(), cmd, args...)
+		},
+	}
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runMigrate(ctx context.Context, cmd *cobra.Command, secretValuesPaths ...string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := git_repo.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	helmChartDir, err := common.GetHelmChartDir(werfConfig, giterminismManager)
+	if err != nil {
+		return fmt.Errorf("getting helm chart dir failed: %s", err)
+	}
+
+	secretsManager := secrets_manager.NewSecretsManager(giterminismManager.ProjectDir(), secrets_manager.SecretsManagerOptions{
+		AllowLegacyFormat:  true,
+		UseVersionedFormat: true,
+		SecretKey:          werfConfig.Meta.SecretKey,
+	})
+
+	encoder, err := secretsManager.GetYamlEncoder(ctx)
+	if err != nil {
+		common.PrintHelp(cmd)
+		return err
+	}
+
+	return secret_common.RegenerateSecretFiles(helmChartDir, secretValuesPaths, func(data []byte) ([]byte, error) {
+		if !secret.IsLegacyFormat(data) {
+			return nil, nil
+		}
+
+		decodedData, err := encoder.Decrypt(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return encoder.Encrypt(decodedData)
+	}, func(data []byte) ([]byte, error) {
+		resultData, migratedValues, err := encoder.MigrateYamlData(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if migratedValues == 0 {
+			return nil, nil
+		}
+
+		logboek.Context(ctx).Default().LogFDetails("Migrated %d secret values\n", migratedValues)
+
+		return resultData, nil
+	})
+}
@@ -1,27 +1,24 @@
 package secret
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
-
-	"github.com/werf/werf/pkg/secret"
 
 	"github.com/spf13/cobra"
 
-	"github.com/werf/logboek"
-
 	"github.com/werf/werf/cmd/werf/common"
+	secret_common "github.com/werf/werf/cmd/werf/helm/secret/common"
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/true_git"
-	"github.com/werf/werf/pkg/util"
 	"github.com/werf/werf/pkg/werf"
 )
 
+var cmdData struct {
+	SecretKeyName       string
+	OldEncryptedKeyFile string
+}
+
 var commonCmdData common.CmdData
 
 func NewCmd() *cobra.Command {
@@ -37,7 +34,14 @@ New key should reside either in the $WERF_SECRET_KEY or .werf_secret_key file.
 Command will extract data with the old key, generate new secret data and rewrite files:
 * standard raw secret files in the .helm/secret folder;
 * standard secret values yaml file .helm/secret-values.yaml;
-* additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params`),
+* additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params.
+
+The named secret key configured with secretKey.keys directive in werf.yaml can be rotated with --secret-key-name option:
+only the secret values encrypted with the named key are regenerated, other values and raw secret files are left untouched.
+The same way the values encrypted with the named keys are left untouched when the default secret key is rotated.
+
+The old named key should be specified in the $WERF_OLD_SECRET_KEY_<NAME>. For the age, pgp and exec secret key providers
+the old key can be decrypted by the same provider from the encrypted key file which was used before the rotation (--old-encrypted-key-file option).`),
 		Annotations: map[string]string{
 			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfOldSecretKey),
 		},
@@ -58,12 +62,16 @@ Command will extract data with the old key, generate new secret data and rewrite
 	common.SetupGitWorkTree(&commonCmdData, cmd)
 	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
 	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
 	common.SetupEnvironment(&commonCmdData, cmd)
 
 	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
+	cmd.Flags().StringVarP(&cmdData.SecretKeyName, "secret-key-name", "", "", "Rotate the named secret key configured with secretKey.keys directive in werf.yaml instead of the default one")
+	cmd.Flags().StringVarP(&cmdData.OldEncryptedKeyFile, "old-encrypted-key-file", "", "", "The encrypted key file of the named secret key before the rotation, the old key is decrypted from the file by the secret key provider (age, pgp or exec) if $WERF_OLD_SECRET_KEY_<NAME> is not specified")
+
 	return cmd
 }
 
@@ -99,7 +107,13 @@ func runRotateSecretKey(ctx context.Context, cmd *cobra.Command, secretValuesPat
 		return fmt.Errorf("getting helm chart dir failed: %s", err)
 	}
 
-	secretsManager := secrets_manager.NewSecretsManager(giterminismManager.ProjectDir(), secrets_manager.SecretsManagerOptions{})
+	secretsManager := secrets_manager.NewSecretsManager(giterminismManager.ProjectDir(), secrets_manager.SecretsManagerOptions{SecretKey: werfConfig.Meta.SecretKey})
+
+	if cmdData.SecretKeyName != "" {
+		if _, err := secretsManager.GetNamedSecretKeyProvider(cmdData.SecretKeyName); err != nil {
+			return err
+		}
+	}
 
 	newEncoder, err := secretsManager.GetYamlEncoder(ctx)
 	if err != nil {
@@ -107,148 +121,36 @@ func runRotateSecretKey(ctx context.Context, cmd *cobra.Command, secretValuesPat
 		return err
 	}
 
-	oldEncoder, err := secretsManager.GetYamlEncoderForOldKey(ctx)
-	if err != nil {
+	if cmdData.OldEncryptedKeyFile != "" && cmdData.SecretKeyName == "" {
 		common.PrintHelp(cmd)
-		return err
+		return fmt.Errorf("--old-encrypted-key-file can be used only with --secret-key-name")
 	}
 
-	return secretsRegenerate(newEncoder, oldEncoder, helmChartDir, secretValuesPaths...)
-}
-
-func secretsRegenerate(newEncoder, oldEncoder *secret.YamlEncoder, helmChartDir string, secretValuesPaths ...string) error {
-	var secretFilesPaths []string
-	regeneratedFilesData := map[string][]byte{}
-	secretFilesData := map[string][]byte{}
-	secretValuesFilesData := map[string][]byte{}
-
-	isHelmChartDirExist, err := util.FileExists(helmChartDir)
+	oldEncoder, err := secretsManager.GetYamlEncoderForOldKey(ctx, cmdData.SecretKeyName, cmdData.OldEncryptedKeyFile)
 	if err != nil {
+		common.PrintHelp(cmd)
 		return err
 	}
 
-	if isHelmChartDirExist {
-		defaultSecretValuesPath := filepath.Join(helmChartDir, "secret-values.yaml")
-		isDefaultSecretValuesExist, err := util.FileExists(defaultSecretValuesPath)
-		if err != nil {
-			return err
-		}
-
-		if isDefaultSecretValuesExist {
-			secretValuesPaths = append(secretValuesPaths, defaultSecretValuesPath)
-		}
-
-		secretDirectory := filepath.Join(helmChartDir, "secret")
-		isSecretDirectoryExist, err := util.FileExists(secretDirectory)
-		if err != nil {
-			return err
-		}
-
-		if isSecretDirectoryExist {
-			err = filepath.Walk(secretDirectory,
-				func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-
-					fileInfo, err := os.Stat(path)
-					if err != nil {
-						return err
-					}
-
-					if !fileInfo.IsDir() {
-						secretFilesPaths = append(secretFilesPaths, path)
-					}
-
-					return nil
-				})
+	// raw secret files are always encrypted with the default secret key
+	var regenerateFileFunc func([]byte) ([]byte, error)
+	if cmdData.SecretKeyName == "" {
+		regenerateFileFunc = func(data []byte) ([]byte, error) {
+			decodedData, err := oldEncoder.Decrypt(data)
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("check old encryption key and file data: %s", err)
 			}
-		}
-	}
-
-	pwd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
 
-	secretFilesData, err = readFilesToDecode(secretFilesPaths, pwd)
-	if err != nil {
-		return err
-	}
-
-	secretValuesFilesData, err = readFilesToDecode(secretValuesPaths, pwd)
-	if err != nil {
-		return err
-	}
-
-	if err := regenerateSecrets(secretFilesData, regeneratedFilesData, oldEncoder.Decrypt, newEncoder.Encrypt); err != nil {
-		return err
-	}
-
-	if err := regenerateSecrets(secretValuesFilesData, regeneratedFilesData, oldEncoder.DecryptYamlData, newEncoder.EncryptYamlData); err != nil {
-		return err
-	}
-
-	for filePath, fileData := range regeneratedFilesData {
-		err := logboek.LogProcess(fmt.Sprintf("Saving file %q", filePath)).DoError(func() error {
-			fileData = append(bytes.TrimSpace(fileData), []byte("\n")...)
-			return ioutil.WriteFile(filePath, fileData, 0644)
-		})
-
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func regenerateSecrets(filesData, regeneratedFilesData map[string][]byte, decodeFunc, encodeFunc func([]byte) ([]byte, error)) error {
-	for filePath, fileData := range filesData {
-		err := logboek.LogProcess(fmt.Sprintf("Regenerating file %q", filePath)).
-			DoError(func() error {
-				data, err := decodeFunc(fileData)
-				if err != nil {
-					return fmt.Errorf("check old encryption key and file data: %s", err)
-				}
-
-				resultData, err := encodeFunc(data)
-				if err != nil {
-					return err
-				}
-
-				regeneratedFilesData[filePath] = resultData
-
-				return nil
-			})
-
-		if err != nil {
-			return err
+			return newEncoder.Encrypt(decodedData)
 		}
 	}
 
-	return nil
-}
-
-func readFilesToDecode(filePaths []string, pwd string) (map[string][]byte, error) {
-	filesData := map[string][]byte{}
-	for _, filePath := range filePaths {
-		fileData, err := ioutil.ReadFile(filePath)
+	return secret_common.RegenerateSecretFiles(helmChartDir, secretValuesPaths, regenerateFileFunc, func(data []byte) ([]byte, error) {
+		resultData, err := newEncoder.ReencryptYamlData(data, cmdData.SecretKeyName, oldEncoder)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("check old encryption key and file data: %s", err)
 		}
 
-		if filepath.IsAbs(filePath) {
-			filePath, err = filepath.Rel(pwd, filePath)
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		filesData[filePath] = bytes.TrimSpace(fileData)
-	}
-
-	return filesData, nil
+		return resultData, nil
+	})
 }
@@ -0,0 +1,239 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	secret_common "github.com/werf/werf/cmd/werf/helm/secret/common"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var cmdData struct {
+	Rev        string
+	ShowValues bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "diff FILE_PATH",
+		DisableFlagsInUseLine: true,
+		Short:                 "Show changed secret values between git revisions",
+		Long: common.GetLongCommandDescription(`Show the secret values keys which were added (+), removed (-) or changed (~) between git revisions of the secret values file.
+
+The values are compared by the decrypted data, so the values re-encrypted without changes are not shown.
+The decrypted values are shown only with --show-values option.
+
+By default the file in the HEAD commit is compared with the file in the work tree.
+The revisions can be specified with --rev option: REV compares the revision with the work tree, REV_A..REV_B compares two revisions.
+Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file`),
+		Example: `  # Show changes which are not committed yet
+  $ werf helm secret values diff .helm/secret-values.yaml
+  ~ mysql.password
+  + mysql.user
+
+  # Review the changes of the branch
+  $ werf helm secret values diff .helm/secret-values.yaml --rev main..feature
+
+  # Show the decrypted values
+  $ werf helm secret values diff .helm/secret-values.yaml --rev HEAD~1 --show-values
+  ~ mysql.password: "root" -> "s3cr3t"`,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if len(args) != 1 {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("requires exactly one FILE_PATH argument")
+			}
+
+			return runSecretValuesDiff(common.BackgroundContext(), args[0])
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.Rev, "rev", "", "", "Compare the file in the git revision REV with the work tree or in two revisions REV_A..REV_B (default HEAD)")
+	cmd.Flags().BoolVarP(&cmdData.ShowValues, "show-values", "", false, "Show the decrypted values of the changed keys")
+
+	return cmd
+}
+
+func runSecretValuesDiff(ctx context.Context, filePath string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := git_repo.Init(); err != nil {
+		return err
+	}
+
+	workingDir := common.GetWorkingDir(&commonCmdData)
+
+	secretsManager, err := secret_common.GetSecretsManager(ctx, &commonCmdData, workingDir)
+	if err != nil {
+		return err
+	}
+
+	oldRev, newRev := "HEAD", ""
+	if cmdData.Rev != "" {
+		revs := strings.SplitN(cmdData.Rev, "..", 2)
+		oldRev = revs[0]
+		if len(revs) == 2 {
+			newRev = revs[1]
+		}
+	}
+
+	oldData, err := readRevisionFile(ctx, oldRev, filePath)
+	if err != nil {
+		return err
+	}
+
+	var newData []byte
+	if newRev != "" {
+		newData, err = readRevisionFile(ctx, newRev, filePath)
+	} else {
+		newData, err = readWorkTreeFile(filePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	encoder, err := secretsManager.GetYamlEncoder(ctx)
+	if err != nil {
+		return err
+	}
+
+	changes, err := encoder.DiffYamlData(oldData, newData)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		fmt.Println(formatChange(change, cmdData.ShowValues))
+	}
+
+	return nil
+}
+
+func readRevisionFile(ctx context.Context, rev, filePath string) ([]byte, error) {
+	workTreeDir, err := common.GetGitWorkTree(&commonCmdData)
+	if err != nil {
+		return nil, err
+	}
+
+	localGitRepo, err := git_repo.OpenLocalRepo("own", workTreeDir, git_repo.OpenLocalRepoOptions{Revision: rev})
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := localGitRepo.HeadCommit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	relPath, err := getWorkTreeRelPath(workTreeDir, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if exist, err := localGitRepo.IsCommitFileExists(ctx, commit, relPath); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, nil
+	}
+
+	return localGitRepo.ReadCommitFile(ctx, commit, relPath)
+}
+
+func readWorkTreeFile(filePath string) ([]byte, error) {
+	if exist, err := util.FileExists(filePath); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, nil
+	}
+
+	return ioutil.ReadFile(filePath)
+}
+
+func getWorkTreeRelPath(workTreeDir, filePath string) (string, error) {
+	absWorkTreeDir, err := filepath.Abs(workTreeDir)
+	if err != nil {
+		return "", err
+	}
+
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	relPath, err := filepath.Rel(absWorkTreeDir, absFilePath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return "", fmt.Errorf("file %q is not in the git work tree %q", filePath, workTreeDir)
+	}
+
+	return filepath.ToSlash(relPath), nil
+}
+
+func formatChange(change *secret.YamlValueChange, showValues bool) string {
+	var sign string
+	switch change.Type {
+	case secret.YamlValueAdded:
+		sign = "+"
+	case secret.YamlValueRemoved:
+		sign = "-"
+	default:
+		sign = "~"
+	}
+
+	if !showValues {
+		return fmt.Sprintf("%s %s", sign, change.Path)
+	}
+
+	formatValue := func(value *string) string {
+		if value == nil {
+			return "<not decrypted>"
+		}
+		return fmt.Sprintf("%q", *value)
+	}
+
+	switch change.Type {
+	case secret.YamlValueAdded:
+		return fmt.Sprintf("%s %s: %s", sign, change.Path, formatValue(change.NewValue))
+	case secret.YamlValueRemoved:
+		return fmt.Sprintf("%s %s: %s", sign, change.Path, formatValue(change.OldValue))
+	default:
+		return fmt.Sprintf("%s %s: %s -> %s", sign, change.Path, formatValue(change.OldValue), formatValue(change.NewValue))
+	}
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/werf/werf/pkg/deploy/secrets_manager"
-
 	"github.com/spf13/cobra"
 
 	"github.com/werf/werf/cmd/werf/common"
@@ -17,6 +15,7 @@ import (
 
 var cmdData struct {
 	OutputFilePath string
+	WithKeyNames   bool
 }
 
 var commonCmdData common.CmdData
@@ -27,12 +26,19 @@ func NewCmd() *cobra.Command {
 		DisableFlagsInUseLine: true,
 		Short:                 "Encrypt values file data",
 		Long: common.GetLongCommandDescription(`Encrypt data from FILE_PATH or pipe.
-Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file`),
+Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file.
+
+With --with-key-names option the map items with the @NAME key suffix are encrypted with the named secret key NAME
+configured with secretKey.keys directive in werf.yaml, as in the output of werf helm secret values decrypt command`),
 		Annotations: map[string]string{
 			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
 		},
 		Example: `  # Encrypt and save result in file
-  $ werf helm secret values encrypt test.yaml -o .helm/secret-values.yaml`,
+  $ werf helm secret values encrypt test.yaml -o .helm/secret-values.yaml
+
+  # Encrypt the values decrypted with the named secret keys
+  $ werf helm secret values decrypt .helm/secret-values.yaml > values.yaml
+  $ werf helm secret values encrypt --with-key-names values.yaml -o .helm/secret-values.yaml`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
 				common.PrintHelp(cmd)
@@ -61,11 +67,18 @@ Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file`),
 	common.SetupTmpDir(&commonCmdData, cmd)
 	common.SetupHomeDir(&commonCmdData, cmd)
 
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
 	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
 	cmd.Flags().StringVarP(&cmdData.OutputFilePath, "output-file-path", "o", "", "Write to file instead of stdout")
+	cmd.Flags().BoolVarP(&cmdData.WithKeyNames, "with-key-names", "", false, "Encrypt the map items with the @NAME key suffix with the named secret key NAME")
 
 	return cmd
 }
@@ -85,5 +98,10 @@ func runSecretEncrypt(ctx context.Context, filePath string) error {
 
 	workingDir := common.GetWorkingDir(&commonCmdData)
 
-	return secret_common.SecretValuesEncrypt(ctx, secrets_manager.NewSecretsManager(workingDir, secrets_manager.SecretsManagerOptions{}), filePath, cmdData.OutputFilePath)
+	secretsManager, err := secret_common.GetSecretsManager(ctx, &commonCmdData, workingDir)
+	if err != nil {
+		return err
+	}
+
+	return secret_common.SecretValuesEncrypt(ctx, secretsManager, filePath, cmdData.OutputFilePath, cmdData.WithKeyNames)
 }
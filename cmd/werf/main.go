@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/docker/docker/pkg/reexec"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -37,22 +38,34 @@ import (
 	bundle_export "github.com/werf/werf/cmd/werf/bundle/export"
 	bundle_publish "github.com/werf/werf/cmd/werf/bundle/publish"
 
+	config_lint "github.com/werf/werf/cmd/werf/config/lint"
 	config_list "github.com/werf/werf/cmd/werf/config/list"
 	config_render "github.com/werf/werf/cmd/werf/config/render"
+	config_schema "github.com/werf/werf/cmd/werf/config/schema"
+	giterminism_explain "github.com/werf/werf/cmd/werf/giterminism/explain"
 	"github.com/werf/werf/cmd/werf/render"
 
 	"github.com/werf/werf/cmd/werf/completion"
 	"github.com/werf/werf/cmd/werf/docs"
 	"github.com/werf/werf/cmd/werf/version"
 
+	stage_diff_digest "github.com/werf/werf/cmd/werf/stage/diff_digest"
+	stage_graph "github.com/werf/werf/cmd/werf/stage/graph"
 	stage_image "github.com/werf/werf/cmd/werf/stage/image"
+	stage_sync "github.com/werf/werf/cmd/werf/stage/sync"
 
 	"github.com/werf/werf/cmd/werf/common"
 	"github.com/werf/werf/cmd/werf/common/templates"
 	"github.com/werf/werf/pkg/process_exterminator"
+	"github.com/werf/werf/pkg/tracing"
 )
 
 func main() {
+	// the werf binary is used as the localhost runtime container init process
+	if reexec.Init() {
+		return
+	}
+
 	common.EnableTerminationSignalsTrap()
 	log.SetOutput(logboek.ProxyOutStream())
 	logrus.StandardLogger().SetOutput(logboek.ProxyOutStream())
@@ -63,9 +76,23 @@ func main() {
 
 	rootCmd := constructRootCmd()
 
+	if err := tracing.Init(tracingRootSpanName(rootCmd)); err != nil {
+		common.TerminateWithError(fmt.Sprintf("tracing initialization failed: %s", err), 1)
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		common.TerminateWithError(err.Error(), 1)
 	}
+
+	common.ShutdownTracing()
+}
+
+func tracingRootSpanName(rootCmd *cobra.Command) string {
+	if cmd, _, err := rootCmd.Find(os.Args[1:]); err == nil {
+		return cmd.CommandPath()
+	}
+
+	return rootCmd.Name()
 }
 
 func constructRootCmd() *cobra.Command {
@@ -115,6 +142,7 @@ Find more information at https://werf.io`),
 			Message: "Low-level management commands",
 			Commands: []*cobra.Command{
 				configCmd(),
+				giterminismCmd(),
 				managedImagesCmd(),
 				hostCmd(),
 				helm.NewCmd(),
@@ -176,6 +204,20 @@ func configCmd() *cobra.Command {
 	cmd.AddCommand(
 		config_render.NewCmd(),
 		config_list.NewCmd(),
+		config_lint.NewCmd(),
+		config_schema.NewCmd(),
+	)
+
+	return cmd
+}
+
+func giterminismCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "giterminism",
+		Short: "Work with giterminism restrictions of the project",
+	}
+	cmd.AddCommand(
+		giterminism_explain.NewCmd(),
 	)
 
 	return cmd
@@ -202,6 +244,9 @@ func stageCmd() *cobra.Command {
 	}
 	cmd.AddCommand(
 		stage_image.NewCmd(),
+		stage_graph.NewCmd(),
+		stage_diff_digest.NewCmd(),
+		stage_sync.NewCmd(),
 	)
 
 	return cmd
@@ -0,0 +1,337 @@
+package diff_digest
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/logboek/pkg/level"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/build"
+	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/giterminism_manager"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/ssh_agent"
+	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/tmp_manager"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var cmdData struct {
+	FromCommit string
+	ToCommit   string
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff-digest [options] IMAGE_NAME [STAGE_NAME]",
+		Short: "Explain why the stage digests of the image changed",
+		Long: common.GetLongCommandDescription(`Explain why the stage digests of the image changed and print exactly which inputs of the digest calculation differ.
+
+By default the current stage digests are compared with the digests of the last stages of the image stored in the stages storage. The stages must be built by werf which records the digest inputs into the stage image labels.
+
+With --from-commit the stage digests calculated for the specified commit are compared with the stage digests calculated for the --to-commit (or the current state of the project if not specified).
+
+Stages which cannot be calculated without building of the previous stages or dependency images are reported and skipped.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.DisableOptionsInUseLineAnno: "1",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logboek.SetAcceptedLevel(level.Error)
+
+			if len(args) < 1 || len(args) > 2 {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("IMAGE_NAME and optional STAGE_NAME arguments required")
+			}
+
+			var stageName string
+			if len(args) == 2 {
+				stageName = args[1]
+			}
+
+			return run(args[0], stageName)
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupSecondaryStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read and pull images from the specified stages storage")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	common.SetupDryRun(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	common.SetupVirtualMerge(&commonCmdData, cmd)
+	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
+	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
+
+	common.SetupBuildkitAddress(&commonCmdData, cmd)
+	common.SetupLayersCacheRepo(&commonCmdData, cmd)
+	common.SetupContainerRuntime(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.FromCommit, "from-commit", "", os.Getenv("WERF_FROM_COMMIT"), "Calculate the old stage digests for the specified commit instead of using the last stored stages (default $WERF_FROM_COMMIT)")
+	cmd.Flags().StringVarP(&cmdData.ToCommit, "to-commit", "", os.Getenv("WERF_TO_COMMIT"), "Calculate the new stage digests for the specified commit instead of the current state of the project (default $WERF_TO_COMMIT)")
+
+	return cmd
+}
+
+func run(imageName, stageName string) error {
+	ctx := common.BackgroundContext()
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := git_repo.Init(); err != nil {
+		return err
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	ctxWithDockerCli, err := docker.NewContext(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = ctxWithDockerCli
+
+	giterminismManager, err := common.GetGiterminismManagerForRevision(&commonCmdData, cmdData.ToCommit)
+	if err != nil {
+		return err
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, giterminismManager.ProjectDir())
+
+	if err := ssh_agent.Init(ctx, *commonCmdData.SSHKeys); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logboek.Warn().LogF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	if cmdData.FromCommit != "" {
+		fromGiterminismManager, err := common.GetGiterminismManagerForRevision(&commonCmdData, cmdData.FromCommit)
+		if err != nil {
+			return err
+		}
+
+		var oldStages []*build.GraphStage
+		if err := withImageGraph(ctx, fromGiterminismManager, imageName, func(_ *build.Conveyor, graphImage *build.GraphImage) error {
+			oldStages = graphImage.Stages
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to calculate stages for the commit %s: %s", cmdData.FromCommit, err)
+		}
+
+		return withImageGraph(ctx, giterminismManager, imageName, func(_ *build.Conveyor, graphImage *build.GraphImage) error {
+			for _, stg := range selectStages(graphImage.Stages, stageName) {
+				var oldStageInputs *build.StageDigestInputs
+				if oldStage := findStage(oldStages, stg.Name); oldStage != nil && oldStage.Digest != "" {
+					oldStageInputs = &build.StageDigestInputs{ImageName: imageName, StageName: oldStage.Name, Digest: oldStage.Digest, Inputs: oldStage.Inputs}
+				}
+
+				printStageDiff(stg, oldStageInputs, fmt.Sprintf("commit %s", cmdData.FromCommit))
+			}
+
+			return nil
+		})
+	}
+
+	return withImageGraph(ctx, giterminismManager, imageName, func(c *build.Conveyor, graphImage *build.GraphImage) error {
+		stages := selectStages(graphImage.Stages, stageName)
+
+		currentDigestByStageName := map[string]string{}
+		for _, stg := range stages {
+			if stg.Digest != "" {
+				currentDigestByStageName[stg.Name] = stg.Digest
+			}
+		}
+
+		storedInputsByStageName, err := c.GetLastStoredStagesDigestInputs(ctx, imageName, currentDigestByStageName)
+		if err != nil {
+			return err
+		}
+
+		for _, stg := range stages {
+			printStageDiff(stg, storedInputsByStageName[stg.Name], "last stored stage")
+		}
+
+		return nil
+	})
+}
+
+func withImageGraph(ctx context.Context, giterminismManager giterminism_manager.Interface, imageName string, f func(c *build.Conveyor, graphImage *build.GraphImage) error) error {
+	werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	if !werfConfig.HasImageOrArtifact(imageName) {
+		return fmt.Errorf("specified image %s is not defined in werf.yaml", logging.ImageLogName(imageName, false))
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	containerRuntime, err := common.GetContainerRuntime(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	stagesStorageAddress := common.GetOptionalStagesStorageAddress(&commonCmdData)
+	stagesStorage, err := common.GetStagesStorage(stagesStorageAddress, containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
+	if err != nil {
+		return err
+	}
+	stagesStorageCache, err := common.GetStagesStorageCache(synchronization)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return err
+	}
+	secondaryStagesStorageList, err := common.GetSecondaryStagesStorageList(stagesStorage, containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	storageManager := manager.NewStorageManager(projectName, stagesStorage, secondaryStagesStorageList, storageLockManager, stagesStorageCache)
+
+	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, giterminismManager, []string{imageName}, giterminismManager.ProjectDir(), projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, common.GetConveyorOptions(&commonCmdData))
+	defer conveyorWithRetry.Terminate()
+
+	return conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
+		graph, err := c.GetStagesGraph(ctx)
+		if err != nil {
+			return err
+		}
+
+		graphImage := graph.GetImage(imageName)
+		if graphImage == nil {
+			return fmt.Errorf("image %s not found in the stages graph", logging.ImageLogName(imageName, false))
+		}
+
+		return f(c, graphImage)
+	})
+}
+
+func selectStages(stages []*build.GraphStage, stageName string) []*build.GraphStage {
+	if stageName == "" {
+		return stages
+	}
+
+	if stg := findStage(stages, stageName); stg != nil {
+		return []*build.GraphStage{stg}
+	}
+
+	return nil
+}
+
+func findStage(stages []*build.GraphStage, stageName string) *build.GraphStage {
+	for _, stg := range stages {
+		if stg.Name == stageName {
+			return stg
+		}
+	}
+
+	return nil
+}
+
+func printStageDiff(stg *build.GraphStage, oldStageInputs *build.StageDigestInputs, oldDescription string) {
+	switch {
+	case stg.Status == build.GraphStageEmpty:
+		return
+	case stg.Digest == "":
+		fmt.Printf("Stage %s: digest cannot be calculated without building of the previous stages\n", stg.Name)
+		return
+	case oldStageInputs == nil:
+		fmt.Printf("Stage %s: digest %s, nothing to compare with (%s not found)\n", stg.Name, stg.Digest, oldDescription)
+		return
+	case oldStageInputs.Digest == stg.Digest:
+		fmt.Printf("Stage %s: digest %s not changed\n", stg.Name, stg.Digest)
+		return
+	}
+
+	fmt.Printf("Stage %s: digest changed %s -> %s (compared with %s)\n", stg.Name, oldStageInputs.Digest, stg.Digest, oldDescription)
+
+	diffs := stage.DiffDependenciesInputs(oldStageInputs.Inputs, stg.Inputs)
+	if len(diffs) == 0 {
+		fmt.Printf("  inputs are the same, the digest calculation algorithm has been changed\n")
+		return
+	}
+
+	for _, diff := range diffs {
+		switch {
+		case diff.IsAdded:
+			fmt.Printf("  + %s: %q\n", diff.Name, diff.NewValue)
+		case diff.IsRemoved:
+			fmt.Printf("  - %s: %q\n", diff.Name, diff.OldValue)
+		default:
+			fmt.Printf("  ~ %s: %q -> %q\n", diff.Name, diff.OldValue, diff.NewValue)
+		}
+	}
+}
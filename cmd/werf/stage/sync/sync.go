@@ -0,0 +1,191 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+	"github.com/werf/werf/pkg/werf/global_warnings"
+)
+
+var cmdData struct {
+	FromRepo string
+	ToRepo   string
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "sync",
+		DisableFlagsInUseLine: true,
+		Short:                 "Copy project stages and related metadata from one repo to another",
+		Long: common.GetLongCommandDescription(`Copy project stages, stages last use, image metadata, import metadata and managed images from one repo to another.
+
+Stages and records which already exist in the destination repo are skipped, so an interrupted sync can be resumed by running the command again.
+
+Multi-platform image indexes are not copied: they reference the manifests of the source repo, run werf build with the destination repo to publish them again.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defer global_warnings.PrintGlobalWarnings(common.BackgroundContext())
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+			common.LogVersion()
+
+			if cmdData.FromRepo == "" || cmdData.ToRepo == "" {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("both --from-repo and --to-repo should be specified")
+			}
+
+			return common.LogRunningTime(runSync)
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigParams(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.FromRepo, "from-repo", "", os.Getenv("WERF_FROM_REPO"), fmt.Sprintf("Source Docker Repo or %sPATH to copy stages from (default $WERF_FROM_REPO)", storage.OCILayoutStorageAddressPrefix))
+	cmd.Flags().StringVarP(&cmdData.ToRepo, "to-repo", "", os.Getenv("WERF_TO_REPO"), fmt.Sprintf("Destination Docker Repo or %sPATH to copy stages to (default $WERF_TO_REPO)", storage.OCILayoutStorageAddressPrefix))
+	common.SetupCommonRepoData(&commonCmdData, cmd)
+	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultCleanupParallelTasksLimit)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read images from the source repo and push images into the destination repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	common.SetupContainerRuntime(&commonCmdData, cmd)
+
+	common.SetupDryRun(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runSync() error {
+	ctx := common.BackgroundContext()
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := git_repo.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, giterminismManager.ProjectDir())
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	ctxWithDockerCli, err := docker.NewContext(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = ctxWithDockerCli
+
+	werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	logboek.LogOptionalLn()
+
+	containerRuntime, err := common.GetContainerRuntime(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	fromStagesStorage, err := common.GetStagesStorage(cmdData.FromRepo, containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	toStagesStorage, err := common.GetStagesStorage(cmdData.ToRepo, containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, toStagesStorage)
+	if err != nil {
+		return err
+	}
+	stagesStorageCache, err := common.GetStagesStorageCache(synchronization)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return err
+	}
+
+	storageManager := manager.NewStorageManager(projectName, toStagesStorage, nil, storageLockManager, stagesStorageCache)
+
+	if *commonCmdData.Parallel {
+		storageManager.StagesStorageManager.EnableParallel(int(*commonCmdData.ParallelTasksLimit))
+	}
+
+	imagesNames, err := common.GetManagedImagesNames(ctx, projectName, fromStagesStorage, werfConfig)
+	if err != nil {
+		return err
+	}
+	logboek.Debug().LogF("Managed images names: %v\n", imagesNames)
+
+	logboek.LogOptionalLn()
+	return storageManager.SyncStages(ctx, fromStagesStorage, containerRuntime, manager.SyncStagesOptions{
+		ImageNameList: imagesNames,
+		DryRun:        *commonCmdData.DryRun,
+	})
+}
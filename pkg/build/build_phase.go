@@ -26,6 +26,7 @@ import (
 	imagePkg "github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/stapel"
 	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/tracing"
 	"github.com/werf/werf/pkg/util"
 	"github.com/werf/werf/pkg/werf"
 )
@@ -68,7 +69,11 @@ func NewBuildPhase(c *Conveyor, opts BuildPhaseOptions) *BuildPhase {
 	return &BuildPhase{
 		BasePhase:         BasePhase{c},
 		BuildPhaseOptions: opts,
-		ImagesReport:      &ImagesReport{Images: make(map[string]ReportImageRecord)},
+		ImagesReport: &ImagesReport{
+			Images:       make(map[string]ReportImageRecord),
+			Artifacts:    make(map[string]ReportImageRecord),
+			stageRecords: make(map[string][]ReportStageRecord),
+		},
 	}
 }
 
@@ -90,8 +95,11 @@ const (
 type ReportFormat string
 
 type ImagesReport struct {
-	mux    sync.Mutex
-	Images map[string]ReportImageRecord
+	mux       sync.Mutex
+	Images    map[string]ReportImageRecord
+	Artifacts map[string]ReportImageRecord
+
+	stageRecords map[string][]ReportStageRecord
 }
 
 func (report *ImagesReport) SetImageRecord(name string, imageRecord ReportImageRecord) {
@@ -100,6 +108,24 @@ func (report *ImagesReport) SetImageRecord(name string, imageRecord ReportImageR
 	report.Images[name] = imageRecord
 }
 
+func (report *ImagesReport) SetArtifactRecord(name string, artifactRecord ReportImageRecord) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+	report.Artifacts[name] = artifactRecord
+}
+
+func (report *ImagesReport) AddStageRecord(imageName string, stageRecord ReportStageRecord) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+	report.stageRecords[imageName] = append(report.stageRecords[imageName], stageRecord)
+}
+
+func (report *ImagesReport) GetStageRecords(imageName string) []ReportStageRecord {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+	return report.stageRecords[imageName]
+}
+
 func (report *ImagesReport) ToJsonData() ([]byte, error) {
 	report.mux.Lock()
 	defer report.mux.Unlock()
@@ -148,6 +174,35 @@ type ReportImageRecord struct {
 	DockerTag       string
 	DockerImageID   string
 	DockerImageName string
+	Stages          []ReportStageRecord
+	// Platforms is set for the multi-platform image, the image record itself describes the published image index
+	Platforms map[string]ReportImageRecord
+}
+
+const (
+	// ReportStageBuilt is set for the stage built by the current werf invocation
+	ReportStageBuilt ReportStageStatus = "built"
+	// ReportStageCached is set for the stage taken from the stages storage or copied from the secondary stages storage
+	ReportStageCached ReportStageStatus = "cached"
+)
+
+type ReportStageStatus string
+
+type ReportStageRecord struct {
+	Name            string
+	Digest          string
+	StageID         string
+	DockerImageName string
+	Status          ReportStageStatus
+	// StagesStorage is the address of the stages storage the stage was taken from or stored to
+	StagesStorage string
+	// BuildDurationSeconds is zero for the cached stage
+	BuildDurationSeconds float64
+	// Size is the size of the whole stage image, LayerSize is the size added by the stage to the previous stage image
+	Size      int64
+	LayerSize int64
+	// GitCommit is the project repo commit the stage image was built for
+	GitCommit string
 }
 
 func (phase *BuildPhase) Name() string {
@@ -159,23 +214,60 @@ func (phase *BuildPhase) BeforeImages(_ context.Context) error {
 }
 
 func (phase *BuildPhase) AfterImages(ctx context.Context) error {
+	if err := phase.Conveyor.publishImageIndexes(ctx, phase.ShouldBeBuiltMode); err != nil {
+		return err
+	}
+
 	return phase.createReport(ctx)
 }
 
 func (phase *BuildPhase) createReport(ctx context.Context) error {
+	platformRecords := map[string]map[string]ReportImageRecord{}
 	for _, img := range phase.Conveyor.images {
-		if img.isArtifact {
-			continue
-		}
-
 		desc := img.GetLastNonEmptyStage().GetImage().GetStageDescription()
-		phase.ImagesReport.SetImageRecord(img.GetName(), ReportImageRecord{
+		record := ReportImageRecord{
 			WerfImageName:   img.GetName(),
 			DockerRepo:      desc.Info.Repository,
 			DockerTag:       desc.Info.Tag,
 			DockerImageID:   desc.Info.ID,
 			DockerImageName: desc.Info.Name,
-		})
+			Stages:          phase.ImagesReport.GetStageRecords(img.GetPlatformName()),
+		}
+
+		if img.GetPlatform() != "" {
+			if platformRecords[img.GetName()] == nil {
+				platformRecords[img.GetName()] = map[string]ReportImageRecord{}
+			}
+			platformRecords[img.GetName()][img.GetPlatform()] = record
+			continue
+		}
+
+		if img.isArtifact {
+			phase.ImagesReport.SetArtifactRecord(img.GetName(), record)
+		} else {
+			phase.ImagesReport.SetImageRecord(img.GetName(), record)
+		}
+	}
+
+	for imageName, records := range platformRecords {
+		record := ReportImageRecord{
+			WerfImageName: imageName,
+			Platforms:     records,
+		}
+
+		if phase.Conveyor.werfConfig.GetArtifact(imageName) != nil {
+			phase.ImagesReport.SetArtifactRecord(imageName, record)
+			continue
+		}
+
+		if indexName := phase.Conveyor.GetImageIndexName(imageName); indexName != "" {
+			infoGetter := newImageIndexInfoGetter(imageName, indexName)
+			record.DockerRepo = strings.TrimSuffix(indexName, ":"+infoGetter.GetTag())
+			record.DockerTag = infoGetter.GetTag()
+			record.DockerImageName = indexName
+		}
+
+		phase.ImagesReport.SetImageRecord(imageName, record)
 	}
 
 	debugJsonData, err := phase.ImagesReport.ToJsonData()
@@ -315,6 +407,8 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 
 		logboek.Context(ctx).LogOptionalLn()
 
+		phase.addReportStageRecord(img, stg, ReportStageCached, phase.Conveyor.StorageManager.StagesStorage, 0)
+
 		if phase.IntrospectOptions.ImageStageShouldBeIntrospected(img.GetName(), string(stg.Name())) {
 			if err := introspectStage(ctx, stg); err != nil {
 				return err
@@ -324,12 +418,14 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 		return nil
 	}
 
-	foundSuitableSecondaryStage, err := phase.findAndFetchStageFromSecondaryStagesStorage(ctx, img, stg)
+	sourceStagesStorage, err := phase.findAndFetchStageFromSecondaryStagesStorage(ctx, img, stg)
 	if err != nil {
 		return err
 	}
 
-	if !foundSuitableSecondaryStage {
+	if sourceStagesStorage != nil {
+		phase.addReportStageRecord(img, stg, ReportStageCached, sourceStagesStorage, 0)
+	} else {
 		if phase.ShouldBeBuiltMode {
 			phase.printShouldBeBuiltError(ctx, img, stg)
 			return fmt.Errorf("stages required")
@@ -345,9 +441,15 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 		if err := phase.prepareStageInstructions(ctx, img, stg); err != nil {
 			return err
 		}
-		if err := phase.buildStage(ctx, img, stg); err != nil {
+
+		buildStartedAt := time.Now()
+		if err := tracing.Do(ctx, fmt.Sprintf("build stage %s", stg.Name()), []tracing.Attribute{tracing.Attr("werf.stage.digest", stg.GetDigest())}, func(ctx context.Context) error {
+			return phase.buildStage(ctx, img, stg)
+		}); err != nil {
 			return err
 		}
+
+		phase.addReportStageRecord(img, stg, ReportStageBuilt, phase.Conveyor.StorageManager.StagesStorage, time.Since(buildStartedAt))
 	}
 
 	if stg.GetImage().GetStageDescription() == nil {
@@ -360,8 +462,34 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 	return nil
 }
 
-func (phase *BuildPhase) findAndFetchStageFromSecondaryStagesStorage(ctx context.Context, img *Image, stg stage.Interface) (bool, error) {
-	foundSuitableStage := false
+func (phase *BuildPhase) addReportStageRecord(img *Image, stg stage.Interface, status ReportStageStatus, stagesStorage storage.StagesStorage, buildDuration time.Duration) {
+	desc := stg.GetImage().GetStageDescription()
+	if desc == nil {
+		return
+	}
+
+	record := ReportStageRecord{
+		Name:                 string(stg.Name()),
+		Digest:               stg.GetDigest(),
+		DockerImageName:      desc.Info.Name,
+		Status:               status,
+		StagesStorage:        stagesStorage.String(),
+		BuildDurationSeconds: buildDuration.Seconds(),
+		Size:                 desc.Info.Size,
+		LayerSize:            desc.Info.Size - phase.getPrevNonEmptyStageImageSize(),
+		GitCommit:            desc.Info.Labels[image.WerfProjectRepoCommitLabel],
+	}
+
+	if desc.StageID != nil {
+		record.StageID = desc.StageID.String()
+	}
+
+	phase.ImagesReport.AddStageRecord(img.GetPlatformName(), record)
+}
+
+// findAndFetchStageFromSecondaryStagesStorage returns the stages storage the suitable stage has been taken from or nil if there is no suitable stage
+func (phase *BuildPhase) findAndFetchStageFromSecondaryStagesStorage(ctx context.Context, img *Image, stg stage.Interface) (storage.StagesStorage, error) {
+	var sourceStagesStorage storage.StagesStorage
 
 	atomicCopySuitableStageFromSecondaryStagesStorage := func(secondaryStageDesc *image.StageDescription, secondaryStagesStorage storage.StagesStorage) error {
 		// Lock the primary stages storage
@@ -382,6 +510,7 @@ func (phase *BuildPhase) findAndFetchStageFromSecondaryStagesStorage(ctx context
 				i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), stageDesc.Info.Name)
 				i.SetStageDescription(stageDesc)
 				stg.SetImage(i)
+				sourceStagesStorage = phase.Conveyor.StorageManager.StagesStorage
 
 				logboek.Context(ctx).Default().LogFHighlight("Use cache image for %s\n", stg.LogDetailedName())
 				logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), true)
@@ -398,6 +527,7 @@ func (phase *BuildPhase) findAndFetchStageFromSecondaryStagesStorage(ctx context
 					i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), copiedStageDesc.Info.Name)
 					i.SetStageDescription(copiedStageDesc)
 					stg.SetImage(i)
+					sourceStagesStorage = secondaryStagesStorage
 
 					var stageIDs []image.StageID
 					for _, stageDesc := range stages {
@@ -421,21 +551,20 @@ func (phase *BuildPhase) findAndFetchStageFromSecondaryStagesStorage(ctx context
 ScanSecondaryStagesStorageList:
 	for _, secondaryStagesStorage := range phase.Conveyor.StorageManager.SecondaryStagesStorageList {
 		if secondaryStages, err := phase.Conveyor.StorageManager.GetStagesByDigestFromStagesStorage(ctx, stg.LogDetailedName(), stg.GetDigest(), secondaryStagesStorage); err != nil {
-			return false, err
+			return nil, err
 		} else {
 			if secondaryStageDesc, err := phase.Conveyor.StorageManager.SelectSuitableStage(ctx, phase.Conveyor, stg, secondaryStages); err != nil {
-				return false, err
+				return nil, err
 			} else if secondaryStageDesc != nil {
 				if err := atomicCopySuitableStageFromSecondaryStagesStorage(secondaryStageDesc, secondaryStagesStorage); err != nil {
-					return false, fmt.Errorf("unable to copy suitable stage %s from secondary stages storage %s: %s", secondaryStageDesc.StageID.String(), secondaryStagesStorage.String(), err)
+					return nil, fmt.Errorf("unable to copy suitable stage %s from secondary stages storage %s: %s", secondaryStageDesc.StageID.String(), secondaryStagesStorage.String(), err)
 				}
-				foundSuitableStage = true
 				break ScanSecondaryStagesStorageList
 			}
 		}
 	}
 
-	return foundSuitableStage, nil
+	return sourceStagesStorage, nil
 }
 
 func (phase *BuildPhase) fetchBaseImageForStage(ctx context.Context, img *Image, stg stage.Interface) error {
@@ -446,12 +575,80 @@ func (phase *BuildPhase) fetchBaseImageForStage(ctx context.Context, img *Image,
 	} else if stg.Name() == "dockerfile" {
 		return nil
 	} else {
+		if !img.isDockerfileImage {
+			phase.fetchStageFromLayersCache(ctx, phase.StagesIterator.PrevBuiltStage)
+		}
 		return phase.Conveyor.StorageManager.FetchStage(ctx, phase.StagesIterator.PrevBuiltStage)
 	}
 
 	return nil
 }
 
+// fetchStageFromLayersCache restores the stage image on top of the local parent image by fetching only the stage layer.
+// The base image is pulled if it is missing locally, the stage is fetched from the stages storage as usual
+// if the parent stage image or the layers cache record is not available.
+func (phase *BuildPhase) fetchStageFromLayersCache(ctx context.Context, stg stage.Interface) {
+	if phase.Conveyor.LayersCache == nil || stg.GetDependenciesDigest() == "" {
+		return
+	}
+
+	stageImage := castToStageImage(stg.GetImage())
+	if stageImage.FromImage() == nil {
+		return
+	}
+
+	if shouldFetch, err := phase.Conveyor.StorageManager.StagesStorage.ShouldFetchImage(ctx, &container_runtime.DockerImage{Image: stageImage}); err != nil || !shouldFetch {
+		return
+	}
+
+	if err := phase.pullLayersCacheBaseImage(ctx, stageImage.FromImage()); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: unable to pull base image %s for layers cache %s: %s\n", stageImage.FromImage().Name(), phase.Conveyor.LayersCache.String(), err)
+		return
+	}
+
+	if fetched, err := phase.Conveyor.LayersCache.FetchStageLayer(ctx, stg.GetDependenciesDigest(), stageImage.FromImage().Name(), &container_runtime.DockerImage{Image: stageImage}); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: unable to fetch stage %s from layers cache %s: %s\n", stg.LogDetailedName(), phase.Conveyor.LayersCache.String(), err)
+	} else if fetched {
+		logboek.Context(ctx).Default().LogF("Stage %s has been restored from layers cache %s\n", stg.LogDetailedName(), phase.Conveyor.LayersCache.String())
+	}
+}
+
+// pullLayersCacheBaseImage pulls the missing base image, the stage layer could be applied only on top of the local parent image.
+// The parent stage image is not fetched: it costs the same as fetching the stage itself.
+func (phase *BuildPhase) pullLayersCacheBaseImage(ctx context.Context, parentImage *container_runtime.StageImage) error {
+	if desc := parentImage.GetStageDescription(); desc != nil && desc.StageID != nil {
+		return nil
+	}
+
+	if inspect, err := phase.Conveyor.ContainerRuntime.GetImageInspect(ctx, parentImage.Name()); err != nil {
+		return err
+	} else if inspect != nil {
+		return nil
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Pulling base image %s", parentImage.Name()).DoError(func() error {
+		return phase.Conveyor.ContainerRuntime.PullImageFromRegistry(ctx, &container_runtime.DockerImage{Image: parentImage})
+	})
+}
+
+// storeStageToLayersCache is called after the stage lock is released, pushing into the layers cache should not block the concurrent builds
+func (phase *BuildPhase) storeStageToLayersCache(ctx context.Context, img *Image, stg stage.Interface) {
+	if phase.Conveyor.LayersCache == nil || img.isDockerfileImage || stg.GetDependenciesDigest() == "" {
+		return
+	}
+
+	stageImage := castToStageImage(stg.GetImage())
+	if stageImage.FromImage() == nil {
+		return
+	}
+
+	if err := logboek.Context(ctx).Info().LogProcess("Store stage layer into layers cache %s", phase.Conveyor.LayersCache.String()).DoError(func() error {
+		return phase.Conveyor.LayersCache.StoreStageLayer(ctx, stg.GetDependenciesDigest(), stageImage.FromImage().Name(), &container_runtime.DockerImage{Image: stageImage})
+	}); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: unable to store stage %s into layers cache %s: %s\n", stg.LogDetailedName(), phase.Conveyor.LayersCache.String(), err)
+	}
+}
+
 func castToStageImage(img container_runtime.ImageInterface) *container_runtime.StageImage {
 	if img == nil {
 		return nil
@@ -470,7 +667,9 @@ func (phase *BuildPhase) calculateStage(ctx context.Context, img *Image, stg sta
 		return false, nil, err
 	}
 	stg.SetDigest(stageDigest)
+	stg.SetDependenciesDigest(util.Sha3_224Hash(string(stg.Name()), stageDependencies))
 
+	_, lockSpan := tracing.StartSpan(ctx, "lock wait stage digest mutex", tracing.Attr("werf.stage.digest", stg.GetDigest()))
 	logboek.Context(ctx).Info().LogProcessInline("Locking stage %s handling", stg.LogDetailedName()).
 		Options(func(options types.LogProcessInlineOptionsInterface) {
 			if !phase.Conveyor.Parallel {
@@ -478,6 +677,7 @@ func (phase *BuildPhase) calculateStage(ctx context.Context, img *Image, stg sta
 			}
 		}).
 		Do(phase.Conveyor.GetStageDigestMutex(stg.GetDigest()).Lock)
+	lockSpan.End()
 
 	foundSuitableStage := false
 	if stages, err := phase.Conveyor.StorageManager.GetStagesByDigest(ctx, stg.LogDetailedName(), stageDigest); err != nil {
@@ -519,6 +719,14 @@ func (phase *BuildPhase) prepareStageInstructions(ctx context.Context, img *Imag
 		imagePkg.WerfStageContentDigestLabel: stg.GetContentDigest(),
 	}
 
+	stageDigestInputs, err := getStageDigestInputs(ctx, img, stg, phase.StagesIterator.PrevNonEmptyStage, phase.Conveyor)
+	if err != nil {
+		return err
+	}
+	if serviceLabels[imagePkg.WerfStageDigestInputsLabel], err = stageDigestInputs.ToLabelValue(); err != nil {
+		return fmt.Errorf("unable to encode stage %s digest inputs: %s", stg.LogDetailedName(), err)
+	}
+
 	switch stg.(type) {
 	case *stage.DockerfileStage:
 		var buildArgs []string
@@ -550,8 +758,7 @@ func (phase *BuildPhase) prepareStageInstructions(ctx context.Context, img *Imag
 		}
 	}
 
-	err := stg.PrepareImage(ctx, phase.Conveyor, phase.StagesIterator.GetPrevBuiltImage(img, stg), stageImage)
-	if err != nil {
+	if err := stg.PrepareImage(ctx, phase.Conveyor, phase.StagesIterator.GetPrevBuiltImage(img, stg), stageImage); err != nil {
 		return fmt.Errorf("error preparing stage %s: %s", stg.Name(), err)
 	}
 
@@ -559,7 +766,8 @@ func (phase *BuildPhase) prepareStageInstructions(ctx context.Context, img *Imag
 }
 
 func (phase *BuildPhase) buildStage(ctx context.Context, img *Image, stg stage.Interface) error {
-	if !img.isDockerfileImage {
+	// stapel toolchain is prepared by the localhost runtime itself
+	if _, isLocalHostRuntime := phase.Conveyor.ContainerRuntime.(*container_runtime.LocalHostRuntime); !img.isDockerfileImage && !isLocalHostRuntime {
 		_, err := stapel.GetOrCreateContainer(ctx)
 		if err != nil {
 			return fmt.Errorf("get or create stapel container failed: %s", err)
@@ -576,6 +784,7 @@ func (phase *BuildPhase) buildStage(ctx context.Context, img *Image, stg stage.I
 		logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), false)
 	}
 
+	var isNewStageStored bool
 	if err := logboek.Context(ctx).Default().LogProcess("Building stage %s", stg.LogDetailedName()).
 		Options(func(options types.LogProcessOptionsInterface) {
 			options.InfoSectionFunc(infoSectionFunc)
@@ -586,11 +795,16 @@ func (phase *BuildPhase) buildStage(ctx context.Context, img *Image, stg stage.I
 				return fmt.Errorf("%s preRunHook failed: %s", stg.LogDetailedName(), err)
 			}
 
-			return phase.atomicBuildStageImage(ctx, img, stg)
+			isNewStageStored, err = phase.atomicBuildStageImage(ctx, img, stg)
+			return err
 		}); err != nil {
 		return err
 	}
 
+	if isNewStageStored {
+		phase.storeStageToLayersCache(ctx, img, stg)
+	}
+
 	if phase.IntrospectOptions.ImageStageShouldBeIntrospected(img.GetName(), string(stg.Name())) {
 		if err := introspectStage(ctx, stg); err != nil {
 			return err
@@ -600,7 +814,8 @@ func (phase *BuildPhase) buildStage(ctx context.Context, img *Image, stg stage.I
 	return nil
 }
 
-func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image, stg stage.Interface) error {
+// atomicBuildStageImage builds the stage and stores it under the stage lock, returns false if the newly built image is discarded
+func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image, stg stage.Interface) (bool, error) {
 	stageImage := stg.GetImage()
 
 	if v := os.Getenv("WERF_TEST_ATOMIC_STAGE_BUILD__SLEEP_SECONDS_BEFORE_STAGE_BUILD"); v != "" {
@@ -613,7 +828,7 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 	if err := logboek.Context(ctx).Streams().DoErrorWithTag(fmt.Sprintf("%s/%s", img.LogName(), stg.Name()), img.LogTagStyle(), func() error {
 		return stageImage.Build(ctx, phase.ImageBuildOptions)
 	}); err != nil {
-		return fmt.Errorf("failed to build image for stage %s with digest %s: %s", stg.Name(), stg.GetDigest(), err)
+		return false, fmt.Errorf("failed to build image for stage %s with digest %s: %s", stg.Name(), stg.GetDigest(), err)
 	}
 
 	if v := os.Getenv("WERF_TEST_ATOMIC_STAGE_BUILD__SLEEP_SECONDS_BEFORE_STAGE_SAVE"); v != "" {
@@ -624,16 +839,16 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 	}
 
 	if lock, err := phase.Conveyor.StorageLockManager.LockStage(ctx, phase.Conveyor.projectName(), stg.GetDigest()); err != nil {
-		return fmt.Errorf("unable to lock project %s digest %s: %s", phase.Conveyor.projectName(), stg.GetDigest(), err)
+		return false, fmt.Errorf("unable to lock project %s digest %s: %s", phase.Conveyor.projectName(), stg.GetDigest(), err)
 	} else {
 		defer phase.Conveyor.StorageLockManager.Unlock(ctx, lock)
 	}
 
 	if stages, err := phase.Conveyor.StorageManager.GetStagesByDigest(ctx, stg.LogDetailedName(), stg.GetDigest()); err != nil {
-		return err
+		return false, err
 	} else {
 		if stageDesc, err := phase.Conveyor.StorageManager.SelectSuitableStage(ctx, phase.Conveyor, stg, stages); err != nil {
-			return err
+			return false, err
 		} else if stageDesc != nil {
 			logboek.Context(ctx).Default().LogF(
 				"Discarding newly built image for stage %s by digest %s: detected already existing image %s in the repo\n",
@@ -643,7 +858,7 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 			i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), stageDesc.Info.Name)
 			i.SetStageDescription(stageDesc)
 			stg.SetImage(i)
-			return nil
+			return false, nil
 		} else { // use newly built image
 			newStageImageName, uniqueID := phase.Conveyor.StorageManager.GenerateStageUniqueID(stg.GetDigest(), stages)
 			stageImageObj := phase.Conveyor.GetStageImage(stageImage.Name())
@@ -662,7 +877,7 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 				}
 				return nil
 			}); err != nil {
-				return err
+				return false, err
 			}
 
 			var stageIDs []image.StageID
@@ -671,7 +886,7 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 			}
 			stageIDs = append(stageIDs, *stageImage.GetStageDescription().StageID)
 
-			return phase.Conveyor.StorageManager.AtomicStoreStagesByDigestToCache(ctx, string(stg.Name()), stg.GetDigest(), stageIDs)
+			return true, phase.Conveyor.StorageManager.AtomicStoreStagesByDigestToCache(ctx, string(stg.Name()), stg.GetDigest(), stageIDs)
 		}
 	}
 }
@@ -0,0 +1,34 @@
+package build
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImagesReport(t *testing.T) {
+	report := &ImagesReport{
+		Images:       make(map[string]ReportImageRecord),
+		Artifacts:    make(map[string]ReportImageRecord),
+		stageRecords: make(map[string][]ReportStageRecord),
+	}
+
+	report.AddStageRecord("app", ReportStageRecord{Name: "from", Status: ReportStageCached})
+	report.AddStageRecord("app", ReportStageRecord{Name: "install", Status: ReportStageBuilt, BuildDurationSeconds: 1.5})
+	report.SetImageRecord("app", ReportImageRecord{WerfImageName: "app", DockerImageName: "registry/app:digest", Stages: report.GetStageRecords("app")})
+	report.SetArtifactRecord("artifact", ReportImageRecord{WerfImageName: "artifact", DockerImageName: "registry/app:artifact"})
+
+	data, err := report.ToJsonData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{`"Artifacts": {`, `"Name": "install"`, `"Status": "built"`, `"BuildDurationSeconds": 1.5`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected json report to contain %q:\n%s", expected, data)
+		}
+	}
+
+	if envFileData := string(report.ToEnvFileData()); envFileData != "WERF_APP_DOCKER_IMAGE_NAME=registry/app:digest\n" {
+		t.Errorf("unexpected envfile report %q", envFileData)
+	}
+}
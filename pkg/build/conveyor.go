@@ -33,6 +33,7 @@ import (
 	"github.com/werf/werf/pkg/path_matcher"
 	"github.com/werf/werf/pkg/storage"
 	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/tracing"
 	"github.com/werf/werf/pkg/util"
 	"github.com/werf/werf/pkg/util/parallel"
 )
@@ -52,8 +53,9 @@ type Conveyor struct {
 
 	gitReposCaches map[string]*stage.GitRepoCache
 
-	images    []*Image
-	imageSets [][]*Image
+	images          []*Image
+	imageSets       [][]*Image
+	imageIndexNames map[string]string
 
 	stageImages        map[string]*container_runtime.StageImage
 	giterminismManager giterminism_manager.Interface
@@ -65,9 +67,13 @@ type Conveyor struct {
 
 	StorageLockManager storage.LockManager
 	StorageManager     *manager.StorageManager
+	LayersCache        storage.LayersCache
 
 	onTerminateFuncs []func() error
 	importServers    map[string]import_server.ImportServer
+	secrets          map[string]*stage.Secret
+
+	stopStagesLastUseFlushing func()
 
 	ConveyorOptions
 
@@ -80,9 +86,16 @@ type ConveyorOptions struct {
 	Parallel                        bool
 	ParallelTasksLimit              int64
 	LocalGitRepoVirtualMergeOptions stage.VirtualMergeOptions
+	BuildkitAddress                 string
+	LayersCacheRepo                 string
 }
 
 func NewConveyor(werfConfig *config.WerfConfig, giterminismManager giterminism_manager.Interface, imageNamesToProcess []string, projectDir, baseTmpDir, sshAuthSock string, containerRuntime container_runtime.ContainerRuntime, storageManager *manager.StorageManager, storageLockManager storage.LockManager, opts ConveyorOptions) *Conveyor {
+	var layersCache storage.LayersCache
+	if opts.LayersCacheRepo != "" {
+		layersCache = storage.NewRepoLayersCache(opts.LayersCacheRepo, containerRuntime)
+	}
+
 	return &Conveyor{
 		werfConfig:          werfConfig,
 		imageNamesToProcess: imageNamesToProcess,
@@ -101,6 +114,7 @@ func NewConveyor(werfConfig *config.WerfConfig, giterminismManager giterminism_m
 		baseImagesRepoErrCache: make(map[string]error),
 		images:                 []*Image{},
 		imageSets:              [][]*Image{},
+		imageIndexNames:        make(map[string]string),
 		remoteGitRepos:         make(map[string]*git_repo.Remote),
 		tmpDir:                 filepath.Join(baseTmpDir, util.GenerateConsistentRandomString(10)),
 		importServers:          make(map[string]import_server.ImportServer),
@@ -108,6 +122,7 @@ func NewConveyor(werfConfig *config.WerfConfig, giterminismManager giterminism_m
 		ContainerRuntime:   containerRuntime,
 		StorageLockManager: storageLockManager,
 		StorageManager:     storageManager,
+		LayersCache:        layersCache,
 
 		ConveyorOptions: opts,
 
@@ -190,6 +205,10 @@ func (c *Conveyor) GetLocalGitRepoVirtualMergeOptions() stage.VirtualMergeOption
 	return c.ConveyorOptions.LocalGitRepoVirtualMergeOptions
 }
 
+func (c *Conveyor) GetBuildkitAddress() string {
+	return c.ConveyorOptions.BuildkitAddress
+}
+
 func (c *Conveyor) GetImportServer(ctx context.Context, imageName, stageName string) (import_server.ImportServer, error) {
 	c.getServiceRWMutex("ImportServer").Lock()
 	defer c.getServiceRWMutex("ImportServer").Unlock()
@@ -251,9 +270,21 @@ func (c *Conveyor) AppendOnTerminateFunc(f func() error) {
 	c.onTerminateFuncs = append(c.onTerminateFuncs, f)
 }
 
+func (c *Conveyor) startStagesLastUseFlushing(ctx context.Context) {
+	c.stopStagesLastUseFlushing = c.StorageManager.StartStagesLastUseFlushing(ctx)
+}
+
 func (c *Conveyor) Terminate(ctx context.Context) error {
 	var terminateErrors []error
 
+	if c.stopStagesLastUseFlushing != nil {
+		c.stopStagesLastUseFlushing()
+	}
+
+	if err := c.StorageManager.FlushStagesLastUse(ctx); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: Unable to record stages last use: %s\n", err)
+	}
+
 	for _, onTerminateFunc := range c.onTerminateFuncs {
 		if err := onTerminateFunc(); err != nil {
 			terminateErrors = append(terminateErrors, err)
@@ -332,16 +363,31 @@ func (c *Conveyor) ShouldBeBuilt(ctx context.Context) error {
 	return nil
 }
 
+func (c *Conveyor) GetStagesGraph(ctx context.Context) (*StagesGraph, error) {
+	if err := c.determineStages(ctx); err != nil {
+		return nil, err
+	}
+
+	graphPhase := NewGraphPhase(c)
+	if err := c.runPhases(ctx, []Phase{graphPhase}, false); err != nil {
+		return nil, err
+	}
+
+	return graphPhase.Graph, nil
+}
+
 func (c *Conveyor) FetchLastImageStage(ctx context.Context, imageName string) error {
 	lastImageStage := c.GetImage(imageName).GetLastNonEmptyStage()
 	return c.StorageManager.FetchStage(ctx, lastImageStage)
 }
 
 func (c *Conveyor) GetImageInfoGetters() (images []*image.InfoGetter) {
-	for _, img := range c.images {
-		if img.isArtifact {
+	for _, img := range c.getFinalImages() {
+		if indexName := c.GetImageIndexName(img.GetName()); indexName != "" {
+			images = append(images, newImageIndexInfoGetter(img.GetName(), indexName))
 			continue
 		}
+
 		images = append(images, img.GetImageInfoGetter())
 	}
 
@@ -350,8 +396,9 @@ func (c *Conveyor) GetImageInfoGetters() (images []*image.InfoGetter) {
 
 func (c *Conveyor) GetImagesEnvArray() []string {
 	var envArray []string
-	for _, img := range c.images {
-		if img.isArtifact {
+	for _, img := range c.getFinalImages() {
+		if indexName := c.GetImageIndexName(img.GetName()); indexName != "" {
+			envArray = append(envArray, generateImageEnv(img.name, indexName))
 			continue
 		}
 
@@ -361,6 +408,22 @@ func (c *Conveyor) GetImagesEnvArray() []string {
 	return envArray
 }
 
+// getFinalImages returns one image per werf image name omitting artifacts, platform images of a multi-platform image are represented by the first one
+func (c *Conveyor) getFinalImages() []*Image {
+	var images []*Image
+	processedNames := map[string]bool{}
+	for _, img := range c.images {
+		if img.isArtifact || processedNames[img.GetName()] {
+			continue
+		}
+
+		processedNames[img.GetName()] = true
+		images = append(images, img)
+	}
+
+	return images
+}
+
 func (c *Conveyor) Build(ctx context.Context, opts BuildOptions) error {
 	if err := c.determineStages(ctx); err != nil {
 		return err
@@ -398,7 +461,6 @@ func (c *Conveyor) doDetermineStages(ctx context.Context) error {
 		var imageSet []*Image
 
 		for _, imageInterfaceConfig := range iteration {
-			var img *Image
 			var imageLogName string
 			var style *style.Style
 
@@ -416,21 +478,29 @@ func (c *Conveyor) doDetermineStages(ctx context.Context) error {
 					options.Style(style)
 				}).
 				DoError(func() error {
-					var err error
-
-					switch imageConfig := imageInterfaceConfig.(type) {
-					case config.StapelImageInterface:
-						img, err = prepareImageBasedOnStapelImageConfig(ctx, imageConfig, c)
-					case *config.ImageFromDockerfile:
-						img, err = prepareImageBasedOnImageFromDockerfile(ctx, imageConfig, c)
+					platforms := c.werfConfig.GetImagePlatforms(imageInterfaceConfig.GetName())
+					if len(platforms) == 0 {
+						platforms = []string{""}
 					}
 
-					if err != nil {
-						return err
-					}
+					for _, platform := range platforms {
+						var img *Image
+						var err error
 
-					c.images = append(c.images, img)
-					imageSet = append(imageSet, img)
+						switch imageConfig := imageInterfaceConfig.(type) {
+						case config.StapelImageInterface:
+							img, err = prepareImageBasedOnStapelImageConfig(ctx, imageConfig, platform, c)
+						case *config.ImageFromDockerfile:
+							img, err = prepareImageBasedOnImageFromDockerfile(ctx, imageConfig, platform, c)
+						}
+
+						if err != nil {
+							return err
+						}
+
+						c.images = append(c.images, img)
+						imageSet = append(imageSet, img)
+					}
 
 					return nil
 				})
@@ -449,7 +519,7 @@ func (c *Conveyor) runPhases(ctx context.Context, phases []Phase, logImages bool
 	for _, phase := range phases {
 		logProcess := logboek.Context(ctx).Debug().LogProcess("Phase %s -- BeforeImages()", phase.Name())
 		logProcess.Start()
-		if err := phase.BeforeImages(ctx); err != nil {
+		if err := tracing.Do(ctx, fmt.Sprintf("phase %s before images", phase.Name()), nil, phase.BeforeImages); err != nil {
 			logProcess.Fail()
 			return fmt.Errorf("phase %s before images handler failed: %s", phase.Name(), err)
 		}
@@ -463,7 +533,7 @@ func (c *Conveyor) runPhases(ctx context.Context, phases []Phase, logImages bool
 	for _, phase := range phases {
 		if err := logboek.Context(ctx).Debug().LogProcess(fmt.Sprintf("Phase %s -- AfterImages()", phase.Name())).
 			DoError(func() error {
-				if err := phase.AfterImages(ctx); err != nil {
+				if err := tracing.Do(ctx, fmt.Sprintf("phase %s after images", phase.Name()), nil, phase.AfterImages); err != nil {
 					return fmt.Errorf("phase %s after images handler failed: %s", phase.Name(), err)
 				}
 
@@ -551,45 +621,61 @@ func (c *Conveyor) doImage(ctx context.Context, img *Image, phases []Phase, logI
 			options.Style(img.LogProcessStyle())
 		}).
 		DoError(func() error {
-			for _, phase := range phases {
-				logProcess := logboek.Context(ctx).Debug().LogProcess("Phase %s -- BeforeImageStages()", phase.Name())
-				logProcess.Start()
-				if err := phase.BeforeImageStages(ctx, img); err != nil {
-					logProcess.Fail()
-					return fmt.Errorf("phase %s before image %s stages handler failed: %s", phase.Name(), img.GetLogName(), err)
-				}
-				logProcess.End()
-
-				logProcess = logboek.Context(ctx).Debug().LogProcess("Phase %s -- OnImageStage()", phase.Name())
-				logProcess.Start()
-				for _, stg := range img.GetStages() {
-					logboek.Context(ctx).Debug().LogF("Phase %s -- OnImageStage() %s %s\n", phase.Name(), img.GetLogName(), stg.LogDetailedName())
-					if err := phase.OnImageStage(ctx, img, stg); err != nil {
-						logProcess.Fail()
-						return fmt.Errorf("phase %s on image %s stage %s handler failed: %s", phase.Name(), img.GetLogName(), stg.Name(), err)
+			return tracing.Do(ctx, fmt.Sprintf("image %s", img.GetLogName()), []tracing.Attribute{tracing.Attr("werf.image", img.GetName())}, func(ctx context.Context) error {
+				for _, phase := range phases {
+					if shouldBeStopped, err := c.doImagePhase(ctx, img, phase); err != nil {
+						return err
+					} else if shouldBeStopped {
+						return nil
 					}
 				}
-				logProcess.End()
 
-				logProcess = logboek.Context(ctx).Debug().LogProcess("Phase %s -- AfterImageStages()", phase.Name())
-				logProcess.Start()
-				if err := phase.AfterImageStages(ctx, img); err != nil {
-					logProcess.Fail()
-					return fmt.Errorf("phase %s after image %s stages handler failed: %s", phase.Name(), img.GetLogName(), err)
-				}
-				logProcess.End()
+				return nil
+			})
+		})
+}
 
-				logProcess = logboek.Context(ctx).Debug().LogProcess("Phase %s -- ImageProcessingShouldBeStopped()", phase.Name())
-				logProcess.Start()
-				if phase.ImageProcessingShouldBeStopped(ctx, img) {
-					logProcess.End()
-					return nil
-				}
-				logProcess.End()
-			}
+func (c *Conveyor) doImagePhase(ctx context.Context, img *Image, phase Phase) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, fmt.Sprintf("phase %s", phase.Name()), tracing.Attr("werf.image", img.GetName()))
+	defer span.End()
 
-			return nil
-		})
+	logProcess := logboek.Context(ctx).Debug().LogProcess("Phase %s -- BeforeImageStages()", phase.Name())
+	logProcess.Start()
+	if err := phase.BeforeImageStages(ctx, img); err != nil {
+		logProcess.Fail()
+		span.SetError(err)
+		return false, fmt.Errorf("phase %s before image %s stages handler failed: %s", phase.Name(), img.GetLogName(), err)
+	}
+	logProcess.End()
+
+	logProcess = logboek.Context(ctx).Debug().LogProcess("Phase %s -- OnImageStage()", phase.Name())
+	logProcess.Start()
+	for _, stg := range img.GetStages() {
+		logboek.Context(ctx).Debug().LogF("Phase %s -- OnImageStage() %s %s\n", phase.Name(), img.GetLogName(), stg.LogDetailedName())
+		if err := tracing.Do(ctx, fmt.Sprintf("stage %s", stg.Name()), []tracing.Attribute{tracing.Attr("werf.image", img.GetName()), tracing.Attr("werf.stage", string(stg.Name()))}, func(ctx context.Context) error {
+			return phase.OnImageStage(ctx, img, stg)
+		}); err != nil {
+			logProcess.Fail()
+			span.SetError(err)
+			return false, fmt.Errorf("phase %s on image %s stage %s handler failed: %s", phase.Name(), img.GetLogName(), stg.Name(), err)
+		}
+	}
+	logProcess.End()
+
+	logProcess = logboek.Context(ctx).Debug().LogProcess("Phase %s -- AfterImageStages()", phase.Name())
+	logProcess.Start()
+	if err := phase.AfterImageStages(ctx, img); err != nil {
+		logProcess.Fail()
+		span.SetError(err)
+		return false, fmt.Errorf("phase %s after image %s stages handler failed: %s", phase.Name(), img.GetLogName(), err)
+	}
+	logProcess.End()
+
+	logProcess = logboek.Context(ctx).Debug().LogProcess("Phase %s -- ImageProcessingShouldBeStopped()", phase.Name())
+	logProcess.Start()
+	defer logProcess.End()
+
+	return phase.ImageProcessingShouldBeStopped(ctx, img), nil
 }
 
 func (c *Conveyor) projectName() string {
@@ -622,21 +708,47 @@ func (c *Conveyor) GetOrCreateStageImage(fromImage *container_runtime.StageImage
 		return img
 	}
 
-	img := container_runtime.NewStageImage(fromImage, name, c.ContainerRuntime.(*container_runtime.LocalDockerServerRuntime))
+	img := container_runtime.NewStageImage(fromImage, name, c.ContainerRuntime)
 	c.SetStageImage(img)
 	return img
 }
 
+// GetImage returns the image by werf image name or by the name qualified with the platform (see stage.PlatformImageName).
+// The first image with the werf image name is returned if the image is not built for the requested platform.
 func (c *Conveyor) GetImage(name string) *Image {
+	imageName, platform := splitPlatformImageName(name)
+
+	var res *Image
 	for _, img := range c.images {
-		if img.GetName() == name {
+		if img.GetName() != imageName {
+			continue
+		}
+
+		if img.GetPlatform() == platform {
 			return img
 		}
+
+		if res == nil {
+			res = img
+		}
+	}
+
+	if res != nil {
+		return res
 	}
 
 	panic(fmt.Sprintf("Image %q not found!", name))
 }
 
+func splitPlatformImageName(name string) (string, string) {
+	ind := strings.LastIndex(name, "@")
+	if ind == -1 {
+		return name, ""
+	}
+
+	return name[:ind], name[ind+1:]
+}
+
 func (c *Conveyor) GetImageStageContentDigest(imageName, stageName string) string {
 	return c.getImageStage(imageName, stageName).GetContentDigest()
 }
@@ -690,7 +802,7 @@ func (c *Conveyor) RmImportMetadata(ctx context.Context, projectName, id string)
 	return c.StorageManager.StagesStorage.RmImportMetadata(ctx, projectName, id)
 }
 
-func prepareImageBasedOnStapelImageConfig(ctx context.Context, imageInterfaceConfig config.StapelImageInterface, c *Conveyor) (*Image, error) {
+func prepareImageBasedOnStapelImageConfig(ctx context.Context, imageInterfaceConfig config.StapelImageInterface, platform string, c *Conveyor) (*Image, error) {
 	image := &Image{}
 
 	imageBaseConfig := imageInterfaceConfig.ImageBaseConfig()
@@ -700,13 +812,14 @@ func prepareImageBasedOnStapelImageConfig(ctx context.Context, imageInterfaceCon
 	from, fromImageName, fromLatest := getFromFields(imageBaseConfig)
 
 	image.name = imageName
+	image.platform = platform
 
 	if from != "" {
 		if err := handleImageFromName(ctx, from, fromLatest, image, c); err != nil {
 			return nil, err
 		}
 	} else {
-		image.baseImageImageName = fromImageName
+		image.baseImageImageName = stage.PlatformImageName(fromImageName, platform)
 	}
 
 	image.isArtifact = imageArtifact
@@ -780,25 +893,27 @@ func initStages(ctx context.Context, image *Image, imageInterfaceConfig config.S
 	baseStageOptions := &stage.NewBaseStageOptions{
 		ImageName:        imageName,
 		ConfigMounts:     imageBaseConfig.Mount,
-		ImageTmpDir:      c.GetImageTmpDir(imageBaseConfig.Name),
+		ConfigSecrets:    imageBaseConfig.Secrets,
+		ImageTmpDir:      c.GetImageTmpDir(image.GetPlatformName()),
 		ContainerWerfDir: c.containerWerfDir,
 		ProjectName:      c.werfConfig.Meta.Project,
+		Platform:         image.GetPlatform(),
 	}
 
 	gitArchiveStageOptions := &stage.NewGitArchiveStageOptions{
-		ScriptsDir:           getImageScriptsDir(imageName, c),
+		ScriptsDir:           getImageScriptsDir(image.GetPlatformName(), c),
 		ContainerArchivesDir: getImageArchivesContainerDir(c),
 		ContainerScriptsDir:  getImageScriptsContainerDir(c),
 	}
 
 	gitPatchStageOptions := &stage.NewGitPatchStageOptions{
-		ScriptsDir:           getImageScriptsDir(imageName, c),
+		ScriptsDir:           getImageScriptsDir(image.GetPlatformName(), c),
 		ContainerPatchesDir:  getImagePatchesContainerDir(c),
 		ContainerArchivesDir: getImageArchivesContainerDir(c),
 		ContainerScriptsDir:  getImageScriptsContainerDir(c),
 	}
 
-	gitMappings, err := generateGitMappings(ctx, imageBaseConfig, c)
+	gitMappings, err := generateGitMappings(ctx, imageBaseConfig, image.GetPlatformName(), c)
 	if err != nil {
 		return err
 	}
@@ -842,7 +957,7 @@ func initStages(ctx context.Context, image *Image, imageInterfaceConfig config.S
 	return nil
 }
 
-func generateGitMappings(ctx context.Context, imageBaseConfig *config.StapelImageBase, c *Conveyor) ([]*stage.GitMapping, error) {
+func generateGitMappings(ctx context.Context, imageBaseConfig *config.StapelImageBase, imageName string, c *Conveyor) ([]*stage.GitMapping, error) {
 	var gitMappings []*stage.GitMapping
 
 	if len(imageBaseConfig.Git.Local) != 0 {
@@ -870,7 +985,7 @@ func generateGitMappings(ctx context.Context, imageBaseConfig *config.StapelImag
 		}
 
 		for _, localGitMappingConfig := range imageBaseConfig.Git.Local {
-			gitMappings = append(gitMappings, gitLocalPathInit(localGitMappingConfig, imageBaseConfig.Name, c))
+			gitMappings = append(gitMappings, gitLocalPathInit(localGitMappingConfig, imageName, c))
 		}
 	}
 
@@ -893,7 +1008,7 @@ func generateGitMappings(ctx context.Context, imageBaseConfig *config.StapelImag
 			c.SetRemoteGitRepo(remoteGitMappingConfig.Name, remoteGitRepo)
 		}
 
-		gitMappings = append(gitMappings, gitRemoteArtifactInit(remoteGitMappingConfig, remoteGitRepo, imageBaseConfig.Name, c))
+		gitMappings = append(gitMappings, gitRemoteArtifactInit(remoteGitMappingConfig, remoteGitRepo, imageName, c))
 	}
 
 	var res []*stage.GitMapping
@@ -1087,9 +1202,10 @@ func appendIfExist(ctx context.Context, stages []stage.Interface, stage stage.In
 	return stages
 }
 
-func prepareImageBasedOnImageFromDockerfile(ctx context.Context, imageFromDockerfileConfig *config.ImageFromDockerfile, c *Conveyor) (*Image, error) {
+func prepareImageBasedOnImageFromDockerfile(ctx context.Context, imageFromDockerfileConfig *config.ImageFromDockerfile, platform string, c *Conveyor) (*Image, error) {
 	img := &Image{}
 	img.name = imageFromDockerfileConfig.Name
+	img.platform = platform
 	img.isDockerfileImage = true
 
 	for _, contextAddFile := range imageFromDockerfileConfig.ContextAddFile {
@@ -1174,8 +1290,10 @@ func prepareImageBasedOnImageFromDockerfile(ctx context.Context, imageFromDocker
 	}
 
 	baseStageOptions := &stage.NewBaseStageOptions{
-		ImageName:   imageFromDockerfileConfig.Name,
-		ProjectName: c.werfConfig.Meta.Project,
+		ImageName:     imageFromDockerfileConfig.Name,
+		ConfigSecrets: imageFromDockerfileConfig.Secrets,
+		ProjectName:   c.werfConfig.Meta.Project,
+		Platform:      platform,
 	}
 
 	dockerfileStage := stage.GenerateDockerfileStage(
@@ -59,6 +59,7 @@ Retry:
 	)
 
 	if shouldRetry, err := func() (bool, error) {
+		newConveyor.startStagesLastUseFlushing(ctx)
 		defer newConveyor.Terminate(ctx)
 
 		if err := f(newConveyor); manager.ShouldResetStagesStorageCache(err) {
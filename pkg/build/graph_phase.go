@@ -0,0 +1,235 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/config"
+)
+
+func NewGraphPhase(c *Conveyor) *GraphPhase {
+	return &GraphPhase{
+		BasePhase: BasePhase{c},
+		Graph:     &StagesGraph{},
+	}
+}
+
+// GraphPhase calculates digests of the stages and checks their availability in the stages storage without building.
+// Calculation of the image stages stops on the first stage which is not found in the stages storage,
+// because the following stages can depend on the result of the build (git stages, imports).
+type GraphPhase struct {
+	BasePhase
+
+	Graph *StagesGraph
+
+	StagesIterator *StagesIterator
+	graphImage     *GraphImage
+	stopped        bool
+}
+
+func (phase *GraphPhase) Name() string {
+	return "graph"
+}
+
+func (phase *GraphPhase) BeforeImages(_ context.Context) error {
+	for _, img := range phase.Conveyor.images {
+		graphImage := &GraphImage{
+			Name:         img.GetName(),
+			Platform:     img.GetPlatform(),
+			IsArtifact:   img.isArtifact,
+			IsDockerfile: img.isDockerfileImage,
+		}
+
+		for _, stg := range img.GetStages() {
+			graphImage.Stages = append(graphImage.Stages, &GraphStage{Name: string(stg.Name()), Status: GraphStageUnknown})
+		}
+
+		phase.Graph.Images = append(phase.Graph.Images, graphImage)
+	}
+
+	for _, img := range phase.Conveyor.images {
+		phase.Graph.GetPlatformImage(img.GetName(), img.GetPlatform()).Dependencies = phase.getImageDependencies(img)
+	}
+
+	return nil
+}
+
+func (phase *GraphPhase) getImageDependencies(img *Image) []*GraphDependency {
+	if img.isDockerfileImage {
+		return nil
+	}
+
+	var imageBaseConfig *config.StapelImageBase
+	if img.isArtifact {
+		imageBaseConfig = phase.Conveyor.werfConfig.GetArtifact(img.GetName()).StapelImageBase
+	} else {
+		imageBaseConfig = phase.Conveyor.werfConfig.GetStapelImage(img.GetName()).StapelImageBase
+	}
+
+	var dependencies []*GraphDependency
+	if imageBaseConfig.FromImageName != "" {
+		dependencies = append(dependencies, phase.newGraphDependency(GraphDependencyFromImage, imageBaseConfig.FromImageName, img.GetPlatform(), "", string(stage.From)))
+	} else if imageBaseConfig.FromArtifactName != "" {
+		dependencies = append(dependencies, phase.newGraphDependency(GraphDependencyFromArtifact, imageBaseConfig.FromArtifactName, img.GetPlatform(), "", string(stage.From)))
+	}
+
+	for _, importElm := range imageBaseConfig.Import {
+		var targetStage string
+		if importElm.Before != "" {
+			targetStage = fmt.Sprintf("importsBefore%s", strings.Title(importElm.Before))
+		} else {
+			targetStage = fmt.Sprintf("importsAfter%s", strings.Title(importElm.After))
+		}
+
+		dependencies = append(dependencies, phase.newGraphDependency(GraphDependencyImport, getImportSourceImageName(importElm), img.GetPlatform(), importElm.Stage, targetStage))
+	}
+
+	return dependencies
+}
+
+func (phase *GraphPhase) newGraphDependency(dependencyType GraphDependencyType, imageName, platform, sourceStage, targetStage string) *GraphDependency {
+	dependency := &GraphDependency{
+		Type:        dependencyType,
+		ImageName:   imageName,
+		SourceStage: sourceStage,
+		TargetStage: targetStage,
+	}
+
+	if depImage := phase.Graph.GetPlatformImage(imageName, platform); depImage != nil && len(depImage.Stages) != 0 {
+		if dependency.SourceStage == "" || depImage.stageIndex(dependency.SourceStage) == -1 {
+			dependency.SourceStage = depImage.Stages[len(depImage.Stages)-1].Name
+		}
+	}
+
+	return dependency
+}
+
+func getImportSourceImageName(importElm *config.Import) string {
+	if importElm.ImageName != "" {
+		return importElm.ImageName
+	}
+	return importElm.ArtifactName
+}
+
+func (phase *GraphPhase) AfterImages(_ context.Context) error {
+	return nil
+}
+
+func (phase *GraphPhase) ImageProcessingShouldBeStopped(_ context.Context, _ *Image) bool {
+	return false
+}
+
+func (phase *GraphPhase) BeforeImageStages(ctx context.Context, img *Image) error {
+	phase.StagesIterator = NewStagesIterator(phase.Conveyor)
+	phase.graphImage = phase.Graph.GetPlatformImage(img.GetName(), img.GetPlatform())
+	phase.stopped = false
+
+	for _, dep := range phase.graphImage.Dependencies {
+		if depImage := phase.Graph.GetPlatformImage(dep.ImageName, img.GetPlatform()); depImage == nil || !depImage.IsCached() {
+			logboek.Context(ctx).Info().LogF("Stages of %s cannot be calculated: %s %s is not cached\n", img.LogDetailedName(), dep.Type, dep.ImageName)
+			phase.stopped = true
+			return nil
+		}
+	}
+
+	img.SetupBaseImage(phase.Conveyor)
+
+	return nil
+}
+
+func (phase *GraphPhase) AfterImageStages(_ context.Context, img *Image) error {
+	if phase.stopped {
+		return nil
+	}
+
+	img.SetLastNonEmptyStage(phase.StagesIterator.PrevNonEmptyStage)
+	img.SetContentDigest(phase.StagesIterator.PrevNonEmptyStage.GetContentDigest())
+
+	return nil
+}
+
+func (phase *GraphPhase) OnImageStage(ctx context.Context, img *Image, stg stage.Interface) error {
+	if phase.stopped {
+		return nil
+	}
+
+	graphStage := phase.graphImage.Stages[phase.graphImage.stageIndex(string(stg.Name()))]
+
+	return phase.StagesIterator.OnImageStage(ctx, img, stg, func(img *Image, stg stage.Interface, isEmpty bool) error {
+		return phase.onImageStage(ctx, img, stg, isEmpty, graphStage)
+	})
+}
+
+func (phase *GraphPhase) onImageStage(ctx context.Context, img *Image, stg stage.Interface, isEmpty bool, graphStage *GraphStage) error {
+	if isEmpty {
+		graphStage.Status = GraphStageEmpty
+		return nil
+	}
+
+	if err := stg.FetchDependencies(ctx, phase.Conveyor, phase.Conveyor.ContainerRuntime); err != nil {
+		return fmt.Errorf("unable to fetch dependencies for stage %s: %s", stg.LogDetailedName(), err)
+	}
+
+	stageDependencies, err := stg.GetDependencies(ctx, phase.Conveyor, phase.StagesIterator.GetPrevImage(img, stg), phase.StagesIterator.GetPrevBuiltImage(img, stg))
+	if err != nil {
+		return err
+	}
+
+	stageDigest, err := calculateDigest(ctx, string(stg.Name()), stageDependencies, phase.StagesIterator.PrevNonEmptyStage, phase.Conveyor)
+	if err != nil {
+		return err
+	}
+	stg.SetDigest(stageDigest)
+
+	stageDigestInputs, err := getStageDigestInputs(ctx, img, stg, phase.StagesIterator.PrevNonEmptyStage, phase.Conveyor)
+	if err != nil {
+		return err
+	}
+
+	graphStage.Digest = stageDigest
+	graphStage.Inputs = stageDigestInputs.Inputs
+
+	stages, err := phase.Conveyor.StorageManager.GetStagesByDigest(ctx, stg.LogDetailedName(), stageDigest)
+	if err != nil {
+		return err
+	}
+
+	stageDesc, err := phase.Conveyor.StorageManager.SelectSuitableStage(ctx, phase.Conveyor, stg, stages)
+	if err != nil {
+		return err
+	}
+
+	if stageDesc != nil {
+		i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), stageDesc.Info.Name)
+		i.SetStageDescription(stageDesc)
+		stg.SetImage(i)
+
+		graphStage.Status = GraphStageCached
+		graphStage.StageID = stageDesc.StageID.String()
+		graphStage.DockerImage = stageDesc.Info.Name
+	} else {
+		stg.SetImage(phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), uuid.New().String()))
+
+		graphStage.Status = GraphStageNotCached
+		phase.stopped = true
+	}
+
+	stageContentSig, err := calculateDigest(ctx, fmt.Sprintf("%s-content", stg.Name()), "", stg, phase.Conveyor)
+	if err != nil {
+		return fmt.Errorf("unable to calculate stage %s content digest: %s", stg.Name(), err)
+	}
+	stg.SetContentDigest(stageContentSig)
+
+	return nil
+}
+
+func (phase *GraphPhase) Clone() Phase {
+	u := *phase
+	return &u
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	dockerTypes "github.com/docker/docker/api/types"
 	"github.com/fatih/color"
 
 	"github.com/werf/logboek"
@@ -25,7 +26,8 @@ const (
 )
 
 type Image struct {
-	name string
+	name     string
+	platform string
 
 	baseImageName      string
 	baseImageImageName string
@@ -43,11 +45,21 @@ type Image struct {
 }
 
 func (i *Image) LogName() string {
-	return logging.ImageLogName(i.name, i.isArtifact)
+	logName := logging.ImageLogName(i.name, i.isArtifact)
+	if i.platform != "" {
+		logName = fmt.Sprintf("%s[%s]", logName, i.platform)
+	}
+
+	return logName
 }
 
 func (i *Image) LogDetailedName() string {
-	return logging.ImageLogProcessName(i.name, i.isArtifact)
+	logName := logging.ImageLogProcessName(i.name, i.isArtifact)
+	if i.platform != "" {
+		logName = fmt.Sprintf("%s [%s]", logName, i.platform)
+	}
+
+	return logName
 }
 
 func (i *Image) LogProcessStyle() *style.Style {
@@ -128,6 +140,16 @@ func (i *Image) GetName() string {
 	return i.name
 }
 
+// GetPlatform returns the platform the image is built for, empty platform means the default platform of the build host
+func (i *Image) GetPlatform() string {
+	return i.platform
+}
+
+// GetPlatformName returns the name used to address the image built for its platform within the conveyor
+func (i *Image) GetPlatformName() string {
+	return stage.PlatformImageName(i.name, i.platform)
+}
+
 func (i *Image) GetLogName() string {
 	return i.LogName()
 }
@@ -137,6 +159,12 @@ func (i *Image) SetupBaseImage(c *Conveyor) {
 		i.baseImageType = StageAsBaseImage
 		i.stageAsBaseImage = c.GetImage(i.baseImageImageName).GetLastNonEmptyStage()
 		i.baseImage = c.GetOrCreateStageImage(nil, i.stageAsBaseImage.GetImage().Name())
+	} else if i.platform != "" {
+		// docker can keep only one platform variant of the same base image name locally,
+		// so the base image of the platform image is not shared through the conveyor and is addressed by ID after pull
+		i.baseImageType = ImageFromRegistryAsBaseImage
+		i.baseImage = container_runtime.NewStageImage(nil, i.baseImageName, c.ContainerRuntime)
+		i.baseImage.SetPlatform(i.platform)
 	} else {
 		i.baseImageType = ImageFromRegistryAsBaseImage
 		i.baseImage = c.GetOrCreateStageImage(nil, i.baseImageName)
@@ -150,16 +178,15 @@ func (i *Image) GetBaseImage() *container_runtime.StageImage {
 func (i *Image) FetchBaseImage(ctx context.Context, c *Conveyor) error {
 	switch i.baseImageType {
 	case ImageFromRegistryAsBaseImage:
-		containerRuntime := c.ContainerRuntime.(*container_runtime.LocalDockerServerRuntime)
+		if i.platform != "" {
+			return i.fetchPlatformBaseImage(ctx, c)
+		}
 
-		if inspect, err := containerRuntime.GetImageInspect(ctx, i.baseImage.Name()); err != nil {
+		if inspect, err := c.ContainerRuntime.GetImageInspect(ctx, i.baseImage.Name()); err != nil {
 			return fmt.Errorf("unable to inspect local image %s: %s", i.baseImage.Name(), err)
 		} else if inspect != nil {
 			// TODO: do not use container_runtime.StageImage for base image
-			i.baseImage.SetStageDescription(&image.StageDescription{
-				StageID: nil, // this is not a stage actually, TODO
-				Info:    image.NewInfoFromInspect(i.baseImage.Name(), inspect),
-			})
+			i.baseImage.SetStageDescription(newBaseImageDescription(i.baseImage.Name(), inspect))
 
 			baseImageRepoId, err := i.getFromBaseImageIdFromRegistry(ctx, c, i.baseImage.Name())
 			if baseImageRepoId == inspect.ID || err != nil {
@@ -183,15 +210,12 @@ func (i *Image) FetchBaseImage(ctx context.Context, c *Conveyor) error {
 			return err
 		}
 
-		if inspect, err := containerRuntime.GetImageInspect(ctx, i.baseImage.Name()); err != nil {
+		if inspect, err := c.ContainerRuntime.GetImageInspect(ctx, i.baseImage.Name()); err != nil {
 			return fmt.Errorf("unable to inspect local image %s: %s", i.baseImage.Name(), err)
 		} else if inspect == nil {
 			return fmt.Errorf("unable to inspect local image %s after successful pull: image is not exists", i.baseImage.Name())
 		} else {
-			i.baseImage.SetStageDescription(&image.StageDescription{
-				StageID: nil, // this is not a stage actually, TODO
-				Info:    image.NewInfoFromInspect(i.baseImage.Name(), inspect),
-			})
+			i.baseImage.SetStageDescription(newBaseImageDescription(i.baseImage.Name(), inspect))
 		}
 	case StageAsBaseImage:
 		if err := c.ContainerRuntime.RefreshImageObject(ctx, &container_runtime.DockerImage{Image: i.baseImage}); err != nil {
@@ -207,6 +231,40 @@ func (i *Image) FetchBaseImage(ctx context.Context, c *Conveyor) error {
 	return nil
 }
 
+func (i *Image) fetchPlatformBaseImage(ctx context.Context, c *Conveyor) error {
+	c.getServiceRWMutex("pullBaseImage" + i.baseImage.Name()).Lock()
+	defer c.getServiceRWMutex("pullBaseImage" + i.baseImage.Name()).Unlock()
+
+	if err := logboek.Context(ctx).Default().LogProcess("Pulling base image %s for platform %s", i.baseImage.Name(), i.platform).
+		Options(func(options types.LogProcessOptionsInterface) {
+			options.Style(style.Highlight())
+		}).
+		DoError(func() error {
+			return i.baseImage.Pull(ctx)
+		}); err != nil {
+		return err
+	}
+
+	if inspect, err := c.ContainerRuntime.GetImageInspect(ctx, i.baseImage.Name()); err != nil {
+		return fmt.Errorf("unable to inspect local image %s: %s", i.baseImage.Name(), err)
+	} else if inspect == nil {
+		return fmt.Errorf("unable to inspect local image %s after successful pull: image is not exists", i.baseImage.Name())
+	} else {
+		i.baseImage.SetStageDescription(newBaseImageDescription(i.baseImage.Name(), inspect))
+	}
+
+	return nil
+}
+
+// newBaseImageDescription describes the pulled base image, which is not stored in the stages storage,
+// so it has no stage ID: the code distinguishing stages from the base image relies on the nil StageID
+func newBaseImageDescription(name string, inspect *dockerTypes.ImageInspect) *image.StageDescription {
+	return &image.StageDescription{
+		StageID: nil,
+		Info:    image.NewInfoFromInspect(name, inspect),
+	}
+}
+
 func (i *Image) getFromBaseImageIdFromRegistry(ctx context.Context, c *Conveyor, baseImageName string) (string, error) {
 	c.getServiceRWMutex("baseImagesRepoIdsCache" + baseImageName).Lock()
 	defer c.getServiceRWMutex("baseImagesRepoIdsCache" + baseImageName).Unlock()
@@ -0,0 +1,97 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/util"
+)
+
+func (c *Conveyor) GetImageIndexName(imageName string) string {
+	c.getServiceRWMutex("ImageIndexNames").RLock()
+	defer c.getServiceRWMutex("ImageIndexNames").RUnlock()
+
+	return c.imageIndexNames[imageName]
+}
+
+func (c *Conveyor) SetImageIndexName(imageName, indexName string) {
+	c.getServiceRWMutex("ImageIndexNames").Lock()
+	defer c.getServiceRWMutex("ImageIndexNames").Unlock()
+
+	c.imageIndexNames[imageName] = indexName
+}
+
+// getPlatformImages returns the platform images of the multi-platform images grouped by werf image name
+func (c *Conveyor) getPlatformImages() map[string][]*Image {
+	res := map[string][]*Image{}
+	for _, img := range c.images {
+		if img.isArtifact || img.GetPlatform() == "" {
+			continue
+		}
+
+		res[img.GetName()] = append(res[img.GetName()], img)
+	}
+
+	return res
+}
+
+// publishImageIndexes stores the image index for each multi-platform image, the index is only constructed in the should-be-built mode
+func (c *Conveyor) publishImageIndexes(ctx context.Context, shouldBeBuiltMode bool) error {
+	platformImagesByName := c.getPlatformImages()
+
+	var imageNames []string
+	for imageName := range platformImagesByName {
+		imageNames = append(imageNames, imageName)
+	}
+	sort.Strings(imageNames)
+
+	for _, imageName := range imageNames {
+		var platformImages []storage.PlatformImage
+		for _, img := range platformImagesByName[imageName] {
+			platformImages = append(platformImages, storage.PlatformImage{
+				Platform:  img.GetPlatform(),
+				ImageName: img.GetLastNonEmptyStage().GetImage().Name(),
+			})
+		}
+
+		digest := imageIndexDigest(platformImages)
+		indexName := c.StorageManager.StagesStorage.ConstructImageIndexName(c.projectName(), digest)
+
+		if !shouldBeBuiltMode {
+			if err := logboek.Context(ctx).Default().LogProcess("Publishing image index %s", indexName).DoError(func() error {
+				return c.StorageManager.StagesStorage.StoreImageIndex(ctx, c.projectName(), digest, platformImages)
+			}); err != nil {
+				return fmt.Errorf("unable to store image index for image %q: %s", imageName, err)
+			}
+		}
+
+		c.SetImageIndexName(imageName, indexName)
+	}
+
+	return nil
+}
+
+func imageIndexDigest(platformImages []storage.PlatformImage) string {
+	var args []string
+	for _, platformImage := range platformImages {
+		args = append(args, fmt.Sprintf("%s:%s", platformImage.Platform, platformImage.ImageName))
+	}
+	sort.Strings(args)
+
+	return util.Sha3_224Hash(args...)
+}
+
+func newImageIndexInfoGetter(imageName, indexName string) *image.InfoGetter {
+	var tag string
+	if ind := strings.LastIndex(indexName, ":"); ind != -1 && !strings.Contains(indexName[ind:], "/") {
+		tag = indexName[ind+1:]
+	}
+
+	return image.NewInfoGetter(imageName, indexName, tag)
+}
@@ -0,0 +1,52 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/werf/werf/pkg/storage"
+)
+
+func TestConveyorGetPlatformImage(t *testing.T) {
+	c := &Conveyor{
+		images: []*Image{
+			{name: "base"},
+			{name: "app", platform: "linux/amd64"},
+			{name: "app", platform: "linux/arm64"},
+		},
+	}
+
+	if img := c.GetImage("app@linux/arm64"); img.GetPlatform() != "linux/arm64" {
+		t.Errorf("expected image for platform linux/arm64, got %q", img.GetPlatform())
+	}
+
+	if img := c.GetImage("app"); img.GetPlatform() != "linux/amd64" {
+		t.Errorf("expected the first platform image, got %q", img.GetPlatform())
+	}
+
+	if img := c.GetImage("base@linux/arm64"); img.GetName() != "base" || img.GetPlatform() != "" {
+		t.Errorf("expected fallback to the image without platform, got %q %q", img.GetName(), img.GetPlatform())
+	}
+
+	if images := c.getFinalImages(); len(images) != 2 {
+		t.Errorf("expected 2 final images, got %d", len(images))
+	}
+}
+
+func TestImageIndexDigest(t *testing.T) {
+	amd64 := storage.PlatformImage{Platform: "linux/amd64", ImageName: "registry.example.com/project:aaa-1"}
+	arm64 := storage.PlatformImage{Platform: "linux/arm64", ImageName: "registry.example.com/project:bbb-2"}
+
+	if imageIndexDigest([]storage.PlatformImage{amd64, arm64}) != imageIndexDigest([]storage.PlatformImage{arm64, amd64}) {
+		t.Errorf("image index digest should not depend on the order of platform images")
+	}
+
+	arm64.ImageName = "registry.example.com/project:ccc-3"
+	if imageIndexDigest([]storage.PlatformImage{amd64, arm64}) == imageIndexDigest([]storage.PlatformImage{amd64}) {
+		t.Errorf("image index digest should depend on platform images")
+	}
+
+	infoGetter := newImageIndexInfoGetter("app", "registry.example.com:5000/project:index-abc")
+	if infoGetter.GetTag() != "index-abc" {
+		t.Errorf("unexpected image index tag %q", infoGetter.GetTag())
+	}
+}
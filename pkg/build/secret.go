@@ -0,0 +1,94 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/util/secretvalues"
+)
+
+// GetSecret reads the secret from the source and writes it into the conveyor tmp dir, the secrets are prepared once per conveyor.
+// The secret files are removed on the conveyor termination.
+func (c *Conveyor) GetSecret(ctx context.Context, secretCfg *config.Secret) (*stage.Secret, error) {
+	c.getServiceRWMutex("Secrets").Lock()
+	defer c.getServiceRWMutex("Secrets").Unlock()
+
+	if c.secrets == nil {
+		c.secrets = map[string]*stage.Secret{}
+	}
+
+	cacheKey := fmt.Sprintf("%s/%s", secretCfg.FromEnv, secretCfg.FromEncryptedFile)
+	if secret, ok := c.secrets[cacheKey]; ok {
+		return secret, nil
+	}
+
+	data, err := c.readSecretData(ctx, secretCfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read secret %q: %s", secretCfg.Id, err)
+	}
+
+	secretsDir := filepath.Join(c.tmpDir, "secrets")
+	if len(c.secrets) == 0 {
+		if err := os.MkdirAll(secretsDir, 0700); err != nil {
+			return nil, fmt.Errorf("unable to create dir %s: %s", secretsDir, err)
+		}
+
+		c.AppendOnTerminateFunc(func() error {
+			if err := os.RemoveAll(secretsDir); err != nil {
+				return fmt.Errorf("unable to remove secrets dir %s: %s", secretsDir, err)
+			}
+			return nil
+		})
+	}
+
+	hostPath := filepath.Join(secretsDir, fmt.Sprintf("%s-%s", secretCfg.Id, util.GenerateConsistentRandomString(10)))
+	if err := ioutil.WriteFile(hostPath, data, 0600); err != nil {
+		return nil, fmt.Errorf("unable to write secret %q: %s", secretCfg.Id, err)
+	}
+
+	secret := &stage.Secret{
+		HostPath:     hostPath,
+		Data:         data,
+		ValuesToMask: secretvalues.ExtractSecretValuesFromMap(map[string]interface{}{secretCfg.Id: string(data)}),
+	}
+	c.secrets[cacheKey] = secret
+
+	return secret, nil
+}
+
+func (c *Conveyor) readSecretData(ctx context.Context, secretCfg *config.Secret) ([]byte, error) {
+	if secretCfg.FromEnv != "" {
+		value, ok := os.LookupEnv(secretCfg.FromEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", secretCfg.FromEnv)
+		}
+
+		return []byte(value), nil
+	}
+
+	projectDir := c.giterminismManager.ProjectDir()
+	encryptedData, err := ioutil.ReadFile(filepath.Join(projectDir, secretCfg.FromEncryptedFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %s: %s", secretCfg.FromEncryptedFile, err)
+	}
+
+	encoder, err := secrets_manager.NewSecretsManager(projectDir, secrets_manager.SecretsManagerOptions{SecretKey: c.werfConfig.Meta.SecretKey}).GetYamlEncoder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encoder.Decrypt(bytes.TrimRight(encryptedData, "\n\r\t "))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt file %s: %s", secretCfg.FromEncryptedFile, err)
+	}
+
+	return data, nil
+}
@@ -3,6 +3,7 @@ package stage
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 	"github.com/werf/werf/pkg/image"
 	imagePkg "github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/slug"
+	"github.com/werf/werf/pkg/stapel"
 	"github.com/werf/werf/pkg/util"
 	"github.com/werf/werf/pkg/werf"
 )
@@ -63,9 +65,11 @@ var (
 type NewBaseStageOptions struct {
 	ImageName        string
 	ConfigMounts     []*config.Mount
+	ConfigSecrets    []*config.Secret
 	ImageTmpDir      string
 	ContainerWerfDir string
 	ProjectName      string
+	Platform         string
 }
 
 func newBaseStage(name StageName, options *NewBaseStageOptions) *BaseStage {
@@ -73,23 +77,38 @@ func newBaseStage(name StageName, options *NewBaseStageOptions) *BaseStage {
 	s.name = name
 	s.imageName = options.ImageName
 	s.configMounts = options.ConfigMounts
+	s.configSecrets = options.ConfigSecrets
 	s.imageTmpDir = options.ImageTmpDir
 	s.containerWerfDir = options.ContainerWerfDir
 	s.projectName = options.ProjectName
+	s.platform = options.Platform
 	return s
 }
 
 type BaseStage struct {
-	name             StageName
-	imageName        string
-	digest           string
-	contentDigest    string
-	image            container_runtime.ImageInterface
-	gitMappings      []*GitMapping
-	imageTmpDir      string
-	containerWerfDir string
-	configMounts     []*config.Mount
-	projectName      string
+	name               StageName
+	imageName          string
+	digest             string
+	contentDigest      string
+	dependenciesDigest string
+	dependenciesInputs []DependencyInput
+	image              container_runtime.ImageInterface
+	gitMappings        []*GitMapping
+	imageTmpDir        string
+	containerWerfDir   string
+	configMounts       []*config.Mount
+	configSecrets      []*config.Secret
+	projectName        string
+	platform           string
+}
+
+// PlatformImageName returns the name used to address the image built for the specified platform within the conveyor
+func PlatformImageName(imageName, platform string) string {
+	if platform == "" {
+		return imageName
+	}
+
+	return fmt.Sprintf("%s@%s", imageName, platform)
 }
 
 func (s *BaseStage) LogDetailedName() string {
@@ -98,6 +117,10 @@ func (s *BaseStage) LogDetailedName() string {
 		imageName = "~"
 	}
 
+	if s.platform != "" {
+		imageName = fmt.Sprintf("%s[%s]", imageName, s.platform)
+	}
+
 	return fmt.Sprintf("%s/%s", imageName, s.Name())
 }
 
@@ -245,9 +268,75 @@ func (s *BaseStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage
 		return fmt.Errorf("error adding mounts volumes: %s", err)
 	}
 
+	if err := s.addSecrets(ctx, c, image); err != nil {
+		return fmt.Errorf("error adding secrets: %s", err)
+	}
+
+	return nil
+}
+
+// addSecrets mounts the dir with the stage secrets into the werf service dir of the build container.
+// The secret files are copied to the secret container paths before the commands and removed after them,
+// so that neither the secrets nor the secret mountpoints are committed. The secrets are not labeled
+func (s *BaseStage) addSecrets(ctx context.Context, c Conveyor, image container_runtime.ImageInterface) error {
+	var secretsHostDir string
+	secretsContainerDir := path.Join(s.containerWerfDir, "secrets")
+
+	for ind, secretCfg := range s.configSecrets {
+		if !secretCfg.IsUsedInStage(string(s.name)) {
+			continue
+		}
+
+		secret, err := c.GetSecret(ctx, secretCfg)
+		if err != nil {
+			return err
+		}
+
+		// the stage secrets dir is removed with the build secrets dir
+		if secretsHostDir == "" {
+			secretsHostDir = filepath.Join(filepath.Dir(secret.HostPath), fmt.Sprintf("%s-%s", s.name, util.GenerateConsistentRandomString(10)))
+			if err := os.MkdirAll(secretsHostDir, 0700); err != nil {
+				return fmt.Errorf("unable to create dir %s: %s", secretsHostDir, err)
+			}
+
+			image.Container().RunOptions().AddVolume(fmt.Sprintf("%s:%s:ro", secretsHostDir, secretsContainerDir))
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(secretsHostDir, secretCfg.Id), secret.Data, 0600); err != nil {
+			return fmt.Errorf("unable to write secret %q: %s", secretCfg.Id, err)
+		}
+
+		containerPath := path.Join(secretsContainerDir, secretCfg.Id)
+		if secretCfg.ToEnv != "" {
+			image.Container().AddServiceRunCommands(fmt.Sprintf("export %s=\"$(%s %s)\"", secretCfg.ToEnv, stapel.CatBinPath(), containerPath))
+		} else {
+			dirsVar := fmt.Sprintf("werf_secret_dirs_%d", ind)
+			image.Container().AddServiceRunCommands(secretFileCopyCommand(secretCfg.Id, containerPath, secretCfg.ContainerPath(), dirsVar))
+			image.Container().AddServiceCleanupCommands(secretFileRemoveCommand(secretCfg.ContainerPath(), dirsVar))
+		}
+
+		image.Container().AddSecretValuesToMask(secret.ValuesToMask...)
+	}
+
 	return nil
 }
 
+// secretFileCopyCommand copies the secret file to the container path and saves the created parent dirs into the dirsVar shell variable
+func secretFileCopyCommand(id, secretPath, containerPath, dirsVar string) string {
+	return fmt.Sprintf(
+		`{ if [ -e "%[3]s" ]; then echo "secret %[1]s: %[3]s already exists" >&2; exit 1; fi; %[4]s=""; d="%[5]s"; while [ -n "$d" ] && [ ! -e "$d" ]; do %[4]s="$%[4]s $d"; d="${d%%/*}"; done; %[6]s -p "%[5]s"; %[7]s -m 0400 "%[2]s" "%[3]s"; }`,
+		id, secretPath, containerPath, dirsVar, path.Dir(containerPath), stapel.MkdirBinPath(), stapel.InstallBinPath(),
+	)
+}
+
+// secretFileRemoveCommand removes the secret file and the parent dirs created by secretFileCopyCommand unless the dirs are used by the commands
+func secretFileRemoveCommand(containerPath, dirsVar string) string {
+	return fmt.Sprintf(
+		`{ %[1]s -f "%[2]s"; for d in $%[3]s; do %[1]s -d "$d" 2>/dev/null || true; done; }`,
+		stapel.RmBinPath(), containerPath, dirsVar,
+	)
+}
+
 func (s *BaseStage) PreRunHook(_ context.Context, _ Conveyor) error {
 	return nil
 }
@@ -432,6 +521,22 @@ func (s *BaseStage) GetContentDigest() string {
 	return s.contentDigest
 }
 
+func (s *BaseStage) SetDependenciesDigest(dependenciesDigest string) {
+	s.dependenciesDigest = dependenciesDigest
+}
+
+func (s *BaseStage) GetDependenciesDigest() string {
+	return s.dependenciesDigest
+}
+
+func (s *BaseStage) GetDependenciesInputs() []DependencyInput {
+	return s.dependenciesInputs
+}
+
+func (s *BaseStage) setDependenciesInputs(inputs []DependencyInput) {
+	s.dependenciesInputs = inputs
+}
+
 func (s *BaseStage) SetImage(image container_runtime.ImageInterface) {
 	s.image = image
 }
@@ -0,0 +1,132 @@
+package stage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/stapel"
+)
+
+type secretsTestConveyor struct {
+	Conveyor
+
+	secretsDir string
+}
+
+func (c *secretsTestConveyor) GetSecret(_ context.Context, secretCfg *config.Secret) (*Secret, error) {
+	hostPath := filepath.Join(c.secretsDir, secretCfg.Id+"-test")
+	if err := ioutil.WriteFile(hostPath, []byte("s3cr3t"), 0600); err != nil {
+		return nil, err
+	}
+
+	return &Secret{HostPath: hostPath, Data: []byte("s3cr3t"), ValuesToMask: []string{"s3cr3t"}}, nil
+}
+
+func TestBaseStage_addSecrets(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-stage-secrets-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	secrets := []*config.Secret{
+		{Id: "token", FromEnv: "TOKEN"},
+		{Id: "password", FromEnv: "PASSWORD", To: "/etc/app/password"},
+		{Id: "key", FromEnv: "KEY", ToEnv: "KEY"},
+	}
+	baseStageOptions := &NewBaseStageOptions{ConfigSecrets: secrets, ContainerWerfDir: "/.werf", ImageTmpDir: tmpDir}
+	shell := &config.StapelImageBase{Shell: &config.Shell{BeforeInstall: []string{"cat /run/secrets/token"}}}
+
+	s := GenerateBeforeInstallStage(context.Background(), shell, baseStageOptions)
+	img := container_runtime.NewStageImage(nil, "test", nil)
+	if err := s.addSecrets(context.Background(), &secretsTestConveyor{secretsDir: tmpDir}, img); err != nil {
+		t.Fatal(err)
+	}
+
+	volumes := img.Container().RunOptions().(*container_runtime.StageImageContainerOptions).Volume
+	if len(volumes) != 1 || !strings.HasSuffix(volumes[0], ":/.werf/secrets:ro") {
+		t.Fatalf("expected only the stage secrets dir to be mounted into the werf service dir, got %v", volumes)
+	}
+
+	secretsHostDir := strings.TrimSuffix(volumes[0], ":/.werf/secrets:ro")
+	for _, secretCfg := range secrets {
+		if data, err := ioutil.ReadFile(filepath.Join(secretsHostDir, secretCfg.Id)); err != nil || string(data) != "s3cr3t" {
+			t.Errorf("expected secret %q in the stage secrets dir, got %q: %v", secretCfg.Id, data, err)
+		}
+	}
+
+	sWithoutSecrets := GenerateBeforeInstallStage(context.Background(), shell, &NewBaseStageOptions{ContainerWerfDir: "/.werf", ImageTmpDir: tmpDir})
+	dependencies, err := s.GetDependencies(context.Background(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dependenciesWithoutSecrets, err := sWithoutSecrets.GetDependencies(context.Background(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dependencies != dependenciesWithoutSecrets {
+		t.Errorf("expected secrets not to affect the stage dependencies")
+	}
+	for _, input := range s.dependenciesInputs {
+		if strings.Contains(input.Value, "s3cr3t") || strings.Contains(input.Value, "/run/secrets") {
+			t.Errorf("unexpected secret in the dependency input %q: %q", input.Name, input.Value)
+		}
+	}
+}
+
+func TestSecretFileCommands(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is required")
+	}
+
+	rootfs, err := ioutil.TempDir("", "werf-stage-secrets-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	secretPath := filepath.Join(rootfs, ".werf", "secrets", "token")
+	if err := os.MkdirAll(filepath.Dir(secretPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(secretPath, []byte("s3cr3t"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(rootfs, "run"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	containerPath := path.Join(rootfs, "run", "secrets", "nested", "token")
+	command := strings.Join([]string{
+		secretFileCopyCommand("token", secretPath, containerPath, "werf_secret_dirs_0"),
+		`test "$(cat ` + containerPath + `)" = s3cr3t`,
+		secretFileRemoveCommand(containerPath, "werf_secret_dirs_0"),
+	}, " && ")
+	for _, bin := range []string{stapel.MkdirBinPath(), stapel.InstallBinPath(), stapel.RmBinPath()} {
+		command = strings.Replace(command, bin, path.Base(bin), -1)
+	}
+
+	if output, err := exec.Command(bash, "-ec", command).CombinedOutput(); err != nil {
+		t.Fatalf("secret commands failed: %s\n%s", err, output)
+	}
+
+	if _, err := os.Stat(filepath.Join(rootfs, "run", "secrets")); !os.IsNotExist(err) {
+		t.Errorf("expected the secret path with the created parent dirs to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rootfs, "run")); err != nil {
+		t.Errorf("expected the existing parent dir to be kept: %s", err)
+	}
+
+	if output, err := exec.Command(bash, "-ec", strings.Replace(secretFileCopyCommand("token", secretPath, secretPath, "werf_secret_dirs_0"), stapel.MkdirBinPath(), "mkdir", -1)).CombinedOutput(); err == nil || !strings.Contains(string(output), "already exists") {
+		t.Errorf("expected error when the secret path already exists, got %v: %s", err, output)
+	}
+}
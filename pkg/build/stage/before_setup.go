@@ -29,12 +29,15 @@ type BeforeSetupStage struct {
 }
 
 func (s *BeforeSetupStage) GetDependencies(ctx context.Context, c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
-	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(ctx, c, BeforeSetup)
+	stageDependenciesChecksum, stageDependenciesInputs, err := s.getStageDependenciesChecksum(ctx, c, BeforeSetup)
 	if err != nil {
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.BeforeSetupChecksum(ctx), stageDependenciesChecksum), nil
+	builderChecksum := s.builder.BeforeSetupChecksum(ctx)
+	s.setDependenciesInputs(append([]DependencyInput{NewDependencyInput("beforeSetup builder checksum", builderChecksum)}, stageDependenciesInputs...))
+
+	return util.Sha256Hash(builderChecksum, stageDependenciesChecksum), nil
 }
 
 func (s *BeforeSetupStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
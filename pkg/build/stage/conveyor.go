@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/werf/werf/pkg/build/import_server"
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/giterminism_manager"
 	"github.com/werf/werf/pkg/storage"
 )
@@ -24,10 +25,19 @@ type Conveyor interface {
 
 	GetImportServer(ctx context.Context, imageName, stageName string) (import_server.ImportServer, error)
 	GetLocalGitRepoVirtualMergeOptions() VirtualMergeOptions
+	GetBuildkitAddress() string
+	GetSecret(ctx context.Context, secret *config.Secret) (*Secret, error)
 
 	GiterminismManager() giterminism_manager.Interface
 }
 
+// Secret is the secret data prepared for the build, the data is not stored in the built images and does not affect the stage digests
+type Secret struct {
+	HostPath     string
+	Data         []byte
+	ValuesToMask []string
+}
+
 type VirtualMergeOptions struct {
 	VirtualMerge           bool
 	VirtualMergeFromCommit string
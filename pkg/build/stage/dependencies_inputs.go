@@ -0,0 +1,87 @@
+package stage
+
+import (
+	"fmt"
+
+	"github.com/werf/werf/pkg/util"
+)
+
+// dependencyInputMaxValueLength limits the length of the input value kept as is, longer values are replaced with the checksum
+const dependencyInputMaxValueLength = 256
+
+// DependencyInput is a named input of the stage digest calculation
+type DependencyInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func NewDependencyInput(name, value string) DependencyInput {
+	return DependencyInput{Name: name, Value: value}
+}
+
+func dependenciesInputsValues(inputs []DependencyInput) []string {
+	var values []string
+	for _, input := range inputs {
+		values = append(values, input.Value)
+	}
+	return values
+}
+
+// CompactDependenciesInputs replaces long values (patches, scripts) with checksums and makes names unique,
+// so the inputs can be stored in the stage image label and compared by name
+func CompactDependenciesInputs(inputs []DependencyInput) []DependencyInput {
+	var res []DependencyInput
+	namesCounter := map[string]int{}
+	for _, input := range inputs {
+		namesCounter[input.Name]++
+		if namesCounter[input.Name] > 1 {
+			input.Name = fmt.Sprintf("%s #%d", input.Name, namesCounter[input.Name])
+		}
+
+		if len(input.Value) > dependencyInputMaxValueLength {
+			input.Value = fmt.Sprintf("sha256:%s", util.Sha256Hash(input.Value))
+		}
+
+		res = append(res, input)
+	}
+
+	return res
+}
+
+type DependencyInputDiff struct {
+	Name      string `json:"name"`
+	OldValue  string `json:"oldValue,omitempty"`
+	NewValue  string `json:"newValue,omitempty"`
+	IsAdded   bool   `json:"isAdded,omitempty"`
+	IsRemoved bool   `json:"isRemoved,omitempty"`
+}
+
+// DiffDependenciesInputs compares compacted inputs by name and returns changed, added and removed inputs in the order of appearance
+func DiffDependenciesInputs(oldInputs, newInputs []DependencyInput) []DependencyInputDiff {
+	oldValues := map[string]string{}
+	for _, input := range oldInputs {
+		oldValues[input.Name] = input.Value
+	}
+
+	newValues := map[string]string{}
+	for _, input := range newInputs {
+		newValues[input.Name] = input.Value
+	}
+
+	var res []DependencyInputDiff
+	for _, input := range newInputs {
+		if oldValue, hasOld := oldValues[input.Name]; !hasOld {
+			res = append(res, DependencyInputDiff{Name: input.Name, NewValue: input.Value, IsAdded: true})
+		} else if oldValue != input.Value {
+			res = append(res, DependencyInputDiff{Name: input.Name, OldValue: oldValue, NewValue: input.Value})
+		}
+	}
+
+	for _, input := range oldInputs {
+		if _, hasNew := newValues[input.Name]; !hasNew {
+			res = append(res, DependencyInputDiff{Name: input.Name, OldValue: input.Value, IsRemoved: true})
+		}
+	}
+
+	return res
+}
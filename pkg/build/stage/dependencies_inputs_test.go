@@ -0,0 +1,42 @@
+package stage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffDependenciesInputs(t *testing.T) {
+	oldInputs := CompactDependenciesInputs([]DependencyInput{
+		NewDependencyInput("install builder checksum", "aaa"),
+		NewDependencyInput("git mapping / stageDependencies checksum", "bbb"),
+		NewDependencyInput("git mapping / stageDependencies checksum", "ccc"),
+		NewDependencyInput("removed", "ddd"),
+	})
+
+	newInputs := CompactDependenciesInputs([]DependencyInput{
+		NewDependencyInput("install builder checksum", "aaa"),
+		NewDependencyInput("git mapping / stageDependencies checksum", "bbb"),
+		NewDependencyInput("git mapping / stageDependencies checksum", "eee"),
+		NewDependencyInput("added", strings.Repeat("x", dependencyInputMaxValueLength+1)),
+	})
+
+	if !strings.HasPrefix(newInputs[3].Value, "sha256:") {
+		t.Errorf("expected long value to be replaced with checksum, got %q", newInputs[3].Value)
+	}
+
+	diffs := DiffDependenciesInputs(oldInputs, newInputs)
+	expected := []DependencyInputDiff{
+		{Name: "git mapping / stageDependencies checksum #2", OldValue: "ccc", NewValue: "eee"},
+		{Name: "added", NewValue: newInputs[3].Value, IsAdded: true},
+		{Name: "removed", OldValue: "ddd", IsRemoved: true},
+	}
+
+	if len(diffs) != len(expected) {
+		t.Fatalf("expected %d diffs, got %d: %#v", len(expected), len(diffs), diffs)
+	}
+	for ind := range expected {
+		if diffs[ind] != expected[ind] {
+			t.Errorf("expected diff %#v, got %#v", expected[ind], diffs[ind])
+		}
+	}
+}
@@ -2,6 +2,7 @@ package stage
 
 import (
 	"context"
+	"fmt"
 	"sort"
 
 	"github.com/werf/werf/pkg/config"
@@ -43,9 +44,36 @@ func (s *DockerInstructionsStage) GetDependencies(_ context.Context, _ Conveyor,
 	args = append(args, s.instructions.User)
 	args = append(args, s.instructions.HealthCheck)
 
+	var inputs []DependencyInput
+	for _, volume := range s.instructions.Volume {
+		inputs = append(inputs, NewDependencyInput("volume", volume))
+	}
+	for _, expose := range s.instructions.Expose {
+		inputs = append(inputs, NewDependencyInput("expose", expose))
+	}
+	inputs = append(inputs, mapToDependenciesInputs("env", s.instructions.Env)...)
+	inputs = append(inputs, mapToDependenciesInputs("label", s.instructions.Label)...)
+	inputs = append(inputs,
+		NewDependencyInput("cmd", s.instructions.Cmd),
+		NewDependencyInput("entrypoint", s.instructions.Entrypoint),
+		NewDependencyInput("workdir", s.instructions.Workdir),
+		NewDependencyInput("user", s.instructions.User),
+		NewDependencyInput("healthcheck", s.instructions.HealthCheck),
+	)
+	s.setDependenciesInputs(inputs)
+
 	return util.Sha256Hash(args...), nil
 }
 
+func mapToDependenciesInputs(prefix string, h map[string]string) (result []DependencyInput) {
+	args := mapToSortedArgs(h)
+	for ind := 0; ind < len(args); ind += 2 {
+		result = append(result, NewDependencyInput(fmt.Sprintf("%s %s", prefix, args[ind]), args[ind+1]))
+	}
+
+	return
+}
+
 func mapToSortedArgs(h map[string]string) (result []string) {
 	keys := make([]string, 0, len(h))
 	for key := range h {
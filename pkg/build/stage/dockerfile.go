@@ -254,9 +254,7 @@ type dockerfileInstructionInterface interface {
 	Name() string
 }
 
-func (s *DockerfileStage) FetchDependencies(ctx context.Context, _ Conveyor, cr container_runtime.ContainerRuntime) error {
-	containerRuntime := cr.(*container_runtime.LocalDockerServerRuntime)
-
+func (s *DockerfileStage) FetchDependencies(ctx context.Context, _ Conveyor, containerRuntime container_runtime.ContainerRuntime) error {
 outerLoop:
 	for ind, stage := range s.dockerStages {
 		for relatedStageIndex, relatedStage := range s.dockerStages {
@@ -338,21 +336,23 @@ func isUnsupportedMediaTypeError(err error) bool {
 var imageNotExistLocally = errors.New("IMAGE_NOT_EXIST_LOCALLY")
 
 func (s *DockerfileStage) GetDependencies(ctx context.Context, c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
-	var stagesDependencies [][]string
-	var stagesOnBuildDependencies [][]string
+	var stagesDependencies [][]DependencyInput
+	var stagesOnBuildDependencies [][]DependencyInput
 
 	for ind, stage := range s.dockerStages {
-		var dependencies []string
-		var onBuildDependencies []string
+		var dependencies []DependencyInput
+		var onBuildDependencies []DependencyInput
 
-		dependencies = append(dependencies, s.addHost...)
+		for _, addHost := range s.addHost {
+			dependencies = append(dependencies, NewDependencyInput("add-host", addHost))
+		}
 
 		resolvedBaseName, err := s.ShlexProcessWordWithMetaArgs(stage.BaseName)
 		if err != nil {
 			return "", err
 		}
 
-		dependencies = append(dependencies, resolvedBaseName)
+		dependencies = append(dependencies, NewDependencyInput(fmt.Sprintf("stage %d FROM", ind), resolvedBaseName))
 
 		onBuildInstructions, ok := s.imageOnBuildInstructions[resolvedBaseName]
 		if ok {
@@ -406,17 +406,26 @@ func (s *DockerfileStage) GetDependencies(ctx context.Context, c Conveyor, _, _
 	}
 
 	dockerfileStageDependencies := stagesDependencies[s.dockerTargetStageIndex]
+	if s.platform != "" {
+		dockerfileStageDependencies = append(dockerfileStageDependencies, NewDependencyInput("platform", s.platform))
+	}
 
 	if dockerfileStageDependenciesDebug() {
-		logboek.Context(ctx).LogLn(dockerfileStageDependencies)
+		logboek.Context(ctx).LogLn(dependenciesInputsValues(dockerfileStageDependencies))
 	}
 
-	return util.Sha256Hash(dockerfileStageDependencies...), nil
+	s.setDependenciesInputs(dockerfileStageDependencies)
+
+	return util.Sha256Hash(dependenciesInputsValues(dockerfileStageDependencies)...), nil
 }
 
-func (s *DockerfileStage) dockerfileInstructionDependencies(ctx context.Context, giterminismManager giterminism_manager.Interface, dockerStageID int, cmd interface{}, isOnbuildInstruction bool, isBaseImageOnbuildInstruction bool) ([]string, []string, error) {
-	var dependencies []string
-	var onBuildDependencies []string
+func (s *DockerfileStage) dockerfileInstructionDependencies(ctx context.Context, giterminismManager giterminism_manager.Interface, dockerStageID int, cmd interface{}, isOnbuildInstruction bool, isBaseImageOnbuildInstruction bool) ([]DependencyInput, []DependencyInput, error) {
+	var dependencies []DependencyInput
+	var onBuildDependencies []DependencyInput
+
+	inputName := func(instruction string) string {
+		return fmt.Sprintf("stage %d %s", dockerStageID, instruction)
+	}
 
 	resolveValueFunc := func(value string) (string, error) {
 		if isBaseImageOnbuildInstruction {
@@ -509,7 +518,7 @@ func (s *DockerfileStage) dockerfileInstructionDependencies(ctx context.Context,
 			return nil, nil, err
 		}
 
-		dependencies = append(dependencies, fmt.Sprintf("ARG %s=%s", resolvedKey, resolvedValue))
+		dependencies = append(dependencies, NewDependencyInput(inputName(fmt.Sprintf("ARG %s", resolvedKey)), fmt.Sprintf("ARG %s=%s", resolvedKey, resolvedValue)))
 	case *instructions.EnvCommand:
 		for _, keyValuePair := range c.Env {
 			resolvedKey, resolvedValue, err := processEnvFunc(keyValuePair.Key, keyValuePair.Value)
@@ -517,10 +526,10 @@ func (s *DockerfileStage) dockerfileInstructionDependencies(ctx context.Context,
 				return nil, nil, err
 			}
 
-			dependencies = append(dependencies, fmt.Sprintf("ENV %s=%s", resolvedKey, resolvedValue))
+			dependencies = append(dependencies, NewDependencyInput(inputName(fmt.Sprintf("ENV %s", resolvedKey)), fmt.Sprintf("ENV %s=%s", resolvedKey, resolvedValue)))
 		}
 	case *instructions.AddCommand:
-		dependencies = append(dependencies, c.String())
+		dependencies = append(dependencies, NewDependencyInput(inputName("ADD"), c.String()))
 
 		resolvedSources, err := resolveSourcesFunc(c.SourcesAndDest.Sources())
 		if err != nil {
@@ -531,9 +540,9 @@ func (s *DockerfileStage) dockerfileInstructionDependencies(ctx context.Context,
 		if err != nil {
 			return nil, nil, err
 		}
-		dependencies = append(dependencies, checksum)
+		dependencies = append(dependencies, NewDependencyInput(inputName(fmt.Sprintf("ADD %s files checksum", strings.Join(resolvedSources, " "))), checksum))
 	case *instructions.CopyCommand:
-		dependencies = append(dependencies, c.String())
+		dependencies = append(dependencies, NewDependencyInput(inputName("COPY"), c.String()))
 		if c.From == "" {
 			resolvedSources, err := resolveSourcesFunc(c.SourcesAndDest.Sources())
 			if err != nil {
@@ -544,7 +553,7 @@ func (s *DockerfileStage) dockerfileInstructionDependencies(ctx context.Context,
 			if err != nil {
 				return nil, nil, err
 			}
-			dependencies = append(dependencies, checksum)
+			dependencies = append(dependencies, NewDependencyInput(inputName(fmt.Sprintf("COPY %s files checksum", strings.Join(resolvedSources, " "))), checksum))
 		}
 	case *instructions.OnbuildCommand:
 		cDependencies, cOnBuildDependencies, err := s.dockerfileOnBuildInstructionDependencies(ctx, giterminismManager, dockerStageID, c.Expression, false)
@@ -560,7 +569,7 @@ func (s *DockerfileStage) dockerfileInstructionDependencies(ctx context.Context,
 			return nil, nil, err
 		}
 
-		dependencies = append(dependencies, resolvedValue)
+		dependencies = append(dependencies, NewDependencyInput(inputName(strings.ToUpper(c.Name())), resolvedValue))
 	default:
 		panic("runtime error")
 	}
@@ -568,7 +577,7 @@ func (s *DockerfileStage) dockerfileInstructionDependencies(ctx context.Context,
 	return dependencies, onBuildDependencies, nil
 }
 
-func (s *DockerfileStage) dockerfileOnBuildInstructionDependencies(ctx context.Context, giterminismManager giterminism_manager.Interface, dockerStageID int, expression string, isBaseImageOnbuildInstruction bool) ([]string, []string, error) {
+func (s *DockerfileStage) dockerfileOnBuildInstructionDependencies(ctx context.Context, giterminismManager giterminism_manager.Interface, dockerStageID int, expression string, isBaseImageOnbuildInstruction bool) ([]DependencyInput, []DependencyInput, error) {
 	p, err := parser.Parse(bytes.NewReader([]byte(expression)))
 	if err != nil {
 		return nil, nil, err
@@ -589,7 +598,7 @@ func (s *DockerfileStage) dockerfileOnBuildInstructionDependencies(ctx context.C
 		return nil, nil, err
 	}
 
-	return []string{expression}, onBuildDependencies, nil
+	return []DependencyInput{NewDependencyInput(fmt.Sprintf("stage %d ONBUILD", dockerStageID), expression)}, onBuildDependencies, nil
 }
 
 func (s *DockerfileStage) PrepareImage(ctx context.Context, c Conveyor, _, img container_runtime.ImageInterface) error {
@@ -602,10 +611,24 @@ func (s *DockerfileStage) PrepareImage(ctx context.Context, c Conveyor, _, img c
 	img.DockerfileImageBuilder().AppendBuildArgs(fmt.Sprintf("--label=%s=%s", image.WerfProjectRepoCommitLabel, c.GiterminismManager().HeadCommit()))
 	img.DockerfileImageBuilder().SetFilePathToStdin(archivePath)
 
+	if buildkitAddress := c.GetBuildkitAddress(); buildkitAddress != "" {
+		img.DockerfileImageBuilder().UseBuildkit(buildkitAddress)
+	}
+
 	if c.GiterminismManager().Dev() {
 		img.DockerfileImageBuilder().AppendBuildArgs(fmt.Sprintf("--label=%s=true", image.WerfDevLabel))
 	}
 
+	for _, secretCfg := range s.configSecrets {
+		secret, err := c.GetSecret(ctx, secretCfg)
+		if err != nil {
+			return fmt.Errorf("error adding secrets: %s", err)
+		}
+
+		img.DockerfileImageBuilder().AddSecret(secretCfg.Id, secret.Data)
+		img.DockerfileImageBuilder().AddSecretValuesToMask(secret.ValuesToMask...)
+	}
+
 	return nil
 }
 
@@ -668,6 +691,10 @@ func (s *DockerfileStage) DockerBuildArgs() []string {
 		result = append(result, fmt.Sprintf("--ssh=%s", s.ssh))
 	}
 
+	if s.platform != "" {
+		result = append(result, fmt.Sprintf("--platform=%s", s.platform))
+	}
+
 	return result
 }
 
@@ -48,27 +48,37 @@ type FromStage struct {
 }
 
 func (s *FromStage) GetDependencies(_ context.Context, c Conveyor, prevImage, _ container_runtime.ImageInterface) (string, error) {
-	var args []string
+	var inputs []DependencyInput
 
 	if s.cacheVersion != "" {
-		args = append(args, s.cacheVersion)
+		inputs = append(inputs, NewDependencyInput("fromCacheVersion", s.cacheVersion))
 	}
 
 	if s.baseImageRepoIdOrNone != "" {
-		args = append(args, s.baseImageRepoIdOrNone)
+		inputs = append(inputs, NewDependencyInput("base image repo id", s.baseImageRepoIdOrNone))
+	}
+
+	if s.platform != "" {
+		inputs = append(inputs, NewDependencyInput("platform", s.platform))
 	}
 
 	for _, mount := range s.configMounts {
-		args = append(args, filepath.ToSlash(filepath.Clean(mount.From)), path.Clean(mount.To), mount.Type)
+		inputs = append(inputs,
+			NewDependencyInput(fmt.Sprintf("mount %s from", mount.To), filepath.ToSlash(filepath.Clean(mount.From))),
+			NewDependencyInput(fmt.Sprintf("mount %s to", mount.To), path.Clean(mount.To)),
+			NewDependencyInput(fmt.Sprintf("mount %s type", mount.To), mount.Type),
+		)
 	}
 
 	if s.fromImageOrArtifactImageName != "" {
-		args = append(args, c.GetImageContentDigest(s.fromImageOrArtifactImageName))
+		inputs = append(inputs, NewDependencyInput(fmt.Sprintf("image %s content digest", s.fromImageOrArtifactImageName), c.GetImageContentDigest(PlatformImageName(s.fromImageOrArtifactImageName, s.platform))))
 	} else {
-		args = append(args, prevImage.Name())
+		inputs = append(inputs, NewDependencyInput("base image", prevImage.Name()))
 	}
 
-	return util.Sha256Hash(args...), nil
+	s.setDependenciesInputs(inputs)
+
+	return util.Sha256Hash(dependenciesInputsValues(inputs)...), nil
 }
 
 func (s *FromStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
@@ -49,6 +49,7 @@ func (s *GitArchiveStage) SelectSuitableStage(ctx context.Context, c Conveyor, s
 
 func (s *GitArchiveStage) GetDependencies(ctx context.Context, c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
 	var args []string
+	var inputs []DependencyInput
 	for _, gitMapping := range s.gitMappings {
 		if gitMapping.LocalGitRepo != nil {
 			if err := c.GiterminismManager().Inspector().InspectBuildContextFiles(ctx, path_matcher.NewGitMappingPathMatcher(gitMapping.Add, gitMapping.IncludePaths, gitMapping.ExcludePaths, true)); err != nil {
@@ -57,9 +58,11 @@ func (s *GitArchiveStage) GetDependencies(ctx context.Context, c Conveyor, _, _
 		}
 
 		args = append(args, gitMapping.GetParamshash())
+		inputs = append(inputs, NewDependencyInput(fmt.Sprintf("git mapping %s params hash", gitMapping.Name), gitMapping.GetParamshash()))
 	}
 
 	sort.Strings(args)
+	s.setDependenciesInputs(inputs)
 
 	return util.Sha256Hash(args...), nil
 }
@@ -52,7 +52,10 @@ func (s *GitCacheStage) GetDependencies(ctx context.Context, c Conveyor, _, prev
 		return "", err
 	}
 
-	return util.Sha256Hash(fmt.Sprintf("%d", patchSize/patchSizeStep)), nil
+	patchSizeSteps := fmt.Sprintf("%d", patchSize/patchSizeStep)
+	s.setDependenciesInputs([]DependencyInput{NewDependencyInput("git mappings patch size steps", patchSizeSteps)})
+
+	return util.Sha256Hash(patchSizeSteps), nil
 }
 
 func (s *GitCacheStage) gitMappingsPatchSize(ctx context.Context, c Conveyor, prevBuiltImage container_runtime.ImageInterface) (int64, error) {
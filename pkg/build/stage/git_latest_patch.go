@@ -51,7 +51,7 @@ func (s *GitLatestPatchStage) IsEmpty(ctx context.Context, c Conveyor, prevBuilt
 }
 
 func (s *GitLatestPatchStage) GetDependencies(ctx context.Context, c Conveyor, _, prevBuiltImage container_runtime.ImageInterface) (string, error) {
-	var args []string
+	var inputs []DependencyInput
 
 	for _, gitMapping := range s.gitMappings {
 		patchContent, err := gitMapping.GetPatchContent(ctx, c, prevBuiltImage)
@@ -59,10 +59,12 @@ func (s *GitLatestPatchStage) GetDependencies(ctx context.Context, c Conveyor, _
 			return "", fmt.Errorf("error getting patch between previous built image %s and current commit for git mapping %s: %s", prevBuiltImage.Name(), gitMapping.Name, err)
 		}
 
-		args = append(args, patchContent)
+		inputs = append(inputs, NewDependencyInput(fmt.Sprintf("git mapping %s patch", gitMapping.Name), patchContent))
 	}
 
-	return util.Sha256Hash(args...), nil
+	s.setDependenciesInputs(inputs)
+
+	return util.Sha256Hash(dependenciesInputsValues(inputs)...), nil
 }
 
 func (s *GitLatestPatchStage) SelectSuitableStage(ctx context.Context, c Conveyor, stages []*image.StageDescription) (*image.StageDescription, error) {
@@ -53,6 +53,7 @@ type ImportsStage struct {
 
 func (s *ImportsStage) GetDependencies(ctx context.Context, c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
 	var args []string
+	var inputs []DependencyInput
 
 	for ind, elm := range s.imports {
 		var sourceChecksum string
@@ -67,14 +68,23 @@ func (s *ImportsStage) GetDependencies(ctx context.Context, c Conveyor, _, _ con
 		args = append(args, sourceChecksum)
 		args = append(args, elm.To)
 		args = append(args, elm.Group, elm.Owner)
+
+		inputs = append(inputs,
+			NewDependencyInput(fmt.Sprintf("import %s:%s source checksum", getSourceImageName(elm), elm.Add), sourceChecksum),
+			NewDependencyInput(fmt.Sprintf("import %s:%s to", getSourceImageName(elm), elm.Add), elm.To),
+			NewDependencyInput(fmt.Sprintf("import %s:%s group", getSourceImageName(elm), elm.Add), elm.Group),
+			NewDependencyInput(fmt.Sprintf("import %s:%s owner", getSourceImageName(elm), elm.Add), elm.Owner),
+		)
 	}
 
+	s.setDependenciesInputs(inputs)
+
 	return util.Sha256Hash(args...), nil
 }
 
 func (s *ImportsStage) PrepareImage(ctx context.Context, c Conveyor, _, image container_runtime.ImageInterface) error {
 	for _, elm := range s.imports {
-		sourceImageName := getSourceImageName(elm)
+		sourceImageName := PlatformImageName(getSourceImageName(elm), s.platform)
 		srv, err := c.GetImportServer(ctx, sourceImageName, elm.Stage)
 		if err != nil {
 			return fmt.Errorf("unable to get import server for image %q: %s", sourceImageName, err)
@@ -87,7 +97,7 @@ func (s *ImportsStage) PrepareImage(ctx context.Context, c Conveyor, _, image co
 
 		labelKey := imagePkg.WerfImportChecksumLabelPrefix + getImportID(elm)
 
-		importSourceID := getImportSourceID(c, elm)
+		importSourceID := getImportSourceID(c, elm, s.platform)
 		importMetadata, err := c.GetImportMetadata(ctx, s.projectName, importSourceID)
 		if err != nil {
 			return fmt.Errorf("unable to get import source checksum: %s", err)
@@ -103,7 +113,7 @@ func (s *ImportsStage) PrepareImage(ctx context.Context, c Conveyor, _, image co
 }
 
 func (s *ImportsStage) getImportSourceChecksum(ctx context.Context, c Conveyor, importElm *config.Import) (string, error) {
-	importSourceID := getImportSourceID(c, importElm)
+	importSourceID := getImportSourceID(c, importElm, s.platform)
 	importMetadata, err := c.GetImportMetadata(ctx, s.projectName, importSourceID)
 	if err != nil {
 		return "", fmt.Errorf("unable to get import metadata: %s", err)
@@ -115,7 +125,7 @@ func (s *ImportsStage) getImportSourceChecksum(ctx context.Context, c Conveyor,
 			return "", fmt.Errorf("unable to generate import source checksum: %s", err)
 		}
 
-		sourceImageID := getSourceImageID(c, importElm)
+		sourceImageID := getSourceImageID(c, importElm, s.platform)
 		importMetadata = &storage.ImportMetadata{
 			ImportSourceID: importSourceID,
 			SourceImageID:  sourceImageID,
@@ -131,8 +141,8 @@ func (s *ImportsStage) getImportSourceChecksum(ctx context.Context, c Conveyor,
 }
 
 func (s *ImportsStage) generateImportChecksum(ctx context.Context, c Conveyor, importElm *config.Import) (string, error) {
-	sourceImageDockerImageName := getSourceImageDockerImageName(c, importElm)
-	importSourceID := getImportSourceID(c, importElm)
+	sourceImageDockerImageName := getSourceImageDockerImageName(c, importElm, s.platform)
+	importSourceID := getImportSourceID(c, importElm, s.platform)
 
 	stapelContainerName, err := stapel.GetOrCreateContainer(ctx)
 	if err != nil {
@@ -232,17 +242,17 @@ func getImportID(importElm *config.Import) string {
 	)
 }
 
-func getImportSourceID(c Conveyor, importElm *config.Import) string {
+func getImportSourceID(c Conveyor, importElm *config.Import, platform string) string {
 	return util.Sha256Hash(
-		"SourceImageContentDigest", getSourceImageContentDigest(c, importElm),
+		"SourceImageContentDigest", getSourceImageContentDigest(c, importElm, platform),
 		"Add", importElm.Add,
 		"IncludePaths", strings.Join(importElm.IncludePaths, "///"),
 		"ExcludePaths", strings.Join(importElm.ExcludePaths, "///"),
 	)
 }
 
-func getSourceImageDockerImageName(c Conveyor, importElm *config.Import) string {
-	sourceImageName := getSourceImageName(importElm)
+func getSourceImageDockerImageName(c Conveyor, importElm *config.Import, platform string) string {
+	sourceImageName := PlatformImageName(getSourceImageName(importElm), platform)
 
 	var sourceImageDockerImageName string
 	if importElm.Stage == "" {
@@ -254,8 +264,8 @@ func getSourceImageDockerImageName(c Conveyor, importElm *config.Import) string
 	return sourceImageDockerImageName
 }
 
-func getSourceImageID(c Conveyor, importElm *config.Import) string {
-	sourceImageName := getSourceImageName(importElm)
+func getSourceImageID(c Conveyor, importElm *config.Import, platform string) string {
+	sourceImageName := PlatformImageName(getSourceImageName(importElm), platform)
 
 	var sourceImageID string
 	if importElm.Stage == "" {
@@ -267,8 +277,8 @@ func getSourceImageID(c Conveyor, importElm *config.Import) string {
 	return sourceImageID
 }
 
-func getSourceImageContentDigest(c Conveyor, importElm *config.Import) string {
-	sourceImageName := getSourceImageName(importElm)
+func getSourceImageContentDigest(c Conveyor, importElm *config.Import, platform string) string {
+	sourceImageName := PlatformImageName(getSourceImageName(importElm), platform)
 
 	var sourceImageContentDigest string
 	if importElm.Stage == "" {
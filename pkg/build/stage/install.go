@@ -29,12 +29,15 @@ type InstallStage struct {
 }
 
 func (s *InstallStage) GetDependencies(ctx context.Context, c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
-	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(ctx, c, Install)
+	stageDependenciesChecksum, stageDependenciesInputs, err := s.getStageDependenciesChecksum(ctx, c, Install)
 	if err != nil {
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.InstallChecksum(ctx), stageDependenciesChecksum), nil
+	builderChecksum := s.builder.InstallChecksum(ctx)
+	s.setDependenciesInputs(append([]DependencyInput{NewDependencyInput("install builder checksum", builderChecksum)}, stageDependenciesInputs...))
+
+	return util.Sha256Hash(builderChecksum, stageDependenciesChecksum), nil
 }
 
 func (s *InstallStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
@@ -27,6 +27,12 @@ type Interface interface {
 	SetContentDigest(contentDigest string)
 	GetContentDigest() string
 
+	SetDependenciesDigest(dependenciesDigest string)
+	GetDependenciesDigest() string
+
+	// GetDependenciesInputs returns named inputs recorded by the last GetDependencies call
+	GetDependenciesInputs() []DependencyInput
+
 	SetImage(container_runtime.ImageInterface)
 	GetImage() container_runtime.ImageInterface
 
@@ -29,12 +29,15 @@ type SetupStage struct {
 }
 
 func (s *SetupStage) GetDependencies(ctx context.Context, c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
-	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(ctx, c, Setup)
+	stageDependenciesChecksum, stageDependenciesInputs, err := s.getStageDependenciesChecksum(ctx, c, Setup)
 	if err != nil {
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.SetupChecksum(ctx), stageDependenciesChecksum), nil
+	builderChecksum := s.builder.SetupChecksum(ctx)
+	s.setDependenciesInputs(append([]DependencyInput{NewDependencyInput("setup builder checksum", builderChecksum)}, stageDependenciesInputs...))
+
+	return util.Sha256Hash(builderChecksum, stageDependenciesChecksum), nil
 }
 
 func (s *SetupStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
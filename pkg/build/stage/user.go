@@ -2,6 +2,7 @@ package stage
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/werf/logboek"
@@ -36,12 +37,12 @@ type UserStage struct {
 	builder builder.Builder
 }
 
-func (s *UserStage) getStageDependenciesChecksum(ctx context.Context, c Conveyor, name StageName) (string, error) {
-	var args []string
+func (s *UserStage) getStageDependenciesChecksum(ctx context.Context, c Conveyor, name StageName) (string, []DependencyInput, error) {
+	var inputs []DependencyInput
 	for _, gitMapping := range s.gitMappings {
 		checksum, err := gitMapping.StageDependenciesChecksum(ctx, c, name)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 
 		if debugUserStageChecksum() {
@@ -51,10 +52,10 @@ func (s *UserStage) getStageDependenciesChecksum(ctx context.Context, c Conveyor
 			)
 		}
 
-		args = append(args, checksum)
+		inputs = append(inputs, NewDependencyInput(fmt.Sprintf("git mapping %s stageDependencies checksum", gitMapping.Name), checksum))
 	}
 
-	return util.Sha256Hash(args...), nil
+	return util.Sha256Hash(dependenciesInputsValues(inputs)...), inputs, nil
 }
 
 func debugUserStageChecksum() bool {
@@ -0,0 +1,120 @@
+package build
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/image"
+)
+
+// StageDigestInputs is stored in the stage image label to explain the stage digest later
+type StageDigestInputs struct {
+	ImageName string                  `json:"image"`
+	StageName string                  `json:"stage"`
+	Digest    string                  `json:"digest"`
+	Inputs    []stage.DependencyInput `json:"inputs"`
+}
+
+func (inputs *StageDigestInputs) ToLabelValue() (string, error) {
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func NewStageDigestInputsFromLabels(labels map[string]string) (*StageDigestInputs, error) {
+	value, ok := labels[image.WerfStageDigestInputsLabel]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode %s label: %s", image.WerfStageDigestInputsLabel, err)
+	}
+
+	inputs := &StageDigestInputs{}
+	if err := json.Unmarshal(data, inputs); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal %s label: %s", image.WerfStageDigestInputsLabel, err)
+	}
+
+	return inputs, nil
+}
+
+// getStageDigestInputs returns all named inputs of the stage digest (see calculateDigest),
+// stg.GetDependencies should be called before
+func getStageDigestInputs(ctx context.Context, img *Image, stg stage.Interface, prevNonEmptyStage stage.Interface, conveyor *Conveyor) (*StageDigestInputs, error) {
+	inputs := []stage.DependencyInput{stage.NewDependencyInput("build cache version", image.BuildCacheVersion)}
+	inputs = append(inputs, stg.GetDependenciesInputs()...)
+
+	if prevNonEmptyStage != nil {
+		prevStageDependencies, err := prevNonEmptyStage.GetNextStageDependencies(ctx, conveyor)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get prev stage %s dependencies for the stage %s: %s", prevNonEmptyStage.Name(), stg.Name(), err)
+		}
+
+		inputs = append(inputs,
+			stage.NewDependencyInput("previous stage", string(prevNonEmptyStage.Name())),
+			stage.NewDependencyInput("previous stage digest", prevNonEmptyStage.GetDigest()),
+			stage.NewDependencyInput("previous stage dependencies for next stage", prevStageDependencies),
+		)
+	}
+
+	return &StageDigestInputs{
+		ImageName: img.GetName(),
+		StageName: string(stg.Name()),
+		Digest:    stg.GetDigest(),
+		Inputs:    stage.CompactDependenciesInputs(inputs),
+	}, nil
+}
+
+// GetLastStoredStagesDigestInputs finds the most recent stages of the image in the stages storage with digests other than the current ones
+// and returns their digest inputs by stage name, stages built without digest inputs label are skipped
+func (c *Conveyor) GetLastStoredStagesDigestInputs(ctx context.Context, imageName string, currentDigestByStageName map[string]string) (map[string]*StageDigestInputs, error) {
+	stageIDs, err := c.StorageManager.StagesStorage.GetStagesIDs(ctx, c.projectName())
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stages ids from %s: %s", c.StorageManager.StagesStorage.String(), err)
+	}
+
+	sort.Slice(stageIDs, func(i, j int) bool {
+		return stageIDs[i].UniqueID > stageIDs[j].UniqueID
+	})
+
+	res := map[string]*StageDigestInputs{}
+	for _, stageID := range stageIDs {
+		if len(res) == len(currentDigestByStageName) {
+			break
+		}
+
+		stageDesc, err := c.StorageManager.StagesStorage.GetStageDescription(ctx, c.projectName(), stageID.Digest, stageID.UniqueID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get stage %s description: %s", stageID.String(), err)
+		} else if stageDesc == nil {
+			continue
+		}
+
+		inputs, err := NewStageDigestInputsFromLabels(stageDesc.Info.Labels)
+		if err != nil {
+			logboek.Context(ctx).Warn().LogF("WARNING: Skipping stage %s: %s\n", stageID.String(), err)
+			continue
+		} else if inputs == nil || inputs.ImageName != imageName {
+			continue
+		}
+
+		if currentDigest, ok := currentDigestByStageName[inputs.StageName]; !ok || currentDigest == inputs.Digest {
+			continue
+		} else if _, found := res[inputs.StageName]; !found {
+			res[inputs.StageName] = inputs
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,280 @@
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/werf/werf/pkg/build/stage"
+)
+
+const (
+	GraphDOT     GraphFormat = "dot"
+	GraphJSON    GraphFormat = "json"
+	GraphMermaid GraphFormat = "mermaid"
+)
+
+type GraphFormat string
+
+const (
+	GraphStageCached    GraphStageStatus = "cached"
+	GraphStageNotCached GraphStageStatus = "not-cached"
+	GraphStageEmpty     GraphStageStatus = "empty"
+	// GraphStageUnknown is set for the stages that cannot be calculated without building of the previous stages or dependency images
+	GraphStageUnknown GraphStageStatus = "unknown"
+)
+
+type GraphStageStatus string
+
+const (
+	GraphDependencyFromImage    GraphDependencyType = "fromImage"
+	GraphDependencyFromArtifact GraphDependencyType = "fromArtifact"
+	GraphDependencyImport       GraphDependencyType = "import"
+)
+
+type GraphDependencyType string
+
+// StagesGraph is the DAG of images, artifacts and their stages
+type StagesGraph struct {
+	Images []*GraphImage `json:"images"`
+}
+
+type GraphImage struct {
+	Name         string             `json:"name"`
+	Platform     string             `json:"platform,omitempty"`
+	IsArtifact   bool               `json:"isArtifact"`
+	IsDockerfile bool               `json:"isDockerfile"`
+	Stages       []*GraphStage      `json:"stages"`
+	Dependencies []*GraphDependency `json:"dependencies,omitempty"`
+}
+
+type GraphStage struct {
+	Name        string                  `json:"name"`
+	Status      GraphStageStatus        `json:"status"`
+	Digest      string                  `json:"digest,omitempty"`
+	Inputs      []stage.DependencyInput `json:"inputs,omitempty"`
+	StageID     string                  `json:"stageID,omitempty"`
+	DockerImage string                  `json:"dockerImage,omitempty"`
+}
+
+// GraphDependency is the link from the stage of the dependency image to the stage of the dependent image
+type GraphDependency struct {
+	Type        GraphDependencyType `json:"type"`
+	ImageName   string              `json:"imageName"`
+	SourceStage string              `json:"sourceStage"`
+	TargetStage string              `json:"targetStage"`
+}
+
+func (graph *StagesGraph) GetImage(name string) *GraphImage {
+	for _, img := range graph.Images {
+		if img.Name == name {
+			return img
+		}
+	}
+
+	return nil
+}
+
+// GetPlatformImage returns the image built for the platform or the first image with the name if the image is not built for the platform
+func (graph *StagesGraph) GetPlatformImage(name, platform string) *GraphImage {
+	if ind := graph.platformImageIndex(name, platform); ind != -1 {
+		return graph.Images[ind]
+	}
+
+	return nil
+}
+
+func (graph *StagesGraph) platformImageIndex(name, platform string) int {
+	res := -1
+	for ind, img := range graph.Images {
+		if img.Name != name {
+			continue
+		}
+
+		if img.Platform == platform {
+			return ind
+		}
+
+		if res == -1 {
+			res = ind
+		}
+	}
+
+	return res
+}
+
+// IsCached returns true if all non-empty stages of the image are available in the stages storage
+func (img *GraphImage) IsCached() bool {
+	for _, stg := range img.Stages {
+		if stg.Status != GraphStageCached && stg.Status != GraphStageEmpty {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (img *GraphImage) logName() string {
+	kind := "image"
+	if img.IsArtifact {
+		kind = "artifact"
+	}
+
+	name := img.Name
+	if name == "" {
+		name = "~"
+	}
+
+	if img.Platform != "" {
+		name = fmt.Sprintf("%s [%s]", name, img.Platform)
+	}
+
+	return fmt.Sprintf("%s %s", kind, name)
+}
+
+func (img *GraphImage) stageIndex(stageName string) int {
+	for ind, stg := range img.Stages {
+		if stg.Name == stageName {
+			return ind
+		}
+	}
+
+	return -1
+}
+
+func (stg *GraphStage) label(newLine string) string {
+	lines := []string{stg.Name}
+	if stg.Digest != "" {
+		lines = append(lines, shortGraphDigest(stg.Digest))
+	}
+	lines = append(lines, string(stg.Status))
+
+	return strings.Join(lines, newLine)
+}
+
+func shortGraphDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+type graphEdge struct {
+	fromImage, fromStage int
+	toImage, toStage     int
+	label                string
+}
+
+func (graph *StagesGraph) edges() []graphEdge {
+	var edges []graphEdge
+	for imgInd, img := range graph.Images {
+		for stgInd := 1; stgInd < len(img.Stages); stgInd++ {
+			edges = append(edges, graphEdge{fromImage: imgInd, fromStage: stgInd - 1, toImage: imgInd, toStage: stgInd})
+		}
+
+		for _, dep := range img.Dependencies {
+			depImgInd := graph.platformImageIndex(dep.ImageName, img.Platform)
+			if depImgInd == -1 {
+				continue
+			}
+
+			fromStage := graph.Images[depImgInd].stageIndex(dep.SourceStage)
+			toStage := img.stageIndex(dep.TargetStage)
+			if fromStage == -1 || toStage == -1 {
+				continue
+			}
+
+			edges = append(edges, graphEdge{fromImage: depImgInd, fromStage: fromStage, toImage: imgInd, toStage: toStage, label: string(dep.Type)})
+		}
+	}
+
+	return edges
+}
+
+func graphNodeID(imgInd, stgInd int) string {
+	return fmt.Sprintf("image%d_stage%d", imgInd, stgInd)
+}
+
+func (graph *StagesGraph) ToJsonData() ([]byte, error) {
+	data, err := json.MarshalIndent(graph, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, []byte("\n")...)
+
+	return data, nil
+}
+
+var graphDotStageAttributes = map[GraphStageStatus]string{
+	GraphStageCached:    `style=filled, fillcolor="#c8e6c9"`,
+	GraphStageNotCached: `style=filled, fillcolor="#ffcdd2"`,
+	GraphStageEmpty:     `style=dashed, color=gray, fontcolor=gray`,
+	GraphStageUnknown:   `style=filled, fillcolor="#eeeeee"`,
+}
+
+func (graph *StagesGraph) ToDotData() []byte {
+	buf := bytes.NewBuffer([]byte{})
+	buf.WriteString("digraph stages {\n")
+	buf.WriteString("\trankdir=LR;\n")
+	buf.WriteString("\tnode [shape=box];\n")
+
+	for imgInd, img := range graph.Images {
+		buf.WriteString(fmt.Sprintf("\tsubgraph cluster_%d {\n", imgInd))
+		buf.WriteString(fmt.Sprintf("\t\tlabel=%q;\n", img.logName()))
+		for stgInd, stg := range img.Stages {
+			buf.WriteString(fmt.Sprintf("\t\t%s [label=%q, %s];\n", graphNodeID(imgInd, stgInd), stg.label("\n"), graphDotStageAttributes[stg.Status]))
+		}
+		buf.WriteString("\t}\n")
+	}
+
+	for _, edge := range graph.edges() {
+		buf.WriteString(fmt.Sprintf("\t%s -> %s", graphNodeID(edge.fromImage, edge.fromStage), graphNodeID(edge.toImage, edge.toStage)))
+		if edge.label != "" {
+			buf.WriteString(fmt.Sprintf(" [label=%q, style=bold]", edge.label))
+		}
+		buf.WriteString(";\n")
+	}
+
+	buf.WriteString("}\n")
+
+	return buf.Bytes()
+}
+
+var graphMermaidStageClasses = map[GraphStageStatus]string{
+	GraphStageCached:    "fill:#c8e6c9",
+	GraphStageNotCached: "fill:#ffcdd2",
+	GraphStageEmpty:     "stroke-dasharray:5 5,color:gray",
+	GraphStageUnknown:   "fill:#eeeeee",
+}
+
+func (graph *StagesGraph) ToMermaidData() []byte {
+	buf := bytes.NewBuffer([]byte{})
+	buf.WriteString("flowchart LR\n")
+
+	for imgInd, img := range graph.Images {
+		buf.WriteString(fmt.Sprintf("\tsubgraph image%d [\"%s\"]\n", imgInd, img.logName()))
+		for stgInd, stg := range img.Stages {
+			buf.WriteString(fmt.Sprintf("\t\t%s[\"%s\"]:::%s\n", graphNodeID(imgInd, stgInd), stg.label("<br/>"), graphMermaidClassName(stg.Status)))
+		}
+		buf.WriteString("\tend\n")
+	}
+
+	for _, edge := range graph.edges() {
+		arrow := "-->"
+		if edge.label != "" {
+			arrow = fmt.Sprintf("==>|%s|", edge.label)
+		}
+		buf.WriteString(fmt.Sprintf("\t%s %s %s\n", graphNodeID(edge.fromImage, edge.fromStage), arrow, graphNodeID(edge.toImage, edge.toStage)))
+	}
+
+	for _, status := range []GraphStageStatus{GraphStageCached, GraphStageNotCached, GraphStageEmpty, GraphStageUnknown} {
+		buf.WriteString(fmt.Sprintf("\tclassDef %s %s\n", graphMermaidClassName(status), graphMermaidStageClasses[status]))
+	}
+
+	return buf.Bytes()
+}
+
+func graphMermaidClassName(status GraphStageStatus) string {
+	return strings.ReplaceAll(string(status), "-", "")
+}
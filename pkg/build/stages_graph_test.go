@@ -0,0 +1,67 @@
+package build
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStagesGraphRender(t *testing.T) {
+	graph := &StagesGraph{
+		Images: []*GraphImage{
+			{
+				Name:       "artifact",
+				IsArtifact: true,
+				Stages: []*GraphStage{
+					{Name: "from", Status: GraphStageCached, Digest: "0123456789abcdef"},
+					{Name: "install", Status: GraphStageNotCached, Digest: "fedcba9876543210"},
+				},
+			},
+			{
+				Name: "app",
+				Stages: []*GraphStage{
+					{Name: "from", Status: GraphStageCached, Digest: "aaaaaaaaaaaaaaaa"},
+					{Name: "beforeInstall", Status: GraphStageEmpty},
+					{Name: "importsBeforeInstall", Status: GraphStageUnknown},
+				},
+				Dependencies: []*GraphDependency{
+					{Type: GraphDependencyImport, ImageName: "artifact", SourceStage: "install", TargetStage: "importsBeforeInstall"},
+				},
+			},
+		},
+	}
+
+	if graph.GetImage("artifact").IsCached() {
+		t.Errorf("artifact with not cached stage should not be cached")
+	}
+
+	edges := graph.edges()
+	if len(edges) != 4 {
+		t.Fatalf("expected 4 edges, got %d: %v", len(edges), edges)
+	}
+	if importEdge := edges[3]; importEdge != (graphEdge{fromImage: 0, fromStage: 1, toImage: 1, toStage: 2, label: "import"}) {
+		t.Errorf("unexpected import edge %#v", importEdge)
+	}
+
+	dot := string(graph.ToDotData())
+	for _, expected := range []string{
+		`label="artifact artifact";`,
+		`image0_stage1 [label="install\nfedcba987654\nnot-cached"`,
+		`image0_stage1 -> image1_stage2 [label="import", style=bold];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("expected dot output to contain %q:\n%s", expected, dot)
+		}
+	}
+
+	mermaid := string(graph.ToMermaidData())
+	for _, expected := range []string{
+		`subgraph image1 ["image app"]`,
+		`image1_stage1["beforeInstall<br/>empty"]:::empty`,
+		`image0_stage1 ==>|import| image1_stage2`,
+		`classDef notcached fill:#ffcdd2`,
+	} {
+		if !strings.Contains(mermaid, expected) {
+			t.Errorf("expected mermaid output to contain %q:\n%s", expected, mermaid)
+		}
+	}
+}
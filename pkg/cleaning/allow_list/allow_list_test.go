@@ -0,0 +1,115 @@
+package allow_list
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testManifests = `---
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: registry.example.com/app:init
+      containers:
+      - name: app
+        image: registry.example.com/app:v2
+---
+apiVersion: batch/v1beta1
+kind: CronJob
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            image: registry.example.com/job:v2
+---
+apiVersion: v1
+kind: ConfigMap
+data:
+  image: not-a-container-image
+`
+
+func TestManifestsImages(t *testing.T) {
+	images, err := manifestsImages(testManifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertImages(t, images, []string{"registry.example.com/app:init", "registry.example.com/app:v2", "registry.example.com/job:v2"})
+}
+
+func TestHelmReleasesSource(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	secrets := driver.NewSecrets(client.CoreV1().Secrets("default"))
+
+	for _, rel := range []*release.Release{
+		{Name: "app", Namespace: "default", Version: 1, Info: &release.Info{Status: release.StatusSuperseded}, Manifest: "kind: Pod\nspec:\n  containers:\n  - image: registry.example.com/app:v1\n"},
+		{Name: "app", Namespace: "default", Version: 2, Info: &release.Info{Status: release.StatusDeployed}, Manifest: "kind: Pod\nspec:\n  containers:\n  - image: registry.example.com/app:v2\n", Hooks: []*release.Hook{
+			{Manifest: "kind: Job\nspec:\n  template:\n    spec:\n      containers:\n      - image: registry.example.com/migrate:v2\n"},
+		}},
+	} {
+		if err := secrets.Create(makeReleaseKey(rel), rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	images, err := NewHelmReleasesSource("test", client, "").Images(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertImages(t, images, []string{"registry.example.com/app:v1", "registry.example.com/app:v2", "registry.example.com/migrate:v2"})
+}
+
+func TestJsonPathStrings(t *testing.T) {
+	objects := []interface{}{
+		map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"image": "registry.example.com/rollout:v1"},
+			map[string]interface{}{"image": "registry.example.com/sidecar:v1"},
+		}}}}},
+		map[string]interface{}{"spec": map[string]interface{}{}},
+	}
+
+	images, err := jsonPathStrings("{.spec.template.spec.containers[*].image}", objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertImages(t, images, []string{"registry.example.com/rollout:v1", "registry.example.com/sidecar:v1"})
+}
+
+func TestParseImagesList(t *testing.T) {
+	images := parseImagesList([]byte("# rollback targets\nregistry.example.com/app:v1\n\n  registry.example.com/app:v2  \n"))
+	assertImages(t, images, []string{"registry.example.com/app:v1", "registry.example.com/app:v2"})
+}
+
+func TestWerfValuesImages(t *testing.T) {
+	assertImages(t, werfValuesImages("registry.example.com/app:v1"), []string{"registry.example.com/app:v1"})
+	assertImages(t, werfValuesImages(map[interface{}]interface{}{"backend": "registry.example.com/app:backend", "frontend": "registry.example.com/app:frontend"}), []string{"registry.example.com/app:backend", "registry.example.com/app:frontend"})
+}
+
+func makeReleaseKey(rel *release.Release) string {
+	return fmt.Sprintf("sh.helm.release.v1.%s.v%d", rel.Name, rel.Version)
+}
+
+func assertImages(t *testing.T, images, expected []string) {
+	t.Helper()
+
+	sort.Strings(images)
+	if !reflect.DeepEqual(images, expected) {
+		t.Fatalf("expected images %v, got %v", expected, images)
+	}
+}
@@ -0,0 +1,122 @@
+package allow_list
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+	"helm.sh/helm/v3/pkg/action"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// BundlesSource provides the images referenced by the bundles published into the repo,
+// the bundle tags are selected by the regexps
+type BundlesSource struct {
+	RepoAddress    string
+	TagRegexps     []*regexp.Regexp
+	DockerRegistry docker_registry.DockerRegistry
+	ActionConfig   *action.Configuration
+}
+
+func (s *BundlesSource) String() string {
+	return fmt.Sprintf("bundles %s", s.RepoAddress)
+}
+
+func (s *BundlesSource) Images(ctx context.Context) ([]string, error) {
+	tags, err := s.DockerRegistry.Tags(ctx, s.RepoAddress)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get tags of repo %q: %s", s.RepoAddress, err)
+	}
+
+	var images []string
+	for _, tag := range tags {
+		if !s.isBundleTag(tag) {
+			continue
+		}
+
+		bundleRef := fmt.Sprintf("%s:%s", s.RepoAddress, tag)
+		logboek.Context(ctx).Debug().LogF("-- BundlesSource.Images bundle %q\n", bundleRef)
+
+		bundleImages, err := s.bundleImages(bundleRef)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get images of bundle %q: %s", bundleRef, err)
+		}
+
+		images = append(images, bundleImages...)
+	}
+
+	return images, nil
+}
+
+func (s *BundlesSource) isBundleTag(tag string) bool {
+	for _, regex := range s.TagRegexps {
+		if regex.MatchString(tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *BundlesSource) bundleImages(bundleRef string) ([]string, error) {
+	if err := action.NewChartPull(s.ActionConfig).Run(ioutil.Discard, bundleRef); err != nil {
+		return nil, fmt.Errorf("cannot pull bundle: %s", err)
+	}
+
+	bundleDir, err := ioutil.TempDir(werf.GetTmpDir(), "bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(bundleDir)
+
+	chartExport := action.NewChartExport(s.ActionConfig)
+	chartExport.Destination = bundleDir
+	if err := chartExport.RunWithExactDestination(ioutil.Discard, bundleRef); err != nil {
+		return nil, fmt.Errorf("cannot export bundle: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(bundleDir, "values.yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var values struct {
+		Werf struct {
+			Image interface{} `yaml:"image"`
+		} `yaml:"werf"`
+	}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("cannot parse values.yaml: %s", err)
+	}
+
+	return werfValuesImages(values.Werf.Image), nil
+}
+
+// werfValuesImages returns the images of the werf.image service value, which is either the image of the nameless image or the map of the images by names
+func werfValuesImages(value interface{}) []string {
+	var images []string
+	switch v := value.(type) {
+	case string:
+		images = append(images, v)
+	case map[interface{}]interface{}:
+		for _, image := range v {
+			if s, ok := image.(string); ok {
+				images = append(images, s)
+			}
+		}
+	}
+
+	return images
+}
@@ -0,0 +1,101 @@
+package allow_list
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// CustomResourcesSource provides the images referenced by the custom resources (Argo Rollouts, Knative Services, etc.) by JSONPaths
+type CustomResourcesSource struct {
+	ContextName string
+	Client      kubernetes.Interface
+	Namespace   string
+
+	Group      string
+	Version    string
+	Resource   string
+	ImagePaths []string
+}
+
+func (s *CustomResourcesSource) String() string {
+	return fmt.Sprintf("%s (context %s)", s.resourcePath(), s.ContextName)
+}
+
+func (s *CustomResourcesSource) Images(ctx context.Context) ([]string, error) {
+	// custom resources are requested with the raw REST client not to build the dynamic client for each context
+	data, err := s.Client.Discovery().RESTClient().Get().AbsPath(s.listPath()).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list %s: %s", s.resourcePath(), err)
+	}
+
+	var list struct {
+		Items []interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("cannot parse %s list: %s", s.resourcePath(), err)
+	}
+
+	var images []string
+	for _, imagePath := range s.ImagePaths {
+		itemsImages, err := jsonPathStrings(imagePath, list.Items)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, itemsImages...)
+	}
+
+	return images, nil
+}
+
+func (s *CustomResourcesSource) resourcePath() string {
+	if s.Group == "" {
+		return path.Join(s.Version, s.Resource)
+	}
+
+	return path.Join(s.Group, s.Version, s.Resource)
+}
+
+func (s *CustomResourcesSource) listPath() string {
+	apiPath := "/api"
+	if s.Group != "" {
+		apiPath = path.Join("/apis", s.Group)
+	}
+
+	if s.Namespace != "" {
+		return path.Join(apiPath, s.Version, "namespaces", s.Namespace, s.Resource)
+	}
+
+	return path.Join(apiPath, s.Version, s.Resource)
+}
+
+// jsonPathStrings returns the non-empty string results of the JSONPath for each object, the objects without matches are skipped
+func jsonPathStrings(jsonPath string, objects []interface{}) ([]string, error) {
+	jp := jsonpath.New("").AllowMissingKeys(true)
+	if err := jp.Parse(jsonPath); err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %q: %s", jsonPath, err)
+	}
+
+	var res []string
+	for _, obj := range objects {
+		results, err := jp.FindResults(obj)
+		if err != nil {
+			return nil, fmt.Errorf("cannot apply JSONPath %q: %s", jsonPath, err)
+		}
+
+		for _, values := range results {
+			for _, value := range values {
+				if s, ok := value.Interface().(string); ok && s != "" {
+					res = append(res, s)
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
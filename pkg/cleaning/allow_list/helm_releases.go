@@ -0,0 +1,62 @@
+package allow_list
+
+import (
+	"context"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HelmReleasesSource provides the images of all stored helm releases revisions (including superseded revisions which are the rollback targets)
+type HelmReleasesSource struct {
+	ContextName string
+	Client      kubernetes.Interface
+	Namespace   string
+}
+
+func NewHelmReleasesSource(contextName string, client kubernetes.Interface, namespace string) *HelmReleasesSource {
+	return &HelmReleasesSource{ContextName: contextName, Client: client, Namespace: namespace}
+}
+
+func (s *HelmReleasesSource) String() string {
+	return fmt.Sprintf("helm releases (context %s)", s.ContextName)
+}
+
+func (s *HelmReleasesSource) Images(_ context.Context) ([]string, error) {
+	releases, err := driver.NewSecrets(s.Client.CoreV1().Secrets(s.Namespace)).List(func(*release.Release) bool { return true })
+	if err != nil {
+		return nil, fmt.Errorf("cannot list helm releases: %s", err)
+	}
+
+	var images []string
+	for _, rel := range releases {
+		releaseImages, err := releaseImages(rel)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get images of release %q revision %d: %s", rel.Name, rel.Version, err)
+		}
+
+		images = append(images, releaseImages...)
+	}
+
+	return images, nil
+}
+
+func releaseImages(rel *release.Release) ([]string, error) {
+	images, err := manifestsImages(rel.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hook := range rel.Hooks {
+		hookImages, err := manifestsImages(hook.Manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, hookImages...)
+	}
+
+	return images, nil
+}
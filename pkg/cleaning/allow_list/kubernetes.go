@@ -8,6 +8,25 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
+// KubernetesSource provides the images of the workloads deployed into the Kubernetes cluster
+type KubernetesSource struct {
+	ContextName string
+	Client      kubernetes.Interface
+	Namespace   string
+}
+
+func NewKubernetesSource(contextName string, client kubernetes.Interface, namespace string) *KubernetesSource {
+	return &KubernetesSource{ContextName: contextName, Client: client, Namespace: namespace}
+}
+
+func (s *KubernetesSource) String() string {
+	return fmt.Sprintf("Kubernetes (context %s)", s.ContextName)
+}
+
+func (s *KubernetesSource) Images(_ context.Context) ([]string, error) {
+	return DeployedDockerImages(s.Client, s.Namespace)
+}
+
 func DeployedDockerImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
 	var deployedDockerImages []string
 
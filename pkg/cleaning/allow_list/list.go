@@ -0,0 +1,71 @@
+package allow_list
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ListSource provides the images listed in the file or by the URL (one image per line, empty lines and lines starting with # are ignored)
+type ListSource struct {
+	Location string
+}
+
+func NewListSource(location string) *ListSource {
+	return &ListSource{Location: location}
+}
+
+func (s *ListSource) String() string {
+	return fmt.Sprintf("list %s", s.Location)
+}
+
+func (s *ListSource) Images(ctx context.Context) ([]string, error) {
+	data, err := s.read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s", s.Location, err)
+	}
+
+	return parseImagesList(data), nil
+}
+
+func (s *ListSource) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(s.Location, "http://") && !strings.HasPrefix(s.Location, "https://") {
+		return ioutil.ReadFile(s.Location)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.Location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func parseImagesList(data []byte) []string {
+	var images []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		images = append(images, line)
+	}
+
+	return images
+}
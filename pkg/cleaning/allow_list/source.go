@@ -0,0 +1,63 @@
+package allow_list
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Source provides the images that must be kept by the cleanup
+type Source interface {
+	String() string
+	Images(ctx context.Context) ([]string, error)
+}
+
+// manifestsImages returns the images of the containers and init containers defined in the YAML stream of Kubernetes manifests
+func manifestsImages(manifests string) ([]string, error) {
+	var images []string
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(manifests)))
+	for {
+		var obj interface{}
+		if err := decoder.Decode(&obj); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		images = append(images, containersImages(obj)...)
+	}
+
+	return images, nil
+}
+
+// containersImages walks the object and collects the images of all containers and init containers lists
+func containersImages(obj interface{}) []string {
+	var images []string
+	switch v := obj.(type) {
+	case map[interface{}]interface{}:
+		for key, value := range v {
+			if key == "containers" || key == "initContainers" || key == "ephemeralContainers" {
+				if containers, ok := value.([]interface{}); ok {
+					for _, container := range containers {
+						if c, ok := container.(map[interface{}]interface{}); ok {
+							if image, ok := c["image"].(string); ok && image != "" {
+								images = append(images, image)
+							}
+						}
+					}
+					continue
+				}
+			}
+
+			images = append(images, containersImages(value)...)
+		}
+	case []interface{}:
+		for _, value := range v {
+			images = append(images, containersImages(value)...)
+		}
+	}
+
+	return images
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/rodaine/table"
 
-	"github.com/werf/kubedog/pkg/kube"
 	"github.com/werf/logboek"
 
 	"github.com/werf/werf/pkg/cleaning/allow_list"
@@ -26,32 +25,38 @@ import (
 )
 
 type CleanupOptions struct {
-	ImageNameList                           []string
-	LocalGit                                GitRepo
-	KubernetesContextClients                []*kube.ContextClient
-	KubernetesNamespaceRestrictionByContext map[string]string
-	WithoutKube                             bool
-	GitHistoryBasedCleanupOptions           config.MetaCleanup
-	KeepStagesBuiltWithinLastNHours         uint64
-	DryRun                                  bool
+	ImageNameList                   []string
+	LocalGit                        GitRepo
+	AllowListSources                []allow_list.Source
+	GitHistoryBasedCleanupOptions   config.MetaCleanup
+	KeepStagesBuiltWithinLastNHours uint64
+	DryRun                          bool
+	// TriggerRegistryGC triggers the registry garbage collection after the stages deletion if the registry API allows it
+	TriggerRegistryGC bool
 }
 
 func Cleanup(ctx context.Context, projectName string, storageManager *manager.StorageManager, storageLockManager storage.LockManager, options CleanupOptions) error {
-	return newCleanupManager(projectName, storageManager, options).run(ctx)
+	m := newCleanupManager(projectName, storageManager, options)
+	if err := m.run(ctx); err != nil {
+		return err
+	}
+
+	return m.triggerRegistryGC(ctx)
 }
 
 func newCleanupManager(projectName string, storageManager *manager.StorageManager, options CleanupOptions) *cleanupManager {
 	return &cleanupManager{
-		ProjectName:                             projectName,
-		StorageManager:                          storageManager,
-		ImageNameList:                           options.ImageNameList,
-		DryRun:                                  options.DryRun,
-		LocalGit:                                options.LocalGit,
-		KubernetesContextClients:                options.KubernetesContextClients,
-		KubernetesNamespaceRestrictionByContext: options.KubernetesNamespaceRestrictionByContext,
-		WithoutKube:                             options.WithoutKube,
-		GitHistoryBasedCleanupOptions:           options.GitHistoryBasedCleanupOptions,
-		KeepStagesBuiltWithinLastNHours:         options.KeepStagesBuiltWithinLastNHours,
+		ProjectName:                     projectName,
+		StorageManager:                  storageManager,
+		ImageNameList:                   options.ImageNameList,
+		DryRun:                          options.DryRun,
+		LocalGit:                        options.LocalGit,
+		AllowListSources:                options.AllowListSources,
+		GitHistoryBasedCleanupOptions:   options.GitHistoryBasedCleanupOptions,
+		KeepStagesBuiltWithinLastNHours: options.KeepStagesBuiltWithinLastNHours,
+		TriggerRegistryGC:               options.TriggerRegistryGC,
+		stageDeletionReasons:            map[string]CleanupPlanReason{},
+		keepPoliciesStageIDs:            map[string]bool{},
 	}
 }
 
@@ -66,17 +71,30 @@ type cleanupManager struct {
 
 	checksumSourceImageIDs       map[string][]string
 	nonexistentImportMetadataIDs []string
+	invalidImportMetadataIDs     []string
+	importSourceImageIDs         map[string]string
+
+	stagesLastUse manager.StagesLastUse
+
+	// the stages reached by the git history-based keep policies
+	keepPoliciesStageIDs map[string]bool
+
+	// the images provided by the allow list sources are fetched once and mapped to the source description
+	allowListImages map[string]string
+
+	// the deletions are recorded into the plan instead of being performed if the plan is set
+	plan                 *CleanupPlan
+	stageDeletionReasons map[string]CleanupPlanReason
 
-	ProjectName                             string
-	StorageManager                          *manager.StorageManager
-	ImageNameList                           []string
-	LocalGit                                GitRepo
-	KubernetesContextClients                []*kube.ContextClient
-	KubernetesNamespaceRestrictionByContext map[string]string
-	WithoutKube                             bool
-	GitHistoryBasedCleanupOptions           config.MetaCleanup
-	KeepStagesBuiltWithinLastNHours         uint64
-	DryRun                                  bool
+	ProjectName                     string
+	StorageManager                  *manager.StorageManager
+	ImageNameList                   []string
+	LocalGit                        GitRepo
+	AllowListSources                []allow_list.Source
+	GitHistoryBasedCleanupOptions   config.MetaCleanup
+	KeepStagesBuiltWithinLastNHours uint64
+	DryRun                          bool
+	TriggerRegistryGC               bool
 }
 
 type GitRepo interface {
@@ -97,6 +115,14 @@ func (m *cleanupManager) init(ctx context.Context) error {
 		return err
 	}
 
+	if err := logboek.Context(ctx).Info().LogProcess("Fetching stages last use").DoError(func() error {
+		var err error
+		m.stagesLastUse, err = m.StorageManager.GetStagesLastUse(ctx)
+		return err
+	}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -226,8 +252,8 @@ func (m *cleanupManager) run(ctx context.Context) error {
 	}
 
 	if m.LocalGit != nil {
-		if !m.WithoutKube {
-			if err := logboek.Context(ctx).LogProcess("Skipping tags that are being used in Kubernetes").DoError(func() error {
+		if len(m.AllowListSources) != 0 {
+			if err := logboek.Context(ctx).LogProcess("Skipping tags that are being used in Kubernetes or listed in allow lists").DoError(func() error {
 				return m.skipStageIDsThatAreUsedInKubernetes(ctx)
 			}); err != nil {
 				return err
@@ -244,15 +270,84 @@ func (m *cleanupManager) run(ctx context.Context) error {
 		logboek.Context(ctx).Default().LogOptionalLn()
 	}
 
+	quota := m.GitHistoryBasedCleanupOptions.StorageQuota
+	if quota != nil && quota.MaxStagesPerImage != nil {
+		if err := logboek.Context(ctx).LogProcess("Keeping at most %d stages per image", *quota.MaxStagesPerImage).DoError(func() error {
+			return m.storageQuotaStagesPerImageCleanup(ctx, *quota.MaxStagesPerImage)
+		}); err != nil {
+			return err
+		}
+	}
+
 	if err := logboek.Context(ctx).LogProcess("Cleanup unused stages").DoError(func() error {
 		return m.cleanupUnusedStages(ctx)
 	}); err != nil {
 		return err
 	}
 
+	if quota != nil && quota.MaxSize != nil {
+		if err := logboek.Context(ctx).LogProcess("Storage size quota-based cleanup").DoError(func() error {
+			return m.storageQuotaSizeCleanup(ctx, *quota.MaxSize)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if m.plan == nil {
+		if err := m.cleanupNonexistentStagesLastUse(ctx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// cleanupNonexistentStagesLastUse deletes the last use records of the deleted stages
+func (m *cleanupManager) cleanupNonexistentStagesLastUse(ctx context.Context) error {
+	var records []*storage.StageLastUseRecord
+	for stageID, stageRecords := range m.stagesLastUse {
+		if !m.isStageExist(stageID) {
+			records = append(records, stageRecords...)
+		}
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	return logboek.Context(ctx).Info().LogProcess("Deleting last use records of nonexistent stages").DoError(func() error {
+		return deleteStageLastUseRecords(ctx, m.StorageManager, records, m.DryRun)
+	})
+}
+
+// triggerRegistryGC triggers the garbage collection of the stages storage registry, the failure does not fail the cleanup
+func (m *cleanupManager) triggerRegistryGC(ctx context.Context) error {
+	if !m.TriggerRegistryGC || m.DryRun || m.plan != nil {
+		return nil
+	}
+
+	repoStagesStorage, ok := m.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		logboek.Context(ctx).Warn().LogF("WARNING: Registry garbage collection is not triggered: %s is not a container registry\n", m.StorageManager.StagesStorage.String())
+		return nil
+	}
+
+	garbageCollector, ok := repoStagesStorage.DockerRegistry.(docker_registry.GarbageCollector)
+	if !ok {
+		logboek.Context(ctx).Warn().LogF("WARNING: Registry garbage collection is not triggered: the %s registry API does not allow it\n", repoStagesStorage.DockerRegistry.String())
+		return nil
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Triggering registry garbage collection").DoError(func() error {
+		if err := garbageCollector.TriggerGarbageCollection(ctx, repoStagesStorage.RepoAddress); err != nil {
+			logboek.Context(ctx).Warn().LogF("WARNING: Unable to trigger registry garbage collection: %s\n", err)
+		}
+
+		return nil
+	})
+}
+
+// skipStageIDsThatAreUsedInKubernetes keeps the stages which images are provided by any of the allow list sources
 func (m *cleanupManager) skipStageIDsThatAreUsedInKubernetes(ctx context.Context) error {
 	deployedDockerImagesNames, err := m.deployedDockerImagesNames(ctx)
 	if err != nil {
@@ -261,20 +356,17 @@ func (m *cleanupManager) skipStageIDsThatAreUsedInKubernetes(ctx context.Context
 
 	skippedDeployedImages := map[string]bool{}
 	for imageName, stageIDCommitList := range m.imageNameStageIDCommitListToCleanup {
-	Loop:
 		for stageID, _ := range stageIDCommitList {
 			dockerImageName := fmt.Sprintf("%s:%s", m.StorageManager.StagesStorage.String(), stageID)
-			for _, deployedDockerImageName := range deployedDockerImagesNames {
-				if deployedDockerImageName == dockerImageName {
-					m.keepImageNameStageID(imageName, stageID)
-
-					if !skippedDeployedImages[stageID] {
-						logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageID)
-						logboek.Context(ctx).LogOptionalLn()
-						skippedDeployedImages[stageID] = true
-					}
+			if source, ok := deployedDockerImagesNames[dockerImageName]; ok {
+				m.keepImageNameStageID(imageName, stageID)
+
+				if !skippedDeployedImages[stageID] {
+					logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageID)
+					logboek.Context(ctx).LogOptionalLn()
+					skippedDeployedImages[stageID] = true
 
-					continue Loop
+					m.planKeptStage(stageID, CleanupPlanReasonAllowList, source)
 				}
 			}
 		}
@@ -283,17 +375,26 @@ func (m *cleanupManager) skipStageIDsThatAreUsedInKubernetes(ctx context.Context
 	return nil
 }
 
-func (m *cleanupManager) deployedDockerImagesNames(ctx context.Context) ([]string, error) {
-	var deployedDockerImagesNames []string
-	for _, contextClient := range m.KubernetesContextClients {
-		if err := logboek.Context(ctx).LogProcessInline("Getting deployed docker images (context %s)", contextClient.ContextName).
+// deployedDockerImagesNames returns the images provided by the allow list sources mapped to the source description
+func (m *cleanupManager) deployedDockerImagesNames(ctx context.Context) (map[string]string, error) {
+	if m.allowListImages != nil {
+		return m.allowListImages, nil
+	}
+
+	deployedDockerImagesNames := map[string]string{}
+	for _, source := range m.AllowListSources {
+		if err := logboek.Context(ctx).LogProcessInline("Getting images to keep from %s", source).
 			DoError(func() error {
-				kubernetesClientDeployedDockerImagesNames, err := allow_list.DeployedDockerImages(contextClient.Client, m.KubernetesNamespaceRestrictionByContext[contextClient.ContextName])
+				sourceDockerImagesNames, err := source.Images(ctx)
 				if err != nil {
-					return fmt.Errorf("cannot get deployed imagesStageList: %s", err)
+					return fmt.Errorf("cannot get images from %s: %s", source, err)
 				}
 
-				deployedDockerImagesNames = append(deployedDockerImagesNames, kubernetesClientDeployedDockerImagesNames...)
+				for _, name := range sourceDockerImagesNames {
+					if _, ok := deployedDockerImagesNames[name]; !ok {
+						deployedDockerImagesNames[name] = source.String()
+					}
+				}
 
 				return nil
 			}); err != nil {
@@ -301,6 +402,8 @@ func (m *cleanupManager) deployedDockerImagesNames(ctx context.Context) ([]strin
 		}
 	}
 
+	m.allowListImages = deployedDockerImagesNames
+
 	return deployedDockerImagesNames, nil
 }
 
@@ -348,12 +451,17 @@ func (m *cleanupManager) gitHistoryBasedCleanup(ctx context.Context) error {
 				}
 
 				stageIDToUnlink = append(stageIDToUnlink, stageID)
+				m.stageDeletionReasons[stageID] = CleanupPlanReasonKeepPolicies
 			}
 
 			if len(reachedStageIDs) != 0 {
 				m.handleSavedStageIDs(ctx, reachedStageIDs)
 			}
 
+			for _, stageID := range reachedStageIDs {
+				m.keepPoliciesStageIDs[stageID] = true
+			}
+
 			if err := logboek.Context(ctx).LogProcess("Cleaning image metadata").DoError(func() error {
 				return m.cleanupImageMetadata(ctx, imageName, hitStageIDCommitList, stageIDToUnlink)
 			}); err != nil {
@@ -420,11 +528,21 @@ func (m *cleanupManager) handleSavedStageIDs(ctx context.Context, savedStageIDs
 		for _, stageID := range savedStageIDs {
 			logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageID)
 			logboek.Context(ctx).LogOptionalLn()
+
+			m.planKeptStage(stageID, CleanupPlanReasonKeepPolicies, "")
 		}
 	})
 }
 
 func (m *cleanupManager) deleteStages(ctx context.Context, stages []*image.StageDescription) error {
+	if m.plan != nil {
+		for _, stage := range stages {
+			m.planStage(stage)
+		}
+
+		return nil
+	}
+
 	deleteStageOptions := manager.ForEachDeleteStageOptions{
 		DeleteImageOptions: storage.DeleteImageOptions{
 			RmiForce: false,
@@ -449,6 +567,11 @@ func deleteStages(ctx context.Context, storageManager *manager.StorageManager, d
 	}
 
 	return storageManager.ForEachDeleteStage(ctx, deleteStageOptions, stages, func(ctx context.Context, stageDesc *image.StageDescription, err error) error {
+		if docker_registry.IsDeletionScheduledError(err) {
+			logboek.Context(ctx).Default().LogFDetails("  tag: %s (deletion scheduled)\n", stageDesc.Info.Tag)
+			return nil
+		}
+
 		if err != nil {
 			if err := handleDeletionError(err); err != nil {
 				return err
@@ -495,7 +618,7 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 
 		if len(stageIDCommitListToDelete) != 0 {
 			if err := logboek.Context(ctx).Info().LogProcess("Cleaning up metadata").DoError(func() error {
-				return m.deleteImageMetadata(ctx, imageName, stageIDCommitListToDelete, true)
+				return m.deleteImageMetadata(ctx, imageName, stageIDCommitListToDelete, true, CleanupPlanReasonKeepPolicies)
 			}); err != nil {
 				return err
 			}
@@ -504,7 +627,7 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 
 	if len(nonexistentStageIDCommitList) != 0 {
 		if err := logboek.Context(ctx).Info().LogProcess("Deleting metadata for nonexistent stageIDs").DoError(func() error {
-			return m.deleteImageMetadata(ctx, imageName, nonexistentStageIDCommitList, false)
+			return m.deleteImageMetadata(ctx, imageName, nonexistentStageIDCommitList, false, CleanupPlanReasonNonexistentStage)
 		}); err != nil {
 			return err
 		}
@@ -512,7 +635,7 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 
 	if len(stageIDNonexistentCommitList) != 0 {
 		if err := logboek.Context(ctx).Info().LogProcess("Deleting metadata for nonexistent commits").DoError(func() error {
-			return m.deleteImageMetadata(ctx, imageName, stageIDNonexistentCommitList, false)
+			return m.deleteImageMetadata(ctx, imageName, stageIDNonexistentCommitList, false, CleanupPlanReasonNonexistentCommit)
 		}); err != nil {
 			return err
 		}
@@ -528,7 +651,7 @@ func (m *cleanupManager) cleanupNonexistentImageMetadata(ctx context.Context) er
 
 	return logboek.Context(ctx).Default().LogProcess("Deleting metadata for nonexistent images").DoError(func() error {
 		for imageName, stageIDCommitList := range m.nonexistentImageNameStageIDCommitList {
-			if err := m.deleteImageMetadata(ctx, imageName, stageIDCommitList, false); err != nil {
+			if err := m.deleteImageMetadata(ctx, imageName, stageIDCommitList, false, CleanupPlanReasonNonexistentImage); err != nil {
 				return err
 			}
 		}
@@ -537,8 +660,10 @@ func (m *cleanupManager) cleanupNonexistentImageMetadata(ctx context.Context) er
 	})
 }
 
-func (m *cleanupManager) deleteImageMetadata(ctx context.Context, imageName string, stageIDCommitList map[string][]string, updateCache bool) error {
-	if err := deleteImageMetadata(ctx, m.ProjectName, m.StorageManager, imageName, stageIDCommitList, m.DryRun); err != nil {
+func (m *cleanupManager) deleteImageMetadata(ctx context.Context, imageName string, stageIDCommitList map[string][]string, updateCache bool, reason CleanupPlanReason) error {
+	if m.plan != nil {
+		m.planImageMetadata(imageName, stageIDCommitList, reason)
+	} else if err := deleteImageMetadata(ctx, m.ProjectName, m.StorageManager, imageName, stageIDCommitList, m.DryRun); err != nil {
 		return err
 	}
 
@@ -605,7 +730,7 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 	if m.KeepStagesBuiltWithinLastNHours != 0 {
 		var excludedStages []*image.StageDescription
 		for _, stage := range stagesToDelete {
-			if (time.Since(stage.Info.GetCreatedAt()).Hours()) <= float64(m.KeepStagesBuiltWithinLastNHours) {
+			if (time.Since(m.stageLastUsedAt(stage)).Hours()) <= float64(m.KeepStagesBuiltWithinLastNHours) {
 				var excludedStagesByStage []*image.StageDescription
 				stagesToDelete, excludedStagesByStage = m.excludeStageAndRelativesByImageID(stagesToDelete, stage.Info.ID)
 				excludedStages = append(excludedStages, excludedStagesByStage...)
@@ -613,10 +738,12 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 		}
 
 		if len(excludedStages) != 0 {
-			logboek.Context(ctx).Default().LogBlock("Saved stages that were built within last %d hours", m.KeepStagesBuiltWithinLastNHours).Do(func() {
+			logboek.Context(ctx).Default().LogBlock("Saved stages that were built or used within last %d hours", m.KeepStagesBuiltWithinLastNHours).Do(func() {
 				for _, stage := range excludedStages {
 					logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stage.Info.Tag)
 					logboek.Context(ctx).LogOptionalLn()
+
+					m.planKeptStage(stage.Info.Tag, CleanupPlanReasonAge, fmt.Sprintf("built or used within last %d hours", m.KeepStagesBuiltWithinLastNHours))
 				}
 			})
 		}
@@ -628,11 +755,21 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 		}); err != nil {
 			return err
 		}
+
+		m.stages = excludeStages(m.stages, stagesToDelete...)
 	}
 
 	if len(m.nonexistentImportMetadataIDs) != 0 {
 		if err := logboek.Context(ctx).Default().LogProcess("Cleaning imports metadata").DoError(func() error {
-			return m.deleteImportsMetadata(ctx, m.nonexistentImportMetadataIDs)
+			return m.deleteImportsMetadata(ctx, m.nonexistentImportMetadataIDs, CleanupPlanReasonNonexistentImportSource)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if len(m.invalidImportMetadataIDs) != 0 {
+		if err := logboek.Context(ctx).Warn().LogProcess("Deleting invalid imports metadata").DoError(func() error {
+			return m.deleteImportsMetadata(ctx, m.invalidImportMetadataIDs, CleanupPlanReasonInvalidMetadata)
 		}); err != nil {
 			return err
 		}
@@ -643,6 +780,9 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 
 func (m *cleanupManager) initImportsMetadata(ctx context.Context) error {
 	m.checksumSourceImageIDs = map[string][]string{}
+	m.importSourceImageIDs = map[string]string{}
+	m.nonexistentImportMetadataIDs = nil
+	m.invalidImportMetadataIDs = nil
 
 	importMetadataIDs, err := m.StorageManager.StagesStorage.GetImportMetadataIDs(ctx, m.ProjectName)
 	if err != nil {
@@ -655,14 +795,12 @@ func (m *cleanupManager) initImportsMetadata(ctx context.Context) error {
 			return err
 		}
 
-		if metadata == nil {
-			if err := logboek.Context(ctx).Warn().LogProcess("Deleting invalid import metadata %s", metadataID).
-				DoError(func() error {
-					return m.deleteImportsMetadata(ctx, []string{metadataID})
-				}); err != nil {
-				return fmt.Errorf("unable to delete import metadata %s: %s", metadataID, err)
-			}
+		mutex.Lock()
+		defer mutex.Unlock()
 
+		// invalid metadata is deleted along with the metadata of nonexistent import sources
+		if metadata == nil {
+			m.invalidImportMetadataIDs = append(m.invalidImportMetadataIDs, metadataID)
 			return nil
 		}
 
@@ -670,8 +808,7 @@ func (m *cleanupManager) initImportsMetadata(ctx context.Context) error {
 		sourceImageID := metadata.SourceImageID
 		checksum := metadata.Checksum
 
-		mutex.Lock()
-		defer mutex.Unlock()
+		m.importSourceImageIDs[importSourceID] = sourceImageID
 
 		stage := findStageByImageID(m.stages, sourceImageID)
 		if stage != nil {
@@ -689,7 +826,15 @@ func (m *cleanupManager) initImportsMetadata(ctx context.Context) error {
 	})
 }
 
-func (m *cleanupManager) deleteImportsMetadata(ctx context.Context, importMetadataIDs []string) error {
+func (m *cleanupManager) deleteImportsMetadata(ctx context.Context, importMetadataIDs []string, reason CleanupPlanReason) error {
+	if m.plan != nil {
+		for _, importMetadataID := range importMetadataIDs {
+			m.planImportMetadata(importMetadataID, reason)
+		}
+
+		return nil
+	}
+
 	return deleteImportsMetadata(ctx, m.ProjectName, m.StorageManager, importMetadataIDs, m.DryRun)
 }
 
@@ -719,6 +864,32 @@ func deleteImportsMetadata(ctx context.Context, projectName string, storageManag
 	})
 }
 
+func deleteStageLastUseRecords(ctx context.Context, storageManager *manager.StorageManager, records []*storage.StageLastUseRecord, dryRun bool) error {
+	if dryRun {
+		for _, rec := range records {
+			logboek.Context(ctx).Info().LogFDetails("  stageID: %s\n", rec.StageID)
+			logboek.Context(ctx).Info().LogOptionalLn()
+		}
+		return nil
+	}
+
+	return storageManager.ForEachRmStageLastUseRecord(ctx, records, func(ctx context.Context, rec *storage.StageLastUseRecord, err error) error {
+		if err != nil {
+			if err := handleDeletionError(err); err != nil {
+				return err
+			}
+
+			logboek.Context(ctx).Warn().LogF("WARNING: Stage %s last use record deletion failed: %s\n", rec.StageID, err)
+
+			return nil
+		}
+
+		logboek.Context(ctx).Info().LogFDetails("  stageID: %s\n", rec.StageID)
+
+		return nil
+	})
+}
+
 func (m *cleanupManager) excludeStageAndRelativesByImageID(stages []*image.StageDescription, imageID string) ([]*image.StageDescription, []*image.StageDescription) {
 	stage := findStageByImageID(stages, imageID)
 	if stage == nil {
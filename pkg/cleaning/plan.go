@@ -0,0 +1,420 @@
+package cleaning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage/manager"
+)
+
+const CleanupPlanVersion = 1
+
+type CleanupPlanReason string
+
+const (
+	CleanupPlanReasonKeepPolicies            CleanupPlanReason = "keep-policies"
+	CleanupPlanReasonUnused                  CleanupPlanReason = "unused"
+	CleanupPlanReasonNonexistentStage        CleanupPlanReason = "nonexistent-stage"
+	CleanupPlanReasonNonexistentCommit       CleanupPlanReason = "nonexistent-commit"
+	CleanupPlanReasonNonexistentImage        CleanupPlanReason = "nonexistent-image"
+	CleanupPlanReasonNonexistentImportSource CleanupPlanReason = "nonexistent-import-source"
+	CleanupPlanReasonInvalidMetadata         CleanupPlanReason = "invalid-metadata"
+	CleanupPlanReasonAllowList               CleanupPlanReason = "allow-list"
+	CleanupPlanReasonAge                     CleanupPlanReason = "age"
+	CleanupPlanReasonStorageQuota            CleanupPlanReason = "storage-quota"
+)
+
+var cleanupPlanReasonDescriptions = map[CleanupPlanReason]string{
+	CleanupPlanReasonKeepPolicies:            "not kept by the git history-based keep policies",
+	CleanupPlanReasonUnused:                  "not related to any image metadata",
+	CleanupPlanReasonNonexistentStage:        "the stage does not exist",
+	CleanupPlanReasonNonexistentCommit:       "the commit does not exist in the git repository",
+	CleanupPlanReasonNonexistentImage:        "the image is not defined in werf.yaml",
+	CleanupPlanReasonNonexistentImportSource: "the import source image does not exist",
+	CleanupPlanReasonInvalidMetadata:         "the metadata is invalid",
+	CleanupPlanReasonAllowList:               "used in Kubernetes or listed in the allow list",
+	CleanupPlanReasonAge:                     "built recently",
+	CleanupPlanReasonStorageQuota:            "exceeds the storage quota",
+}
+
+func (r CleanupPlanReason) Description() string {
+	if description, ok := cleanupPlanReasonDescriptions[r]; ok {
+		return description
+	}
+
+	return string(r)
+}
+
+// CleanupPlan is the reviewable set of deletions computed by the cleanup,
+// the kept stages are listed for the review only
+type CleanupPlan struct {
+	Version        int                          `json:"version"`
+	ProjectName    string                       `json:"projectName"`
+	StagesStorage  string                       `json:"stagesStorage"`
+	CreatedAt      time.Time                    `json:"createdAt"`
+	Stages         []*CleanupPlanStage          `json:"stages"`
+	ImageMetadata  []*CleanupPlanImageMetadata  `json:"imageMetadata"`
+	ImportMetadata []*CleanupPlanImportMetadata `json:"importMetadata"`
+	KeptStages     []*CleanupPlanKeptStage      `json:"keptStages,omitempty"`
+}
+
+type CleanupPlanStage struct {
+	Tag       string            `json:"tag"`
+	ImageID   string            `json:"imageID"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Reason    CleanupPlanReason `json:"reason"`
+}
+
+type CleanupPlanImageMetadata struct {
+	ImageName string            `json:"imageName"`
+	StageID   string            `json:"stageID"`
+	Commits   []string          `json:"commits"`
+	Reason    CleanupPlanReason `json:"reason"`
+}
+
+type CleanupPlanImportMetadata struct {
+	ID            string            `json:"id"`
+	SourceImageID string            `json:"sourceImageID,omitempty"`
+	Reason        CleanupPlanReason `json:"reason"`
+}
+
+type CleanupPlanKeptStage struct {
+	Tag     string            `json:"tag"`
+	Reason  CleanupPlanReason `json:"reason"`
+	Details string            `json:"details,omitempty"`
+}
+
+func newCleanupPlan(projectName, stagesStorage string) *CleanupPlan {
+	return &CleanupPlan{
+		Version:       CleanupPlanVersion,
+		ProjectName:   projectName,
+		StagesStorage: stagesStorage,
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+func ReadCleanupPlan(path string) (*CleanupPlan, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cleanup plan %s: %s", path, err)
+	}
+
+	plan := &CleanupPlan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("unable to parse cleanup plan %s: %s", path, err)
+	}
+
+	if plan.Version != CleanupPlanVersion {
+		return nil, fmt.Errorf("unsupported cleanup plan %s version %d: expected version %d", path, plan.Version, CleanupPlanVersion)
+	}
+
+	return plan, nil
+}
+
+func (p *CleanupPlan) WriteFile(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to write cleanup plan %s: %s", path, err)
+	}
+
+	return nil
+}
+
+func (p *CleanupPlan) IsEmpty() bool {
+	return len(p.Stages) == 0 && len(p.ImageMetadata) == 0 && len(p.ImportMetadata) == 0
+}
+
+func (p *CleanupPlan) validate(projectName, stagesStorage string) error {
+	if p.ProjectName != projectName {
+		return fmt.Errorf("the cleanup plan is created for the project %q, but the current project is %q", p.ProjectName, projectName)
+	}
+
+	if p.StagesStorage != stagesStorage {
+		return fmt.Errorf("the cleanup plan is created for the repo %q, but the current repo is %q", p.StagesStorage, stagesStorage)
+	}
+
+	return nil
+}
+
+func (p *CleanupPlan) sort() {
+	sort.SliceStable(p.Stages, func(i, j int) bool { return p.Stages[i].Tag < p.Stages[j].Tag })
+	sort.SliceStable(p.ImageMetadata, func(i, j int) bool {
+		if p.ImageMetadata[i].ImageName != p.ImageMetadata[j].ImageName {
+			return p.ImageMetadata[i].ImageName < p.ImageMetadata[j].ImageName
+		}
+
+		return p.ImageMetadata[i].StageID < p.ImageMetadata[j].StageID
+	})
+	sort.SliceStable(p.ImportMetadata, func(i, j int) bool { return p.ImportMetadata[i].ID < p.ImportMetadata[j].ID })
+	sort.SliceStable(p.KeptStages, func(i, j int) bool { return p.KeptStages[i].Tag < p.KeptStages[j].Tag })
+}
+
+// PlanCleanup computes the cleanup deletions without performing them
+func PlanCleanup(ctx context.Context, projectName string, storageManager *manager.StorageManager, options CleanupOptions) (*CleanupPlan, error) {
+	m := newCleanupManager(projectName, storageManager, options)
+	m.plan = newCleanupPlan(projectName, storageManager.StagesStorage.String())
+
+	if err := m.run(ctx); err != nil {
+		return nil, err
+	}
+
+	m.plan.sort()
+
+	return m.plan, nil
+}
+
+// ApplyCleanupPlan performs the deletions of the plan, the items which became used since the plan creation are skipped
+func ApplyCleanupPlan(ctx context.Context, projectName string, storageManager *manager.StorageManager, plan *CleanupPlan, options CleanupOptions) error {
+	if err := plan.validate(projectName, storageManager.StagesStorage.String()); err != nil {
+		return err
+	}
+
+	m := newCleanupManager(projectName, storageManager, options)
+	if err := m.applyPlan(ctx, plan); err != nil {
+		return err
+	}
+
+	return m.triggerRegistryGC(ctx)
+}
+
+func (m *cleanupManager) planStage(stage *image.StageDescription) {
+	reason, ok := m.stageDeletionReasons[stage.Info.Tag]
+	if !ok {
+		reason = CleanupPlanReasonUnused
+	}
+
+	m.plan.Stages = append(m.plan.Stages, &CleanupPlanStage{
+		Tag:       stage.Info.Tag,
+		ImageID:   stage.Info.ID,
+		CreatedAt: stage.Info.GetCreatedAt(),
+		Reason:    reason,
+	})
+}
+
+func (m *cleanupManager) planImageMetadata(imageName string, stageIDCommitList map[string][]string, reason CleanupPlanReason) {
+	for stageID, commitList := range stageIDCommitList {
+		if len(commitList) == 0 {
+			continue
+		}
+
+		m.plan.ImageMetadata = append(m.plan.ImageMetadata, &CleanupPlanImageMetadata{
+			ImageName: imageName,
+			StageID:   stageID,
+			Commits:   commitList,
+			Reason:    reason,
+		})
+	}
+}
+
+func (m *cleanupManager) planImportMetadata(importMetadataID string, reason CleanupPlanReason) {
+	m.plan.ImportMetadata = append(m.plan.ImportMetadata, &CleanupPlanImportMetadata{
+		ID:            importMetadataID,
+		SourceImageID: m.importSourceImageIDs[importMetadataID],
+		Reason:        reason,
+	})
+}
+
+func (m *cleanupManager) planKeptStage(stageID string, reason CleanupPlanReason, details string) {
+	if m.plan == nil {
+		return
+	}
+
+	m.plan.KeptStages = append(m.plan.KeptStages, &CleanupPlanKeptStage{Tag: stageID, Reason: reason, Details: details})
+}
+
+func (m *cleanupManager) applyPlan(ctx context.Context, plan *CleanupPlan) error {
+	if err := logboek.Context(ctx).LogProcess("Fetching manifests and metadata").DoError(func() error {
+		if err := m.init(ctx); err != nil {
+			return err
+		}
+
+		return m.initImportsMetadata(ctx)
+	}); err != nil {
+		return err
+	}
+
+	var usedByAllowList map[string]string
+	if err := logboek.Context(ctx).LogProcess("Checking allow lists").DoError(func() error {
+		var err error
+		usedByAllowList, err = m.usedStagesByAllowList(ctx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := logboek.Context(ctx).LogProcess("Deleting image metadata").DoError(func() error {
+		return m.applyPlanImageMetadata(ctx, plan, usedByAllowList)
+	}); err != nil {
+		return err
+	}
+
+	var stagesToDelete []*image.StageDescription
+	if err := logboek.Context(ctx).LogProcess("Deleting stages tags").DoError(func() error {
+		stagesToDelete = m.planStagesToDelete(ctx, plan, usedByAllowList)
+		if len(stagesToDelete) == 0 {
+			return nil
+		}
+
+		return m.deleteStages(ctx, stagesToDelete)
+	}); err != nil {
+		return err
+	}
+
+	return logboek.Context(ctx).LogProcess("Cleaning imports metadata").DoError(func() error {
+		var importMetadataIDs []string
+		for _, importMetadata := range plan.ImportMetadata {
+			if importMetadata.SourceImageID != "" {
+				if stage := findStageByImageID(m.stages, importMetadata.SourceImageID); stage != nil && findStageByImageID(stagesToDelete, importMetadata.SourceImageID) == nil {
+					logboek.Context(ctx).Warn().LogF("WARNING: Skipping import metadata %s: the source image %s exists\n", importMetadata.ID, importMetadata.SourceImageID)
+					continue
+				}
+			}
+
+			importMetadataIDs = append(importMetadataIDs, importMetadata.ID)
+		}
+
+		if len(importMetadataIDs) == 0 {
+			return nil
+		}
+
+		return m.deleteImportsMetadata(ctx, importMetadataIDs, "")
+	})
+}
+
+// usedStagesByAllowList returns the stage tags which images are provided by the allow list sources mapped to the source description
+func (m *cleanupManager) usedStagesByAllowList(ctx context.Context) (map[string]string, error) {
+	res := map[string]string{}
+	if len(m.AllowListSources) == 0 {
+		return res, nil
+	}
+
+	deployedDockerImagesNames, err := m.deployedDockerImagesNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stage := range m.stages {
+		dockerImageName := fmt.Sprintf("%s:%s", m.StorageManager.StagesStorage.String(), stage.Info.Tag)
+		if source, ok := deployedDockerImagesNames[dockerImageName]; ok {
+			res[stage.Info.Tag] = source
+		}
+	}
+
+	return res, nil
+}
+
+func (m *cleanupManager) applyPlanImageMetadata(ctx context.Context, plan *CleanupPlan, usedByAllowList map[string]string) error {
+	imageNameStageIDCommitList := map[string]map[string][]string{}
+	for _, imageMetadata := range plan.ImageMetadata {
+		if source, ok := usedByAllowList[imageMetadata.StageID]; ok {
+			logboek.Context(ctx).Warn().LogF("WARNING: Skipping image %s metadata for stage %s: the stage is used (%s)\n", imageMetadata.ImageName, imageMetadata.StageID, source)
+			continue
+		}
+
+		if _, ok := imageNameStageIDCommitList[imageMetadata.ImageName]; !ok {
+			imageNameStageIDCommitList[imageMetadata.ImageName] = map[string][]string{}
+		}
+
+		stageIDCommitList := imageNameStageIDCommitList[imageMetadata.ImageName]
+		stageIDCommitList[imageMetadata.StageID] = append(stageIDCommitList[imageMetadata.StageID], imageMetadata.Commits...)
+	}
+
+	for imageName, stageIDCommitList := range imageNameStageIDCommitList {
+		if err := deleteImageMetadata(ctx, m.ProjectName, m.StorageManager, imageName, stageIDCommitList, m.DryRun); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// planStagesToDelete returns the existing stages of the plan excluding the stages (and their relatives) which became used since the plan creation:
+// the stages provided by the allow list sources, the stages built or used since the plan creation or within the last KeepStagesBuiltWithinLastNHours hours
+// and the stages with image metadata that is not deleted by the plan
+func (m *cleanupManager) planStagesToDelete(ctx context.Context, plan *CleanupPlan, usedByAllowList map[string]string) []*image.StageDescription {
+	var stagesToDelete []*image.StageDescription
+	for _, planStage := range plan.Stages {
+		stage := m.getStage(planStage.Tag)
+		if stage == nil {
+			logboek.Context(ctx).Info().LogF("Skipping stage %s: the stage does not exist\n", planStage.Tag)
+			continue
+		}
+
+		if planStage.ImageID != "" && stage.Info.ID != planStage.ImageID {
+			logboek.Context(ctx).Warn().LogF("WARNING: Skipping stage %s: the stage image has been changed\n", planStage.Tag)
+			continue
+		}
+
+		stagesToDelete = append(stagesToDelete, stage)
+	}
+
+	plannedCommits := map[string]bool{}
+	for _, imageMetadata := range plan.ImageMetadata {
+		for _, commit := range imageMetadata.Commits {
+			plannedCommits[imageMetadataKey(imageMetadata.ImageName, imageMetadata.StageID, commit)] = true
+		}
+	}
+
+	usedStages := map[string]string{}
+	for stageID, source := range usedByAllowList {
+		usedStages[stageID] = fmt.Sprintf("used (%s)", source)
+	}
+
+	for _, stage := range m.stages {
+		if _, ok := usedStages[stage.Info.Tag]; ok {
+			continue
+		}
+
+		lastUsedAt := m.stageLastUsedAt(stage)
+		if m.KeepStagesBuiltWithinLastNHours != 0 && time.Since(lastUsedAt).Hours() <= float64(m.KeepStagesBuiltWithinLastNHours) {
+			usedStages[stage.Info.Tag] = fmt.Sprintf("built or used within last %d hours", m.KeepStagesBuiltWithinLastNHours)
+		} else if lastUsedAt.After(plan.CreatedAt) {
+			usedStages[stage.Info.Tag] = "built or used since the plan creation"
+		}
+	}
+
+	for imageName, stageIDCommitList := range m.imageNameStageIDCommitList {
+		for stageID, commitList := range stageIDCommitList {
+			for _, commit := range commitList {
+				if !plannedCommits[imageMetadataKey(imageName, stageID, commit)] {
+					usedStages[stageID] = fmt.Sprintf("image %s metadata for commit %s exists", imageName, commit)
+					break
+				}
+			}
+		}
+	}
+
+	for _, stage := range m.stages {
+		reason, ok := usedStages[stage.Info.Tag]
+		if !ok {
+			continue
+		}
+
+		var excludedStages []*image.StageDescription
+		stagesToDelete, excludedStages = m.excludeStageAndRelativesByStage(stagesToDelete, stage)
+		for _, excludedStage := range excludedStages {
+			for _, planStage := range plan.Stages {
+				if planStage.Tag == excludedStage.Info.Tag {
+					logboek.Context(ctx).Warn().LogF("WARNING: Skipping stage %s: %s\n", excludedStage.Info.Tag, reason)
+				}
+			}
+		}
+	}
+
+	return stagesToDelete
+}
+
+func imageMetadataKey(imageName, stageID, commit string) string {
+	return fmt.Sprintf("%s/%s/%s", imageName, stageID, commit)
+}
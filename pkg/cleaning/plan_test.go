@@ -0,0 +1,132 @@
+package cleaning
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/storage/manager"
+)
+
+func newTestStage(tag, id, parentID string) *image.StageDescription {
+	return &image.StageDescription{Info: &image.Info{Tag: tag, ID: id, ParentID: parentID}}
+}
+
+func stagesTags(stages []*image.StageDescription) []string {
+	var tags []string
+	for _, stage := range stages {
+		tags = append(tags, stage.Info.Tag)
+	}
+
+	return tags
+}
+
+func TestCleanupPlan_WriteAndRead(t *testing.T) {
+	plan := newCleanupPlan("project", "registry.example.com/project")
+	plan.Stages = []*CleanupPlanStage{{Tag: "a-1", ImageID: "sha256:a", Reason: CleanupPlanReasonUnused}}
+	plan.ImageMetadata = []*CleanupPlanImageMetadata{{ImageName: "app", StageID: "a-1", Commits: []string{"c1"}, Reason: CleanupPlanReasonKeepPolicies}}
+	plan.ImportMetadata = []*CleanupPlanImportMetadata{{ID: "i1", Reason: CleanupPlanReasonInvalidMetadata}}
+
+	dir, err := ioutil.TempDir("", "werf-cleanup-plan-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "plan.json")
+	if err := plan.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	readPlan, err := ReadCleanupPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(plan, readPlan) {
+		t.Errorf("got %+v, expected %+v", readPlan, plan)
+	}
+
+	if err := readPlan.validate("project", "registry.example.com/other"); err == nil {
+		t.Errorf("expected the repo mismatch error")
+	}
+}
+
+func TestCleanupManager_planStagesToDelete(t *testing.T) {
+	m := &cleanupManager{
+		stages: []*image.StageDescription{
+			newTestStage("base-1", "sha256:base", ""),
+			newTestStage("app-1", "sha256:app1", "sha256:base"),
+			newTestStage("app-2", "sha256:app2", "sha256:base"),
+			newTestStage("other-1", "sha256:other", ""),
+		},
+		imageNameStageIDCommitList: map[string]map[string][]string{
+			// the commit c2 has been added after the plan creation
+			"app": {"app-1": {"c1", "c2"}},
+		},
+	}
+
+	plan := &CleanupPlan{
+		CreatedAt: time.Now(),
+		Stages: []*CleanupPlanStage{
+			{Tag: "base-1", ImageID: "sha256:base"},
+			{Tag: "app-1", ImageID: "sha256:app1"},
+			{Tag: "app-2", ImageID: "sha256:changed"},
+			{Tag: "other-1", ImageID: "sha256:other"},
+			{Tag: "deleted-1", ImageID: "sha256:deleted"},
+		},
+		ImageMetadata: []*CleanupPlanImageMetadata{
+			{ImageName: "app", StageID: "app-1", Commits: []string{"c1"}},
+		},
+	}
+
+	t.Run("used by image metadata", func(t *testing.T) {
+		stages := m.planStagesToDelete(context.Background(), plan, nil)
+		if tags := stagesTags(stages); !reflect.DeepEqual(tags, []string{"other-1"}) {
+			t.Errorf("got %v, expected [other-1]", tags)
+		}
+	})
+
+	t.Run("used by allow list", func(t *testing.T) {
+		m.imageNameStageIDCommitList = map[string]map[string][]string{"app": {"app-1": {"c1"}}}
+
+		stages := m.planStagesToDelete(context.Background(), plan, map[string]string{"other-1": "list"})
+		if tags := stagesTags(stages); !reflect.DeepEqual(tags, []string{"base-1", "app-1"}) {
+			t.Errorf("got %v, expected [base-1 app-1]", tags)
+		}
+	})
+
+	t.Run("built or used since the plan creation", func(t *testing.T) {
+		m.imageNameStageIDCommitList = map[string]map[string][]string{"app": {"app-1": {"c1"}}}
+		plan.CreatedAt = time.Now().Add(-time.Hour)
+
+		m.stages[1].Info.CreatedAtUnixNano = time.Now().Add(-2 * time.Hour).UnixNano()
+		m.stagesLastUse = manager.NewStagesLastUse([]*storage.StageLastUseRecord{
+			{StageID: "app-1", TimestampMillisec: time.Now().UnixNano() / int64(time.Millisecond)},
+		})
+		defer func() { m.stagesLastUse = nil }()
+
+		stages := m.planStagesToDelete(context.Background(), plan, nil)
+		if tags := stagesTags(stages); !reflect.DeepEqual(tags, []string{"other-1"}) {
+			t.Errorf("got %v, expected [other-1]", tags)
+		}
+	})
+
+	t.Run("built within last n hours", func(t *testing.T) {
+		m.imageNameStageIDCommitList = map[string]map[string][]string{"app": {"app-1": {"c1"}}}
+		plan.CreatedAt = time.Now()
+		m.KeepStagesBuiltWithinLastNHours = 3
+		defer func() { m.KeepStagesBuiltWithinLastNHours = 0 }()
+
+		stages := m.planStagesToDelete(context.Background(), plan, nil)
+		if tags := stagesTags(stages); !reflect.DeepEqual(tags, []string{"other-1"}) {
+			t.Errorf("got %v, expected [other-1]", tags)
+		}
+	})
+}
@@ -74,6 +74,17 @@ func (m *purgeManager) run(ctx context.Context) error {
 		return err
 	}
 
+	if err := logboek.Context(ctx).Default().LogProcess("Deleting stages last use records").DoError(func() error {
+		records, err := m.StorageManager.StagesStorage.GetStageLastUseRecords(ctx, m.ProjectName)
+		if err != nil {
+			return err
+		}
+
+		return deleteStageLastUseRecords(ctx, m.StorageManager, records, m.DryRun)
+	}); err != nil {
+		return err
+	}
+
 	if err := logboek.Context(ctx).Default().LogProcess("Deleting images metadata").DoError(func() error {
 		_, imageMetadataByImageName, err := m.StorageManager.StagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, m.ProjectName, []string{})
 		if err != nil {
@@ -0,0 +1,263 @@
+package cleaning
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+// storageQuotaStagesPerImageCleanup unlinks the least recently used stages of each image exceeding the limit,
+// the unlinked stages are deleted by the unused stages cleanup
+func (m *cleanupManager) storageQuotaStagesPerImageCleanup(ctx context.Context, maxStagesPerImage int) error {
+	protectedStageIDs, err := m.storageQuotaProtectedStageIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	imageNameStageIDCommitListToUnlink := map[string]map[string][]string{}
+	for imageName, stageIDCommitList := range m.imageNameStageIDCommitList {
+		if len(stageIDCommitList) <= maxStagesPerImage {
+			continue
+		}
+
+		var stages []*image.StageDescription
+		for stageID := range stageIDCommitList {
+			stages = append(stages, m.mustGetStage(stageID))
+		}
+		sortStagesByLastUse(stages, m.stageLastUsedAt)
+
+		stageIDCommitListToUnlink := map[string][]string{}
+		for _, stage := range stages[:len(stages)-maxStagesPerImage] {
+			if protectedStageIDs[stage.Info.Tag] {
+				continue
+			}
+
+			stageIDCommitListToUnlink[stage.Info.Tag] = stageIDCommitList[stage.Info.Tag]
+		}
+
+		if len(stageIDCommitListToUnlink) != 0 {
+			imageNameStageIDCommitListToUnlink[imageName] = stageIDCommitListToUnlink
+		}
+	}
+
+	for imageName, stageIDCommitList := range imageNameStageIDCommitListToUnlink {
+		logboek.Context(ctx).Default().LogBlock("Unlinked stages (%s)", imageName).Do(func() {
+			for stageID := range stageIDCommitList {
+				logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageID)
+				logboek.Context(ctx).LogOptionalLn()
+
+				m.stageDeletionReasons[stageID] = CleanupPlanReasonStorageQuota
+			}
+		})
+
+		if err := m.deleteImageMetadata(ctx, imageName, stageIDCommitList, true, CleanupPlanReasonStorageQuota); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storageQuotaSizeCleanup deletes the least recently used stages until the stages storage fits the size quota
+func (m *cleanupManager) storageQuotaSizeCleanup(ctx context.Context, maxSize int64) error {
+	var totalSize int64
+	for _, size := range stagesOwnSizes(m.stages) {
+		totalSize += size
+	}
+
+	logboek.Context(ctx).Default().LogF("Stages storage size: %s (quota %s)\n", units.HumanSize(float64(totalSize)), units.HumanSize(float64(maxSize)))
+	if totalSize <= maxSize {
+		return nil
+	}
+
+	protectedStageIDs, err := m.storageQuotaProtectedStageIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	stagesToDelete, freedSize := selectLeastRecentlyUsedStages(m.stages, protectedStageIDs, totalSize-maxSize, m.stageLastUsedAt)
+	if freedSize < totalSize-maxSize {
+		logboek.Context(ctx).Warn().LogF("WARNING: Unable to fit the stages storage into the quota: %s of used stages exceed the quota\n", units.HumanSize(float64(totalSize-maxSize-freedSize)))
+	}
+
+	if len(stagesToDelete) == 0 {
+		return nil
+	}
+
+	for _, stage := range stagesToDelete {
+		m.stageDeletionReasons[stage.Info.Tag] = CleanupPlanReasonStorageQuota
+	}
+
+	imageNameStageIDCommitListToDelete := map[string]map[string][]string{}
+	for imageName, stageIDCommitList := range m.imageNameStageIDCommitList {
+		for _, stage := range stagesToDelete {
+			if commitList, ok := stageIDCommitList[stage.Info.Tag]; ok {
+				if _, ok := imageNameStageIDCommitListToDelete[imageName]; !ok {
+					imageNameStageIDCommitListToDelete[imageName] = map[string][]string{}
+				}
+
+				imageNameStageIDCommitListToDelete[imageName][stage.Info.Tag] = commitList
+			}
+		}
+	}
+
+	for imageName, stageIDCommitList := range imageNameStageIDCommitListToDelete {
+		if err := logboek.Context(ctx).Info().LogProcess("Deleting %s metadata", imageName).DoError(func() error {
+			return m.deleteImageMetadata(ctx, imageName, stageIDCommitList, true, CleanupPlanReasonStorageQuota)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Deleting stages tags (%s to free)", units.HumanSize(float64(freedSize))).DoError(func() error {
+		return m.deleteStages(ctx, stagesToDelete)
+	}); err != nil {
+		return err
+	}
+
+	m.stages = excludeStages(m.stages, stagesToDelete...)
+
+	return nil
+}
+
+// storageQuotaProtectedStageIDs returns the stages which the storage quota must not delete:
+// the stages used by the allow list sources, the stages built or used within last N hours,
+// the stages retained by the git history-based keep policies (unless the quota overrides keep policies) and their relatives
+func (m *cleanupManager) storageQuotaProtectedStageIDs(ctx context.Context) (map[string]bool, error) {
+	var stagesToProtect []*image.StageDescription
+
+	if quota := m.GitHistoryBasedCleanupOptions.StorageQuota; quota == nil || !quota.OverrideKeepPolicies {
+		for _, stage := range m.stages {
+			if m.keepPoliciesStageIDs[stage.Info.Tag] {
+				stagesToProtect = append(stagesToProtect, stage)
+			}
+		}
+	}
+
+	if len(m.AllowListSources) != 0 {
+		deployedDockerImagesNames, err := m.deployedDockerImagesNames(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, stage := range m.stages {
+			dockerImageName := fmt.Sprintf("%s:%s", m.StorageManager.StagesStorage.String(), stage.Info.Tag)
+			if _, ok := deployedDockerImagesNames[dockerImageName]; ok {
+				stagesToProtect = append(stagesToProtect, stage)
+			}
+		}
+	}
+
+	if m.KeepStagesBuiltWithinLastNHours != 0 {
+		for _, stage := range m.stages {
+			if time.Since(m.stageLastUsedAt(stage)).Hours() <= float64(m.KeepStagesBuiltWithinLastNHours) {
+				stagesToProtect = append(stagesToProtect, stage)
+			}
+		}
+	}
+
+	protectedStageIDs := map[string]bool{}
+	stages := m.stages
+	for _, stage := range stagesToProtect {
+		var excludedStages []*image.StageDescription
+		stages, excludedStages = m.excludeStageAndRelativesByStage(stages, stage)
+		for _, excludedStage := range excludedStages {
+			protectedStageIDs[excludedStage.Info.Tag] = true
+		}
+	}
+
+	return protectedStageIDs, nil
+}
+
+// stageLastUsedAt returns the last time the stage was built, selected or fetched
+func (m *cleanupManager) stageLastUsedAt(stage *image.StageDescription) time.Time {
+	createdAt := stage.Info.GetCreatedAt()
+	if lastUsedAt := m.stagesLastUse.LastUsedAt(stage.Info.Tag); lastUsedAt.After(createdAt) {
+		return lastUsedAt
+	}
+
+	return createdAt
+}
+
+// sortStagesByLastUse sorts the stages from the least recently used to the most recently used
+func sortStagesByLastUse(stages []*image.StageDescription, lastUsedAt func(*image.StageDescription) time.Time) {
+	sort.SliceStable(stages, func(i, j int) bool {
+		iLastUsedAt, jLastUsedAt := lastUsedAt(stages[i]), lastUsedAt(stages[j])
+		if iLastUsedAt.Equal(jLastUsedAt) {
+			return stages[i].Info.Tag < stages[j].Info.Tag
+		}
+
+		return iLastUsedAt.Before(jLastUsedAt)
+	})
+}
+
+// stagesOwnSizes returns the sizes of the layers added by each stage:
+// the stage image includes the layers of the parent stage, which are stored only once
+func stagesOwnSizes(stages []*image.StageDescription) map[string]int64 {
+	stageByImageID := map[string]*image.StageDescription{}
+	for _, stage := range stages {
+		stageByImageID[stage.Info.ID] = stage
+	}
+
+	res := map[string]int64{}
+	for _, stage := range stages {
+		size := stage.Info.Size
+		if parent, ok := stageByImageID[stage.Info.ParentID]; ok {
+			size -= parent.Info.Size
+		}
+
+		if size < 0 {
+			size = 0
+		}
+
+		res[stage.Info.Tag] = size
+	}
+
+	return res
+}
+
+// selectLeastRecentlyUsedStages selects the least recently used stages until the selected stages size reaches sizeToFree.
+// Only the stages without children are deleted because the layers of a stage are still stored while its children exist
+func selectLeastRecentlyUsedStages(stages []*image.StageDescription, protectedStageIDs map[string]bool, sizeToFree int64, lastUsedAt func(*image.StageDescription) time.Time) ([]*image.StageDescription, int64) {
+	ownSizes := stagesOwnSizes(stages)
+
+	childrenCount := map[string]int{}
+	for _, stage := range stages {
+		childrenCount[stage.Info.ParentID]++
+	}
+
+	candidates := append([]*image.StageDescription{}, stages...)
+	sortStagesByLastUse(candidates, lastUsedAt)
+
+	var selectedStages []*image.StageDescription
+	var freedSize int64
+	for freedSize < sizeToFree {
+		var selectedStage *image.StageDescription
+		for ind, stage := range candidates {
+			if protectedStageIDs[stage.Info.Tag] || childrenCount[stage.Info.ID] != 0 {
+				continue
+			}
+
+			selectedStage = stage
+			candidates = append(candidates[:ind], candidates[ind+1:]...)
+			break
+		}
+
+		if selectedStage == nil {
+			break
+		}
+
+		selectedStages = append(selectedStages, selectedStage)
+		freedSize += ownSizes[selectedStage.Info.Tag]
+		childrenCount[selectedStage.Info.ParentID]--
+	}
+
+	return selectedStages, freedSize
+}
@@ -0,0 +1,127 @@
+package cleaning
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/image"
+)
+
+func newTestSizedStage(tag, id, parentID string, size int64, createdAt int64) *image.StageDescription {
+	stage := newTestStage(tag, id, parentID)
+	stage.Info.Size = size
+	stage.Info.SetCreatedAtUnix(createdAt)
+	return stage
+}
+
+func TestStagesOwnSizes(t *testing.T) {
+	stages := []*image.StageDescription{
+		newTestSizedStage("base-1", "sha256:base", "sha256:external", 100, 1),
+		newTestSizedStage("app-1", "sha256:app1", "sha256:base", 130, 2),
+		newTestSizedStage("app-2", "sha256:app2", "sha256:base", 150, 3),
+	}
+
+	expected := map[string]int64{"base-1": 100, "app-1": 30, "app-2": 50}
+	if sizes := stagesOwnSizes(stages); !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("got %v, expected %v", sizes, expected)
+	}
+}
+
+func TestSelectLeastRecentlyUsedStages(t *testing.T) {
+	stages := []*image.StageDescription{
+		newTestSizedStage("base-1", "sha256:base", "", 100, 1),
+		newTestSizedStage("app-1", "sha256:app1", "sha256:base", 130, 2),
+		newTestSizedStage("app-2", "sha256:app2", "sha256:base", 150, 3),
+		newTestSizedStage("other-1", "sha256:other", "", 40, 4),
+	}
+
+	lastUsedAt := func(stage *image.StageDescription) time.Time {
+		return stage.Info.GetCreatedAt()
+	}
+
+	for _, tt := range []struct {
+		name              string
+		protectedStageIDs map[string]bool
+		sizeToFree        int64
+		expectedTags      []string
+		expectedFreedSize int64
+	}{
+		{
+			name:              "the least recently used leaf first",
+			sizeToFree:        10,
+			expectedTags:      []string{"app-1"},
+			expectedFreedSize: 30,
+		},
+		{
+			name:              "the parent after its children",
+			sizeToFree:        150,
+			expectedTags:      []string{"app-1", "app-2", "base-1"},
+			expectedFreedSize: 180,
+		},
+		{
+			name:              "protected stages are kept",
+			protectedStageIDs: map[string]bool{"app-2": true, "base-1": true},
+			sizeToFree:        1000,
+			expectedTags:      []string{"app-1", "other-1"},
+			expectedFreedSize: 70,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			selectedStages, freedSize := selectLeastRecentlyUsedStages(stages, tt.protectedStageIDs, tt.sizeToFree, lastUsedAt)
+			if tags := stagesTags(selectedStages); !reflect.DeepEqual(tags, tt.expectedTags) {
+				t.Errorf("got %v, expected %v", tags, tt.expectedTags)
+			}
+
+			if freedSize != tt.expectedFreedSize {
+				t.Errorf("got freed size %d, expected %d", freedSize, tt.expectedFreedSize)
+			}
+		})
+	}
+}
+
+func TestStorageQuotaProtectedStageIDs(t *testing.T) {
+	stages := []*image.StageDescription{
+		newTestStage("base-1", "sha256:base", ""),
+		newTestStage("app-1", "sha256:app1", "sha256:base"),
+		newTestStage("app-2", "sha256:app2", "sha256:base"),
+	}
+
+	for _, tt := range []struct {
+		name                 string
+		overrideKeepPolicies bool
+		expected             map[string]bool
+	}{
+		{
+			name:     "the stages retained by keep policies and their parents are protected",
+			expected: map[string]bool{"app-1": true, "base-1": true},
+		},
+		{
+			name:                 "keep policies are overridden",
+			overrideKeepPolicies: true,
+			expected:             map[string]bool{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			maxStagesPerImage := 1
+			m := &cleanupManager{
+				stages:               stages,
+				keepPoliciesStageIDs: map[string]bool{"app-1": true},
+				GitHistoryBasedCleanupOptions: config.MetaCleanup{
+					StorageQuota: &config.MetaCleanupStorageQuota{MaxStagesPerImage: &maxStagesPerImage, OverrideKeepPolicies: tt.overrideKeepPolicies},
+				},
+			}
+
+			protectedStageIDs, err := m.storageQuotaProtectedStageIDs(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(protectedStageIDs, tt.expected) {
+				t.Errorf("got %v, expected %v", protectedStageIDs, tt.expected)
+			}
+		})
+	}
+}
@@ -103,3 +103,26 @@ func InterfaceToStringArray(stringOrStringArray interface{}, configSection inter
 // Stack for setting parents in UnmarshalYAML calls
 // Set this to util.NewStack before yaml.Unmarshal
 var parentStack *util.Stack
+
+func validatePlatforms(platforms []string, configSection interface{}, doc *doc) error {
+	platformByName := map[string]bool{}
+	for _, platform := range platforms {
+		parts := strings.Split(platform, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return newDetailedConfigError(fmt.Sprintf("invalid platform `%s`: expected format `OS/ARCH[/VARIANT]`!", platform), configSection, doc)
+		}
+
+		for _, part := range parts {
+			if part == "" {
+				return newDetailedConfigError(fmt.Sprintf("invalid platform `%s`: expected format `OS/ARCH[/VARIANT]`!", platform), configSection, doc)
+			}
+		}
+
+		if platformByName[platform] {
+			return newDetailedConfigError(fmt.Sprintf("duplicate platform `%s`!", platform), configSection, doc)
+		}
+		platformByName[platform] = true
+	}
+
+	return nil
+}
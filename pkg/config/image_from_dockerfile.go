@@ -16,6 +16,8 @@ type ImageFromDockerfile struct {
 	AddHost        []string
 	Network        string
 	SSH            string
+	Platform       []string
+	Secrets        []*Secret
 
 	raw *rawImageFromDockerfile
 }
@@ -29,6 +31,10 @@ func (c *ImageFromDockerfile) validate(giterminismManager giterminism_manager.In
 		return newDetailedConfigError("`contextAddFile: [PATH, ...]|PATH` each path should be relative to context!", nil, c.raw.doc)
 	}
 
+	if err := validatePlatforms(c.Platform, nil, c.raw.doc); err != nil {
+		return err
+	}
+
 	if len(c.ContextAddFile) != 0 {
 		for _, contextAddFile := range c.ContextAddFile {
 			if err := giterminismManager.Inspector().InspectConfigDockerfileContextAddFile(filepath.Join(c.Context, contextAddFile)); err != nil {
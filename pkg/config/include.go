@@ -0,0 +1,268 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/bmatcuk/doublestar"
+	"gopkg.in/yaml.v2"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/giterminism_manager"
+	"github.com/werf/werf/pkg/util"
+)
+
+// renderWerfConfigIncludes renders the configs included by the meta section of the werf config.
+// The included content is addressed by commit, so it is read from the remote git repository bypassing giterminism file checks.
+// The image, artifact and template documents of the included configs are returned as the single YAML stream,
+// the meta sections of the included configs are returned separately (only the cleanup policies are used).
+// The parameters of the including config are available in the included configs as .Params.
+func renderWerfConfigIncludes(ctx context.Context, werfConfigRenderContent string, giterminismManager giterminism_manager.Interface, env string, params map[string]interface{}) (string, []*Meta, error) {
+	includes, err := getMetaIncludes(werfConfigRenderContent)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var includedDocs []string
+	var includedMetas []*Meta
+	for _, include := range includes {
+		content, err := renderWerfConfigInclude(ctx, include, giterminismManager, env, params)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to include %s: %s", include, err)
+		}
+
+		docs, metas, err := splitIncludedDocs(content)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to include %s: %s", include, err)
+		}
+
+		includedDocs = append(includedDocs, docs...)
+		includedMetas = append(includedMetas, metas...)
+	}
+
+	return strings.Join(includedDocs, "---\n"), includedMetas, nil
+}
+
+// getMetaIncludes returns the includes of the meta section, the invalid meta section is left to be reported by the parser
+func getMetaIncludes(werfConfigRenderContent string) ([]*MetaInclude, error) {
+	for _, docContent := range splitContent([]byte(werfConfigRenderContent)) {
+		if emptyDocContent(docContent) {
+			continue
+		}
+
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal(docContent, &raw); err != nil || !isMetaDoc(raw) {
+			continue
+		}
+
+		if _, ok := raw["include"]; !ok {
+			return nil, nil
+		}
+
+		parentStack = util.NewStack()
+		rawMeta := &rawMeta{doc: &doc{Content: docContent}}
+		if err := yaml.UnmarshalStrict(docContent, &rawMeta); err != nil {
+			return nil, newYamlUnmarshalError(err, rawMeta.doc)
+		}
+
+		return rawMeta.toMeta().Include, nil
+	}
+
+	return nil, nil
+}
+
+func splitIncludedDocs(content string) ([]string, []*Meta, error) {
+	var docs []string
+	var metas []*Meta
+	for _, docContent := range splitContent([]byte(content)) {
+		if emptyDocContent(docContent) {
+			continue
+		}
+
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal(docContent, &raw); err == nil && isMetaDoc(raw) {
+			parentStack = util.NewStack()
+			rawMeta := &rawMeta{doc: &doc{Content: docContent}}
+			if err := yaml.UnmarshalStrict(docContent, &rawMeta); err != nil {
+				return nil, nil, newYamlUnmarshalError(err, rawMeta.doc)
+			}
+
+			if len(rawMeta.Include) != 0 {
+				return nil, nil, newDetailedConfigError("nested includes are not supported!", nil, rawMeta.doc)
+			}
+
+			metas = append(metas, rawMeta.toMeta())
+			continue
+		}
+
+		docContent := string(docContent)
+		if !strings.HasSuffix(docContent, "\n") {
+			docContent += "\n"
+		}
+
+		docs = append(docs, docContent)
+	}
+
+	return docs, metas, nil
+}
+
+func renderWerfConfigInclude(ctx context.Context, include *MetaInclude, giterminismManager giterminism_manager.Interface, env string, params map[string]interface{}) (string, error) {
+	repo, commit, err := openIncludeRepo(ctx, include)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl := template.New("werfConfig")
+	tmpl.Funcs(funcMap(tmpl, giterminismManager))
+
+	filePathList, err := repo.GetCommitFilePathList(ctx, commit)
+	if err != nil {
+		return "", fmt.Errorf("unable to get files list of commit %s: %s", commit, err)
+	}
+
+	for _, filePath := range filePathList {
+		filePath = filepath.ToSlash(filePath)
+		if matched, err := doublestar.Match(path.Join(include.TemplatesDir, "**", "*.tmpl"), filePath); err != nil {
+			return "", err
+		} else if !matched {
+			continue
+		}
+
+		data, err := repo.ReadCommitFile(ctx, commit, filePath)
+		if err != nil {
+			return "", fmt.Errorf("unable to read template %q: %s", filePath, err)
+		}
+
+		if err := addTemplate(tmpl, filepath.ToSlash(util.GetRelativeToBaseFilepath(include.TemplatesDir, filePath)), string(data)); err != nil {
+			return "", err
+		}
+	}
+
+	if exist, err := repo.IsCommitFileExists(ctx, commit, include.Path); err != nil {
+		return "", err
+	} else if !exist {
+		return "", fmt.Errorf("the file %q not found in the commit %s", include.Path, commit)
+	}
+
+	configData, err := repo.ReadCommitFile(ctx, commit, include.Path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read file %q: %s", include.Path, err)
+	}
+
+	if _, err := tmpl.Parse(string(configData)); err != nil {
+		return "", err
+	}
+
+	templateData := make(map[string]interface{})
+	templateData["Files"] = includeFiles{
+		ctx:            ctx,
+		repo:           repo,
+		commit:         commit,
+		filePathList:   filePathList,
+		includeDirPath: path.Dir(include.Path),
+	}
+	templateData["Env"] = env
+	templateData["Params"] = params
+
+	return executeTemplate(tmpl, "werfConfig", templateData)
+}
+
+// openIncludeRepo clones or fetches the included repository and returns the pinned commit of the include.
+// The repository is not fetched if the pinned commit (and the tag if specified) is already in the cache,
+// the tag only verifies the pinned commit, so the same werf config always renders the same included config
+func openIncludeRepo(ctx context.Context, include *MetaInclude) (*git_repo.Remote, string, error) {
+	repo, err := git_repo.OpenRemoteRepo(getRepositoryID(include.Git), include.Git)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// the refreshing is logged into the error stream not to mix the logs with the rendered config output
+	logger := logboek.Context(ctx).NewSubLogger(logboek.Context(ctx).ProxyErrStream(), logboek.Context(ctx).ProxyErrStream())
+	logger.GetStreamsSettingsFrom(logboek.Context(ctx))
+	logger.SetAcceptedLevel(logboek.Context(ctx).AcceptedLevel())
+	ctx = logboek.NewContext(ctx, logger)
+
+	if err := logboek.Context(ctx).Info().LogProcess("Refreshing %s repository", include.Git).DoError(func() error {
+		isCloned, err := repo.Clone(ctx)
+		if err != nil || isCloned {
+			return err
+		}
+
+		if exist, err := repo.IsCommitExists(ctx, include.Commit); err != nil {
+			return err
+		} else if exist && include.Tag == "" {
+			return nil
+		} else if exist {
+			if _, err := repo.TagCommit(ctx, include.Tag); err == nil {
+				return nil
+			}
+		}
+
+		return repo.Fetch(ctx)
+	}); err != nil {
+		return nil, "", err
+	}
+
+	if exist, err := repo.IsCommitExists(ctx, include.Commit); err != nil {
+		return nil, "", err
+	} else if !exist {
+		return nil, "", fmt.Errorf("commit %s not found in the repository", include.Commit)
+	}
+
+	if include.Tag != "" {
+		tagCommit, err := repo.TagCommit(ctx, include.Tag)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if tagCommit != include.Commit {
+			return nil, "", fmt.Errorf("tag %s has been moved: the tag points to commit %s instead of the pinned commit %s", include.Tag, tagCommit, include.Commit)
+		}
+	}
+
+	return repo, include.Commit, nil
+}
+
+// includeFiles provides .Files for the included config, the paths are relative to the included config directory
+type includeFiles struct {
+	ctx            context.Context
+	repo           *git_repo.Remote
+	commit         string
+	filePathList   []string
+	includeDirPath string
+}
+
+func (f includeFiles) Get(relPath string) string {
+	data, err := f.repo.ReadCommitFile(f.ctx, f.commit, path.Join(f.includeDirPath, relPath))
+	if err != nil {
+		panic(fmt.Sprintf("{{ .Files.Get %q }}: %s", relPath, err))
+	}
+
+	return string(data)
+}
+
+func (f includeFiles) Glob(pattern string) map[string]interface{} {
+	result := map[string]interface{}{}
+	for _, filePath := range f.filePathList {
+		relPath := filepath.ToSlash(util.GetRelativeToBaseFilepath(f.includeDirPath, filePath))
+		if matched, err := doublestar.Match(pattern, relPath); err != nil {
+			panic(fmt.Sprintf("{{ .Files.Glob %q }}: %s", pattern, err))
+		} else if !matched {
+			continue
+		}
+
+		result[relPath] = f.Get(relPath)
+	}
+
+	if len(result) == 0 {
+		logboek.Context(f.ctx).Warn().LogF("WARNING: No matches found for {{ .Files.Glob %q }}\n", pattern)
+	}
+
+	return result
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	templateDirective = "template"
+	extendsDirective  = "extends"
+)
+
+type inheritanceDoc struct {
+	content []byte
+	data    yaml.MapSlice
+
+	template string
+	extends  string
+	names    []string
+
+	merged yaml.MapSlice
+}
+
+func (d *inheritanceDoc) logName() string {
+	if d.template != "" {
+		return fmt.Sprintf("template %q", d.template)
+	}
+
+	switch len(d.names) {
+	case 0:
+		return "image"
+	case 1:
+		return fmt.Sprintf("image %q", d.names[0])
+	default:
+		return fmt.Sprintf("images %q", d.names)
+	}
+}
+
+// resolveInheritance merges the base configs into the documents with the `extends: NAME` directive and drops the `template: NAME` documents.
+// The base is looked up among the templates first and then among the images and artifacts.
+// The merge rules:
+//   - the name directives (image, artifact, template) are not inherited;
+//   - maps are merged recursively, the derived values override the base ones;
+//   - lists and scalars of the derived config replace the base ones;
+//   - the null value removes the inherited field.
+//
+// The content is returned as is if there are no templates and no extends directives.
+func resolveInheritance(werfConfigRenderContent string) (string, error) {
+	var docs []*inheritanceDoc
+	var withInheritance bool
+	for _, docContent := range splitContent([]byte(werfConfigRenderContent)) {
+		if emptyDocContent(docContent) {
+			continue
+		}
+
+		d := &inheritanceDoc{content: docContent}
+		docs = append(docs, d)
+
+		// the invalid documents are left as is to be reported by the parser
+		if err := yaml.Unmarshal(docContent, &d.data); err != nil {
+			continue
+		}
+
+		for _, item := range d.data {
+			switch fmt.Sprint(item.Key) {
+			case templateDirective:
+				withInheritance = true
+				if name, ok := item.Value.(string); !ok || name == "" {
+					return "", newConfigError(fmt.Sprintf("invalid template name `%v`: non-empty string expected!", item.Value))
+				} else {
+					d.template = name
+				}
+			case extendsDirective:
+				withInheritance = true
+				if name, ok := item.Value.(string); !ok {
+					return "", newConfigError(fmt.Sprintf("invalid `extends: %v` directive: image or template name expected!", item.Value))
+				} else {
+					d.extends = name
+				}
+			case "image":
+				switch value := item.Value.(type) {
+				case nil:
+					d.names = append(d.names, "")
+				case string:
+					d.names = append(d.names, value)
+				case []interface{}:
+					for _, name := range value {
+						d.names = append(d.names, fmt.Sprint(name))
+					}
+				}
+			case "artifact":
+				if name, ok := item.Value.(string); ok {
+					d.names = append(d.names, name)
+				}
+			}
+		}
+	}
+
+	if !withInheritance {
+		return werfConfigRenderContent, nil
+	}
+
+	templates := map[string]*inheritanceDoc{}
+	images := map[string]*inheritanceDoc{}
+	for _, d := range docs {
+		if d.template != "" {
+			if _, ok := templates[d.template]; ok {
+				return "", newConfigError(fmt.Sprintf("duplicate template %q definition!", d.template))
+			}
+			templates[d.template] = d
+
+			continue
+		}
+
+		for _, name := range d.names {
+			if _, ok := images[name]; !ok {
+				images[name] = d
+			}
+		}
+	}
+
+	lookupBase := func(name string) *inheritanceDoc {
+		if d, ok := templates[name]; ok {
+			return d
+		}
+		return images[name]
+	}
+
+	var docsContents []string
+	for _, d := range docs {
+		if err := d.resolve(lookupBase, nil); err != nil {
+			return "", err
+		}
+
+		switch {
+		case d.template != "":
+			continue
+		case d.extends == "":
+			docsContents = append(docsContents, string(d.content))
+		default:
+			data, err := yaml.Marshal(d.merged)
+			if err != nil {
+				return "", fmt.Errorf("unable to marshal %s config: %s", d.logName(), err)
+			}
+			docsContents = append(docsContents, string(data))
+		}
+	}
+
+	for ind, content := range docsContents {
+		if !strings.HasSuffix(content, "\n") {
+			docsContents[ind] = content + "\n"
+		}
+	}
+
+	return strings.Join(docsContents, "---\n"), nil
+}
+
+func (d *inheritanceDoc) resolve(lookupBase func(name string) *inheritanceDoc, stack []*inheritanceDoc) error {
+	if d.merged != nil || d.extends == "" {
+		return nil
+	}
+
+	for _, stackDoc := range stack {
+		if stackDoc == d {
+			var chain []string
+			for _, chainDoc := range append(stack, d) {
+				chain = append(chain, chainDoc.logName())
+			}
+
+			return newConfigError(fmt.Sprintf("infinite loop detected in extends directives: %s", strings.Join(chain, " -> ")))
+		}
+	}
+
+	base := lookupBase(d.extends)
+	if base == nil {
+		return newConfigError(fmt.Sprintf("%s extends unknown image or template %q!", d.logName(), d.extends))
+	}
+
+	if err := base.resolve(lookupBase, append(stack, d)); err != nil {
+		return err
+	}
+
+	baseData := base.data
+	if base.merged != nil {
+		baseData = base.merged
+	}
+
+	var merged yaml.MapSlice
+	for _, item := range d.data {
+		switch fmt.Sprint(item.Key) {
+		case "image", "artifact", templateDirective:
+			merged = append(merged, item)
+		}
+	}
+
+	d.merged = append(merged, mergeMapSlices(withoutInheritanceDirectives(baseData), withoutInheritanceDirectives(d.data))...)
+
+	return nil
+}
+
+func withoutInheritanceDirectives(data yaml.MapSlice) yaml.MapSlice {
+	var res yaml.MapSlice
+	for _, item := range data {
+		switch fmt.Sprint(item.Key) {
+		case "image", "artifact", templateDirective, extendsDirective:
+		default:
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
+
+func mergeMapSlices(base, derived yaml.MapSlice) yaml.MapSlice {
+	derivedValues := map[string]interface{}{}
+	for _, item := range derived {
+		derivedValues[fmt.Sprint(item.Key)] = item.Value
+	}
+
+	var res yaml.MapSlice
+	baseKeys := map[string]bool{}
+	for _, item := range base {
+		key := fmt.Sprint(item.Key)
+		baseKeys[key] = true
+
+		derivedValue, ok := derivedValues[key]
+		switch {
+		case !ok:
+			res = append(res, item)
+		case derivedValue == nil:
+			// the null value removes the inherited field
+		default:
+			baseMap, isBaseMap := item.Value.(yaml.MapSlice)
+			derivedMap, isDerivedMap := derivedValue.(yaml.MapSlice)
+			if isBaseMap && isDerivedMap {
+				res = append(res, yaml.MapItem{Key: item.Key, Value: mergeMapSlices(baseMap, derivedMap)})
+			} else {
+				res = append(res, yaml.MapItem{Key: item.Key, Value: derivedValue})
+			}
+		}
+	}
+
+	for _, item := range derived {
+		if !baseKeys[fmt.Sprint(item.Key)] && item.Value != nil {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
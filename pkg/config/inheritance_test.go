@@ -0,0 +1,150 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type inheritanceEntry struct {
+	content         string
+	expectedContent string
+	expectedError   string
+}
+
+var _ = DescribeTable("resolving extends directives", func(e inheritanceEntry) {
+	content, err := resolveInheritance(e.content)
+	if e.expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(content).Should(Equal(e.expectedContent))
+	}
+},
+	Entry("without inheritance", inheritanceEntry{
+		content: `configVersion: 1
+project: test
+---
+image: app
+from: alpine # comment
+`,
+		expectedContent: `configVersion: 1
+project: test
+---
+image: app
+from: alpine # comment
+`,
+	}),
+	Entry("template", inheritanceEntry{
+		content: `configVersion: 1
+project: test
+---
+template: service
+from: alpine
+git:
+- add: /
+  to: /app
+docker:
+  WORKDIR: /app
+  ENV:
+    A: a
+    B: b
+---
+image: api
+extends: service
+docker:
+  ENV:
+    B: api
+    C: c
+---
+image: worker
+extends: service
+git: null
+mount:
+- from: tmp_dir
+  to: /tmp
+`,
+		expectedContent: `configVersion: 1
+project: test
+---
+image: api
+from: alpine
+git:
+- add: /
+  to: /app
+docker:
+  WORKDIR: /app
+  ENV:
+    A: a
+    B: api
+    C: c
+---
+image: worker
+from: alpine
+docker:
+  WORKDIR: /app
+  ENV:
+    A: a
+    B: b
+mount:
+- from: tmp_dir
+  to: /tmp
+`,
+	}),
+	Entry("extends image and chain of templates", inheritanceEntry{
+		content: `configVersion: 1
+project: test
+---
+template: base
+from: alpine
+---
+template: service
+extends: base
+shell:
+  install: make
+---
+image: [api, api2]
+extends: service
+---
+image: worker
+extends: api2
+from: ubuntu
+`,
+		expectedContent: `configVersion: 1
+project: test
+---
+image:
+- api
+- api2
+from: alpine
+shell:
+  install: make
+---
+image: worker
+from: ubuntu
+shell:
+  install: make
+`,
+	}),
+	Entry("unknown base", inheritanceEntry{
+		content: `image: app
+extends: unknown
+`,
+		expectedError: `image "app" extends unknown image or template "unknown"`,
+	}),
+	Entry("infinite loop", inheritanceEntry{
+		content: `template: a
+extends: b
+---
+template: b
+extends: a
+`,
+		expectedError: `infinite loop detected in extends directives: template "a" -> template "b" -> template "a"`,
+	}),
+	Entry("duplicate template", inheritanceEntry{
+		content: `template: a
+---
+template: a
+`,
+		expectedError: `duplicate template "a" definition`,
+	}))
@@ -0,0 +1,534 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/werf/werf/pkg/giterminism_manager"
+)
+
+type LintSeverity string
+
+const (
+	LintSeverityError   LintSeverity = "error"
+	LintSeverityWarning LintSeverity = "warning"
+)
+
+type LintIssue struct {
+	Severity LintSeverity
+	// Line is the line of the rendered werf.yaml, zero for the issues that concern the whole config
+	Line    int
+	Message string
+}
+
+type LintResult struct {
+	Issues []*LintIssue
+}
+
+func (r *LintResult) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == LintSeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LintWerfConfig validates every document of the rendered werf.yaml and collects all errors and best-practice warnings instead of stopping at the first error
+func LintWerfConfig(ctx context.Context, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath string, giterminismManager giterminism_manager.Interface, opts WerfConfigOptions) (*LintResult, error) {
+	werfConfigRender, err := renderWerfConfigYaml(ctx, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath, giterminismManager, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return lintWerfConfigRenderContent(giterminismManager, werfConfigRender.content)
+}
+
+func lintWerfConfigRenderContent(giterminismManager giterminism_manager.Interface, werfConfigRenderContent string) (*LintResult, error) {
+	docs, err := splitByDocs(werfConfigRenderContent, "")
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{
+		giterminismManager: giterminismManager,
+		schema:             GetWerfConfigJSONSchema(),
+		result:             &LintResult{},
+	}
+
+	for _, d := range docs {
+		l.lintDoc(d)
+	}
+
+	if l.result.HasErrors() {
+		return l.result, nil
+	}
+
+	if l.meta == nil {
+		l.addError(0, "meta config section with `configVersion: 1` and `project: NAME` fields is not defined")
+		return l.result, nil
+	}
+
+	werfConfig, err := prepareWerfConfig(giterminismManager, l.rawStapelImages, l.rawImagesFromDockerfile, l.meta)
+	if err != nil {
+		l.addConfigError(err)
+		return l.result, nil
+	}
+
+	l.lintUnusedArtifacts(werfConfig)
+
+	return l.result, nil
+}
+
+type linter struct {
+	giterminismManager giterminism_manager.Interface
+	schema             *JSONSchema
+	result             *LintResult
+
+	meta                    *Meta
+	rawStapelImages         []*rawStapelImage
+	rawImagesFromDockerfile []*rawImageFromDockerfile
+}
+
+func (l *linter) addError(line int, format string, a ...interface{}) {
+	l.result.Issues = append(l.result.Issues, &LintIssue{Severity: LintSeverityError, Line: line, Message: fmt.Sprintf(format, a...)})
+}
+
+func (l *linter) addWarning(line int, format string, a ...interface{}) {
+	l.result.Issues = append(l.result.Issues, &LintIssue{Severity: LintSeverityWarning, Line: line, Message: fmt.Sprintf(format, a...)})
+}
+
+var errorLineRegexp = regexp.MustCompile("line ([0-9]+)")
+
+func (l *linter) addConfigError(err error) {
+	var line int
+	message := err.Error()
+	if configErr, ok := err.(*configError); ok {
+		message = configErr.message
+		if configErr.doc != nil {
+			line = configErr.doc.Line + 1
+		}
+	}
+
+	if res := errorLineRegexp.FindStringSubmatch(message); len(res) == 2 {
+		if n, err := strconv.Atoi(res[1]); err == nil {
+			line = n
+		}
+	}
+
+	l.addError(line, "%s", strings.TrimSpace(message))
+}
+
+func (l *linter) lintDoc(d *doc) {
+	var raw map[string]interface{}
+	if err := yaml.UnmarshalStrict(d.Content, &raw); err != nil {
+		l.addConfigError(newYamlUnmarshalError(err, d))
+		return
+	}
+
+	var definition string
+	switch {
+	case isMetaDoc(raw):
+		if l.meta != nil {
+			l.addError(d.Line+1, "duplicate meta config section definition")
+			return
+		}
+		definition = "meta"
+	case isImageFromDockerfileDoc(raw):
+		definition = "imageFromDockerfile"
+	case isImageDoc(raw):
+		definition = "stapelImage"
+	default:
+		l.addError(d.Line+1, "cannot recognize type of config section: 'configVersion' required for meta config section, 'image' or 'artifact' for image config sections")
+		return
+	}
+
+	definitionSchema := &JSONSchema{Ref: "#/definitions/" + definition}
+	if violations := definitionSchema.validate(l.schema, raw, nil); len(violations) != 0 {
+		for _, v := range violations {
+			l.addError(d.Line+lookupDocPathLine(d.Content, v.path), "%s", v)
+		}
+		return
+	}
+
+	// every document is parsed separately to report errors of all documents
+	meta, rawStapelImages, rawImagesFromDockerfile, err := splitByMetaAndRawImages([]*doc{d})
+	if err != nil {
+		l.addConfigError(err)
+		return
+	}
+
+	if meta != nil {
+		l.meta = meta
+	}
+
+	for _, rawImageFromDockerfile := range rawImagesFromDockerfile {
+		if _, err := rawImageFromDockerfile.toImageFromDockerfileDirectives(l.giterminismManager); err != nil {
+			l.addConfigError(err)
+			continue
+		}
+
+		l.rawImagesFromDockerfile = append(l.rawImagesFromDockerfile, rawImageFromDockerfile)
+	}
+
+	for _, rawStapelImage := range rawStapelImages {
+		var err error
+		if rawStapelImage.stapelImageType() == "images" {
+			_, err = rawStapelImage.toStapelImageDirectives(l.giterminismManager)
+		} else {
+			_, err = rawStapelImage.toStapelImageArtifactDirectives(l.giterminismManager)
+		}
+
+		if err != nil {
+			l.addConfigError(err)
+			continue
+		}
+
+		l.lintStapelImage(rawStapelImage)
+		l.rawStapelImages = append(l.rawStapelImages, rawStapelImage)
+	}
+}
+
+func (l *linter) lintStapelImage(rawStapelImage *rawStapelImage) {
+	d := rawStapelImage.doc
+
+	if rawStapelImage.FromLatest {
+		l.addWarning(d.Line+lookupDocPathLine(d.Content, docPath{"fromLatest"}), "fromLatest: the build is not reproducible, the base image is updated each time a new version is published with the same tag")
+	}
+
+	if hasUserStagesInstructions(rawStapelImage) {
+		for ind, rawGit := range rawStapelImage.RawGit {
+			if rawGit.RawStageDependencies == nil {
+				path := docPath{"git", ind}
+				l.addWarning(d.Line+lookupDocPathLine(d.Content, path), "%s: stageDependencies are not specified, changes of the git mapping files will not trigger rebuilding of install, beforeSetup and setup stages", path)
+			}
+		}
+	}
+}
+
+func hasUserStagesInstructions(rawStapelImage *rawStapelImage) bool {
+	if s := rawStapelImage.RawShell; s != nil {
+		if s.Install != nil || s.BeforeSetup != nil || s.Setup != nil {
+			return true
+		}
+	}
+
+	if a := rawStapelImage.RawAnsible; a != nil {
+		if len(a.Install) != 0 || len(a.BeforeSetup) != 0 || len(a.Setup) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *linter) lintUnusedArtifacts(werfConfig *WerfConfig) {
+	var imageBases []*StapelImageBase
+	for _, image := range werfConfig.StapelImages {
+		imageBases = append(imageBases, image.StapelImageBase)
+	}
+	for _, artifact := range werfConfig.Artifacts {
+		imageBases = append(imageBases, artifact.StapelImageBase)
+	}
+
+	usedArtifacts := map[string]bool{}
+	for _, imageBase := range imageBases {
+		if imageBase.FromArtifactName != "" {
+			usedArtifacts[imageBase.FromArtifactName] = true
+		}
+
+		for _, imp := range imageBase.Import {
+			if imp.ArtifactName != "" {
+				usedArtifacts[imp.ArtifactName] = true
+			}
+		}
+	}
+
+	for _, artifact := range werfConfig.Artifacts {
+		if usedArtifacts[artifact.Name] {
+			continue
+		}
+
+		d := artifact.raw.doc
+		l.addWarning(d.Line+lookupDocPathLine(d.Content, docPath{"artifact"}), "artifact %q is not used: it is neither imported nor used in fromArtifact", artifact.Name)
+	}
+}
+
+// docPath is the path to the document value, the elements are mapping keys (string) and sequence indexes (int)
+type docPath []interface{}
+
+func (p docPath) String() string {
+	var res string
+	for _, elm := range p {
+		switch e := elm.(type) {
+		case int:
+			res += fmt.Sprintf("[%d]", e)
+		default:
+			if res != "" {
+				res += "."
+			}
+			res += fmt.Sprint(e)
+		}
+	}
+
+	return res
+}
+
+func (p docPath) add(elm interface{}) docPath {
+	res := make(docPath, len(p), len(p)+1)
+	copy(res, p)
+	return append(res, elm)
+}
+
+var docKeyRegexp = regexp.MustCompile(`^(\s*(?:-\s+)*)['"]?([^'":]+)['"]?\s*:(\s|$)`)
+
+// lookupDocPathLine returns the 1-based document line with the value of the path, the line of the closest found parent is returned if the value line is not found.
+// yaml.v2 does not provide positions of the nodes, so the lookup is made by the indentation of block mappings and sequences.
+func lookupDocPathLine(content []byte, path docPath) int {
+	lines := strings.Split(string(content), "\n")
+
+	foundLine := 0
+	parentIndent := -1
+	pos := 0
+	posIsItem := false
+
+pathLoop:
+	for _, elm := range path {
+		switch e := elm.(type) {
+		case int:
+			var itemIndent = -1
+			var itemInd int
+			for i := pos; i < len(lines); i++ {
+				indent, ok := lineIndent(lines[i])
+				if !ok {
+					continue
+				}
+
+				isItem := strings.HasPrefix(strings.TrimSpace(lines[i]), "-")
+				if indent < parentIndent || (indent == parentIndent && !isItem) {
+					break pathLoop
+				}
+
+				if !isItem || (itemIndent != -1 && indent != itemIndent) {
+					continue
+				}
+
+				itemIndent = indent
+				if itemInd == e {
+					foundLine, parentIndent, pos, posIsItem = i+1, indent, i, true
+					continue pathLoop
+				}
+				itemInd++
+			}
+			break pathLoop
+		default:
+			key := fmt.Sprint(e)
+			for i := pos; i < len(lines); i++ {
+				indent, ok := lineIndent(lines[i])
+				if !ok {
+					continue
+				}
+
+				if indent <= parentIndent && !(i == pos && posIsItem) {
+					break pathLoop
+				}
+
+				res := docKeyRegexp.FindStringSubmatch(lines[i])
+				if res == nil || strings.TrimSpace(res[2]) != key {
+					continue
+				}
+
+				if keyIndent := len(res[1]); keyIndent > parentIndent {
+					foundLine, parentIndent, pos, posIsItem = i+1, keyIndent, i+1, false
+					continue pathLoop
+				}
+			}
+			break pathLoop
+		}
+	}
+
+	if foundLine == 0 {
+		return 1
+	}
+
+	return foundLine
+}
+
+// lineIndent returns the indentation of the line, the blank and comment lines are skipped
+func lineIndent(line string) (int, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if trimmed == "" || strings.TrimSpace(trimmed) == "" || strings.HasPrefix(trimmed, "#") {
+		return 0, false
+	}
+
+	return len(line) - len(trimmed), true
+}
+
+type schemaViolation struct {
+	path    docPath
+	message string
+}
+
+func (v *schemaViolation) String() string {
+	if len(v.path) == 0 {
+		return v.message
+	}
+
+	return fmt.Sprintf("%s: %s", v.path, v.message)
+}
+
+// validate checks the value unmarshalled by yaml.v2 against the schema, only the keywords produced by GetWerfConfigJSONSchema are supported
+func (s *JSONSchema) validate(root *JSONSchema, value interface{}, path docPath) []*schemaViolation {
+	s = s.resolve(root)
+
+	// yaml.v2 leaves the fields with null values empty
+	if value == nil {
+		return nil
+	}
+
+	if len(s.AnyOf) != 0 {
+		var expected []string
+		for _, schema := range s.AnyOf {
+			schema = schema.resolve(root)
+			if schema.matchesType(value) {
+				return schema.validate(root, value, path)
+			}
+
+			expected = append(expected, schema.describe(root))
+		}
+
+		return []*schemaViolation{{path: path, message: fmt.Sprintf("%s expected, got %s", strings.Join(expected, " or "), describeValue(value))}}
+	}
+
+	if !s.matchesType(value) {
+		return []*schemaViolation{{path: path, message: fmt.Sprintf("%s expected, got %s", s.describe(root), describeValue(value))}}
+	}
+
+	if len(s.Enum) != 0 {
+		var enum []string
+		var found bool
+		for _, v := range s.Enum {
+			enum = append(enum, fmt.Sprint(v))
+			if fmt.Sprint(v) == fmt.Sprint(value) {
+				found = true
+			}
+		}
+
+		if !found {
+			return []*schemaViolation{{path: path, message: fmt.Sprintf("unsupported value %q, expected %s", fmt.Sprint(value), strings.Join(enum, " or "))}}
+		}
+	}
+
+	var violations []*schemaViolation
+	switch v := value.(type) {
+	case []interface{}:
+		if s.Items != nil {
+			for ind, item := range v {
+				violations = append(violations, s.Items.validate(root, item, path.add(ind))...)
+			}
+		}
+	case map[interface{}]interface{}, map[string]interface{}:
+		values := map[string]interface{}{}
+		if m, ok := v.(map[string]interface{}); ok {
+			values = m
+		} else {
+			for key, val := range v.(map[interface{}]interface{}) {
+				values[fmt.Sprint(key)] = val
+			}
+		}
+
+		var keys []string
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if property, ok := s.Properties[key]; ok {
+				violations = append(violations, property.validate(root, values[key], path.add(key))...)
+				continue
+			}
+
+			switch additionalProperties := s.AdditionalProperties.(type) {
+			case bool:
+				if !additionalProperties {
+					violations = append(violations, &schemaViolation{path: path.add(key), message: "unknown field"})
+				}
+			case *JSONSchema:
+				violations = append(violations, additionalProperties.validate(root, values[key], path.add(key))...)
+			}
+		}
+
+		for _, required := range s.Required {
+			if _, ok := values[required]; !ok {
+				violations = append(violations, &schemaViolation{path: path, message: fmt.Sprintf("required field %q is not specified", required)})
+			}
+		}
+	}
+
+	return violations
+}
+
+func (s *JSONSchema) matchesType(value interface{}) bool {
+	types := s.typeNames()
+	if len(types) == 0 {
+		return true
+	}
+
+	valueType := describeValue(value)
+	for _, t := range types {
+		switch {
+		case t == valueType:
+			return true
+		case t == "number" && valueType == "integer":
+			return true
+		case t == "string" && s.anyScalar && (valueType == "integer" || valueType == "number" || valueType == "boolean"):
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *JSONSchema) describe(root *JSONSchema) string {
+	s = s.resolve(root)
+
+	types := s.typeNames()
+	if len(types) == 0 {
+		return "value"
+	}
+
+	if types[0] == "array" && s.Items != nil {
+		return fmt.Sprintf("array of %ss", s.Items.describe(root))
+	}
+
+	return strings.Join(types, " or ")
+}
+
+func describeValue(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[interface{}]interface{}, map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
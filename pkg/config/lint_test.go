@@ -0,0 +1,119 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type lintEntry struct {
+	content        string
+	expectedIssues []*LintIssue
+}
+
+var _ = DescribeTable("linting werf config", func(e lintEntry) {
+	result, err := lintWerfConfigRenderContent(nil, e.content)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(result.Issues).Should(HaveLen(len(e.expectedIssues)))
+
+	for ind, issue := range result.Issues {
+		Ω(issue.Severity).Should(Equal(e.expectedIssues[ind].Severity))
+		Ω(issue.Line).Should(Equal(e.expectedIssues[ind].Line))
+		Ω(issue.Message).Should(ContainSubstring(e.expectedIssues[ind].Message))
+	}
+},
+	Entry("valid config", lintEntry{
+		content: `configVersion: 1
+project: test
+---
+image: app
+from: alpine
+import:
+- artifact: builder
+  add: /app
+  to: /app
+  after: install
+---
+artifact: builder
+from: golang
+`,
+	}),
+	Entry("errors of all documents", lintEntry{
+		content: `configVersion: 1
+project: test
+---
+image: app
+from: alpine
+git:
+- add: /
+  to: /app
+  stageDependencies:
+    instal: "*"
+---
+image: worker
+from: alpine
+shell:
+  install: 1
+  setup: [true, false]
+`,
+		expectedIssues: []*LintIssue{
+			{Severity: LintSeverityError, Line: 10, Message: "git[0].stageDependencies.instal: unknown field"},
+			{Severity: LintSeverityError, Line: 15, Message: "shell.install: string or array of strings expected, got integer"},
+			{Severity: LintSeverityError, Line: 16, Message: "shell.setup[0]: string expected, got boolean"},
+			{Severity: LintSeverityError, Line: 16, Message: "shell.setup[1]: string expected, got boolean"},
+		},
+	}),
+	Entry("yaml syntax error", lintEntry{
+		content: `configVersion: 1
+project: test
+---
+image: app
+from: alpine
+ docker: {}
+`,
+		expectedIssues: []*LintIssue{
+			{Severity: LintSeverityError, Line: 6, Message: "line 6"},
+		},
+	}),
+	Entry("unsupported operator", lintEntry{
+		content: `configVersion: 1
+project: test
+cleanup:
+  keepPolicies:
+  - references:
+      tag: /.*/
+  - imagesPerReference:
+      last: 10
+      operator: Xor
+`,
+		expectedIssues: []*LintIssue{
+			{Severity: LintSeverityError, Line: 9, Message: `cleanup.keepPolicies[1].imagesPerReference.operator: unsupported value "Xor"`},
+		},
+	}),
+	Entry("missing meta section", lintEntry{
+		content: `image: app
+from: alpine
+`,
+		expectedIssues: []*LintIssue{
+			{Severity: LintSeverityError, Line: 0, Message: "meta config section"},
+		},
+	}),
+	Entry("git mapping without stageDependencies and unused artifact", lintEntry{
+		content: `configVersion: 1
+project: test
+---
+image: app
+from: alpine
+git:
+- add: /
+  to: /app
+shell:
+  install: make install
+---
+artifact: builder
+from: golang
+`,
+		expectedIssues: []*LintIssue{
+			{Severity: LintSeverityWarning, Line: 7, Message: "git[0]: stageDependencies are not specified"},
+			{Severity: LintSeverityWarning, Line: 12, Message: `artifact "builder" is not used`},
+		},
+	}))
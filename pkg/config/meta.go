@@ -6,4 +6,7 @@ type Meta struct {
 	Deploy        MetaDeploy
 	Cleanup       MetaCleanup
 	GitWorktree   MetaGitWorktree
+	SecretKey     MetaSecretKey
+	Include       []*MetaInclude
+	Parameters    []*MetaParameter
 }
@@ -9,6 +9,8 @@ import (
 
 type MetaCleanup struct {
 	KeepPolicies []*MetaCleanupKeepPolicy
+	AllowList    MetaCleanupAllowList
+	StorageQuota *MetaCleanupStorageQuota
 }
 
 type MetaCleanupKeepPolicy struct {
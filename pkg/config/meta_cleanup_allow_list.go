@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MetaCleanupAllowList configures the sources of the images that must be kept by the cleanup in addition to the images used in Kubernetes
+type MetaCleanupAllowList struct {
+	HelmReleases    bool
+	Bundles         []*regexp.Regexp
+	CustomResources []*MetaCleanupAllowListCustomResource
+	Lists           []string
+}
+
+type MetaCleanupAllowListCustomResource struct {
+	Group      string
+	Version    string
+	Resource   string
+	ImagePaths []string
+}
+
+func (r *MetaCleanupAllowListCustomResource) String() string {
+	if r.Group == "" {
+		return fmt.Sprintf("%s/%s", r.Version, r.Resource)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", r.Group, r.Version, r.Resource)
+}
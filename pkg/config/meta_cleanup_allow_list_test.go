@@ -0,0 +1,74 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type metaCleanupAllowListEntry struct {
+	content           string
+	expectedAllowList MetaCleanupAllowList
+	expectedBundles   []string
+	expectedError     string
+}
+
+var _ = DescribeTable("parsing meta cleanup allow list", func(e metaCleanupAllowListEntry) {
+	meta, _, _, err := splitByMetaAndRawImages([]*doc{{Content: []byte("configVersion: 1\nproject: test\ncleanup:\n  allowList:\n" + e.content)}})
+	if e.expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+
+		var bundles []string
+		for _, regex := range meta.Cleanup.AllowList.Bundles {
+			bundles = append(bundles, regex.String())
+		}
+		Ω(bundles).Should(Equal(e.expectedBundles))
+
+		meta.Cleanup.AllowList.Bundles = nil
+		Ω(meta.Cleanup.AllowList).Should(Equal(e.expectedAllowList))
+	}
+},
+	Entry("all sources", metaCleanupAllowListEntry{
+		content: `    helmReleases: true
+    bundles: [v1.0.0, /v2\..*/]
+    lists: [.werf/keep-images.txt, https://ci.example.com/keep-images.txt]
+    customResources:
+    - apiVersion: argoproj.io/v1alpha1
+      resource: rollouts
+      imagePaths: ["{.spec.template.spec.containers[*].image}"]
+    - apiVersion: v1
+      resource: pods
+      imagePaths: ["{.spec.containers[*].image}"]
+`,
+		expectedBundles: []string{`^v1\.0\.0$`, `^v2\..*$`},
+		expectedAllowList: MetaCleanupAllowList{
+			HelmReleases: true,
+			Lists:        []string{".werf/keep-images.txt", "https://ci.example.com/keep-images.txt"},
+			CustomResources: []*MetaCleanupAllowListCustomResource{
+				{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts", ImagePaths: []string{"{.spec.template.spec.containers[*].image}"}},
+				{Version: "v1", Resource: "pods", ImagePaths: []string{"{.spec.containers[*].image}"}},
+			},
+		},
+	}),
+	Entry("invalid bundle regexp", metaCleanupAllowListEntry{
+		content:       "    bundles: [/v(/]\n",
+		expectedError: "invalid value \"/v(/\" for `bundles: [string|REGEX, ...]`",
+	}),
+	Entry("custom resource without resource", metaCleanupAllowListEntry{
+		content:       "    customResources:\n    - apiVersion: argoproj.io/v1alpha1\n      imagePaths: [\"{.spec.image}\"]\n",
+		expectedError: "`resource: PLURAL_NAME` required",
+	}),
+	Entry("custom resource without image paths", metaCleanupAllowListEntry{
+		content:       "    customResources:\n    - apiVersion: serving.knative.dev/v1\n      resource: services\n",
+		expectedError: "`imagePaths: [JSONPATH, ...]` required",
+	}),
+	Entry("invalid JSONPath", metaCleanupAllowListEntry{
+		content:       "    customResources:\n    - apiVersion: serving.knative.dev/v1\n      resource: services\n      imagePaths: [\"{.spec.template.spec.containers[*].image\"]\n",
+		expectedError: "invalid JSONPath",
+	}),
+	Entry("unknown source", metaCleanupAllowListEntry{
+		content:       "    configMaps: true\n",
+		expectedError: "configMaps",
+	}))
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+)
+
+// MetaCleanupStorageQuota bounds the stages storage, the least recently used stages are deleted first.
+// The stages retained by the git history-based keep policies are deleted only if OverrideKeepPolicies is set
+type MetaCleanupStorageQuota struct {
+	MaxSize           *int64
+	MaxStagesPerImage *int
+
+	OverrideKeepPolicies bool
+}
+
+func (q *MetaCleanupStorageQuota) String() string {
+	var parts []string
+
+	if q.MaxSize != nil {
+		parts = append(parts, fmt.Sprintf("maxSize=%s", units.HumanSize(float64(*q.MaxSize))))
+	}
+
+	if q.MaxStagesPerImage != nil {
+		parts = append(parts, fmt.Sprintf("maxStagesPerImage=%d", *q.MaxStagesPerImage))
+	}
+
+	if q.OverrideKeepPolicies {
+		parts = append(parts, "overrideKeepPolicies")
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type metaCleanupStorageQuotaEntry struct {
+	content       string
+	expectedQuota string
+	expectedError string
+}
+
+var _ = DescribeTable("parsing meta cleanup storage quota", func(e metaCleanupStorageQuotaEntry) {
+	meta, _, _, err := splitByMetaAndRawImages([]*doc{{Content: []byte("configVersion: 1\nproject: test\ncleanup:\n  storageQuota:\n" + e.content)}})
+	if e.expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(meta.Cleanup.StorageQuota.String()).Should(Equal(e.expectedQuota))
+	}
+},
+	Entry("max size and max stages per image", metaCleanupStorageQuotaEntry{
+		content:       "    maxSize: 50GB\n    maxStagesPerImage: 10\n",
+		expectedQuota: "maxSize=50GB maxStagesPerImage=10",
+	}),
+	Entry("override keep policies", metaCleanupStorageQuotaEntry{
+		content:       "    maxSize: 50GB\n    overrideKeepPolicies: true\n",
+		expectedQuota: "maxSize=50GB overrideKeepPolicies",
+	}),
+	Entry("max size in bytes", metaCleanupStorageQuotaEntry{
+		content:       "    maxSize: 500000000\n",
+		expectedQuota: "maxSize=500MB",
+	}),
+	Entry("empty quota", metaCleanupStorageQuotaEntry{
+		content:       "    maxSize: \"\"\n",
+		expectedError: "`maxSize: SIZE` or `maxStagesPerImage: int` required",
+	}),
+	Entry("invalid max size", metaCleanupStorageQuotaEntry{
+		content:       "    maxSize: 50 apples\n",
+		expectedError: "invalid value \"50 apples\" for `maxSize: SIZE`",
+	}),
+	Entry("invalid max stages per image", metaCleanupStorageQuotaEntry{
+		content:       "    maxStagesPerImage: 0\n",
+		expectedError: "invalid value 0 for `maxStagesPerImage: int`",
+	}),
+)
@@ -0,0 +1,26 @@
+package config
+
+import "fmt"
+
+const (
+	DefaultIncludePath         = "werf.yaml"
+	DefaultIncludeTemplatesDir = ".werf"
+)
+
+// MetaInclude is the werf config from the other git repository pinned to the commit,
+// the optional tag is verified to point to the pinned commit
+type MetaInclude struct {
+	Git          string
+	Commit       string
+	Tag          string
+	Path         string
+	TemplatesDir string
+}
+
+func (i *MetaInclude) String() string {
+	if i.Tag != "" {
+		return fmt.Sprintf("%s:%s (tag %s, commit %s)", i.Git, i.Path, i.Tag, i.Commit)
+	}
+
+	return fmt.Sprintf("%s:%s (commit %s)", i.Git, i.Path, i.Commit)
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+const testIncludeCommit = "0123456789abcdef0123456789abcdef01234567"
+
+type metaIncludeEntry struct {
+	content         string
+	expectedInclude []*MetaInclude
+	expectedError   string
+}
+
+var _ = DescribeTable("parsing meta include", func(e metaIncludeEntry) {
+	meta, _, _, err := splitByMetaAndRawImages([]*doc{{Content: []byte("configVersion: 1\nproject: test\n" + e.content)}})
+	if e.expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(meta.Include).Should(Equal(e.expectedInclude))
+	}
+},
+	Entry("no include", metaIncludeEntry{}),
+	Entry("commit with defaults", metaIncludeEntry{
+		content: "include:\n- git: https://github.com/org/shared.git\n  commit: " + testIncludeCommit + "\n",
+		expectedInclude: []*MetaInclude{
+			{Git: "https://github.com/org/shared.git", Commit: testIncludeCommit, Path: "werf.yaml", TemplatesDir: ".werf"},
+		},
+	}),
+	Entry("commit and tag with path and templatesDir", metaIncludeEntry{
+		content: "include:\n- git: https://github.com/org/shared.git\n  commit: " + testIncludeCommit + "\n  tag: v1.2.0\n  path: ./werf/images.yaml\n  templatesDir: werf/templates/\n",
+		expectedInclude: []*MetaInclude{
+			{Git: "https://github.com/org/shared.git", Commit: testIncludeCommit, Tag: "v1.2.0", Path: "werf/images.yaml", TemplatesDir: "werf/templates"},
+		},
+	}),
+	Entry("no git", metaIncludeEntry{
+		content:       "include:\n- tag: v1.2.0\n",
+		expectedError: "`git: URL` required",
+	}),
+	Entry("not pinned", metaIncludeEntry{
+		content:       "include:\n- git: https://github.com/org/shared.git\n",
+		expectedError: "must be pinned",
+	}),
+	Entry("tag without commit", metaIncludeEntry{
+		content:       "include:\n- git: https://github.com/org/shared.git\n  tag: v1\n",
+		expectedError: "must be pinned",
+	}),
+	Entry("short commit", metaIncludeEntry{
+		content:       "include:\n- git: https://github.com/org/shared.git\n  commit: 0123456\n",
+		expectedError: "full commit hash expected",
+	}),
+	Entry("path outside repository", metaIncludeEntry{
+		content:       "include:\n- git: https://github.com/org/shared.git\n  commit: " + testIncludeCommit + "\n  path: ../werf.yaml\n",
+		expectedError: "must be relative to the included git repository root",
+	}),
+	Entry("unknown directive", metaIncludeEntry{
+		content:       "include:\n- git: https://github.com/org/shared.git\n  commit: " + testIncludeCommit + "\n  branch: main\n",
+		expectedError: "branch",
+	}))
+
+type includedDocsEntry struct {
+	content              string
+	expectedDocs         []string
+	expectedKeepPolicies int
+	expectedError        string
+}
+
+var _ = DescribeTable("splitting included config", func(e includedDocsEntry) {
+	docs, metas, err := splitIncludedDocs(e.content)
+	if e.expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(docs).Should(Equal(e.expectedDocs))
+
+		var keepPolicies int
+		for _, meta := range metas {
+			keepPolicies += len(meta.Cleanup.KeepPolicies)
+		}
+		Ω(keepPolicies).Should(Equal(e.expectedKeepPolicies))
+	}
+},
+	Entry("images", includedDocsEntry{
+		content:      "image: a\nfrom: alpine\n---\n\n---\ntemplate: base\nfrom: alpine",
+		expectedDocs: []string{"image: a\nfrom: alpine\n", "template: base\nfrom: alpine\n"},
+	}),
+	Entry("meta with cleanup", includedDocsEntry{
+		content:              "configVersion: 1\nproject: shared\ncleanup:\n  keepPolicies:\n  - references:\n      tag: /.*/\n---\nimage: a\nfrom: alpine\n",
+		expectedDocs:         []string{"image: a\nfrom: alpine\n"},
+		expectedKeepPolicies: 1,
+	}),
+	Entry("nested include", includedDocsEntry{
+		content:       "configVersion: 1\nproject: shared\ninclude:\n- git: https://github.com/org/other.git\n  commit: " + testIncludeCommit + "\n",
+		expectedError: "nested includes are not supported",
+	}))
+
+var _ = Describe("opening included repository", func() {
+	var dir string
+	var rawRepo *git.Repository
+	var commits []plumbing.Hash
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "werf-include-test-")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home"))).Should(Succeed())
+
+		rawRepo, err = git.PlainInit(filepath.Join(dir, "org", "shared"), false)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		worktree, err := rawRepo.Worktree()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		commits = nil
+		for _, content := range []string{"image: a\n", "image: b\n"} {
+			Ω(ioutil.WriteFile(filepath.Join(dir, "org", "shared", "werf.yaml"), []byte(content), 0644)).Should(Succeed())
+			_, err := worktree.Add("werf.yaml")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			commit, err := worktree.Commit(content, &git.CommitOptions{Author: &object.Signature{Name: "werf", Email: "werf@example.com", When: time.Now()}})
+			Ω(err).ShouldNot(HaveOccurred())
+			commits = append(commits, commit)
+		}
+
+		_, err = rawRepo.CreateTag("v1", commits[0], nil)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("returns the pinned commit verified by the tag", func() {
+		_, commit, err := openIncludeRepo(context.Background(), &MetaInclude{Git: filepath.Join(dir, "org", "shared"), Commit: commits[0].String(), Tag: "v1"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(commit).Should(Equal(commits[0].String()))
+	})
+
+	It("fails when the tag has been moved from the pinned commit", func() {
+		Ω(rawRepo.DeleteTag("v1")).Should(Succeed())
+		_, err := rawRepo.CreateTag("v1", commits[1], nil)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, _, err = openIncludeRepo(context.Background(), &MetaInclude{Git: filepath.Join(dir, "org", "shared"), Commit: commits[0].String(), Tag: "v1"})
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring("tag v1 has been moved"))
+	})
+})
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	ParameterTypeString = "string"
+	ParameterTypeInt    = "int"
+	ParameterTypeFloat  = "float"
+	ParameterTypeBool   = "bool"
+)
+
+var parameterTypes = []string{ParameterTypeString, ParameterTypeInt, ParameterTypeFloat, ParameterTypeBool}
+
+// MetaParameter is the typed werf config parameter available as .Params during the config rendering
+type MetaParameter struct {
+	Name        string
+	Type        string
+	Description string
+	Default     interface{}
+	Required    bool
+	Enum        []interface{}
+	Pattern     *regexp.Regexp
+}
+
+// ParseValue converts the command line value into the parameter type and validates it
+func (p *MetaParameter) ParseValue(value string) (interface{}, error) {
+	var res interface{}
+	var err error
+	switch p.Type {
+	case ParameterTypeInt:
+		res, err = strconv.Atoi(value)
+	case ParameterTypeFloat:
+		res, err = strconv.ParseFloat(value, 64)
+	case ParameterTypeBool:
+		res, err = strconv.ParseBool(value)
+	default:
+		res = value
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s value expected, got %q", p.Type, value)
+	}
+
+	if err := p.validateValue(res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ConvertValue converts the YAML value into the parameter type and validates it
+func (p *MetaParameter) ConvertValue(value interface{}) (interface{}, error) {
+	res, err := convertParameterValue(p.Type, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.validateValue(res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// DefaultValue returns the default of the parameter or the zero value of the parameter type
+func (p *MetaParameter) DefaultValue() interface{} {
+	if p.Default != nil {
+		return p.Default
+	}
+
+	switch p.Type {
+	case ParameterTypeInt:
+		return 0
+	case ParameterTypeFloat:
+		return float64(0)
+	case ParameterTypeBool:
+		return false
+	default:
+		return ""
+	}
+}
+
+func (p *MetaParameter) validateValue(value interface{}) error {
+	if len(p.Enum) != 0 {
+		var found bool
+		for _, enumValue := range p.Enum {
+			if enumValue == value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("value %v is not one of %v", value, p.Enum)
+		}
+	}
+
+	if p.Pattern != nil && !p.Pattern.MatchString(fmt.Sprint(value)) {
+		return fmt.Errorf("value %q does not match pattern %q", value, p.Pattern.String())
+	}
+
+	return nil
+}
+
+// convertParameterValue converts the value unmarshalled from YAML into the parameter type,
+// the strings are not converted implicitly to keep such values as "1.10" intact (the value must be quoted)
+func convertParameterValue(parameterType string, value interface{}) (interface{}, error) {
+	switch parameterType {
+	case ParameterTypeInt:
+		if v, ok := value.(int); ok {
+			return v, nil
+		}
+	case ParameterTypeFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		}
+	case ParameterTypeBool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	default:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+
+		return nil, fmt.Errorf("string value expected, got %v (quote the value)", value)
+	}
+
+	return nil, fmt.Errorf("%s value expected, got %v", parameterType, value)
+}
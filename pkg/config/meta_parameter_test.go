@@ -0,0 +1,137 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type metaParametersEntry struct {
+	content            string
+	expectedParameters []*MetaParameter
+	expectedError      string
+}
+
+var _ = DescribeTable("parsing meta parameters", func(e metaParametersEntry) {
+	meta, _, _, err := splitByMetaAndRawImages([]*doc{{Content: []byte("configVersion: 1\nproject: test\n" + e.content)}})
+	if e.expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(meta.Parameters).Should(Equal(e.expectedParameters))
+	}
+},
+	Entry("no parameters", metaParametersEntry{}),
+	Entry("typed parameters sorted by name", metaParametersEntry{
+		content: "parameters:\n  replicas:\n    type: int\n    default: 2\n  ratio:\n    type: float\n    default: 1\n  debug:\n    type: bool\n  tier:\n    enum: [frontend, backend]\n    required: true\n",
+		expectedParameters: []*MetaParameter{
+			{Name: "debug", Type: ParameterTypeBool},
+			{Name: "ratio", Type: ParameterTypeFloat, Default: float64(1)},
+			{Name: "replicas", Type: ParameterTypeInt, Default: 2},
+			{Name: "tier", Type: ParameterTypeString, Required: true, Enum: []interface{}{"frontend", "backend"}},
+		},
+	}),
+	Entry("invalid name", metaParametersEntry{
+		content:       "parameters:\n  app-name: {}\n",
+		expectedError: "invalid parameter name \"app-name\"",
+	}),
+	Entry("unsupported type", metaParametersEntry{
+		content:       "parameters:\n  replicas:\n    type: integer\n",
+		expectedError: "unsupported type \"integer\"",
+	}),
+	Entry("default of the wrong type", metaParametersEntry{
+		content:       "parameters:\n  replicas:\n    type: int\n    default: two\n",
+		expectedError: "invalid default: int value expected",
+	}),
+	Entry("unquoted string default", metaParametersEntry{
+		content:       "parameters:\n  version:\n    default: 1.10\n",
+		expectedError: "quote the value",
+	}),
+	Entry("default not matching enum", metaParametersEntry{
+		content:       "parameters:\n  tier:\n    enum: [frontend, backend]\n    default: database\n",
+		expectedError: "is not one of",
+	}),
+	Entry("default with required", metaParametersEntry{
+		content:       "parameters:\n  tier:\n    required: true\n    default: frontend\n",
+		expectedError: "cannot be used with `required: true`",
+	}),
+	Entry("pattern for int", metaParametersEntry{
+		content:       "parameters:\n  replicas:\n    type: int\n    pattern: ^[0-9]$\n",
+		expectedError: "`pattern` can be used only with the string type",
+	}),
+	Entry("unknown directive", metaParametersEntry{
+		content:       "parameters:\n  replicas:\n    type: int\n    min: 1\n",
+		expectedError: "min",
+	}))
+
+type metaParameterValueEntry struct {
+	parameter     *MetaParameter
+	value         string
+	expectedValue interface{}
+	expectedError string
+}
+
+var _ = DescribeTable("parsing meta parameter command line value", func(e metaParameterValueEntry) {
+	value, err := e.parameter.ParseValue(e.value)
+	if e.expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(value).Should(Equal(e.expectedValue))
+	}
+},
+	Entry("string", metaParameterValueEntry{
+		parameter:     &MetaParameter{Type: ParameterTypeString},
+		value:         "1.10",
+		expectedValue: "1.10",
+	}),
+	Entry("int", metaParameterValueEntry{
+		parameter:     &MetaParameter{Type: ParameterTypeInt},
+		value:         "3",
+		expectedValue: 3,
+	}),
+	Entry("invalid int", metaParameterValueEntry{
+		parameter:     &MetaParameter{Type: ParameterTypeInt},
+		value:         "3.5",
+		expectedError: "int value expected",
+	}),
+	Entry("float", metaParameterValueEntry{
+		parameter:     &MetaParameter{Type: ParameterTypeFloat},
+		value:         "0.5",
+		expectedValue: 0.5,
+	}),
+	Entry("bool", metaParameterValueEntry{
+		parameter:     &MetaParameter{Type: ParameterTypeBool},
+		value:         "true",
+		expectedValue: true,
+	}),
+	Entry("enum", metaParameterValueEntry{
+		parameter:     &MetaParameter{Type: ParameterTypeInt, Enum: []interface{}{1, 3}},
+		value:         "2",
+		expectedError: "is not one of",
+	}),
+	Entry("pattern", metaParameterValueEntry{
+		parameter:     (&rawMetaParameter{Pattern: "^v[0-9]+$"}).toMetaParameter("version"),
+		value:         "1",
+		expectedError: "does not match pattern",
+	}))
+
+var _ = DescribeTable("getting meta parameters before rendering", func(content string, expectedNames []string, expectedError string) {
+	parameters, err := getMetaParameters([]byte(content))
+	if expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+
+		var names []string
+		for _, p := range parameters {
+			names = append(names, p.Name)
+		}
+		Ω(names).Should(Equal(expectedNames))
+	}
+},
+	Entry("templated images", "configVersion: 1\nproject: {{ .Env }}\n---\n{{ range $i := until 2 }}\nimage: a{{ $i }}\n{{ end }}\n", nil, ""),
+	Entry("parameters", "project: test\nconfigVersion: 1\nparameters:\n  replicas:\n    type: int\n---\nimage: {{ .Params.replicas }}\n", []string{"replicas"}, ""),
+	Entry("templated meta with parameters", "configVersion: 1\nproject: {{ .Env }}\nparameters:\n  replicas: {}\n", nil, "must be valid YAML before rendering"))
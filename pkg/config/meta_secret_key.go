@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/werf/werf/pkg/giterminism_manager"
+)
+
+const (
+	SecretKeyProviderStatic = "static"
+	SecretKeyProviderAge    = "age"
+	SecretKeyProviderPGP    = "pgp"
+	SecretKeyProviderExec   = "exec"
+)
+
+var secretKeyProviders = []string{SecretKeyProviderStatic, SecretKeyProviderAge, SecretKeyProviderPGP, SecretKeyProviderExec}
+
+// MetaSecretKey describes how werf gets the secret key which is used to encrypt and decrypt secret values and files.
+// The static key is read from $WERF_SECRET_KEY or .werf_secret_key file,
+// other providers decrypt the key from the encrypted key file stored in the project.
+// The named keys are used to encrypt the secret values for the particular recipients or environments.
+// UseVersionedFormat enables encryption in the versioned authenticated format which cannot be decrypted by the previous werf versions,
+// RejectLegacyFormat disables decryption of the secrets in the legacy unauthenticated format (and also enables the versioned format).
+// The values of the Optional named key are skipped with the warning when the key is not available.
+type MetaSecretKey struct {
+	Name               string
+	Provider           string
+	EncryptedKeyFile   string
+	Recipients         []string
+	Command            []string
+	UseVersionedFormat bool
+	RejectLegacyFormat bool
+	Optional           bool
+	Keys               map[string]MetaSecretKey
+}
+
+func (obj MetaSecretKey) GetProvider() string {
+	if obj.Provider != "" {
+		return obj.Provider
+	} else {
+		return SecretKeyProviderStatic
+	}
+}
+
+func (obj MetaSecretKey) GetEncryptedKeyFile() string {
+	if obj.EncryptedKeyFile != "" {
+		return obj.EncryptedKeyFile
+	}
+
+	baseName := ".werf_secret_key"
+	if obj.Name != "" {
+		baseName = fmt.Sprintf(".werf_secret_key_%s", obj.Name)
+	}
+
+	switch obj.GetProvider() {
+	case SecretKeyProviderAge:
+		return baseName + ".age"
+	case SecretKeyProviderPGP:
+		return baseName + ".asc"
+	default:
+		return ""
+	}
+}
+
+// GetStaticKeyEnvName returns the environment variable with the static secret key: $WERF_SECRET_KEY or $WERF_SECRET_KEY_<NAME> for the named key
+func (obj MetaSecretKey) GetStaticKeyEnvName() string {
+	if obj.Name == "" {
+		return "WERF_SECRET_KEY"
+	}
+
+	return fmt.Sprintf("WERF_SECRET_KEY_%s", strings.ToUpper(strings.Replace(obj.Name, "-", "_", -1)))
+}
+
+// GetOldStaticKeyEnvName returns the environment variable with the old secret key used by the secret key rotation
+func (obj MetaSecretKey) GetOldStaticKeyEnvName() string {
+	return strings.Replace(obj.GetStaticKeyEnvName(), "WERF_SECRET_KEY", "WERF_OLD_SECRET_KEY", 1)
+}
+
+// GetStaticKeyFile returns the project file with the static secret key: .werf_secret_key or .werf_secret_key_<name> for the named key
+func (obj MetaSecretKey) GetStaticKeyFile() string {
+	if obj.Name == "" {
+		return ".werf_secret_key"
+	}
+
+	return fmt.Sprintf(".werf_secret_key_%s", obj.Name)
+}
+
+func (obj MetaSecretKey) GetKeyNames() []string {
+	var names []string
+	for name := range obj.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (obj MetaSecretKey) validate(giterminismManager giterminism_manager.Interface) error {
+	isExecProviderUsed := obj.GetProvider() == SecretKeyProviderExec
+	for _, key := range obj.Keys {
+		if key.GetProvider() == SecretKeyProviderExec {
+			isExecProviderUsed = true
+		}
+	}
+
+	if isExecProviderUsed {
+		return giterminismManager.Inspector().InspectConfigSecretKeyExecProvider()
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type metaSecretKeyEntry struct {
+	content           string
+	expectedSecretKey MetaSecretKey
+	expectedError     string
+}
+
+var _ = DescribeTable("parsing meta secretKey", func(e metaSecretKeyEntry) {
+	meta, _, _, err := splitByMetaAndRawImages([]*doc{{Content: []byte("configVersion: 1\nproject: test\n" + e.content)}})
+	if e.expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(meta.SecretKey).Should(Equal(e.expectedSecretKey))
+	}
+},
+	Entry("default", metaSecretKeyEntry{}),
+	Entry("age", metaSecretKeyEntry{
+		content: `secretKey:
+  provider: age
+  recipients: [age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj]
+`,
+		expectedSecretKey: MetaSecretKey{
+			Provider:   SecretKeyProviderAge,
+			Recipients: []string{"age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj"},
+		},
+	}),
+	Entry("exec", metaSecretKeyEntry{
+		content: `secretKey:
+  provider: exec
+  encryptedKeyFile: .werf/secret_key.enc
+  command: [vault-unwrap, --key, werf]
+`,
+		expectedSecretKey: MetaSecretKey{
+			Provider:         SecretKeyProviderExec,
+			EncryptedKeyFile: ".werf/secret_key.enc",
+			Command:          []string{"vault-unwrap", "--key", "werf"},
+		},
+	}),
+	Entry("named keys", metaSecretKeyEntry{
+		content: `secretKey:
+  keys:
+    production:
+      provider: age
+      recipients: [age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj]
+    staging:
+`,
+		expectedSecretKey: MetaSecretKey{
+			Keys: map[string]MetaSecretKey{
+				"production": {
+					Name:       "production",
+					Provider:   SecretKeyProviderAge,
+					Recipients: []string{"age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj"},
+				},
+				"staging": {Name: "staging"},
+			},
+		},
+	}),
+	Entry("reject legacy format", metaSecretKeyEntry{
+		content: `secretKey:
+  rejectLegacyFormat: true
+  keys:
+    production: {}
+    staging:
+      rejectLegacyFormat: false
+`,
+		expectedSecretKey: MetaSecretKey{
+			RejectLegacyFormat: true,
+			Keys: map[string]MetaSecretKey{
+				"production": {Name: "production", RejectLegacyFormat: true},
+				"staging":    {Name: "staging"},
+			},
+		},
+	}),
+	Entry("use versioned format", metaSecretKeyEntry{
+		content: `secretKey:
+  useVersionedFormat: true
+  keys:
+    production: {}
+    staging:
+      useVersionedFormat: false
+`,
+		expectedSecretKey: MetaSecretKey{
+			UseVersionedFormat: true,
+			Keys: map[string]MetaSecretKey{
+				"production": {Name: "production", UseVersionedFormat: true},
+				"staging":    {Name: "staging"},
+			},
+		},
+	}),
+	Entry("optional named key", metaSecretKeyEntry{
+		content: `secretKey:
+  keys:
+    production:
+      optional: true
+`,
+		expectedSecretKey: MetaSecretKey{
+			Keys: map[string]MetaSecretKey{
+				"production": {Name: "production", Optional: true},
+			},
+		},
+	}),
+	Entry("optional default key", metaSecretKeyEntry{
+		content: `secretKey:
+  optional: true
+`,
+		expectedError: "`optional` can be used only in the named secret key",
+	}),
+	Entry("invalid key name", metaSecretKeyEntry{
+		content: `secretKey:
+  keys:
+    prod@eu: {}
+`,
+		expectedError: "invalid secret key name `prod@eu`",
+	}),
+	Entry("nested named keys", metaSecretKeyEntry{
+		content: `secretKey:
+  keys:
+    production:
+      keys:
+        eu: {}
+`,
+		expectedError: "`keys` cannot be used in the named secret key",
+	}),
+	Entry("named key with unsupported provider", metaSecretKeyEntry{
+		content: `secretKey:
+  keys:
+    production:
+      provider: kms
+`,
+		expectedError: "unsupported secret key provider `kms`",
+	}),
+	Entry("unsupported provider", metaSecretKeyEntry{
+		content: `secretKey:
+  provider: kms
+`,
+		expectedError: "unsupported secret key provider `kms`",
+	}),
+	Entry("pgp without recipients", metaSecretKeyEntry{
+		content: `secretKey:
+  provider: pgp
+`,
+		expectedError: "`recipients: [RECIPIENT, ...]` required",
+	}),
+	Entry("exec without command", metaSecretKeyEntry{
+		content: `secretKey:
+  provider: exec
+`,
+		expectedError: "`command: [PROGRAM, ARG, ...]` required",
+	}),
+	Entry("static with recipients", metaSecretKeyEntry{
+		content: `secretKey:
+  recipients: [alice]
+`,
+		expectedError: "cannot be used with the static secret key provider",
+	}),
+	Entry("absolute encrypted key file", metaSecretKeyEntry{
+		content: `secretKey:
+  provider: age
+  recipients: [alice]
+  encryptedKeyFile: /etc/werf_secret_key.age
+`,
+		expectedError: "should be relative to project directory",
+	}))
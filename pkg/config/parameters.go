@@ -0,0 +1,191 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/werf/werf/pkg/giterminism_manager"
+	"github.com/werf/werf/pkg/util"
+)
+
+const (
+	configParamsFileName        = "werf-params.yaml"
+	configParamsCommandLineFlag = "--config-param"
+)
+
+var metaParametersDirectiveRegexp = regexp.MustCompile(`(?m)^parameters:`)
+
+func configParamsEnvFileName(env string) string {
+	return fmt.Sprintf("werf-params.%s.yaml", env)
+}
+
+// configParam is the resolved werf config parameter, the Source is where the value came from
+type configParam struct {
+	Parameter *MetaParameter
+	Value     interface{}
+	Source    string
+}
+
+func (p *configParam) String() string {
+	value := fmt.Sprint(p.Value)
+	if p.Parameter.Type == ParameterTypeString {
+		value = fmt.Sprintf("%q", p.Value)
+	}
+
+	return fmt.Sprintf("%s: %s (%s)", p.Parameter.Name, value, p.Source)
+}
+
+func configParamsTemplateData(params []*configParam) map[string]interface{} {
+	data := map[string]interface{}{}
+	for _, p := range params {
+		data[p.Parameter.Name] = p.Value
+	}
+
+	return data
+}
+
+// resolveConfigParams resolves the values of the parameters declared in the werf config meta section.
+// The default is overridden by werf-params.yaml, werf-params.<ENV>.yaml and then by the command line values.
+func resolveConfigParams(ctx context.Context, werfConfigData []byte, giterminismManager giterminism_manager.Interface, env string, commandLineParams []string) ([]*configParam, error) {
+	parameters, err := getMetaParameters(werfConfigData)
+	if err != nil {
+		return nil, err
+	}
+
+	var params []*configParam
+	paramsByName := map[string]*configParam{}
+	for _, parameter := range parameters {
+		p := &configParam{Parameter: parameter, Value: parameter.DefaultValue(), Source: "default"}
+		params = append(params, p)
+		paramsByName[parameter.Name] = p
+	}
+
+	paramsFiles := []string{configParamsFileName}
+	if env != "" {
+		paramsFiles = append(paramsFiles, configParamsEnvFileName(env))
+	}
+
+	isSet := map[string]bool{}
+	for _, relPath := range paramsFiles {
+		values, err := readConfigParamsFile(ctx, giterminismManager, relPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, value := range values {
+			p, ok := paramsByName[name]
+			if !ok {
+				return nil, fmt.Errorf("%s: parameter %q is not declared in the werf config meta section", relPath, name)
+			}
+
+			if p.Value, err = p.Parameter.ConvertValue(value); err != nil {
+				return nil, fmt.Errorf("%s: parameter %q: %s", relPath, name, err)
+			}
+
+			p.Source = relPath
+			isSet[name] = true
+		}
+	}
+
+	for _, param := range commandLineParams {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("%s %q: key=value expected", configParamsCommandLineFlag, param)
+		}
+
+		name, value := parts[0], parts[1]
+		p, ok := paramsByName[name]
+		if !ok {
+			return nil, fmt.Errorf("%s %q: parameter %q is not declared in the werf config meta section", configParamsCommandLineFlag, param, name)
+		}
+
+		if p.Value, err = p.Parameter.ParseValue(value); err != nil {
+			return nil, fmt.Errorf("%s %q: %s", configParamsCommandLineFlag, param, err)
+		}
+
+		p.Source = configParamsCommandLineFlag
+		isSet[name] = true
+	}
+
+	for _, p := range params {
+		if p.Parameter.Required && !isSet[p.Parameter.Name] {
+			return nil, fmt.Errorf("required parameter %q is not set: use %s or %s %s=VALUE", p.Parameter.Name, configParamsFileName, configParamsCommandLineFlag, p.Parameter.Name)
+		}
+	}
+
+	return params, nil
+}
+
+func readConfigParamsFile(ctx context.Context, giterminismManager giterminism_manager.Interface, relPath string) (map[string]interface{}, error) {
+	if exist, err := giterminismManager.FileReader().IsConfigParamsFileExistAnywhere(ctx, relPath); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, nil
+	}
+
+	data, err := giterminismManager.FileReader().ReadConfigParamsFile(ctx, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", relPath, err)
+	}
+
+	return values, nil
+}
+
+// getMetaParameters returns the parameters declared in the meta section of the werf config before rendering,
+// thus the meta section with parameters must be the valid YAML document without templating
+func getMetaParameters(werfConfigData []byte) ([]*MetaParameter, error) {
+	for _, docContent := range splitContent(werfConfigData) {
+		if emptyDocContent(docContent) {
+			continue
+		}
+
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal(docContent, &raw); err != nil {
+			if metaParametersDirectiveRegexp.Match(docContent) {
+				return nil, fmt.Errorf("unable to parse the werf config meta section with parameters: the section must be valid YAML before rendering (templating is not supported there): %s", err)
+			}
+
+			continue
+		}
+
+		if !isMetaDoc(raw) {
+			continue
+		}
+
+		if _, ok := raw["parameters"]; !ok {
+			return nil, nil
+		}
+
+		// only the parameters are parsed, the rest of the meta section is validated after rendering
+		parentStack = util.NewStack()
+		rawMeta := &rawMeta{doc: &doc{Content: docContent}}
+		parentStack.Push(rawMeta)
+
+		var rawParameters struct {
+			Parameters map[string]*rawMetaParameter `yaml:"parameters"`
+		}
+		err := yaml.Unmarshal(docContent, &rawParameters)
+		parentStack.Pop()
+		if err != nil {
+			return nil, newYamlUnmarshalError(err, rawMeta.doc)
+		}
+
+		rawMeta.Parameters = rawParameters.Parameters
+		if err := rawMeta.validateParameters(); err != nil {
+			return nil, err
+		}
+
+		return rawMeta.toMeta().Parameters, nil
+	}
+
+	return nil, nil
+}
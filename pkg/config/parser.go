@@ -28,6 +28,7 @@ import (
 type WerfConfigOptions struct {
 	LogRenderedFilePath bool
 	Env                 string
+	Params              []string // key=value
 }
 
 func RenderWerfConfig(ctx context.Context, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath string, imagesToProcess []string, giterminismManager giterminism_manager.Interface, opts WerfConfigOptions) error {
@@ -37,12 +38,13 @@ func RenderWerfConfig(ctx context.Context, customWerfConfigRelPath, customWerfCo
 	}
 
 	if len(imagesToProcess) == 0 {
-		werfConfigRenderContent, err := renderWerfConfigYaml(ctx, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath, giterminismManager, opts.Env)
+		werfConfigRender, err := renderWerfConfigYaml(ctx, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath, giterminismManager, opts)
 		if err != nil {
 			return err
 		}
 
-		fmt.Print(werfConfigRenderContent)
+		printConfigParams(werfConfigRender.params)
+		fmt.Print(werfConfigRender.content)
 	} else {
 		var imageDocs []string
 
@@ -67,10 +69,11 @@ func RenderWerfConfig(ctx context.Context, customWerfConfigRelPath, customWerfCo
 }
 
 func GetWerfConfig(ctx context.Context, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath string, giterminismManager giterminism_manager.Interface, opts WerfConfigOptions) (*WerfConfig, error) {
-	werfConfigRenderContent, err := renderWerfConfigYaml(ctx, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath, giterminismManager, opts.Env)
+	werfConfigRender, err := renderWerfConfigYaml(ctx, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath, giterminismManager, opts)
 	if err != nil {
 		return nil, err
 	}
+	werfConfigRenderContent := werfConfigRender.content
 
 	werfConfigRenderPath, err := tmp_manager.CreateWerfConfigRender(ctx)
 	if err != nil {
@@ -118,6 +121,10 @@ func GetWerfConfig(ctx context.Context, customWerfConfigRelPath, customWerfConfi
 		return nil, fmt.Errorf(format, defaultProjectName)
 	}
 
+	for _, includedMeta := range werfConfigRender.includedMetas {
+		meta.Cleanup.KeepPolicies = append(meta.Cleanup.KeepPolicies, includedMeta.Cleanup.KeepPolicies...)
+	}
+
 	werfConfig, err := prepareWerfConfig(giterminismManager, rawStapelImages, rawImagesFromDockerfile, meta)
 	if err != nil {
 		return nil, err
@@ -185,16 +192,41 @@ func splitByDocs(werfConfigRenderContent string, werfConfigRenderPath string) ([
 	return docs, nil
 }
 
-func renderWerfConfigYaml(ctx context.Context, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath string, giterminismManager giterminism_manager.Interface, env string) (string, error) {
+// printConfigParams prints the resolved parameters as the YAML comment not to break the rendered config
+func printConfigParams(params []*configParam) {
+	if len(params) == 0 {
+		return
+	}
+
+	fmt.Println("# parameters:")
+	for _, p := range params {
+		fmt.Printf("#   %s\n", p)
+	}
+}
+
+type werfConfigRender struct {
+	content       string
+	includedMetas []*Meta // the meta sections of the included configs
+	params        []*configParam
+}
+
+// renderWerfConfigYaml renders the werf config with the included configs, the meta sections of the included configs are returned separately
+func renderWerfConfigYaml(ctx context.Context, customWerfConfigRelPath, customWerfConfigTemplatesDirRelPath string, giterminismManager giterminism_manager.Interface, opts WerfConfigOptions) (*werfConfigRender, error) {
 	tmpl := template.New("werfConfig")
 	tmpl.Funcs(funcMap(tmpl, giterminismManager))
 
 	if err := parseWerfConfigTemplatesDir(ctx, tmpl, giterminismManager, customWerfConfigTemplatesDirRelPath); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if err := parseWerfConfig(ctx, tmpl, giterminismManager, customWerfConfigRelPath); err != nil {
-		return "", err
+	configData, err := parseWerfConfig(ctx, tmpl, giterminismManager, customWerfConfigRelPath)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := resolveConfigParams(ctx, configData, giterminismManager, opts.Env, opts.Params)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve werf config parameters: %s", err)
 	}
 
 	templateData := make(map[string]interface{})
@@ -202,24 +234,45 @@ func renderWerfConfigYaml(ctx context.Context, customWerfConfigRelPath, customWe
 		ctx:                ctx,
 		giterminismManager: giterminismManager,
 	}
-	templateData["Env"] = env
+	templateData["Env"] = opts.Env
+	templateData["Params"] = configParamsTemplateData(params)
 
 	config, err := executeTemplate(tmpl, "werfConfig", templateData)
+	if err != nil {
+		return nil, err
+	}
+
+	includedContent, includedMetas, err := renderWerfConfigIncludes(ctx, config, giterminismManager, opts.Env, configParamsTemplateData(params))
+	if err != nil {
+		return nil, err
+	}
+
+	if includedContent != "" {
+		if !strings.HasSuffix(config, "\n") {
+			config += "\n"
+		}
+		config += "---\n" + includedContent
+	}
 
-	return config, err
+	config, err = resolveInheritance(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &werfConfigRender{content: config, includedMetas: includedMetas, params: params}, nil
 }
 
-func parseWerfConfig(ctx context.Context, tmpl *template.Template, giterminismManager giterminism_manager.Interface, relWerfConfigPath string) error {
+func parseWerfConfig(ctx context.Context, tmpl *template.Template, giterminismManager giterminism_manager.Interface, relWerfConfigPath string) ([]byte, error) {
 	configData, err := giterminismManager.FileReader().ReadConfig(ctx, relWerfConfigPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if _, err := tmpl.Parse(string(configData)); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return configData, nil
 }
 
 func parseWerfConfigTemplatesDir(ctx context.Context, tmpl *template.Template, giterminismManager giterminism_manager.Interface, customWerfConfigTemplatesDirRelPath string) error {
@@ -432,6 +485,10 @@ func emptyDocContent(content []byte) bool {
 }
 
 func prepareWerfConfig(giterminismManager giterminism_manager.Interface, rawImages []*rawStapelImage, rawImagesFromDockerfile []*rawImageFromDockerfile, meta *Meta) (*WerfConfig, error) {
+	if err := meta.SecretKey.validate(giterminismManager); err != nil {
+		return nil, err
+	}
+
 	var stapelImages []*StapelImage
 	var imagesFromDockerfile []*ImageFromDockerfile
 	var artifacts []*StapelImageArtifact
@@ -487,6 +544,10 @@ func prepareWerfConfig(giterminismManager giterminism_manager.Interface, rawImag
 		return nil, err
 	}
 
+	if err := werfConfig.validateImagesPlatforms(); err != nil {
+		return nil, err
+	}
+
 	return werfConfig, nil
 }
 
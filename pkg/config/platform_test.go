@@ -0,0 +1,38 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type platformsEntry struct {
+	platforms     []string
+	expectedError bool
+}
+
+var _ = DescribeTable("validating image platforms", func(e platformsEntry) {
+	err := validatePlatforms(e.platforms, nil, &doc{})
+	if e.expectedError {
+		Ω(err).Should(HaveOccurred())
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+},
+	Entry("os and arch", platformsEntry{
+		platforms: []string{"linux/amd64", "linux/arm64"},
+	}),
+	Entry("os, arch and variant", platformsEntry{
+		platforms: []string{"linux/arm/v7"},
+	}),
+	Entry("arch only", platformsEntry{
+		platforms:     []string{"amd64"},
+		expectedError: true,
+	}),
+	Entry("empty part", platformsEntry{
+		platforms:     []string{"linux//v7"},
+		expectedError: true,
+	}),
+	Entry("duplicate", platformsEntry{
+		platforms:     []string{"linux/amd64", "linux/amd64"},
+		expectedError: true,
+	}))
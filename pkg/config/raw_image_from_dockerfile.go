@@ -16,6 +16,8 @@ type rawImageFromDockerfile struct {
 	AddHost        interface{}            `yaml:"addHost,omitempty"`
 	Network        string                 `yaml:"network,omitempty"`
 	SSH            string                 `yaml:"ssh,omitempty"`
+	RawPlatform    interface{}            `yaml:"platform,omitempty"`
+	RawSecrets     []*rawSecret           `yaml:"secrets,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -101,6 +103,14 @@ func (c *rawImageFromDockerfile) toImageFromDockerfileDirective(giterminismManag
 	image.Network = c.Network
 	image.SSH = c.SSH
 
+	if image.Platform, err = InterfaceToStringArray(c.RawPlatform, nil, c.doc); err != nil {
+		return nil, err
+	}
+
+	if image.Secrets, err = secretsToDirectives(c.RawSecrets, true, c.doc); err != nil {
+		return nil, err
+	}
+
 	image.raw = c
 
 	if err := image.validate(giterminismManager); err != nil {
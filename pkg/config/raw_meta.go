@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/werf/werf/pkg/slug"
 )
@@ -13,6 +14,9 @@ type rawMeta struct {
 	Deploy             *rawMetaDeploy      `yaml:"deploy,omitempty"`
 	Cleanup            *rawMetaCleanup     `yaml:"cleanup,omitempty"`
 	GitWorktree        *rawMetaGitWorktree `yaml:"gitWorktree,omitempty"`
+	SecretKey          *rawMetaSecretKey   `yaml:"secretKey,omitempty"`
+	Include            []*rawMetaInclude   `yaml:"include,omitempty"`
+	Parameters         map[string]*rawMetaParameter `yaml:"parameters,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -44,9 +48,29 @@ func (c *rawMeta) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return newDetailedConfigError(fmt.Sprintf("bad project name %q specified in config: %s", *c.Project, err), nil, c.doc)
 	}
 
+	return c.validateParameters()
+}
+
+func (c *rawMeta) validateParameters() error {
+	for _, name := range c.parameterNames() {
+		if err := c.Parameters[name].validate(name); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (c *rawMeta) parameterNames() []string {
+	var names []string
+	for name := range c.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
 func (c *rawMeta) toMeta() *Meta {
 	meta := &Meta{}
 
@@ -75,5 +99,17 @@ func (c *rawMeta) toMeta() *Meta {
 		meta.GitWorktree = c.GitWorktree.toMetaGitWorktree()
 	}
 
+	if c.SecretKey != nil {
+		meta.SecretKey = c.SecretKey.toMetaSecretKey()
+	}
+
+	for _, include := range c.Include {
+		meta.Include = append(meta.Include, include.toMetaInclude())
+	}
+
+	for _, name := range c.parameterNames() {
+		meta.Parameters = append(meta.Parameters, c.Parameters[name].toMetaParameter(name))
+	}
+
 	return meta
 }
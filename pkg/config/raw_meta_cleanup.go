@@ -9,6 +9,8 @@ import (
 
 type rawMetaCleanup struct {
 	KeepPolicies []*rawMetaCleanupKeepPolicy `yaml:"keepPolicies,omitempty"`
+	AllowList    *rawMetaCleanupAllowList    `yaml:"allowList,omitempty"`
+	StorageQuota *rawMetaCleanupStorageQuota `yaml:"storageQuota,omitempty"`
 
 	rawMeta               *rawMeta
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
@@ -185,6 +187,14 @@ func (c *rawMetaCleanup) toMetaCleanup() MetaCleanup {
 		metaCleanup.KeepPolicies = append(metaCleanup.KeepPolicies, policy.toMetaCleanupKeepPolicy())
 	}
 
+	if c.AllowList != nil {
+		metaCleanup.AllowList = c.AllowList.toMetaCleanupAllowList()
+	}
+
+	if c.StorageQuota != nil {
+		metaCleanup.StorageQuota = c.StorageQuota.toMetaCleanupStorageQuota()
+	}
+
 	return metaCleanup
 }
 
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+type rawMetaCleanupAllowList struct {
+	HelmReleases    bool                                     `yaml:"helmReleases,omitempty"`
+	Bundles         []string                                 `yaml:"bundles,omitempty"`
+	CustomResources []*rawMetaCleanupAllowListCustomResource `yaml:"customResources,omitempty"`
+	Lists           []string                                 `yaml:"lists,omitempty"`
+
+	rawMetaCleanup        *rawMetaCleanup
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+type rawMetaCleanupAllowListCustomResource struct {
+	ApiVersion string   `yaml:"apiVersion,omitempty"`
+	Resource   string   `yaml:"resource,omitempty"`
+	ImagePaths []string `yaml:"imagePaths,omitempty"`
+
+	rawMetaCleanup        *rawMetaCleanup
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaCleanupAllowList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMetaCleanup); ok {
+		c.rawMetaCleanup = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaCleanupAllowList
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawMetaCleanup.rawMeta.doc); err != nil {
+		return err
+	}
+
+	for _, bundle := range c.Bundles {
+		if _, err := c.processBundleRegexpString(bundle); err != nil {
+			return err
+		}
+	}
+
+	for _, list := range c.Lists {
+		if strings.TrimSpace(list) == "" {
+			return newDetailedConfigError("the allow list `lists: [PATH|URL, ...]` item cannot be empty!", c, c.rawMetaCleanup.rawMeta.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *rawMetaCleanupAllowListCustomResource) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMetaCleanupAllowList); ok {
+		c.rawMetaCleanup = parent.rawMetaCleanup
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaCleanupAllowListCustomResource
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawMetaCleanup.rawMeta.doc); err != nil {
+		return err
+	}
+
+	if c.ApiVersion == "" || strings.Count(c.ApiVersion, "/") > 1 {
+		return newDetailedConfigError("`apiVersion: [GROUP/]VERSION` required for the custom resource!", c, c.rawMetaCleanup.rawMeta.doc)
+	}
+
+	if c.Resource == "" {
+		return newDetailedConfigError("`resource: PLURAL_NAME` required for the custom resource (e.g. `resource: rollouts`)!", c, c.rawMetaCleanup.rawMeta.doc)
+	}
+
+	if len(c.ImagePaths) == 0 {
+		return newDetailedConfigError("`imagePaths: [JSONPATH, ...]` required for the custom resource (e.g. `imagePaths: [\"{.spec.template.spec.containers[*].image}\"]`)!", c, c.rawMetaCleanup.rawMeta.doc)
+	}
+
+	for _, imagePath := range c.ImagePaths {
+		if err := jsonpath.New("").Parse(imagePath); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("invalid JSONPath %q: %s", imagePath, err), c, c.rawMetaCleanup.rawMeta.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *rawMetaCleanupAllowList) processBundleRegexpString(configValue string) (*regexp.Regexp, error) {
+	var value string
+	if strings.HasPrefix(configValue, "/") && strings.HasSuffix(configValue, "/") {
+		value = strings.TrimPrefix(configValue, "/")
+		value = strings.TrimSuffix(value, "/")
+	} else {
+		value = regexp.QuoteMeta(configValue)
+	}
+
+	expr := fmt.Sprintf("^%s$", value)
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, newDetailedConfigError(fmt.Sprintf("invalid value %q for `bundles: [string|REGEX, ...]`!", configValue), c, c.rawMetaCleanup.rawMeta.doc)
+	}
+
+	return regex, nil
+}
+
+func (c *rawMetaCleanupAllowList) toMetaCleanupAllowList() MetaCleanupAllowList {
+	allowList := MetaCleanupAllowList{
+		HelmReleases: c.HelmReleases,
+		Lists:        c.Lists,
+	}
+
+	for _, bundle := range c.Bundles {
+		regex, _ := c.processBundleRegexpString(bundle)
+		allowList.Bundles = append(allowList.Bundles, regex)
+	}
+
+	for _, customResource := range c.CustomResources {
+		allowList.CustomResources = append(allowList.CustomResources, customResource.toMetaCleanupAllowListCustomResource())
+	}
+
+	return allowList
+}
+
+func (c *rawMetaCleanupAllowListCustomResource) toMetaCleanupAllowListCustomResource() *MetaCleanupAllowListCustomResource {
+	customResource := &MetaCleanupAllowListCustomResource{
+		Resource:   c.Resource,
+		ImagePaths: c.ImagePaths,
+	}
+
+	if parts := strings.SplitN(c.ApiVersion, "/", 2); len(parts) == 2 {
+		customResource.Group, customResource.Version = parts[0], parts[1]
+	} else {
+		customResource.Version = parts[0]
+	}
+
+	return customResource
+}
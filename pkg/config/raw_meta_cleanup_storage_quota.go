@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/docker/go-units"
+)
+
+type rawMetaCleanupStorageQuota struct {
+	MaxSize           string `yaml:"maxSize,omitempty"`
+	MaxStagesPerImage *int   `yaml:"maxStagesPerImage,omitempty"`
+
+	OverrideKeepPolicies bool `yaml:"overrideKeepPolicies,omitempty"`
+
+	rawMetaCleanup        *rawMetaCleanup
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaCleanupStorageQuota) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMetaCleanup); ok {
+		c.rawMetaCleanup = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaCleanupStorageQuota
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawMetaCleanup.rawMeta.doc); err != nil {
+		return err
+	}
+
+	if c.MaxSize == "" && c.MaxStagesPerImage == nil {
+		return newDetailedConfigError("`maxSize: SIZE` or `maxStagesPerImage: int` required for the cleanup storage quota!", c, c.rawMetaCleanup.rawMeta.doc)
+	}
+
+	if c.MaxSize != "" {
+		if size, err := units.FromHumanSize(c.MaxSize); err != nil || size <= 0 {
+			return newDetailedConfigError(fmt.Sprintf("invalid value %q for `maxSize: SIZE`: expected positive size such as 500MB or 50GB!", c.MaxSize), c, c.rawMetaCleanup.rawMeta.doc)
+		}
+	}
+
+	if c.MaxStagesPerImage != nil && *c.MaxStagesPerImage < 1 {
+		return newDetailedConfigError(fmt.Sprintf("invalid value %d for `maxStagesPerImage: int`: expected positive number!", *c.MaxStagesPerImage), c, c.rawMetaCleanup.rawMeta.doc)
+	}
+
+	return nil
+}
+
+func (c *rawMetaCleanupStorageQuota) toMetaCleanupStorageQuota() *MetaCleanupStorageQuota {
+	quota := &MetaCleanupStorageQuota{OverrideKeepPolicies: c.OverrideKeepPolicies}
+
+	if c.MaxSize != "" {
+		size, _ := units.FromHumanSize(c.MaxSize)
+		quota.MaxSize = &size
+	}
+
+	if c.MaxStagesPerImage != nil {
+		maxStagesPerImage := *c.MaxStagesPerImage
+		quota.MaxStagesPerImage = &maxStagesPerImage
+	}
+
+	return quota
+}
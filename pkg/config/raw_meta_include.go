@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var commitHashRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+type rawMetaInclude struct {
+	Git          string `yaml:"git,omitempty"`
+	Commit       string `yaml:"commit,omitempty"`
+	Tag          string `yaml:"tag,omitempty"`
+	Path         string `yaml:"path,omitempty"`
+	TemplatesDir string `yaml:"templatesDir,omitempty"`
+
+	rawMeta *rawMeta
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaInclude) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMeta); ok {
+		c.rawMeta = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaInclude
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMeta.doc); err != nil {
+		return err
+	}
+
+	return c.validate()
+}
+
+func (c *rawMetaInclude) validate() error {
+	if c.Git == "" {
+		return newDetailedConfigError("`git: URL` required for the include!", c, c.rawMeta.doc)
+	}
+
+	if c.Commit == "" {
+		return newDetailedConfigError("the include must be pinned with `commit: HASH` directive, `tag: TAG` directive only verifies that the tag points to the pinned commit!", c, c.rawMeta.doc)
+	}
+
+	if !commitHashRegexp.MatchString(c.Commit) {
+		return newDetailedConfigError(fmt.Sprintf("invalid `commit: %s`: the full commit hash expected!", c.Commit), c, c.rawMeta.doc)
+	}
+
+	for _, p := range []string{c.Path, c.TemplatesDir} {
+		if p == "" {
+			continue
+		}
+
+		if path.IsAbs(p) || strings.HasPrefix(path.Clean(p), "..") {
+			return newDetailedConfigError(fmt.Sprintf("invalid path %q: the path must be relative to the included git repository root!", p), c, c.rawMeta.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *rawMetaInclude) toMetaInclude() *MetaInclude {
+	include := &MetaInclude{
+		Git:          c.Git,
+		Commit:       c.Commit,
+		Tag:          c.Tag,
+		Path:         DefaultIncludePath,
+		TemplatesDir: DefaultIncludeTemplatesDir,
+	}
+
+	if c.Path != "" {
+		include.Path = path.Clean(c.Path)
+	}
+
+	if c.TemplatesDir != "" {
+		include.TemplatesDir = path.Clean(c.TemplatesDir)
+	}
+
+	return include
+}
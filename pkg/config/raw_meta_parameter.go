@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var parameterNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type rawMetaParameter struct {
+	Type        string        `yaml:"type,omitempty"`
+	Description string        `yaml:"description,omitempty"`
+	Default     interface{}   `yaml:"default,omitempty"`
+	Required    bool          `yaml:"required,omitempty"`
+	Enum        []interface{} `yaml:"enum,omitempty"`
+	Pattern     string        `yaml:"pattern,omitempty"`
+
+	rawMeta *rawMeta
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaParameter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMeta); ok {
+		c.rawMeta = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaParameter
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMeta.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawMetaParameter) validate(name string) error {
+	if !parameterNameRegexp.MatchString(name) {
+		return newDetailedConfigError(fmt.Sprintf("invalid parameter name %q: the name must consist of alphanumeric characters and underscores and must not start with a digit!", name), nil, c.rawMeta.doc)
+	}
+
+	parameterType := c.parameterType()
+	var isTypeSupported bool
+	for _, t := range parameterTypes {
+		if t == parameterType {
+			isTypeSupported = true
+		}
+	}
+
+	if !isTypeSupported {
+		return newDetailedConfigError(fmt.Sprintf("parameter %q: unsupported type %q, expected one of: %s!", name, parameterType, strings.Join(parameterTypes, ", ")), nil, c.rawMeta.doc)
+	}
+
+	if c.Required && c.Default != nil {
+		return newDetailedConfigError(fmt.Sprintf("parameter %q: `default` cannot be used with `required: true`!", name), nil, c.rawMeta.doc)
+	}
+
+	if c.Pattern != "" {
+		if parameterType != ParameterTypeString {
+			return newDetailedConfigError(fmt.Sprintf("parameter %q: `pattern` can be used only with the %s type!", name, ParameterTypeString), nil, c.rawMeta.doc)
+		}
+
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("parameter %q: invalid `pattern: %s`: %s", name, c.Pattern, err), nil, c.rawMeta.doc)
+		}
+	}
+
+	for _, enumValue := range c.Enum {
+		if _, err := convertParameterValue(parameterType, enumValue); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("parameter %q: invalid enum value: %s", name, err), nil, c.rawMeta.doc)
+		}
+	}
+
+	if c.Default != nil {
+		if _, err := c.toMetaParameter(name).ConvertValue(c.Default); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("parameter %q: invalid default: %s", name, err), nil, c.rawMeta.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *rawMetaParameter) parameterType() string {
+	if c.Type == "" {
+		return ParameterTypeString
+	}
+
+	return c.Type
+}
+
+func (c *rawMetaParameter) toMetaParameter(name string) *MetaParameter {
+	parameter := &MetaParameter{
+		Name:        name,
+		Type:        c.parameterType(),
+		Description: c.Description,
+		Required:    c.Required,
+	}
+
+	for _, enumValue := range c.Enum {
+		value, _ := convertParameterValue(parameter.Type, enumValue)
+		parameter.Enum = append(parameter.Enum, value)
+	}
+
+	if c.Pattern != "" {
+		parameter.Pattern = regexp.MustCompile(c.Pattern)
+	}
+
+	if c.Default != nil {
+		parameter.Default, _ = convertParameterValue(parameter.Type, c.Default)
+	}
+
+	return parameter
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var secretKeyNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+type rawMetaSecretKey struct {
+	Provider         *string  `yaml:"provider,omitempty"`
+	EncryptedKeyFile *string  `yaml:"encryptedKeyFile,omitempty"`
+	Recipients       []string `yaml:"recipients,omitempty"`
+	Command          []string `yaml:"command,omitempty"`
+
+	UseVersionedFormat *bool `yaml:"useVersionedFormat,omitempty"`
+	RejectLegacyFormat *bool `yaml:"rejectLegacyFormat,omitempty"`
+	Optional           *bool `yaml:"optional,omitempty"`
+
+	Keys map[string]*rawMetaSecretKey `yaml:"keys,omitempty"`
+
+	rawMeta *rawMeta
+	isNamed bool
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaSecretKey) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	switch parent := parentStack.Peek().(type) {
+	case *rawMeta:
+		c.rawMeta = parent
+	case *rawMetaSecretKey:
+		c.rawMeta = parent.rawMeta
+		c.isNamed = true
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaSecretKey
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMeta.doc); err != nil {
+		return err
+	}
+
+	if c.Optional != nil && !c.isNamed {
+		return newDetailedConfigError("`optional` can be used only in the named secret key!", nil, c.rawMeta.doc)
+	}
+
+	if len(c.Keys) != 0 {
+		if c.isNamed {
+			return newDetailedConfigError("`keys` cannot be used in the named secret key!", nil, c.rawMeta.doc)
+		}
+
+		for name := range c.Keys {
+			if !secretKeyNameRegexp.MatchString(name) {
+				return newDetailedConfigError(fmt.Sprintf("invalid secret key name `%s`: expected letters, digits, `-` and `_`!", name), nil, c.rawMeta.doc)
+			}
+		}
+	}
+
+	provider := SecretKeyProviderStatic
+	if c.Provider != nil {
+		provider = *c.Provider
+	}
+
+	switch provider {
+	case SecretKeyProviderStatic:
+		if c.EncryptedKeyFile != nil || len(c.Recipients) != 0 || len(c.Command) != 0 {
+			return newDetailedConfigError("`encryptedKeyFile`, `recipients` and `command` cannot be used with the static secret key provider!", nil, c.rawMeta.doc)
+		}
+	case SecretKeyProviderAge, SecretKeyProviderPGP:
+		if len(c.Recipients) == 0 {
+			return newDetailedConfigError(fmt.Sprintf("`recipients: [RECIPIENT, ...]` required for the %s secret key provider!", provider), nil, c.rawMeta.doc)
+		}
+
+		if len(c.Command) != 0 {
+			return newDetailedConfigError(fmt.Sprintf("`command` cannot be used with the %s secret key provider!", provider), nil, c.rawMeta.doc)
+		}
+	case SecretKeyProviderExec:
+		if len(c.Command) == 0 || c.Command[0] == "" {
+			return newDetailedConfigError("`command: [PROGRAM, ARG, ...]` required for the exec secret key provider!", nil, c.rawMeta.doc)
+		}
+
+		if len(c.Recipients) != 0 {
+			return newDetailedConfigError("`recipients` cannot be used with the exec secret key provider!", nil, c.rawMeta.doc)
+		}
+	default:
+		return newDetailedConfigError(fmt.Sprintf("unsupported secret key provider `%s`: expected %s!", provider, strings.Join(secretKeyProviders, ", ")), nil, c.rawMeta.doc)
+	}
+
+	if c.EncryptedKeyFile != nil && !isRelativePath(*c.EncryptedKeyFile) {
+		return newDetailedConfigError("`encryptedKeyFile: PATH` should be relative to project directory!", nil, c.rawMeta.doc)
+	}
+
+	return nil
+}
+
+func (c *rawMetaSecretKey) toMetaSecretKey() MetaSecretKey {
+	metaSecretKey := MetaSecretKey{}
+
+	if c.Provider != nil {
+		metaSecretKey.Provider = *c.Provider
+	}
+
+	if c.EncryptedKeyFile != nil {
+		metaSecretKey.EncryptedKeyFile = *c.EncryptedKeyFile
+	}
+
+	metaSecretKey.Recipients = c.Recipients
+	metaSecretKey.Command = c.Command
+
+	if c.UseVersionedFormat != nil {
+		metaSecretKey.UseVersionedFormat = *c.UseVersionedFormat
+	}
+
+	if c.RejectLegacyFormat != nil {
+		metaSecretKey.RejectLegacyFormat = *c.RejectLegacyFormat
+	}
+
+	if c.Optional != nil {
+		metaSecretKey.Optional = *c.Optional
+	}
+
+	if len(c.Keys) != 0 {
+		metaSecretKey.Keys = map[string]MetaSecretKey{}
+		for name, rawKey := range c.Keys {
+			key := MetaSecretKey{UseVersionedFormat: metaSecretKey.UseVersionedFormat, RejectLegacyFormat: metaSecretKey.RejectLegacyFormat}
+			if rawKey != nil {
+				key = rawKey.toMetaSecretKey()
+				if rawKey.UseVersionedFormat == nil {
+					key.UseVersionedFormat = metaSecretKey.UseVersionedFormat
+				}
+				if rawKey.RejectLegacyFormat == nil {
+					key.RejectLegacyFormat = metaSecretKey.RejectLegacyFormat
+				}
+			}
+			key.Name = name
+
+			metaSecretKey.Keys[name] = key
+		}
+	}
+
+	return metaSecretKey
+}
@@ -0,0 +1,77 @@
+package config
+
+import "fmt"
+
+type rawSecret struct {
+	Id                string      `yaml:"id,omitempty"`
+	FromEnv           string      `yaml:"fromEnv,omitempty"`
+	FromEncryptedFile string      `yaml:"fromEncryptedFile,omitempty"`
+	To                string      `yaml:"to,omitempty"`
+	ToEnv             string      `yaml:"toEnv,omitempty"`
+	Stages            interface{} `yaml:"stages,omitempty"`
+
+	doc *doc `yaml:"-"` // parent doc
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawSecret) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	switch parent := parentStack.Peek().(type) {
+	case *rawStapelImage:
+		c.doc = parent.doc
+	case *rawImageFromDockerfile:
+		c.doc = parent.doc
+	}
+
+	type plain rawSecret
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawSecret) toDirective(isDockerfileImage bool) (secret *Secret, err error) {
+	secret = &Secret{}
+	secret.Id = c.Id
+	secret.FromEnv = c.FromEnv
+	secret.FromEncryptedFile = c.FromEncryptedFile
+	secret.To = c.To
+	secret.ToEnv = c.ToEnv
+
+	if secret.Stages, err = InterfaceToStringArray(c.Stages, c, c.doc); err != nil {
+		return nil, err
+	}
+
+	secret.raw = c
+
+	if err := secret.validate(isDockerfileImage); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+func secretsToDirectives(rawSecrets []*rawSecret, isDockerfileImage bool, doc *doc) ([]*Secret, error) {
+	var secrets []*Secret
+	ids := map[string]bool{}
+	for _, rawSecret := range rawSecrets {
+		secret, err := rawSecret.toDirective(isDockerfileImage)
+		if err != nil {
+			return nil, err
+		}
+
+		if ids[secret.Id] {
+			return nil, newDetailedConfigError(fmt.Sprintf("duplicate secret `id: %s`!", secret.Id), rawSecret, doc)
+		}
+		ids[secret.Id] = true
+
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
+}
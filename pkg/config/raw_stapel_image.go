@@ -14,10 +14,12 @@ type rawStapelImage struct {
 	FromCacheVersion string       `yaml:"fromCacheVersion,omitempty"`
 	FromImage        string       `yaml:"fromImage,omitempty"`
 	FromArtifact     string       `yaml:"fromArtifact,omitempty"`
+	RawPlatform      interface{}  `yaml:"platform,omitempty"`
 	RawGit           []*rawGit    `yaml:"git,omitempty"`
 	RawShell         *rawShell    `yaml:"shell,omitempty"`
 	RawAnsible       *rawAnsible  `yaml:"ansible,omitempty"`
 	RawMount         []*rawMount  `yaml:"mount,omitempty"`
+	RawSecrets       []*rawSecret `yaml:"secrets,omitempty"`
 	RawDocker        *rawDocker   `yaml:"docker,omitempty"`
 	RawImport        []*rawImport `yaml:"import,omitempty"`
 
@@ -213,6 +215,10 @@ func (c *rawStapelImage) toStapelImageBaseDirective(giterminismManager gitermini
 	imageBase.FromLatest = c.FromLatest
 	imageBase.FromCacheVersion = c.FromCacheVersion
 
+	if imageBase.Platform, err = InterfaceToStringArray(c.RawPlatform, nil, c.doc); err != nil {
+		return nil, err
+	}
+
 	for _, git := range c.RawGit {
 		if git.gitType() == "local" {
 			if gitLocal, err := git.toGitLocalDirective(); err != nil {
@@ -280,6 +286,10 @@ func (c *rawStapelImage) toBaseStapelImageBaseDirective(giterminismManager giter
 		}
 	}
 
+	if imageBase.Secrets, err = secretsToDirectives(c.RawSecrets, false, c.doc); err != nil {
+		return nil, err
+	}
+
 	imageBase.Git = &GitManager{}
 
 	imageBase.raw = c
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// JSONSchema is the subset of the JSON Schema (draft-07) that is used to describe werf.yaml documents
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	Definitions          map[string]*JSONSchema `json:"definitions,omitempty"`
+
+	// anyScalar is set for the string fields of the raw config types, yaml.v2 unmarshals any scalar into such fields
+	anyScalar bool
+}
+
+// propertyOverrides are the properties which cannot be described by the raw config types
+var propertyOverrides = map[string]map[string]*JSONSchema{
+	"stapelImage": {
+		"image":           imageNameSchema(),
+		templateDirective: {Type: "string"},
+		extendsDirective:  {Type: "string"},
+	},
+	"imageFromDockerfile": {
+		"image":           imageNameSchema(),
+		templateDirective: {Type: "string"},
+		extendsDirective:  {Type: "string"},
+	},
+	"metaSecretKey": {
+		"provider": {Type: "string", Enum: []interface{}{SecretKeyProviderStatic, SecretKeyProviderAge, SecretKeyProviderPGP, SecretKeyProviderExec}},
+	},
+	"metaParameter": {
+		"type":    {Type: "string", Enum: []interface{}{ParameterTypeString, ParameterTypeInt, ParameterTypeFloat, ParameterTypeBool}},
+		"default": {Type: []interface{}{"string", "integer", "number", "boolean"}},
+		"enum":    {Type: "array", Items: &JSONSchema{Type: []interface{}{"string", "integer", "number", "boolean"}}},
+	},
+	"metaCleanupKeepPolicyImagesPerReference": {
+		"operator": {Type: "string", Enum: []interface{}{"And", "Or"}},
+	},
+}
+
+var requiredProperties = map[string][]string{
+	"meta": {"configVersion", "project"},
+}
+
+// GetWerfConfigJSONSchema generates the JSON Schema of werf.yaml documents from the raw config types
+func GetWerfConfigJSONSchema() *JSONSchema {
+	g := &schemaGenerator{definitions: map[string]*JSONSchema{}}
+
+	metaRef := g.definitionRef(reflect.TypeOf(rawMeta{}))
+	stapelImageRef := g.definitionRef(reflect.TypeOf(rawStapelImage{}))
+	imageFromDockerfileRef := g.definitionRef(reflect.TypeOf(rawImageFromDockerfile{}))
+
+	g.definitions["meta"].Properties["configVersion"] = &JSONSchema{Type: "integer", Enum: []interface{}{1}}
+
+	return &JSONSchema{
+		Schema:      jsonSchemaDraft,
+		Title:       "werf.yaml",
+		Description: "werf configuration, each YAML document is either the meta section, a stapel image (artifact) or an image from Dockerfile",
+		AnyOf:       []*JSONSchema{metaRef, stapelImageRef, imageFromDockerfileRef},
+		Definitions: g.definitions,
+	}
+}
+
+// GetWerfConfigJSONSchemaData returns the JSON Schema of werf.yaml documents in JSON format
+func GetWerfConfigJSONSchemaData() ([]byte, error) {
+	return json.MarshalIndent(GetWerfConfigJSONSchema(), "", "  ")
+}
+
+func imageNameSchema() *JSONSchema {
+	return &JSONSchema{
+		AnyOf: []*JSONSchema{
+			{Type: "string"},
+			{Type: "array", Items: &JSONSchema{Type: "string"}},
+			{Type: "null"},
+		},
+	}
+}
+
+func stringOrStringArraySchema() *JSONSchema {
+	return &JSONSchema{
+		AnyOf: []*JSONSchema{
+			{Type: "string"},
+			{Type: "array", Items: &JSONSchema{Type: "string"}},
+		},
+	}
+}
+
+type schemaGenerator struct {
+	definitions map[string]*JSONSchema
+}
+
+func (g *schemaGenerator) definitionRef(t reflect.Type) *JSONSchema {
+	name := definitionName(t)
+	if _, ok := g.definitions[name]; !ok {
+		def := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+		g.definitions[name] = def
+
+		additionalProperties := g.fillProperties(def, t)
+		if !additionalProperties {
+			def.AdditionalProperties = false
+		}
+
+		for property, schema := range propertyOverrides[name] {
+			def.Properties[property] = schema
+		}
+
+		def.Required = requiredProperties[name]
+	}
+
+	return &JSONSchema{Ref: "#/definitions/" + name}
+}
+
+// fillProperties adds fields of the struct to the definition properties and reports whether arbitrary properties are allowed
+func (g *schemaGenerator) fillProperties(def *JSONSchema, t reflect.Type) bool {
+	var additionalProperties bool
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name, inline, skip := parseYamlTag(field)
+		if skip {
+			continue
+		}
+
+		if inline {
+			switch field.Type.Kind() {
+			case reflect.Struct:
+				if g.fillProperties(def, field.Type) {
+					additionalProperties = true
+				}
+			case reflect.Map:
+				// UnsupportedAttributes only collects unknown fields to report them
+				if field.Name != "UnsupportedAttributes" {
+					additionalProperties = true
+				}
+			}
+
+			continue
+		}
+
+		def.Properties[name] = g.typeSchema(field.Type)
+	}
+
+	return additionalProperties
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) *JSONSchema {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return &JSONSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.String:
+		return &JSONSchema{Type: "string", anyScalar: true}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice:
+		return &JSONSchema{Type: "array", Items: g.typeSchema(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &JSONSchema{Type: "object"}
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		return g.definitionRef(t)
+	case reflect.Interface:
+		return stringOrStringArraySchema()
+	default:
+		panic(fmt.Sprintf("unsupported config field type %s", t))
+	}
+}
+
+func parseYamlTag(field reflect.StructField) (name string, inline, skip bool) {
+	tag := field.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	for _, flag := range parts[1:] {
+		if flag == "inline" {
+			inline = true
+		}
+	}
+
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	return name, inline, false
+}
+
+func definitionName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "raw")
+	if name == "" {
+		return name
+	}
+
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// resolve returns the schema referenced by $ref
+func (s *JSONSchema) resolve(root *JSONSchema) *JSONSchema {
+	for s.Ref != "" {
+		s = root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+	}
+
+	return s
+}
+
+func (s *JSONSchema) typeNames() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("werf config JSON schema", func() {
+	It("should be generated from the raw config types", func() {
+		schema := GetWerfConfigJSONSchema()
+
+		stapelImage := schema.Definitions["stapelImage"]
+		Ω(stapelImage.AdditionalProperties).Should(Equal(false))
+		Ω(stapelImage.Properties).Should(HaveKey("image"))
+		Ω(stapelImage.Properties).Should(HaveKey("fromLatest"))
+		Ω(stapelImage.Properties["git"].Items.Ref).Should(Equal("#/definitions/git"))
+
+		git := schema.Definitions["git"]
+		Ω(git.Properties).Should(HaveKey("add"))
+		Ω(git.Properties).Should(HaveKey("stageDependencies"))
+		Ω(git.Properties).ShouldNot(HaveKey("unsupportedattributes"))
+
+		Ω(schema.Definitions["ansibleTask"].AdditionalProperties).Should(BeNil())
+		Ω(schema.Definitions["meta"].Required).Should(ConsistOf("configVersion", "project"))
+	})
+
+	It("should be marshalled to JSON", func() {
+		data, err := GetWerfConfigJSONSchemaData()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		var res map[string]interface{}
+		Ω(json.Unmarshal(data, &res)).Should(Succeed())
+		Ω(res["$schema"]).Should(Equal(jsonSchemaDraft))
+	})
+})
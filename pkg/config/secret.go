@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Secret is mounted into the build containers only for the duration of the build instructions,
+// it is not saved in the image and does not affect stages digests
+type Secret struct {
+	Id                string
+	FromEnv           string
+	FromEncryptedFile string
+	To                string
+	ToEnv             string
+	Stages            []string
+
+	raw *rawSecret
+}
+
+var (
+	secretIdRegexp   = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+	envNameRegexp    = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	secretUserStages = []string{"beforeInstall", "install", "beforeSetup", "setup"}
+)
+
+// IsUsedInStage returns true if the secret is available for the stapel user stage, all user stages are used by default
+func (c *Secret) IsUsedInStage(stageName string) bool {
+	if len(c.Stages) == 0 {
+		for _, userStage := range secretUserStages {
+			if userStage == stageName {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, stage := range c.Stages {
+		if stage == stageName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainerPath returns the path of the secret file in the build container
+func (c *Secret) ContainerPath() string {
+	if c.To != "" {
+		return c.To
+	}
+
+	return path.Join("/run/secrets", c.Id)
+}
+
+func (c *Secret) validate(isDockerfileImage bool) error {
+	if c.Id == "" || !secretIdRegexp.MatchString(c.Id) {
+		return newDetailedConfigError("`id: ID` consisting of alphanumeric characters, '_', '.' or '-' required for secret!", c.raw, c.raw.doc)
+	}
+
+	if (c.FromEnv == "") == (c.FromEncryptedFile == "") {
+		return newDetailedConfigError("specify only one secret source using `fromEnv: NAME` or `fromEncryptedFile: PATH`!", c.raw, c.raw.doc)
+	}
+
+	if c.FromEncryptedFile != "" && !isRelativePath(c.FromEncryptedFile) {
+		return newDetailedConfigError("`fromEncryptedFile: PATH` should be relative to project directory!", c.raw, c.raw.doc)
+	}
+
+	if isDockerfileImage {
+		if c.To != "" || c.ToEnv != "" || len(c.Stages) != 0 {
+			return newDetailedConfigError("`to`, `toEnv` and `stages` are not supported for the Dockerfile image secret: use `RUN --mount=type=secret,id=ID` instruction in Dockerfile!", c.raw, c.raw.doc)
+		}
+
+		return nil
+	}
+
+	if c.To != "" && c.ToEnv != "" {
+		return newDetailedConfigError(fmt.Sprintf("cannot use `to: %s` and `toEnv: %s` at the same time for secret!", c.To, c.ToEnv), c.raw, c.raw.doc)
+	}
+
+	if c.To != "" && !isAbsolutePath(c.To) {
+		return newDetailedConfigError("`to: PATH` should be absolute path for secret!", c.raw, c.raw.doc)
+	}
+
+	if c.ToEnv != "" && !envNameRegexp.MatchString(c.ToEnv) {
+		return newDetailedConfigError(fmt.Sprintf("invalid environment variable name `toEnv: %s` for secret!", c.ToEnv), c.raw, c.raw.doc)
+	}
+
+	for _, stage := range c.Stages {
+		var supported bool
+		for _, userStage := range secretUserStages {
+			if stage == userStage {
+				supported = true
+			}
+		}
+
+		if !supported {
+			return newDetailedConfigError(fmt.Sprintf("unsupported stage `%s` for secret: expected %s!", stage, strings.Join(secretUserStages, ", ")), c.raw, c.raw.doc)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type secretEntry struct {
+	secret            Secret
+	isDockerfileImage bool
+	expectedError     string
+}
+
+var _ = DescribeTable("validating secrets", func(e secretEntry) {
+	e.secret.raw = &rawSecret{doc: &doc{}}
+
+	err := e.secret.validate(e.isDockerfileImage)
+	if e.expectedError != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedError))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+},
+	Entry("from env to file", secretEntry{
+		secret: Secret{Id: "npmrc", FromEnv: "NPMRC", To: "/root/.npmrc"},
+	}),
+	Entry("from encrypted file to env", secretEntry{
+		secret: Secret{Id: "token", FromEncryptedFile: ".werf/token", ToEnv: "TOKEN", Stages: []string{"install"}},
+	}),
+	Entry("dockerfile image", secretEntry{
+		secret:            Secret{Id: "token", FromEnv: "TOKEN"},
+		isDockerfileImage: true,
+	}),
+	Entry("invalid id", secretEntry{
+		secret:        Secret{Id: "my token", FromEnv: "TOKEN"},
+		expectedError: "`id: ID`",
+	}),
+	Entry("both sources", secretEntry{
+		secret:        Secret{Id: "token", FromEnv: "TOKEN", FromEncryptedFile: "token"},
+		expectedError: "only one secret source",
+	}),
+	Entry("no source", secretEntry{
+		secret:        Secret{Id: "token"},
+		expectedError: "only one secret source",
+	}),
+	Entry("absolute encrypted file path", secretEntry{
+		secret:        Secret{Id: "token", FromEncryptedFile: "/etc/token"},
+		expectedError: "relative to project directory",
+	}),
+	Entry("to and toEnv", secretEntry{
+		secret:        Secret{Id: "token", FromEnv: "TOKEN", To: "/token", ToEnv: "TOKEN"},
+		expectedError: "at the same time",
+	}),
+	Entry("relative to", secretEntry{
+		secret:        Secret{Id: "token", FromEnv: "TOKEN", To: "token"},
+		expectedError: "absolute path",
+	}),
+	Entry("invalid toEnv", secretEntry{
+		secret:        Secret{Id: "token", FromEnv: "TOKEN", ToEnv: "1TOKEN"},
+		expectedError: "invalid environment variable name",
+	}),
+	Entry("non-user stage", secretEntry{
+		secret:        Secret{Id: "token", FromEnv: "TOKEN", Stages: []string{"from"}},
+		expectedError: "unsupported stage `from`",
+	}),
+	Entry("dockerfile image with to", secretEntry{
+		secret:            Secret{Id: "token", FromEnv: "TOKEN", To: "/token"},
+		isDockerfileImage: true,
+		expectedError:     "RUN --mount=type=secret",
+	}))
@@ -16,10 +16,12 @@ type StapelImageBase struct {
 	FromImageName    string
 	FromArtifactName string
 	FromCacheVersion string
+	Platform         []string
 	Git              *GitManager
 	Shell            *Shell
 	Ansible          *Ansible
 	Mount            []*Mount
+	Secrets          []*Secret
 	Import           []*Import
 
 	raw *rawStapelImage
@@ -106,6 +108,10 @@ func (c *StapelImageBase) validate(giterminismManager giterminism_manager.Interf
 		logboek.Context(context.Background()).Warn().LogLn("WARNING: Do not use artifacts as a base for other images and artifacts. The feature is deprecated, and the directive 'fromArtifact' will be completely removed in version v1.3.\n\nCareless use of artifacts may lead to difficult to trace issues that may arise long after the configuration has been written. The artifact image is cached after the first build and ignores any changes in the project git repository unless the user has explicitly specified stage dependencies. As found, this behavior is completely unexpected for users despite the fact that it is absolutely correct in the werf logic.")
 	}
 
+	if err := validatePlatforms(c.Platform, nil, c.raw.doc); err != nil {
+		return err
+	}
+
 	// TODO: валидацию формата `From`
 
 	return nil
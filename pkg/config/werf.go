@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/werf/werf/pkg/util"
 )
 
 type WerfConfig struct {
@@ -257,6 +259,70 @@ func (c *WerfConfig) validateImageFrom(i *StapelImageBase) error {
 	return nil
 }
 
+func (c *WerfConfig) validateImagesPlatforms() error {
+	var images []*StapelImageBase
+	for _, image := range c.StapelImages {
+		images = append(images, image.StapelImageBase)
+	}
+
+	for _, image := range c.Artifacts {
+		images = append(images, image.StapelImageBase)
+	}
+
+	for _, image := range images {
+		if len(image.Platform) == 0 {
+			continue
+		}
+
+		var dependencyNames []string
+		if image.FromImageName != "" {
+			dependencyNames = append(dependencyNames, image.FromImageName)
+		} else if image.FromArtifactName != "" {
+			dependencyNames = append(dependencyNames, image.FromArtifactName)
+		}
+
+		for _, imp := range image.Import {
+			if imp.ImageName != "" {
+				dependencyNames = append(dependencyNames, imp.ImageName)
+			} else if imp.ArtifactName != "" {
+				dependencyNames = append(dependencyNames, imp.ArtifactName)
+			}
+		}
+
+		for _, dependencyName := range dependencyNames {
+			dependencyPlatforms := c.GetImagePlatforms(dependencyName)
+			if len(dependencyPlatforms) == 0 {
+				continue
+			}
+
+			for _, platform := range image.Platform {
+				if !util.IsStringsContainValue(dependencyPlatforms, platform) {
+					return newDetailedConfigError(fmt.Sprintf("image `%s` is not built for platform `%s` required by image `%s`!", dependencyName, platform, image.Name), nil, image.raw.doc)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetImagePlatforms returns platforms of the image or artifact, empty platforms mean the default platform of the build host
+func (c *WerfConfig) GetImagePlatforms(imageName string) []string {
+	if image := c.GetStapelImage(imageName); image != nil {
+		return image.Platform
+	}
+
+	if image := c.GetDockerfileImage(imageName); image != nil {
+		return image.Platform
+	}
+
+	if artifact := c.GetArtifact(imageName); artifact != nil {
+		return artifact.Platform
+	}
+
+	return nil
+}
+
 func (c *WerfConfig) validateInfiniteLoopBetweenRelatedImages() error {
 	var imageAndArtifactNames []string
 
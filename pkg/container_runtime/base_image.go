@@ -15,13 +15,13 @@ type baseImage struct {
 	inspect   *types.ImageInspect
 	stageDesc *image.StageDescription
 
-	LocalDockerServerRuntime *LocalDockerServerRuntime
+	ContainerRuntime ContainerRuntime
 }
 
-func newBaseImage(name string, localDockerServerRuntime *LocalDockerServerRuntime) *baseImage {
+func newBaseImage(name string, containerRuntime ContainerRuntime) *baseImage {
 	image := &baseImage{}
 	image.name = name
-	image.LocalDockerServerRuntime = localDockerServerRuntime
+	image.ContainerRuntime = containerRuntime
 	return image
 }
 
@@ -34,15 +34,14 @@ func (i *baseImage) SetName(name string) {
 }
 
 func (i *baseImage) MustResetInspect(ctx context.Context) error {
-	if inspect, err := i.LocalDockerServerRuntime.GetImageInspect(ctx, i.Name()); err != nil {
+	if inspect, err := i.ContainerRuntime.GetImageInspect(ctx, i.Name()); err != nil {
 		return fmt.Errorf("unable to get inspect for image %s: %s", i.Name(), err)
+	} else if inspect == nil {
+		return fmt.Errorf("image %s not found", i.Name())
 	} else {
 		i.SetInspect(inspect)
 	}
 
-	if i.inspect == nil {
-		panic(fmt.Sprintf("runtime error: inspect must be (%s)", i.name))
-	}
 	return nil
 }
 
@@ -59,7 +58,11 @@ func (i *baseImage) UnsetInspect() {
 }
 
 func (i *baseImage) Untag(ctx context.Context) error {
-	if err := docker.CliRmi(ctx, i.name, "--force"); err != nil {
+	if localHostRuntime, ok := i.ContainerRuntime.(*LocalHostRuntime); ok {
+		if err := localHostRuntime.Rmi(ctx, i.name); err != nil {
+			return err
+		}
+	} else if err := docker.CliRmi(ctx, i.name, "--force"); err != nil {
 		return err
 	}
 
@@ -0,0 +1,344 @@
+package container_runtime
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/pkg/fileutils"
+	"github.com/moby/buildkit/session/filesync"
+	"github.com/tonistiigi/fsutil"
+	fstypes "github.com/tonistiigi/fsutil/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// the metadata keys of the buildkit filesync requests
+const (
+	buildkitFileSyncDirNameKey         = "dir-name"
+	buildkitFileSyncIncludePatternsKey = "include-patterns"
+	buildkitFileSyncExcludePatternsKey = "exclude-patterns"
+	buildkitFileSyncFollowPathsKey     = "followpaths"
+)
+
+// buildContextArchiveProvider serves the local sources of the dockerfile frontend from the build context archive.
+// Only the stats of the archive entries are sent beforehand, the file contents are read from the archive when buildkitd requests them,
+// so the files excluded by .dockerignore or unchanged since the previous build are never transferred
+type buildContextArchiveProvider struct {
+	archive  *contextArchive
+	dirNames map[string]bool
+}
+
+func newBuildContextArchiveProvider(archivePath string, dirNames ...string) (*buildContextArchiveProvider, error) {
+	archive, err := openContextArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &buildContextArchiveProvider{archive: archive, dirNames: map[string]bool{}}
+	for _, dirName := range dirNames {
+		p.dirNames[dirName] = true
+	}
+
+	return p, nil
+}
+
+func (p *buildContextArchiveProvider) Register(server *grpc.Server) {
+	filesync.RegisterFileSyncServer(server, p)
+}
+
+func (p *buildContextArchiveProvider) DiffCopy(stream filesync.FileSync_DiffCopyServer) error {
+	opts, _ := metadata.FromIncomingContext(stream.Context())
+
+	var dirName string
+	if values := opts[buildkitFileSyncDirNameKey]; len(values) != 0 {
+		dirName = values[0]
+	}
+
+	if !p.dirNames[dirName] {
+		return status.Errorf(codes.NotFound, "no access allowed to dir %q", dirName)
+	}
+
+	fs, err := p.archive.filter(opts[buildkitFileSyncIncludePatternsKey], opts[buildkitFileSyncExcludePatternsKey], opts[buildkitFileSyncFollowPathsKey])
+	if err != nil {
+		return err
+	}
+
+	return fsutil.Send(stream.Context(), stream, fs, nil)
+}
+
+func (p *buildContextArchiveProvider) TarStream(_ filesync.FileSync_TarStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "tarstream protocol is not supported for the build context archive")
+}
+
+type contextArchiveEntry struct {
+	stat   *fstypes.Stat
+	offset int64
+}
+
+// contextArchive is the index of the uncompressed tar archive entries sorted in the order expected by the fsutil receiver
+type contextArchive struct {
+	path    string
+	entries []*contextArchiveEntry
+	byPath  map[string]*contextArchiveEntry
+}
+
+func openContextArchive(archivePath string) (*contextArchive, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	a := &contextArchive{path: archivePath, byPath: map[string]*contextArchiveEntry{}}
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read build context archive %s: %s", archivePath, err)
+		}
+
+		entryPath := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if entryPath == "." || entryPath == ".." || strings.HasPrefix(entryPath, "../") {
+			continue
+		}
+
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+
+		stat := &fstypes.Stat{
+			Path:     entryPath,
+			Mode:     uint32(hdr.FileInfo().Mode()),
+			ModTime:  hdr.ModTime.UnixNano(),
+			Devmajor: hdr.Devmajor,
+			Devminor: hdr.Devminor,
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			stat.Linkname = hdr.Linkname
+		case tar.TypeLink:
+			stat.Linkname = path.Clean(strings.TrimPrefix(hdr.Linkname, "/"))
+		case tar.TypeReg, tar.TypeRegA:
+			stat.Size_ = hdr.Size
+		}
+
+		a.add(&contextArchiveEntry{stat: stat, offset: offset})
+	}
+
+	// the archive may not contain the parent directories of the files
+	for _, entry := range append([]*contextArchiveEntry{}, a.entries...) {
+		for dir := path.Dir(entry.stat.Path); dir != "."; dir = path.Dir(dir) {
+			if _, ok := a.byPath[dir]; ok {
+				break
+			}
+
+			a.add(&contextArchiveEntry{stat: &fstypes.Stat{Path: dir, Mode: uint32(os.ModeDir | 0755)}})
+		}
+	}
+
+	sort.Slice(a.entries, func(i, j int) bool {
+		return fsutil.ComparePath(a.entries[i].stat.Path, a.entries[j].stat.Path) < 0
+	})
+
+	return a, nil
+}
+
+// add registers the entry, the later entry with the same path replaces the earlier one as tar extraction does
+func (a *contextArchive) add(entry *contextArchiveEntry) {
+	if existing, ok := a.byPath[entry.stat.Path]; ok {
+		*existing = *entry
+		return
+	}
+
+	a.entries = append(a.entries, entry)
+	a.byPath[entry.stat.Path] = entry
+}
+
+// filter returns the archive entries selected by the include and exclude patterns the same way as the fsutil directory walker does
+func (a *contextArchive) filter(includePatterns, excludePatterns, followPaths []string) (*contextArchiveFS, error) {
+	var pm *fileutils.PatternMatcher
+	if len(excludePatterns) != 0 {
+		var err error
+		pm, err = fileutils.NewPatternMatcher(excludePatterns)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude patterns %v: %s", excludePatterns, err)
+		}
+	}
+
+	var includes []string
+	for _, pattern := range includePatterns {
+		includes = append(includes, filepath.Clean(pattern))
+	}
+	includes = append(includes, a.followLinks(followPaths)...)
+
+	fs := &contextArchiveFS{archive: a}
+
+	var skippedDir, includedDir string
+	for _, entry := range a.entries {
+		entryPath := entry.stat.Path
+		isDir := os.FileMode(entry.stat.Mode).IsDir()
+
+		if skippedDir != "" && strings.HasPrefix(entryPath, skippedDir+"/") {
+			continue
+		}
+		skippedDir = ""
+
+		if len(includes) != 0 && (includedDir == "" || !strings.HasPrefix(entryPath, includedDir+"/")) {
+			includedDir = ""
+
+			matched, partial := matchIncludePatterns(includes, entryPath)
+			if !matched {
+				if isDir {
+					skippedDir = entryPath
+				}
+				continue
+			}
+
+			if !partial && isDir {
+				includedDir = entryPath
+			}
+		}
+
+		if pm != nil {
+			excluded, err := pm.Matches(entryPath)
+			if err != nil {
+				return nil, fmt.Errorf("unable to match exclude patterns: %s", err)
+			}
+
+			if excluded {
+				if isDir && !hasExclusionPatternInDir(pm, entryPath) {
+					skippedDir = entryPath
+				}
+
+				if !isDir || !hasExclusionPatternInDir(pm, entryPath) {
+					continue
+				}
+			}
+		}
+
+		stat := *entry.stat
+		fs.entries = append(fs.entries, &contextArchiveEntry{stat: &stat, offset: entry.offset})
+	}
+
+	return fs, nil
+}
+
+// followLinks returns the paths of the symlinks and their targets that should be included into the sent files
+func (a *contextArchive) followLinks(followPaths []string) []string {
+	var res []string
+	for _, followPath := range followPaths {
+		followPath = path.Clean(followPath)
+		res = append(res, followPath)
+
+		if entry, ok := a.byPath[followPath]; ok && os.FileMode(entry.stat.Mode)&os.ModeSymlink != 0 {
+			target := entry.stat.Linkname
+			if !path.IsAbs(target) {
+				target = path.Join(path.Dir(followPath), target)
+			}
+			res = append(res, path.Clean(strings.TrimPrefix(target, "/")))
+		}
+	}
+
+	return res
+}
+
+// matchIncludePatterns reports whether the path matches any of the patterns and whether the match is partial:
+// the partial match of a directory means that only some of its descendants are included
+func matchIncludePatterns(patterns []string, p string) (bool, bool) {
+	matched, partial := false, true
+	for _, pattern := range patterns {
+		if ok, isPartial := matchIncludePatternPrefix(pattern, p); ok {
+			matched = true
+			if !isPartial {
+				partial = false
+				break
+			}
+		}
+	}
+
+	return matched, partial
+}
+
+func matchIncludePatternPrefix(pattern, p string) (bool, bool) {
+	depth := strings.Count(p, "/")
+
+	partial := false
+	if patternParts := strings.Split(pattern, "/"); len(patternParts) > depth+1 {
+		pattern = strings.Join(patternParts[:depth+1], "/")
+		partial = true
+	}
+
+	matched, _ := path.Match(pattern, p)
+	return matched, partial
+}
+
+// hasExclusionPatternInDir reports whether some of the directory descendants are re-included by the !pattern
+func hasExclusionPatternInDir(pm *fileutils.PatternMatcher, dir string) bool {
+	if !pm.Exclusions() {
+		return false
+	}
+
+	for _, pattern := range pm.Patterns() {
+		if pattern.Exclusion() && strings.HasPrefix(pattern.String()+"/", dir+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contextArchiveFS implements the fsutil.FS reading the file contents directly from the archive
+type contextArchiveFS struct {
+	archive *contextArchive
+	entries []*contextArchiveEntry
+}
+
+func (fs *contextArchiveFS) Walk(ctx context.Context, fn filepath.WalkFunc) error {
+	for _, entry := range fs.entries {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := fn(entry.stat.Path, &fsutil.StatInfo{Stat: entry.stat}, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fs *contextArchiveFS) Open(p string) (io.ReadCloser, error) {
+	entry, ok := fs.archive.byPath[p]
+	if !ok || !os.FileMode(entry.stat.Mode).IsRegular() {
+		return nil, &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+	}
+
+	f, err := os.Open(fs.archive.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contextArchiveFile{Reader: io.NewSectionReader(f, entry.offset, entry.stat.Size_), Closer: f}, nil
+}
+
+type contextArchiveFile struct {
+	io.Reader
+	io.Closer
+}
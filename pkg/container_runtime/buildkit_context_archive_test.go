@@ -0,0 +1,159 @@
+package container_runtime
+
+import (
+	"archive/tar"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type contextArchiveTestEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+func writeContextArchive(t *testing.T, dir string, entries []contextArchiveTestEntry) string {
+	archivePath := filepath.Join(dir, "context.tar")
+
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for _, entry := range entries {
+		hdr := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Linkname: entry.linkname, Mode: 0644}
+		switch entry.typeflag {
+		case tar.TypeDir:
+			hdr.Mode = 0755
+		case tar.TypeReg:
+			hdr.Size = int64(len(entry.content))
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return archivePath
+}
+
+func walkContextArchiveFS(t *testing.T, fs *contextArchiveFS) []string {
+	var paths []string
+	if err := fs.Walk(context.Background(), func(path string, _ os.FileInfo, _ error) error {
+		paths = append(paths, path)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return paths
+}
+
+func TestContextArchive(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-context-archive-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := writeContextArchive(t, tmpDir, []contextArchiveTestEntry{
+		{name: "Dockerfile", typeflag: tar.TypeReg, content: "FROM alpine\n"},
+		{name: "src/app/main.go", typeflag: tar.TypeReg, content: "package main\n"},
+		{name: "src/app/main_test.go", typeflag: tar.TypeReg, content: "package main_test\n"},
+		{name: "src-link", typeflag: tar.TypeSymlink, linkname: "src/app"},
+		{name: "docs/", typeflag: tar.TypeDir},
+		{name: "docs/README.md", typeflag: tar.TypeReg, content: "old"},
+		{name: "docs/README.md", typeflag: tar.TypeReg, content: "new"},
+	})
+
+	archive, err := openContextArchive(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name            string
+		includePatterns []string
+		excludePatterns []string
+		followPaths     []string
+		expectedPaths   []string
+	}{
+		{
+			name:          "all entries with the missing parent directories in the receiver order",
+			expectedPaths: []string{"Dockerfile", "docs", "docs/README.md", "src", "src/app", "src/app/main.go", "src/app/main_test.go", "src-link"},
+		},
+		{
+			name:            "include patterns",
+			includePatterns: []string{"src/app/main.go", "Dockerfile"},
+			expectedPaths:   []string{"Dockerfile", "src", "src/app", "src/app/main.go"},
+		},
+		{
+			name:            "exclude patterns",
+			excludePatterns: []string{"docs", "**/*_test.go"},
+			expectedPaths:   []string{"Dockerfile", "src", "src/app", "src/app/main.go", "src-link"},
+		},
+		{
+			name:            "exclusion pattern in the excluded directory",
+			excludePatterns: []string{"src", "!src/app/main.go"},
+			expectedPaths:   []string{"Dockerfile", "docs", "docs/README.md", "src", "src/app", "src/app/main.go", "src-link"},
+		},
+		{
+			name:            "follow paths",
+			includePatterns: []string{"Dockerfile"},
+			followPaths:     []string{"src-link"},
+			expectedPaths:   []string{"Dockerfile", "src", "src/app", "src/app/main.go", "src/app/main_test.go", "src-link"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs, err := archive.filter(test.includePatterns, test.excludePatterns, test.followPaths)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if paths := walkContextArchiveFS(t, fs); !reflect.DeepEqual(paths, test.expectedPaths) {
+				t.Errorf("expected paths %v, got %v", test.expectedPaths, paths)
+			}
+		})
+	}
+
+	fs, err := archive.filter(nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expectedContent := range map[string]string{"Dockerfile": "FROM alpine\n", "docs/README.md": "new"} {
+		rc, err := fs.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := ioutil.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != expectedContent {
+			t.Errorf("expected %s content %q, got %q", path, expectedContent, content)
+		}
+	}
+
+	if _, err := fs.Open("src"); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error for the directory, got %v", err)
+	}
+}
@@ -13,6 +13,8 @@ import (
 )
 
 type ContainerRuntime interface {
+	GetImageInspect(ctx context.Context, ref string) (*types.ImageInspect, error)
+	PullImage(ctx context.Context, ref string) error
 	RefreshImageObject(ctx context.Context, img Image) error
 	PullImageFromRegistry(ctx context.Context, img Image) error
 	RenameImage(ctx context.Context, img Image, newImageName string, removeOldName bool) error
@@ -22,7 +24,6 @@ type ContainerRuntime interface {
 
 type LocalDockerServerRuntime struct{}
 
-// GetImageInspect only available for LocalDockerServerRuntime
 func (runtime *LocalDockerServerRuntime) GetImageInspect(ctx context.Context, ref string) (*types.ImageInspect, error) {
 	inspect, err := docker.ImageInspect(ctx, ref)
 	if client.IsErrNotFound(err) {
@@ -31,7 +32,6 @@ func (runtime *LocalDockerServerRuntime) GetImageInspect(ctx context.Context, re
 	return inspect, err
 }
 
-// PullImage only available for LocalDockerServerRuntime
 func (runtime *LocalDockerServerRuntime) PullImage(ctx context.Context, ref string) error {
 	if err := docker.CliPull(ctx, ref); err != nil {
 		return fmt.Errorf("unable to pull image %s: %s", ref, err)
@@ -162,11 +162,3 @@ func (runtime *LocalDockerServerRuntime) TagImageByName(ctx context.Context, img
 func (runtime *LocalDockerServerRuntime) String() string {
 	return "local-docker-server"
 }
-
-type LocalHostRuntime struct {
-	ContainerRuntime // TODO: kaniko-like builds
-}
-
-func (runtime *LocalHostRuntime) String() string {
-	return "localhost"
-}
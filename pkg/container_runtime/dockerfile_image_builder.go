@@ -12,14 +12,19 @@ import (
 )
 
 type DockerfileImageBuilder struct {
-	temporalId      string
-	isBuilt         bool
-	buildArgs       []string
-	filePathToStdin string
+	containerRuntime ContainerRuntime
+	temporalId       string
+	isBuilt          bool
+	buildArgs        []string
+	filePathToStdin  string
+	buildkitAddress  string
+
+	secrets            map[string][]byte
+	secretValuesToMask []string
 }
 
-func NewDockerfileImageBuilder() *DockerfileImageBuilder {
-	return &DockerfileImageBuilder{temporalId: uuid.New().String()}
+func NewDockerfileImageBuilder(containerRuntime ContainerRuntime) *DockerfileImageBuilder {
+	return &DockerfileImageBuilder{containerRuntime: containerRuntime, temporalId: uuid.New().String()}
 }
 
 func (b *DockerfileImageBuilder) GetBuiltId() string {
@@ -37,7 +42,42 @@ func (b *DockerfileImageBuilder) SetFilePathToStdin(path string) {
 	b.filePathToStdin = path
 }
 
+// UseBuildkit switches the builder from the legacy docker builder to the buildkitd daemon available by the address
+func (b *DockerfileImageBuilder) UseBuildkit(address string) {
+	b.buildkitAddress = address
+}
+
+// AddSecret makes the secret available for the RUN --mount=type=secret,id=ID instructions, the secret requires buildkit
+func (b *DockerfileImageBuilder) AddSecret(id string, data []byte) {
+	if b.secrets == nil {
+		b.secrets = map[string][]byte{}
+	}
+	b.secrets[id] = data
+}
+
+func (b *DockerfileImageBuilder) AddSecretValuesToMask(values ...string) {
+	b.secretValuesToMask = append(b.secretValuesToMask, values...)
+}
+
 func (b *DockerfileImageBuilder) Build(ctx context.Context) error {
+	if b.buildkitAddress != "" {
+		if err := b.buildWithBuildkit(ctx); err != nil {
+			return err
+		}
+
+		b.isBuilt = true
+
+		return nil
+	}
+
+	if len(b.secrets) != 0 {
+		return fmt.Errorf("dockerfile image build with secrets requires buildkit: specify --buildkit-addr option ($WERF_BUILDKIT_ADDR)")
+	}
+
+	if _, ok := b.containerRuntime.(*LocalHostRuntime); ok {
+		return fmt.Errorf("dockerfile image build with %s container runtime requires buildkit: specify --buildkit-addr option ($WERF_BUILDKIT_ADDR)", b.containerRuntime.String())
+	}
+
 	buildArgs := append(b.buildArgs, fmt.Sprintf("--tag=%s", b.temporalId))
 
 	if b.filePathToStdin != "" {
@@ -72,6 +112,13 @@ func (b *DockerfileImageBuilder) Build(ctx context.Context) error {
 }
 
 func (b *DockerfileImageBuilder) Cleanup(ctx context.Context) error {
+	if localHostRuntime, ok := b.containerRuntime.(*LocalHostRuntime); ok {
+		if err := localHostRuntime.Rmi(ctx, b.temporalId); err != nil {
+			return fmt.Errorf("unable to remove temporal dockerfile image %q: %s", b.temporalId, err)
+		}
+		return nil
+	}
+
 	if err := docker.CliRmi(ctx, b.temporalId, "--force"); err != nil {
 		return fmt.Errorf("unable to remove temporal dockerfile image %q: %s", b.temporalId, err)
 	}
@@ -0,0 +1,214 @@
+package container_runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/auth/authprovider"
+	"github.com/moby/buildkit/session/secrets/secretsprovider"
+	"github.com/moby/buildkit/session/sshforward/sshprovider"
+	"github.com/moby/buildkit/util/entitlements"
+	"github.com/moby/buildkit/util/progress/progressui"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/util/secretvalues"
+	"github.com/werf/werf/pkg/werf"
+)
+
+const (
+	buildkitDockerfileFrontend = "dockerfile.v0"
+	buildkitContextDirName     = "context"
+	buildkitDockerfileDirName  = "dockerfile"
+)
+
+func (b *DockerfileImageBuilder) buildWithBuildkit(ctx context.Context) error {
+	if b.filePathToStdin == "" {
+		return fmt.Errorf("buildkit build requires build context archive")
+	}
+
+	contextProvider, err := newBuildContextArchiveProvider(b.filePathToStdin, buildkitContextDirName, buildkitDockerfileDirName)
+	if err != nil {
+		return fmt.Errorf("unable to open build context archive %s: %s", b.filePathToStdin, err)
+	}
+
+	solveOpt, err := newBuildkitSolveOpt(b.buildArgs)
+	if err != nil {
+		return err
+	}
+
+	// The context is not extracted: buildkitd requests only the files it needs through the session
+	solveOpt.Session = append(solveOpt.Session, contextProvider)
+
+	if len(b.secrets) != 0 {
+		solveOpt.Session = append(solveOpt.Session, secretsprovider.FromMap(b.secrets))
+	}
+
+	if debugDockerRunCommand() {
+		fmt.Printf("Buildkit solve (%s):\nfrontend %s %v\n", b.buildkitAddress, solveOpt.Frontend, solveOpt.FrontendAttrs)
+	}
+
+	c, err := client.New(ctx, b.buildkitAddress, client.WithFailFast())
+	if err != nil {
+		return fmt.Errorf("unable to connect to buildkitd %s: %s", b.buildkitAddress, err)
+	}
+	defer c.Close()
+
+	// Result image is exported in docker archive format and loaded into the container runtime by the temporal id,
+	// so the rest of the build process works with it the same way as with the image built by the legacy builder
+	pr, pw := io.Pipe()
+	solveOpt.Exports = []client.ExportEntry{
+		{
+			Type:  client.ExporterDocker,
+			Attrs: map[string]string{"name": b.temporalId},
+			Output: func(map[string]string) (io.WriteCloser, error) {
+				return pw, nil
+			},
+		},
+	}
+
+	statusCh := make(chan *client.SolveStatus)
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		_, err := c.Solve(egCtx, nil, *solveOpt, statusCh)
+		if err != nil {
+			_ = pw.CloseWithError(err)
+			return fmt.Errorf("buildkit solve failed: %s", err)
+		}
+
+		return pw.Close()
+	})
+
+	eg.Go(func() error {
+		if err := b.loadBuildkitResult(egCtx, pr); err != nil {
+			_ = pr.CloseWithError(err)
+			return fmt.Errorf("unable to load built image into %s: %s", b.containerRuntime.String(), err)
+		}
+
+		return nil
+	})
+
+	eg.Go(func() error {
+		out := secretvalues.NewMaskWriter(logboek.Context(ctx).ProxyOutStream(), b.secretValuesToMask)
+		if err := progressui.DisplaySolveStatus(context.Background(), "", nil, out, statusCh); err != nil {
+			return err
+		}
+
+		return out.Flush()
+	})
+
+	return eg.Wait()
+}
+
+func (b *DockerfileImageBuilder) loadBuildkitResult(ctx context.Context, r io.Reader) error {
+	localHostRuntime, ok := b.containerRuntime.(*LocalHostRuntime)
+	if !ok {
+		return docker.ImageLoad(ctx, r)
+	}
+
+	f, err := ioutil.TempFile(werf.GetTmpDir(), "buildkit-result-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return localHostRuntime.LoadDockerArchive(ctx, f.Name(), b.temporalId)
+}
+
+// newBuildkitSolveOpt converts docker build options, prepared for the legacy builder, into the buildkit dockerfile frontend options
+func newBuildkitSolveOpt(dockerBuildArgs []string) (*client.SolveOpt, error) {
+	frontendAttrs := map[string]string{"filename": "Dockerfile"}
+	sessionAttachables := []session.Attachable{authprovider.NewDockerAuthProvider(os.Stderr)}
+
+	var allowedEntitlements []entitlements.Entitlement
+	var addHosts []string
+
+	for _, arg := range dockerBuildArgs {
+		parts := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unsupported docker build option %q for buildkit", arg)
+		}
+		key, value := parts[0], parts[1]
+
+		switch key {
+		case "file":
+			frontendAttrs["filename"] = value
+		case "target":
+			frontendAttrs["target"] = value
+		case "build-arg":
+			kv := strings.SplitN(value, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid build-arg %q: expected KEY=VALUE", value)
+			}
+			frontendAttrs["build-arg:"+kv[0]] = kv[1]
+		case "label":
+			kv := strings.SplitN(value, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid label %q: expected KEY=VALUE", value)
+			}
+			frontendAttrs["label:"+kv[0]] = kv[1]
+		case "add-host":
+			kv := strings.SplitN(value, ":", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid add-host %q: expected HOST:IP", value)
+			}
+			addHosts = append(addHosts, fmt.Sprintf("%s=%s", kv[0], kv[1]))
+		case "network":
+			frontendAttrs["force-network-mode"] = value
+			if value == "host" {
+				allowedEntitlements = append(allowedEntitlements, entitlements.EntitlementNetworkHost)
+			}
+		case "ssh":
+			sshProvider, err := newBuildkitSSHAgentProvider(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ssh %q: %s", value, err)
+			}
+			sessionAttachables = append(sessionAttachables, sshProvider)
+		case "platform":
+			frontendAttrs["platform"] = value
+		default:
+			return nil, fmt.Errorf("unsupported docker build option %q for buildkit", arg)
+		}
+	}
+
+	if len(addHosts) != 0 {
+		frontendAttrs["add-hosts"] = strings.Join(addHosts, ",")
+	}
+
+	return &client.SolveOpt{
+		Frontend:            buildkitDockerfileFrontend,
+		FrontendAttrs:       frontendAttrs,
+		Session:             sessionAttachables,
+		AllowedEntitlements: allowedEntitlements,
+	}, nil
+}
+
+// newBuildkitSSHAgentProvider accepts the docker --ssh format: default|<id>[=<socket>|<key>[,<key>]]
+func newBuildkitSSHAgentProvider(value string) (session.Attachable, error) {
+	parts := strings.SplitN(value, "=", 2)
+
+	config := sshprovider.AgentConfig{ID: parts[0]}
+	if len(parts) == 2 {
+		config.Paths = strings.Split(parts[1], ",")
+	}
+
+	return sshprovider.NewSSHAgentProvider([]sshprovider.AgentConfig{config})
+}
@@ -0,0 +1,94 @@
+package container_runtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/moby/buildkit/util/entitlements"
+)
+
+func TestNewBuildkitSolveOpt(t *testing.T) {
+	solveOpt, err := newBuildkitSolveOpt([]string{
+		"--file=docker/Dockerfile",
+		"--target=production",
+		"--build-arg=VERSION=1.0=rc",
+		"--build-arg=EMPTY=",
+		"--label=werf-stage=dockerfile",
+		"--add-host=db:10.0.0.1",
+		"--add-host=cache:10.0.0.2",
+		"--network=host",
+		"--platform=linux/arm64",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if solveOpt.Frontend != buildkitDockerfileFrontend {
+		t.Errorf("unexpected frontend %q", solveOpt.Frontend)
+	}
+
+	expectedAttrs := map[string]string{
+		"filename":           "docker/Dockerfile",
+		"target":             "production",
+		"build-arg:VERSION":  "1.0=rc",
+		"build-arg:EMPTY":    "",
+		"label:werf-stage":   "dockerfile",
+		"add-hosts":          "db=10.0.0.1,cache=10.0.0.2",
+		"force-network-mode": "host",
+		"platform":           "linux/arm64",
+	}
+	if len(solveOpt.FrontendAttrs) != len(expectedAttrs) {
+		t.Errorf("unexpected frontend attrs %v", solveOpt.FrontendAttrs)
+	}
+	for key, value := range expectedAttrs {
+		if got, ok := solveOpt.FrontendAttrs[key]; !ok || got != value {
+			t.Errorf("expected frontend attr %s=%q, got %q", key, value, got)
+		}
+	}
+
+	if len(solveOpt.AllowedEntitlements) != 1 || solveOpt.AllowedEntitlements[0] != entitlements.EntitlementNetworkHost {
+		t.Errorf("expected network.host entitlement, got %v", solveOpt.AllowedEntitlements)
+	}
+
+	if len(solveOpt.LocalDirs) != 0 {
+		t.Errorf("expected the build context to be served through the session, got local dirs %v", solveOpt.LocalDirs)
+	}
+}
+
+func TestNewBuildkitSolveOpt_Defaults(t *testing.T) {
+	solveOpt, err := newBuildkitSolveOpt(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(solveOpt.FrontendAttrs) != 1 || solveOpt.FrontendAttrs["filename"] != "Dockerfile" {
+		t.Errorf("unexpected frontend attrs %v", solveOpt.FrontendAttrs)
+	}
+
+	if len(solveOpt.AllowedEntitlements) != 0 {
+		t.Errorf("unexpected entitlements %v", solveOpt.AllowedEntitlements)
+	}
+}
+
+func TestNewBuildkitSolveOpt_InvalidOptions(t *testing.T) {
+	for _, test := range []struct {
+		arg           string
+		expectedError string
+	}{
+		{arg: "--squash", expectedError: "unsupported docker build option"},
+		{arg: "--cpu-shares=512", expectedError: "unsupported docker build option"},
+		{arg: "--build-arg=VERSION", expectedError: "invalid build-arg"},
+		{arg: "--label=werf", expectedError: "invalid label"},
+		{arg: "--add-host=db", expectedError: "invalid add-host"},
+	} {
+		_, err := newBuildkitSolveOpt([]string{test.arg})
+		if err == nil {
+			t.Errorf("expected error for %q", test.arg)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), test.expectedError) {
+			t.Errorf("expected error for %q to contain %q, got: %s", test.arg, test.expectedError, err)
+		}
+	}
+}
@@ -48,6 +48,11 @@ type Container interface {
 
 	AddServiceRunCommands(commands ...string)
 	AddRunCommands(commands ...string)
+	// AddServiceCleanupCommands adds the commands which are run after the user commands to remove the service files before the commit
+	AddServiceCleanupCommands(commands ...string)
+
+	// AddSecretValuesToMask adds the values which are replaced with asterisks in the container run output
+	AddSecretValuesToMask(values ...string)
 
 	RunOptions() ContainerOptions
 	CommitChangeOptions() ContainerOptions
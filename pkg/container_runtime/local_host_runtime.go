@@ -0,0 +1,285 @@
+package container_runtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/oci_layout"
+)
+
+// LocalHostRuntime builds and keeps images without docker server:
+// images are stored in the OCI image layout dir and stapel commands are run by the rootless executor.
+// The executor run by the unprivileged user maps only the root user, so the commands cannot chown the files to other users.
+type LocalHostRuntime struct {
+	Store *oci_layout.Store
+}
+
+func NewLocalHostRuntime(layoutDir string) (*LocalHostRuntime, error) {
+	store, err := oci_layout.NewStore(layoutDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalHostRuntime{Store: store}, nil
+}
+
+func (runtime *LocalHostRuntime) GetImageInspect(ctx context.Context, ref string) (*types.ImageInspect, error) {
+	img, err := runtime.Store.GetImage(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image %s: %s", ref, err)
+	} else if img == nil {
+		return nil, nil
+	}
+
+	return runtime.newImageInspect(ctx, img)
+}
+
+func (runtime *LocalHostRuntime) PullImage(ctx context.Context, ref string) error {
+	if err := runtime.Pull(ctx, ref); err != nil {
+		return fmt.Errorf("unable to pull image %s: %s", ref, err)
+	}
+
+	return nil
+}
+
+func (runtime *LocalHostRuntime) RefreshImageObject(ctx context.Context, img Image) error {
+	dockerImage := img.(*DockerImage)
+
+	if inspect, err := runtime.GetImageInspect(ctx, dockerImage.Image.Name()); err != nil {
+		return err
+	} else {
+		dockerImage.Image.SetInspect(inspect)
+	}
+	return nil
+}
+
+func (runtime *LocalHostRuntime) PullImageFromRegistry(ctx context.Context, img Image) error {
+	dockerImage := img.(*DockerImage)
+
+	if err := runtime.Pull(ctx, dockerImage.Image.Name()); err != nil {
+		return fmt.Errorf("unable to pull image %s: %s", dockerImage.Image.Name(), err)
+	}
+
+	if inspect, err := runtime.GetImageInspect(ctx, dockerImage.Image.Name()); err != nil {
+		return fmt.Errorf("unable to get inspect of image %s: %s", dockerImage.Image.Name(), err)
+	} else {
+		dockerImage.Image.SetInspect(inspect)
+	}
+
+	return nil
+}
+
+func (runtime *LocalHostRuntime) RenameImage(ctx context.Context, img Image, newImageName string, removeOldName bool) error {
+	dockerImage := img.(*DockerImage)
+
+	if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Tagging image %s by name %s", dockerImage.Image.Name(), newImageName)).DoError(func() error {
+		if err := runtime.Tag(ctx, dockerImage.Image.Name(), newImageName); err != nil {
+			return fmt.Errorf("unable to tag image %s by name %s: %s", dockerImage.Image.Name(), newImageName, err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if removeOldName {
+		if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Removing old image tag %s", dockerImage.Image.Name())).DoError(func() error {
+			return runtime.Rmi(ctx, dockerImage.Image.Name())
+		}); err != nil {
+			return err
+		}
+	}
+
+	dockerImage.Image.SetName(newImageName)
+
+	return nil
+}
+
+func (runtime *LocalHostRuntime) RemoveImage(ctx context.Context, img Image) error {
+	dockerImage := img.(*DockerImage)
+
+	return logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Removing image tag %s", dockerImage.Image.Name())).DoError(func() error {
+		return runtime.Rmi(ctx, dockerImage.Image.Name())
+	})
+}
+
+func (runtime *LocalHostRuntime) PushImage(ctx context.Context, img Image) error {
+	dockerImage := img.(*DockerImage)
+
+	return logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Pushing %s", dockerImage.Image.Name())).DoError(func() error {
+		return runtime.Push(ctx, dockerImage.Image.Name())
+	})
+}
+
+func (runtime *LocalHostRuntime) PushBuiltImage(ctx context.Context, img Image) error {
+	dockerImage := img.(*DockerImage)
+
+	if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Tagging built image by name %s", dockerImage.Image.Name())).DoError(func() error {
+		if err := dockerImage.Image.TagBuiltImage(ctx, dockerImage.Image.Name()); err != nil {
+			return fmt.Errorf("unable to tag built image by name %s: %s", dockerImage.Image.Name(), err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return runtime.PushImage(ctx, img)
+}
+
+func (runtime *LocalHostRuntime) TagImageByName(ctx context.Context, img Image) error {
+	dockerImage := img.(*DockerImage)
+
+	if dockerImage.Image.GetBuiltId() != "" {
+		if err := dockerImage.Image.TagBuiltImage(ctx, dockerImage.Image.Name()); err != nil {
+			return fmt.Errorf("unable to tag image %s: %s", dockerImage.Image.Name(), err)
+		}
+	} else {
+		return runtime.RefreshImageObject(ctx, img)
+	}
+
+	return nil
+}
+
+func (runtime *LocalHostRuntime) Pull(ctx context.Context, ref string) error {
+	img, err := docker_registry.API().GetRepoV1Image(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	return runtime.Store.WriteImage(ctx, img, ref)
+}
+
+func (runtime *LocalHostRuntime) Push(ctx context.Context, ref string) error {
+	return runtime.Store.UseImage(ctx, ref, func(img v1.Image) error {
+		return docker_registry.API().WriteRepoV1Image(ctx, ref, img)
+	})
+}
+
+func (runtime *LocalHostRuntime) Tag(ctx context.Context, ref, newRef string) error {
+	return runtime.Store.Tag(ctx, ref, newRef)
+}
+
+func (runtime *LocalHostRuntime) Rmi(ctx context.Context, ref string) error {
+	return runtime.Store.Untag(ctx, ref)
+}
+
+// LoadDockerArchive puts the image from the archive in the docker save format into the store by the specified name
+func (runtime *LocalHostRuntime) LoadDockerArchive(ctx context.Context, archivePath, ref string) error {
+	img, err := tarball.ImageFromPath(archivePath, nil)
+	if err != nil {
+		return fmt.Errorf("unable to read image archive %s: %s", archivePath, err)
+	}
+
+	return runtime.Store.WriteImage(ctx, img, ref)
+}
+
+func (runtime *LocalHostRuntime) String() string {
+	return "localhost"
+}
+
+// newImageInspect fills the docker image inspect fields used by werf, so the images are handled the same way for both runtimes
+func (runtime *LocalHostRuntime) newImageInspect(ctx context.Context, img v1.Image) (*types.ImageInspect, error) {
+	configName, err := img.ConfigName()
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := runtime.Store.GetImageRefs(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	var repoDigests []string
+	for _, ref := range refs {
+		if parsedRef, err := name.ParseReference(ref, name.WeakValidation); err == nil {
+			repoDigests = append(repoDigests, fmt.Sprintf("%s@%s", parsedRef.Context().Name(), digest))
+		}
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	for _, l := range layers {
+		if lSize, err := l.Size(); err != nil {
+			return nil, err
+		} else {
+			size += lSize
+		}
+	}
+
+	var diffIDs []string
+	for _, diffID := range configFile.RootFS.DiffIDs {
+		diffIDs = append(diffIDs, diffID.String())
+	}
+
+	cfg := configFile.Config
+	exposedPorts := nat.PortSet{}
+	for port := range cfg.ExposedPorts {
+		exposedPorts[nat.Port(port)] = struct{}{}
+	}
+
+	var healthcheck *container.HealthConfig
+	if cfg.Healthcheck != nil {
+		healthcheck = &container.HealthConfig{
+			Test:        cfg.Healthcheck.Test,
+			Interval:    cfg.Healthcheck.Interval,
+			Timeout:     cfg.Healthcheck.Timeout,
+			StartPeriod: cfg.Healthcheck.StartPeriod,
+			Retries:     cfg.Healthcheck.Retries,
+		}
+	}
+
+	return &types.ImageInspect{
+		ID:            configName.String(),
+		RepoTags:      refs,
+		RepoDigests:   repoDigests,
+		Created:       configFile.Created.Format(time.RFC3339Nano),
+		Architecture:  configFile.Architecture,
+		Os:            configFile.OS,
+		Size:          size,
+		VirtualSize:   size,
+		DockerVersion: configFile.DockerVersion,
+		Config: &container.Config{
+			Hostname:     cfg.Hostname,
+			Domainname:   cfg.Domainname,
+			User:         cfg.User,
+			ExposedPorts: exposedPorts,
+			Env:          cfg.Env,
+			Cmd:          cfg.Cmd,
+			Healthcheck:  healthcheck,
+			ArgsEscaped:  cfg.ArgsEscaped,
+			Image:        cfg.Image,
+			Volumes:      cfg.Volumes,
+			WorkingDir:   cfg.WorkingDir,
+			Entrypoint:   cfg.Entrypoint,
+			OnBuild:      cfg.OnBuild,
+			Labels:       cfg.Labels,
+			StopSignal:   cfg.StopSignal,
+			Shell:        cfg.Shell,
+		},
+		RootFS: types.RootFS{Type: "layers", Layers: diffIDs},
+	}, nil
+}
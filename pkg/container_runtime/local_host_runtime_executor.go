@@ -0,0 +1,645 @@
+package container_runtime
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/archive"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/werf/lockgate"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/stapel"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// localHostContainer describes the container run by the rootless executor
+type localHostContainer struct {
+	Rootfs  string             `json:"rootfs"`
+	Workdir string             `json:"workdir"`
+	Env     []string           `json:"env"`
+	Args    []string           `json:"args"`
+	Mounts  []localHostMount   `json:"mounts"`
+	Owners  map[string][2]int  `json:"-"`
+	State   map[string]fsEntry `json:"-"`
+
+	SecretValuesToMask []string `json:"-"`
+}
+
+type localHostMount struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"readOnly"`
+}
+
+type fsEntry struct {
+	Mode    os.FileMode
+	Size    int64
+	ModTime time.Time
+	Link    string
+}
+
+// skipped in the container layer diff, these paths are mounted by the executor
+var localHostContainerSystemDirs = []string{"/proc", "/dev", "/sys", "/etc/resolv.conf"}
+
+// RunAndCommit runs the command in the rootfs of fromImageId and saves the changed files as a new image layer.
+// The secret values are replaced with asterisks in the command output.
+// Returns the built image ID.
+func (runtime *LocalHostRuntime) RunAndCommit(ctx context.Context, fromImageId string, runOptions *StageImageContainerOptions, command string, commitChanges []string, secretValuesToMask []string) (string, error) {
+	var imageId string
+	err := runtime.Store.UseImage(ctx, fromImageId, func(fromImg v1.Image) error {
+		var err error
+		imageId, err = runtime.runAndCommit(ctx, fromImg, fromImageId, runOptions, command, commitChanges, secretValuesToMask)
+		return err
+	})
+
+	return imageId, err
+}
+
+func (runtime *LocalHostRuntime) runAndCommit(ctx context.Context, fromImg v1.Image, fromImageId string, runOptions *StageImageContainerOptions, command string, commitChanges []string, secretValuesToMask []string) (string, error) {
+	tmpDir, err := ioutil.TempDir(werf.GetTmpDir(), "localhost-container-")
+	if err != nil {
+		return "", fmt.Errorf("unable to create tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	c, err := runtime.newLocalHostContainer(ctx, fromImg, filepath.Join(tmpDir, "rootfs"), runOptions, command)
+	if err != nil {
+		return "", err
+	}
+	c.SecretValuesToMask = secretValuesToMask
+
+	if debugDockerRunCommand() {
+		fmt.Printf("Localhost container run:\nrootfs %s\nmounts %v\nargs %v\n", c.Rootfs, c.Mounts, c.Args)
+	}
+
+	if err := c.prepareMountpoints(); err != nil {
+		return "", fmt.Errorf("unable to prepare container mountpoints: %s", err)
+	}
+
+	if c.State, err = c.snapshot(); err != nil {
+		return "", fmt.Errorf("unable to snapshot container rootfs: %s", err)
+	}
+
+	if err := runLocalHostContainer(ctx, c, tmpDir); err != nil {
+		if os.Geteuid() != 0 {
+			return "", fmt.Errorf("container run failed: %s\nNOTE: only the root user is mapped into the container run by the unprivileged user, changing the files ownership to other users (chown) is not supported: run werf as root to build such stages", err)
+		}
+		return "", fmt.Errorf("container run failed: %s", err)
+	}
+
+	layerPath := filepath.Join(tmpDir, "layer.tar")
+	if err := c.writeDiff(layerPath); err != nil {
+		return "", fmt.Errorf("unable to prepare container layer: %s", err)
+	}
+
+	layer, err := tarball.LayerFromFile(layerPath)
+	if err != nil {
+		return "", err
+	}
+
+	img, err := mutate.Append(fromImg, mutate.Addendum{
+		Layer: layer,
+		History: v1.History{
+			Created:   v1.Time{Time: time.Now()},
+			CreatedBy: "werf localhost runtime",
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return "", err
+	}
+	configFile = configFile.DeepCopy()
+
+	if err := applyCommitChanges(&configFile.Config, commitChanges); err != nil {
+		return "", err
+	}
+	configFile.Config.Image = fromImageId
+	configFile.Created = v1.Time{Time: time.Now()}
+
+	img, err = mutate.ConfigFile(img, configFile)
+	if err != nil {
+		return "", err
+	}
+
+	if err := runtime.Store.WriteImage(ctx, img); err != nil {
+		return "", err
+	}
+
+	configName, err := img.ConfigName()
+	if err != nil {
+		return "", err
+	}
+
+	return configName.String(), nil
+}
+
+func (runtime *LocalHostRuntime) newLocalHostContainer(ctx context.Context, fromImg v1.Image, rootfs string, runOptions *StageImageContainerOptions, command string) (*localHostContainer, error) {
+	configFile, err := fromImg.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &localHostContainer{Rootfs: rootfs, Workdir: "/"}
+
+	if runOptions.Workdir != "" {
+		c.Workdir = runOptions.Workdir
+	} else if configFile.Config.WorkingDir != "" {
+		c.Workdir = configFile.Config.WorkingDir
+	}
+
+	if runOptions.User != "" && runOptions.User != "0:0" && runOptions.User != "0" && runOptions.User != "root" {
+		return nil, fmt.Errorf("localhost container runtime supports only root user, got %q", runOptions.User)
+	}
+
+	env := map[string]string{}
+	for _, kv := range configFile.Config.Env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	for k, v := range runOptions.Env {
+		env[k] = v
+	}
+	if _, hasPath := env["PATH"]; !hasPath {
+		env["PATH"] = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	}
+	env["COLUMNS"] = fmt.Sprintf("%d", logboek.Context(ctx).Streams().ContentWidth())
+	for k, v := range env {
+		c.Env = append(c.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(c.Env)
+
+	if runOptions.Entrypoint != "" {
+		c.Args = []string{runOptions.Entrypoint}
+	} else {
+		c.Args = append([]string{}, configFile.Config.Entrypoint...)
+	}
+	if len(c.Args) == 0 {
+		return nil, fmt.Errorf("entrypoint is not specified")
+	}
+	c.Args = append(c.Args, "-ec", command)
+
+	for _, volume := range runOptions.Volume {
+		mount, err := parseLocalHostMount(volume)
+		if err != nil {
+			return nil, err
+		}
+		c.Mounts = append(c.Mounts, mount)
+	}
+
+	for _, volumesFrom := range runOptions.VolumesFrom {
+		if volumesFrom != stapel.ContainerName() {
+			return nil, fmt.Errorf("volumes from container %s are not supported by localhost container runtime", volumesFrom)
+		}
+
+		toolchainDir, err := runtime.prepareStapelToolchain(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to prepare stapel toolchain: %s", err)
+		}
+		c.Mounts = append(c.Mounts, localHostMount{Source: toolchainDir, Target: stapel.VolumePath(), ReadOnly: true})
+	}
+
+	if err := logboek.Context(ctx).Info().LogProcess("Preparing container rootfs").DoError(func() error {
+		c.Owners, err = extractImageRootfs(fromImg, rootfs, "")
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("unable to extract image rootfs: %s", err)
+	}
+
+	return c, nil
+}
+
+func parseLocalHostMount(volume string) (localHostMount, error) {
+	parts := strings.Split(volume, ":")
+	switch {
+	case len(parts) == 2:
+		return localHostMount{Source: parts[0], Target: parts[1]}, nil
+	case len(parts) == 3 && (parts[2] == "ro" || parts[2] == "rw"):
+		return localHostMount{Source: parts[0], Target: parts[1], ReadOnly: parts[2] == "ro"}, nil
+	default:
+		return localHostMount{}, fmt.Errorf("unsupported volume %q: expected SOURCE:TARGET[:ro|rw]", volume)
+	}
+}
+
+// prepareStapelToolchain extracts stapel toolchain from the stapel image once and shares it between builds,
+// the same way docker server runtime shares the stapel container volume
+func (runtime *LocalHostRuntime) prepareStapelToolchain(ctx context.Context) (string, error) {
+	toolchainDir := filepath.Join(werf.GetLocalCacheDir(), "localhost_runtime", "stapel", util.Sha256Hash(stapel.ImageName()))
+	doneFile := toolchainDir + ".done"
+
+	if exists, err := util.RegularFileExists(doneFile); err != nil {
+		return "", err
+	} else if exists {
+		return toolchainDir, nil
+	}
+
+	lockName := fmt.Sprintf("localhost_runtime.stapel.%s", stapel.ImageName())
+	return toolchainDir, werf.WithHostLock(ctx, lockName, lockgate.AcquireOptions{Timeout: time.Second * 600}, func() error {
+		if exists, err := util.RegularFileExists(doneFile); err != nil || exists {
+			return err
+		}
+
+		return logboek.Context(ctx).LogProcess("Preparing stapel toolchain %s", stapel.ImageName()).DoError(func() error {
+			if img, err := runtime.Store.GetImage(ctx, stapel.ImageName()); err != nil {
+				return err
+			} else if img == nil {
+				if err := runtime.PullImage(ctx, stapel.ImageName()); err != nil {
+					return err
+				}
+			}
+
+			if err := os.RemoveAll(toolchainDir); err != nil {
+				return err
+			}
+
+			if err := runtime.Store.UseImage(ctx, stapel.ImageName(), func(img v1.Image) error {
+				_, err := extractImageRootfs(img, toolchainDir, strings.TrimPrefix(stapel.VolumePath(), "/"))
+				return err
+			}); err != nil {
+				return err
+			}
+
+			return ioutil.WriteFile(doneFile, nil, 0644)
+		})
+	})
+}
+
+// extractImageRootfs unpacks the flattened image filesystem (or only its subdir) into the dir.
+// Device nodes are skipped because the unprivileged user cannot create them, /dev is mounted from the host anyway.
+// Returns the original files ownership, which is restored in the container layer for unchanged owners.
+func extractImageRootfs(img v1.Image, dir, subdir string) (map[string][2]int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	rc := mutate.Extract(img)
+	defer rc.Close()
+
+	owners := map[string][2]int{}
+	pr, pw := io.Pipe()
+
+	go func() {
+		tr := tar.NewReader(rc)
+		tw := tar.NewWriter(pw)
+
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+
+			switch hdr.Typeflag {
+			case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+				continue
+			}
+
+			name := filepath.Clean("/" + hdr.Name)
+			if subdir != "" {
+				prefix := filepath.Clean("/" + subdir)
+				if name != prefix && !strings.HasPrefix(name, prefix+"/") {
+					continue
+				}
+				name = "/" + strings.TrimPrefix(strings.TrimPrefix(name, prefix), "/")
+				hdr.Name = strings.TrimPrefix(name, "/")
+				if hdr.Typeflag == tar.TypeLink {
+					hdr.Linkname = strings.TrimPrefix(strings.TrimPrefix(filepath.Clean("/"+hdr.Linkname), prefix), "/")
+				}
+				if hdr.Name == "" {
+					continue
+				}
+			}
+			owners[name] = [2]int{hdr.Uid, hdr.Gid}
+
+			if err := tw.WriteHeader(hdr); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+		}
+
+		_ = pw.CloseWithError(tw.Close())
+	}()
+
+	if err := archive.Untar(pr, dir, &archive.TarOptions{NoLchown: os.Geteuid() != 0}); err != nil {
+		_ = pr.CloseWithError(err)
+		return nil, err
+	}
+
+	return owners, nil
+}
+
+// localHostSystemMounts returns the host paths mounted into each container by the executor
+func localHostSystemMounts() []localHostMount {
+	mounts := []localHostMount{
+		{Source: "/dev", Target: "/dev"},
+		{Source: "/sys", Target: "/sys"},
+	}
+
+	if _, err := os.Stat("/etc/resolv.conf"); err == nil {
+		mounts = append(mounts, localHostMount{Source: "/etc/resolv.conf", Target: "/etc/resolv.conf", ReadOnly: true})
+	}
+
+	return mounts
+}
+
+// prepareMountpoints creates the mount targets with the parent dirs in the rootfs before the snapshot,
+// so that the mountpoints created for the executor do not get into the container layer
+func (c *localHostContainer) prepareMountpoints() error {
+	if err := os.MkdirAll(filepath.Join(c.Rootfs, "proc"), 0555); err != nil {
+		return err
+	}
+
+	for _, m := range append(localHostSystemMounts(), c.Mounts...) {
+		target := filepath.Join(c.Rootfs, m.Target)
+
+		info, err := os.Stat(m.Source)
+		if err != nil {
+			return fmt.Errorf("bad mount source %s: %s", m.Source, err)
+		}
+
+		if info.IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if f, err := os.OpenFile(target, os.O_CREATE, 0644); err != nil {
+			return err
+		} else {
+			f.Close()
+		}
+	}
+
+	return nil
+}
+
+func (c *localHostContainer) isExcluded(path string) bool {
+	for _, dir := range localHostContainerSystemDirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+
+	for _, mount := range c.Mounts {
+		target := filepath.Clean(mount.Target)
+		if path == target || strings.HasPrefix(path, target+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *localHostContainer) snapshot() (map[string]fsEntry, error) {
+	state := map[string]fsEntry{}
+
+	err := filepath.Walk(c.Rootfs, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := "/" + strings.TrimPrefix(strings.TrimPrefix(path, c.Rootfs), "/")
+		if name == "/" {
+			return nil
+		}
+
+		if c.isExcluded(name) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		entry := fsEntry{Mode: info.Mode(), Size: info.Size(), ModTime: info.ModTime()}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if entry.Link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		state[name] = entry
+
+		return nil
+	})
+
+	return state, err
+}
+
+// writeDiff writes changes of the container rootfs since the snapshot as a layer tar with whiteouts for removed files
+func (c *localHostContainer) writeDiff(layerPath string) error {
+	newState, err := c.snapshot()
+	if err != nil {
+		return err
+	}
+
+	var changed, removed []string
+	for name, entry := range newState {
+		if oldEntry, exists := c.State[name]; !exists || oldEntry != entry {
+			changed = append(changed, name)
+		}
+	}
+	for name := range c.State {
+		if _, exists := newState[name]; !exists {
+			if _, parentExists := newState[filepath.Dir(name)]; parentExists || filepath.Dir(name) == "/" {
+				removed = append(removed, name)
+			}
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+
+	f, err := os.Create(layerPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+
+	for _, name := range removed {
+		hdr := &tar.Header{
+			Name:     strings.TrimPrefix(filepath.Join(filepath.Dir(name), ".wh."+filepath.Base(name)), "/"),
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range changed {
+		entry := newState[name]
+		path := filepath.Join(c.Rootfs, name)
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, entry.Link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = strings.TrimPrefix(name, "/")
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uname, hdr.Gname = "", ""
+		// the files of the container run by the unprivileged user are owned by the host user mapped to root,
+		// the original ownership of the image files is restored
+		if os.Geteuid() != 0 {
+			hdr.Uid, hdr.Gid = 0, 0
+			if owner, exists := c.Owners[name]; exists {
+				hdr.Uid, hdr.Gid = owner[0], owner[1]
+			}
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if hdr.Typeflag == tar.TypeReg {
+			if err := copyFileInto(tw, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tw.Close()
+}
+
+func copyFileInto(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// applyCommitChanges applies dockerfile instructions, prepared the same way as for the docker commit, to the image config
+func applyCommitChanges(config *v1.Config, changes []string) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	result, err := parser.Parse(strings.NewReader(strings.Join(changes, "\n")))
+	if err != nil {
+		return fmt.Errorf("unable to parse commit changes: %s", err)
+	}
+
+	for _, node := range result.AST.Children {
+		instruction, err := instructions.ParseInstruction(node)
+		if err != nil {
+			return fmt.Errorf("unable to parse commit change %q: %s", node.Original, err)
+		}
+
+		switch cmd := instruction.(type) {
+		case *instructions.EnvCommand:
+			for _, kv := range cmd.Env {
+				config.Env = setEnv(config.Env, kv.Key, kv.Value)
+			}
+		case *instructions.LabelCommand:
+			if config.Labels == nil {
+				config.Labels = map[string]string{}
+			}
+			for _, kv := range cmd.Labels {
+				config.Labels[kv.Key] = kv.Value
+			}
+		case *instructions.ExposeCommand:
+			if config.ExposedPorts == nil {
+				config.ExposedPorts = map[string]struct{}{}
+			}
+			for _, port := range cmd.Ports {
+				if !strings.Contains(port, "/") {
+					port += "/tcp"
+				}
+				config.ExposedPorts[port] = struct{}{}
+			}
+		case *instructions.VolumeCommand:
+			if config.Volumes == nil {
+				config.Volumes = map[string]struct{}{}
+			}
+			for _, volume := range cmd.Volumes {
+				config.Volumes[volume] = struct{}{}
+			}
+		case *instructions.WorkdirCommand:
+			config.WorkingDir = cmd.Path
+		case *instructions.UserCommand:
+			config.User = cmd.User
+		case *instructions.CmdCommand:
+			config.Cmd = shellDependantCmdLine(cmd.ShellDependantCmdLine, config.Shell)
+		case *instructions.EntrypointCommand:
+			config.Entrypoint = shellDependantCmdLine(cmd.ShellDependantCmdLine, config.Shell)
+			if len(config.Entrypoint) == 1 && config.Entrypoint[0] == "" {
+				config.Entrypoint = nil
+			}
+		case *instructions.HealthCheckCommand:
+			config.Healthcheck = &v1.HealthConfig{
+				Test:        cmd.Health.Test,
+				Interval:    cmd.Health.Interval,
+				Timeout:     cmd.Health.Timeout,
+				StartPeriod: cmd.Health.StartPeriod,
+				Retries:     cmd.Health.Retries,
+			}
+		default:
+			return fmt.Errorf("unsupported commit change %q", node.Original)
+		}
+	}
+
+	return nil
+}
+
+func shellDependantCmdLine(cmdLine instructions.ShellDependantCmdLine, shell []string) []string {
+	if !cmdLine.PrependShell {
+		return cmdLine.CmdLine
+	}
+
+	if len(shell) == 0 {
+		shell = []string{"/bin/sh", "-c"}
+	}
+
+	return append(append([]string{}, shell...), strings.Join(cmdLine.CmdLine, " "))
+}
+
+func setEnv(env []string, key, value string) []string {
+	for ind, kv := range env {
+		if strings.SplitN(kv, "=", 2)[0] == key {
+			env[ind] = fmt.Sprintf("%s=%s", key, value)
+			return env
+		}
+	}
+
+	return append(env, fmt.Sprintf("%s=%s", key, value))
+}
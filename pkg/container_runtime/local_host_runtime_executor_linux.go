@@ -0,0 +1,153 @@
+// +build linux
+
+package container_runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/docker/docker/pkg/reexec"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/util/secretvalues"
+)
+
+const (
+	localHostContainerInitName      = "werf-localhost-container-init"
+	localHostContainerConfigEnvName = "WERF_LOCALHOST_CONTAINER_CONFIG"
+)
+
+func init() {
+	reexec.Register(localHostContainerInitName, localHostContainerInit)
+}
+
+// runLocalHostContainer starts the werf binary itself in the new user, mount and pid namespaces,
+// the child prepares mounts, chroots into the container rootfs and executes the container command.
+// Only the current user is mapped to root in the user namespace of the unprivileged user,
+// so the files cannot be chowned to other users in such container
+func runLocalHostContainer(ctx context.Context, c *localHostContainer, tmpDir string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	configPath := filepath.Join(tmpDir, "container.json")
+	if err := ioutil.WriteFile(configPath, data, 0600); err != nil {
+		return err
+	}
+
+	cmd := reexec.Command(localHostContainerInitName)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", localHostContainerConfigEnvName, configPath))
+	stdout := secretvalues.NewMaskWriter(logboek.Context(ctx).ProxyOutStream(), c.SecretValuesToMask)
+	stderr := secretvalues.NewMaskWriter(logboek.Context(ctx).ProxyErrStream(), c.SecretValuesToMask)
+	defer stdout.Flush()
+	defer stderr.Flush()
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+
+	// unprivileged user is mapped to root in the new user namespace
+	if os.Geteuid() != 0 {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}}
+		cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to start container process (unprivileged user namespaces should be enabled): %s", err)
+	}
+
+	waitCh := make(chan error, 1)
+	go func() { waitCh <- cmd.Wait() }()
+
+	select {
+	case err := <-waitCh:
+		return err
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-waitCh
+		return ctx.Err()
+	}
+}
+
+func localHostContainerInit() {
+	if err := doLocalHostContainerInit(); err != nil {
+		fmt.Fprintf(os.Stderr, "werf localhost container init failed: %s\n", err)
+		os.Exit(125)
+	}
+}
+
+func doLocalHostContainerInit() error {
+	data, err := ioutil.ReadFile(os.Getenv(localHostContainerConfigEnvName))
+	if err != nil {
+		return err
+	}
+
+	var c localHostContainer
+	if err := json.Unmarshal(data, &c); err != nil {
+		return err
+	}
+
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("unable to make mounts private: %s", err)
+	}
+
+	// the mountpoints are prepared by the parent before the rootfs snapshot
+	for _, m := range append(localHostSystemMounts(), c.Mounts...) {
+		if err := bindMount(c.Rootfs, m); err != nil {
+			return err
+		}
+	}
+
+	procDir := filepath.Join(c.Rootfs, "proc")
+	if err := syscall.Mount("proc", procDir, "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("unable to mount proc: %s", err)
+	}
+
+	if err := syscall.Chroot(c.Rootfs); err != nil {
+		return fmt.Errorf("chroot failed: %s", err)
+	}
+
+	if err := os.MkdirAll(c.Workdir, 0755); err != nil {
+		return err
+	}
+	if err := os.Chdir(c.Workdir); err != nil {
+		return err
+	}
+
+	return syscall.Exec(c.Args[0], c.Args, c.Env)
+}
+
+func bindMount(rootfs string, m localHostMount) error {
+	target := filepath.Join(rootfs, m.Target)
+
+	if err := syscall.Mount(m.Source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("unable to mount %s to %s: %s", m.Source, m.Target, err)
+	}
+
+	if m.ReadOnly {
+		// locked flags of the source mount should be preserved, otherwise remount in the user namespace is not permitted
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(target, &st); err != nil {
+			return err
+		}
+		lockedFlags := uintptr(st.Flags) & (syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC | syscall.MS_NOATIME | syscall.MS_NODIRATIME | syscall.MS_RELATIME)
+
+		if err := syscall.Mount("", target, "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY|lockedFlags, ""); err != nil {
+			return fmt.Errorf("unable to remount %s read-only: %s", m.Target, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package container_runtime
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+func newLocalHostContainerTestImage(t *testing.T, config v1.Config) v1.Image {
+	img, err := mutate.Config(empty.Image, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+func TestNewLocalHostContainer(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-localhost-container-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	img := newLocalHostContainerTestImage(t, v1.Config{
+		Entrypoint: []string{"/bin/bash"},
+		WorkingDir: "/app",
+		Env:        []string{"PATH=/opt/bin", "MODE=image", "IMAGE_ONLY=1"},
+	})
+
+	runtime := &LocalHostRuntime{}
+	c, err := runtime.newLocalHostContainer(context.Background(), img, filepath.Join(tmpDir, "rootfs"), &StageImageContainerOptions{
+		Env:    map[string]string{"MODE": "stage"},
+		Volume: []string{"/host/cache:/cache", "/host/src:/src:ro"},
+	}, "echo hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Workdir != "/app" {
+		t.Errorf("expected workdir from image config, got %q", c.Workdir)
+	}
+
+	if expected := []string{"/bin/bash", "-ec", "echo hello"}; !reflect.DeepEqual(c.Args, expected) {
+		t.Errorf("expected args %v, got %v", expected, c.Args)
+	}
+
+	var env []string
+	for _, kv := range c.Env {
+		if !strings.HasPrefix(kv, "COLUMNS=") {
+			env = append(env, kv)
+		}
+	}
+	if expected := []string{"IMAGE_ONLY=1", "MODE=stage", "PATH=/opt/bin"}; !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected env %v, got %v", expected, env)
+	}
+
+	expectedMounts := []localHostMount{
+		{Source: "/host/cache", Target: "/cache"},
+		{Source: "/host/src", Target: "/src", ReadOnly: true},
+	}
+	if !reflect.DeepEqual(c.Mounts, expectedMounts) {
+		t.Errorf("expected mounts %v, got %v", expectedMounts, c.Mounts)
+	}
+}
+
+func TestNewLocalHostContainer_RunOptionsOverrideImageConfig(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-localhost-container-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	img := newLocalHostContainerTestImage(t, v1.Config{Entrypoint: []string{"/bin/bash"}, WorkingDir: "/app"})
+
+	runtime := &LocalHostRuntime{}
+	c, err := runtime.newLocalHostContainer(context.Background(), img, filepath.Join(tmpDir, "rootfs"), &StageImageContainerOptions{
+		Workdir:    "/build",
+		Entrypoint: "/bin/sh",
+		User:       "root",
+	}, "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Workdir != "/build" {
+		t.Errorf("expected workdir from run options, got %q", c.Workdir)
+	}
+
+	if expected := []string{"/bin/sh", "-ec", "true"}; !reflect.DeepEqual(c.Args, expected) {
+		t.Errorf("expected args %v, got %v", expected, c.Args)
+	}
+
+	var hasDefaultPath bool
+	for _, kv := range c.Env {
+		if kv == "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin" {
+			hasDefaultPath = true
+		}
+	}
+	if !hasDefaultPath {
+		t.Errorf("expected default PATH in env %v", c.Env)
+	}
+}
+
+func TestNewLocalHostContainer_InvalidRunOptions(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-localhost-container-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, test := range []struct {
+		name          string
+		config        v1.Config
+		runOptions    *StageImageContainerOptions
+		expectedError string
+	}{
+		{
+			name:          "non-root user",
+			config:        v1.Config{Entrypoint: []string{"/bin/sh"}},
+			runOptions:    &StageImageContainerOptions{User: "app"},
+			expectedError: "supports only root user",
+		},
+		{
+			name:          "no entrypoint",
+			runOptions:    &StageImageContainerOptions{},
+			expectedError: "entrypoint is not specified",
+		},
+		{
+			name:          "invalid volume",
+			config:        v1.Config{Entrypoint: []string{"/bin/sh"}},
+			runOptions:    &StageImageContainerOptions{Volume: []string{"/host:/container:z"}},
+			expectedError: "unsupported volume",
+		},
+		{
+			name:          "volumes from the unknown container",
+			config:        v1.Config{Entrypoint: []string{"/bin/sh"}},
+			runOptions:    &StageImageContainerOptions{VolumesFrom: []string{"other"}},
+			expectedError: "are not supported by localhost container runtime",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime := &LocalHostRuntime{}
+			_, err := runtime.newLocalHostContainer(context.Background(), newLocalHostContainerTestImage(t, test.config), filepath.Join(tmpDir, "rootfs"), test.runOptions, "true")
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("expected error containing %q, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestLocalHostContainer_WriteDiff_Mountpoints(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-localhost-container-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	hostDir := filepath.Join(tmpDir, "host")
+	hostFile := filepath.Join(tmpDir, "host_file")
+	if err := os.MkdirAll(hostDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(hostFile, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	img := newLocalHostContainerTestImage(t, v1.Config{Entrypoint: []string{"/bin/sh"}})
+
+	runtime := &LocalHostRuntime{}
+	c, err := runtime.newLocalHostContainer(context.Background(), img, filepath.Join(tmpDir, "rootfs"), &StageImageContainerOptions{
+		Volume: []string{hostDir + ":/.werf/tmp", hostFile + ":/.werf/secrets/id:ro", hostFile + ":/run/secrets/password:ro"},
+	}, "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.prepareMountpoints(); err != nil {
+		t.Fatal(err)
+	}
+	if c.State, err = c.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the container command creates the file
+	if err := os.MkdirAll(filepath.Join(c.Rootfs, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(c.Rootfs, "app", "result"), []byte("ok"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	layerPath := filepath.Join(tmpDir, "layer.tar")
+	if err := c.writeDiff(layerPath); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(layerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+
+	if expected := []string{"app/", "app/result"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected layer paths %v, got %v", expected, names)
+	}
+}
+
+func TestApplyCommitChanges(t *testing.T) {
+	config := &v1.Config{Env: []string{"PATH=/bin", "MODE=old"}, Shell: []string{"/bin/bash", "-c"}}
+
+	if err := applyCommitChanges(config, []string{
+		`ENV MODE=new EXTRA=1`,
+		`LABEL werf-stage=install`,
+		`EXPOSE 80 53/udp`,
+		`VOLUME /data`,
+		`WORKDIR /app`,
+		`USER app`,
+		`CMD run --fast`,
+		`ENTRYPOINT ["/entrypoint.sh"]`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &v1.Config{
+		Env:          []string{"PATH=/bin", "MODE=new", "EXTRA=1"},
+		Shell:        []string{"/bin/bash", "-c"},
+		Labels:       map[string]string{"werf-stage": "install"},
+		ExposedPorts: map[string]struct{}{"80/tcp": {}, "53/udp": {}},
+		Volumes:      map[string]struct{}{"/data": {}},
+		WorkingDir:   "/app",
+		User:         "app",
+		Cmd:          []string{"/bin/bash", "-c", "run --fast"},
+		Entrypoint:   []string{"/entrypoint.sh"},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("expected config %#v, got %#v", expected, config)
+	}
+
+	if err := applyCommitChanges(config, []string{`ENTRYPOINT [""]`}); err != nil {
+		t.Fatal(err)
+	} else if config.Entrypoint != nil {
+		t.Errorf("expected empty entrypoint to reset the entrypoint, got %v", config.Entrypoint)
+	}
+
+	if err := applyCommitChanges(config, []string{`RUN true`}); err == nil || !strings.Contains(err.Error(), "unsupported commit change") {
+		t.Errorf("expected unsupported commit change error, got %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+// +build !linux
+
+package container_runtime
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+func runLocalHostContainer(_ context.Context, _ *localHostContainer, _ string) error {
+	return fmt.Errorf("localhost container runtime is not supported on %s", runtime.GOOS)
+}
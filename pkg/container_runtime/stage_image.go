@@ -23,16 +23,29 @@ type StageImage struct {
 	container              *StageImageContainer
 	buildImage             *buildImage
 	dockerfileImageBuilder *DockerfileImageBuilder
+	platform               string
 }
 
-func NewStageImage(fromImage *StageImage, name string, localDockerServerRuntime *LocalDockerServerRuntime) *StageImage {
+func NewStageImage(fromImage *StageImage, name string, containerRuntime ContainerRuntime) *StageImage {
 	stage := &StageImage{}
-	stage.baseImage = newBaseImage(name, localDockerServerRuntime)
+	stage.baseImage = newBaseImage(name, containerRuntime)
 	stage.fromImage = fromImage
 	stage.container = newStageImageContainer(stage)
 	return stage
 }
 
+func (i *StageImage) SetPlatform(platform string) {
+	i.platform = platform
+}
+
+func (i *StageImage) GetPlatform() string {
+	return i.platform
+}
+
+func (i *StageImage) FromImage() *StageImage {
+	return i.fromImage
+}
+
 func (i *StageImage) Inspect() *types.ImageInspect {
 	return i.inspect
 }
@@ -58,6 +71,14 @@ func (i *StageImage) Build(ctx context.Context, options BuildOptions) error {
 		if err := i.dockerfileImageBuilder.Build(ctx); err != nil {
 			return err
 		}
+	} else if localHostRuntime, ok := i.ContainerRuntime.(*LocalHostRuntime); ok {
+		if options.IntrospectBeforeError || options.IntrospectAfterError {
+			logboek.Context(ctx).Warn().LogF("WARNING: Introspection is not supported by %s container runtime\n", localHostRuntime.String())
+		}
+
+		if err := i.buildWithLocalHostRuntime(ctx, localHostRuntime); err != nil {
+			return err
+		}
 	} else {
 		containerLockName := ContainerLockName(i.container.Name())
 		if _, lock, err := werf.AcquireHostLock(ctx, containerLockName, lockgate.AcquireOptions{}); err != nil {
@@ -120,7 +141,7 @@ func (i *StageImage) Build(ctx context.Context, options BuildOptions) error {
 		}
 	}
 
-	if inspect, err := i.LocalDockerServerRuntime.GetImageInspect(ctx, i.MustGetBuiltId()); err != nil {
+	if inspect, err := i.ContainerRuntime.GetImageInspect(ctx, i.MustGetBuiltId()); err != nil {
 		return err
 	} else {
 		i.SetInspect(inspect)
@@ -133,18 +154,47 @@ func (i *StageImage) Build(ctx context.Context, options BuildOptions) error {
 	return nil
 }
 
+func (i *StageImage) buildWithLocalHostRuntime(ctx context.Context, localHostRuntime *LocalHostRuntime) error {
+	runOptions, err := i.container.prepareRunOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	commitChanges, err := i.container.prepareCommitChanges(ctx)
+	if err != nil {
+		return err
+	}
+
+	if debugDockerRunCommand() && len(i.container.prepareAllRunCommands()) != 0 {
+		fmt.Printf("Decoded command:\n%s\n", strings.Join(i.container.prepareAllRunCommands(), " && "))
+	}
+
+	builtId, err := localHostRuntime.RunAndCommit(ctx, i.fromImage.GetID(), runOptions, i.container.prepareRunCommand(), commitChanges, i.container.secretValuesToMask)
+	if err != nil {
+		return err
+	}
+
+	i.buildImage = newBuildImage(builtId, i.ContainerRuntime)
+
+	return nil
+}
+
 func (i *StageImage) Commit(ctx context.Context) error {
 	builtId, err := i.container.commit(ctx)
 	if err != nil {
 		return err
 	}
 
-	i.buildImage = newBuildImage(builtId, i.LocalDockerServerRuntime)
+	i.buildImage = newBuildImage(builtId, i.ContainerRuntime)
 
 	return nil
 }
 
 func (i *StageImage) Introspect(ctx context.Context) error {
+	if _, ok := i.ContainerRuntime.(*LocalHostRuntime); ok {
+		return fmt.Errorf("introspection is not supported by %s container runtime", i.ContainerRuntime.String())
+	}
+
 	if err := i.container.introspect(ctx); err != nil {
 		return err
 	}
@@ -163,6 +213,17 @@ func (i *StageImage) introspectBefore(ctx context.Context) error {
 func (i *StageImage) MustResetInspect(ctx context.Context) error {
 	if i.buildImage != nil {
 		return i.buildImage.MustResetInspect(ctx)
+	} else if i.platform != "" && i.baseImage.GetStageDescription() != nil {
+		// the local name may point to the image of another platform, so the pulled image is addressed by ID
+		inspect, err := i.ContainerRuntime.GetImageInspect(ctx, i.GetID())
+		if err != nil {
+			return fmt.Errorf("unable to get inspect for image %s: %s", i.GetID(), err)
+		} else if inspect == nil {
+			return fmt.Errorf("image %s of base image %s for platform %s not found", i.GetID(), i.Name(), i.platform)
+		}
+
+		i.baseImage.SetInspect(inspect)
+		return nil
 	} else {
 		return i.baseImage.MustResetInspect(ctx)
 	}
@@ -195,15 +256,15 @@ func (i *StageImage) GetBuiltId() string {
 }
 
 func (i *StageImage) TagBuiltImage(ctx context.Context, name string) error {
-	return docker.CliTag(ctx, i.MustGetBuiltId(), i.name)
+	return i.tag(ctx, i.MustGetBuiltId(), i.name)
 }
 
 func (i *StageImage) Tag(ctx context.Context, name string) error {
-	return docker.CliTag(ctx, i.GetID(), name)
+	return i.tag(ctx, i.GetID(), name)
 }
 
 func (i *StageImage) Pull(ctx context.Context) error {
-	if err := docker.CliPullWithRetries(ctx, i.name); err != nil {
+	if err := i.pull(ctx, i.name); err != nil {
 		return err
 	}
 
@@ -213,23 +274,23 @@ func (i *StageImage) Pull(ctx context.Context) error {
 }
 
 func (i *StageImage) Push(ctx context.Context) error {
-	return docker.CliPushWithRetries(ctx, i.name)
+	return i.push(ctx, i.name)
 }
 
 func (i *StageImage) Import(ctx context.Context, name string) error {
-	importedImage := newBaseImage(name, i.LocalDockerServerRuntime)
+	importedImage := newBaseImage(name, i.ContainerRuntime)
 
-	if err := docker.CliPullWithRetries(ctx, name); err != nil {
+	if err := i.pull(ctx, name); err != nil {
 		return err
 	}
 
 	importedImageId := importedImage.GetStageDescription().Info.ID
 
-	if err := docker.CliTag(ctx, importedImageId, i.name); err != nil {
+	if err := i.tag(ctx, importedImageId, i.name); err != nil {
 		return err
 	}
 
-	if err := docker.CliRmi(ctx, name); err != nil {
+	if err := i.rmi(ctx, name); err != nil {
 		return err
 	}
 
@@ -245,7 +306,7 @@ func (i *StageImage) Export(ctx context.Context, name string) error {
 
 	defer func() {
 		if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Untagging %s", name)).DoError(func() error {
-			return docker.CliRmi(ctx, name)
+			return i.rmi(ctx, name)
 		}); err != nil {
 			// TODO: errored image state
 			logboek.Context(ctx).Error().LogF("Unable to remote temporary image %q: %s", name, err)
@@ -253,7 +314,7 @@ func (i *StageImage) Export(ctx context.Context, name string) error {
 	}()
 
 	if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Pushing %s", name)).DoError(func() error {
-		return docker.CliPushWithRetries(ctx, name)
+		return i.push(ctx, name)
 	}); err != nil {
 		return err
 	}
@@ -261,9 +322,51 @@ func (i *StageImage) Export(ctx context.Context, name string) error {
 	return nil
 }
 
+func (i *StageImage) tag(ctx context.Context, ref, newRef string) error {
+	switch containerRuntime := i.ContainerRuntime.(type) {
+	case *LocalHostRuntime:
+		return containerRuntime.Tag(ctx, ref, newRef)
+	default:
+		return docker.CliTag(ctx, ref, newRef)
+	}
+}
+
+func (i *StageImage) rmi(ctx context.Context, ref string) error {
+	switch containerRuntime := i.ContainerRuntime.(type) {
+	case *LocalHostRuntime:
+		return containerRuntime.Rmi(ctx, ref)
+	default:
+		return docker.CliRmi(ctx, ref)
+	}
+}
+
+func (i *StageImage) pull(ctx context.Context, ref string) error {
+	switch containerRuntime := i.ContainerRuntime.(type) {
+	case *LocalHostRuntime:
+		if i.platform != "" {
+			return fmt.Errorf("pulling image for the specified platform %s is not supported by %s container runtime", i.platform, containerRuntime.String())
+		}
+		return containerRuntime.Pull(ctx, ref)
+	default:
+		if i.platform != "" {
+			return docker.CliPullWithRetries(ctx, fmt.Sprintf("--platform=%s", i.platform), ref)
+		}
+		return docker.CliPullWithRetries(ctx, ref)
+	}
+}
+
+func (i *StageImage) push(ctx context.Context, ref string) error {
+	switch containerRuntime := i.ContainerRuntime.(type) {
+	case *LocalHostRuntime:
+		return containerRuntime.Push(ctx, ref)
+	default:
+		return docker.CliPushWithRetries(ctx, ref)
+	}
+}
+
 func (i *StageImage) DockerfileImageBuilder() *DockerfileImageBuilder {
 	if i.dockerfileImageBuilder == nil {
-		i.dockerfileImageBuilder = NewDockerfileImageBuilder()
+		i.dockerfileImageBuilder = NewDockerfileImageBuilder(i.ContainerRuntime)
 	}
 	return i.dockerfileImageBuilder
 }
@@ -21,9 +21,11 @@ type StageImageContainer struct {
 	name                       string
 	runCommands                []string
 	serviceRunCommands         []string
+	serviceCleanupCommands     []string
 	runOptions                 *StageImageContainerOptions
 	commitChangeOptions        *StageImageContainerOptions
 	serviceCommitChangeOptions *StageImageContainerOptions
+	secretValuesToMask         []string
 }
 
 func newStageImageContainer(img *StageImage) *StageImageContainer {
@@ -56,6 +58,14 @@ func (c *StageImageContainer) AddServiceRunCommands(commands ...string) {
 	c.serviceRunCommands = append(c.serviceRunCommands, commands...)
 }
 
+func (c *StageImageContainer) AddServiceCleanupCommands(commands ...string) {
+	c.serviceCleanupCommands = append(c.serviceCleanupCommands, commands...)
+}
+
+func (c *StageImageContainer) AddSecretValuesToMask(values ...string) {
+	c.secretValuesToMask = append(c.secretValuesToMask, values...)
+}
+
 func (c *StageImageContainer) RunOptions() ContainerOptions {
 	return c.runOptions
 }
@@ -117,6 +127,7 @@ func (c *StageImageContainer) prepareAllRunCommands() []string {
 
 	commands = append(commands, c.serviceRunCommands...)
 	commands = append(commands, c.runCommands...)
+	commands = append(commands, c.serviceCleanupCommands...)
 
 	return commands
 }
@@ -188,12 +199,16 @@ func (c *StageImageContainer) prepareServiceRunOptions(ctx context.Context) (*St
 	serviceRunOptions.Entrypoint = stapel.BashBinPath()
 	serviceRunOptions.User = "0:0"
 
-	stapelContainerName, err := stapel.GetOrCreateContainer(ctx)
-	if err != nil {
-		return nil, err
-	}
+	if _, ok := c.image.ContainerRuntime.(*LocalHostRuntime); ok {
+		serviceRunOptions.VolumesFrom = []string{stapel.ContainerName()}
+	} else {
+		stapelContainerName, err := stapel.GetOrCreateContainer(ctx)
+		if err != nil {
+			return nil, err
+		}
 
-	serviceRunOptions.VolumesFrom = []string{stapelContainerName}
+		serviceRunOptions.VolumesFrom = []string{stapelContainerName}
+	}
 
 	return serviceRunOptions, nil
 }
@@ -208,7 +223,7 @@ func (c *StageImageContainer) prepareCommitChanges(ctx context.Context) ([]strin
 		return nil, err
 	}
 
-	commitChanges, err := commitOptions.prepareCommitChanges(ctx)
+	commitChanges, err := commitOptions.prepareCommitChanges(ctx, c.image.ContainerRuntime)
 	if err != nil {
 		return nil, err
 	}
@@ -260,7 +275,7 @@ func (c *StageImageContainer) run(ctx context.Context) error {
 		return err
 	}
 
-	if err := docker.CliRun_LiveOutput(ctx, runArgs...); err != nil {
+	if err := docker.CliRun_LiveOutputWithMaskedValues(ctx, c.secretValuesToMask, runArgs...); err != nil {
 		return fmt.Errorf("container run failed: %s", err.Error())
 	}
 
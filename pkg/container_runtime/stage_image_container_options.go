@@ -202,7 +202,7 @@ func (co *StageImageContainerOptions) toCommitChanges() []string {
 	return args
 }
 
-func (co *StageImageContainerOptions) prepareCommitChanges(ctx context.Context) ([]string, error) {
+func (co *StageImageContainerOptions) prepareCommitChanges(ctx context.Context, containerRuntime ContainerRuntime) ([]string, error) {
 	var args []string
 
 	for _, volume := range co.Volume {
@@ -233,6 +233,8 @@ func (co *StageImageContainerOptions) prepareCommitChanges(ctx context.Context)
 	var err error
 	if co.Entrypoint != "" {
 		entrypoint = co.Entrypoint
+	} else if _, ok := containerRuntime.(*LocalHostRuntime); ok {
+		entrypoint = "[\"\"]"
 	} else {
 		entrypoint, err = getEmptyEntrypointInstructionValue(ctx)
 		if err != nil {
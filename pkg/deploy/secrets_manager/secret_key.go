@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/secret"
 
 	"github.com/werf/werf/pkg/util"
@@ -26,25 +27,37 @@ func GetRequiredOldSecretKey() ([]byte, error) {
 }
 
 func GetRequiredSecretKey(workingDir string) ([]byte, error) {
+	return getRequiredStaticSecretKey(workingDir, "WERF_SECRET_KEY", ".werf_secret_key", true)
+}
+
+// GetRequiredNamedSecretKey returns the named secret key from the $WERF_SECRET_KEY_<NAME> or .werf_secret_key_<name> file
+func GetRequiredNamedSecretKey(workingDir, name string) ([]byte, error) {
+	secretKeyConfig := config.MetaSecretKey{Name: name}
+	return getRequiredStaticSecretKey(workingDir, secretKeyConfig.GetStaticKeyEnvName(), secretKeyConfig.GetStaticKeyFile(), false)
+}
+
+func getRequiredStaticSecretKey(workingDir, envName, fileName string, useGlobalSecretKey bool) ([]byte, error) {
 	var secretKey []byte
 	var werfSecretKeyPaths []string
 	var notFoundIn []string
 
-	secretKey = []byte(os.Getenv("WERF_SECRET_KEY"))
+	secretKey = []byte(os.Getenv(envName))
 	if len(secretKey) == 0 {
-		notFoundIn = append(notFoundIn, "$WERF_SECRET_KEY")
+		notFoundIn = append(notFoundIn, fmt.Sprintf("$%s", envName))
 
 		var werfSecretKeyPath string
 
 		if workingDir != "" {
-			if defaultWerfSecretKeyPath, err := filepath.Abs(filepath.Join(workingDir, ".werf_secret_key")); err != nil {
+			if defaultWerfSecretKeyPath, err := filepath.Abs(filepath.Join(workingDir, fileName)); err != nil {
 				return nil, err
 			} else {
 				werfSecretKeyPaths = append(werfSecretKeyPaths, defaultWerfSecretKeyPath)
 			}
 		}
 
-		werfSecretKeyPaths = append(werfSecretKeyPaths, filepath.Join(werf.GetHomeDir(), "global_secret_key"))
+		if useGlobalSecretKey {
+			werfSecretKeyPaths = append(werfSecretKeyPaths, filepath.Join(werf.GetHomeDir(), "global_secret_key"))
+		}
 
 		for _, path := range werfSecretKeyPaths {
 			exist, err := util.FileExists(path)
@@ -0,0 +1,92 @@
+package secrets_manager
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/util"
+)
+
+// SecretKeyProvider returns the secret key which is used to encrypt and decrypt werf secrets
+type SecretKeyProvider interface {
+	GetSecretKey(ctx context.Context) ([]byte, error)
+	String() string
+}
+
+// EnvelopeSecretKeyProvider stores the secret key in the project encrypted for the recipients,
+// so each recipient decrypts the key with its own private key
+type EnvelopeSecretKeyProvider interface {
+	SecretKeyProvider
+	EncryptSecretKey(ctx context.Context, key []byte) error
+	EncryptedKeyFilePath() string
+}
+
+func NewSecretKeyProvider(workingDir string, secretKeyConfig config.MetaSecretKey) (SecretKeyProvider, error) {
+	var encryptedKeyFilePath string
+	if encryptedKeyFile := secretKeyConfig.GetEncryptedKeyFile(); encryptedKeyFile != "" {
+		encryptedKeyFilePath = filepath.Join(workingDir, encryptedKeyFile)
+	}
+
+	switch secretKeyConfig.GetProvider() {
+	case config.SecretKeyProviderStatic:
+		return &staticSecretKeyProvider{workingDir: workingDir, name: secretKeyConfig.Name}, nil
+	case config.SecretKeyProviderAge:
+		return &ageSecretKeyProvider{encryptedKeyFile: encryptedKeyFile{path: encryptedKeyFilePath}, recipients: secretKeyConfig.Recipients}, nil
+	case config.SecretKeyProviderPGP:
+		return &pgpSecretKeyProvider{encryptedKeyFile: encryptedKeyFile{path: encryptedKeyFilePath}, recipients: secretKeyConfig.Recipients}, nil
+	case config.SecretKeyProviderExec:
+		return &execSecretKeyProvider{encryptedKeyFile: encryptedKeyFile{path: encryptedKeyFilePath}, workingDir: workingDir, command: secretKeyConfig.Command}, nil
+	default:
+		return nil, fmt.Errorf("unsupported secret key provider %q", secretKeyConfig.Provider)
+	}
+}
+
+type staticSecretKeyProvider struct {
+	workingDir string
+	name       string
+}
+
+func (p *staticSecretKeyProvider) GetSecretKey(_ context.Context) ([]byte, error) {
+	if p.name != "" {
+		return GetRequiredNamedSecretKey(p.workingDir, p.name)
+	}
+
+	return GetRequiredSecretKey(p.workingDir)
+}
+
+func (p *staticSecretKeyProvider) String() string {
+	return config.SecretKeyProviderStatic
+}
+
+type encryptedKeyFile struct {
+	path string
+}
+
+func (f encryptedKeyFile) EncryptedKeyFilePath() string {
+	return f.path
+}
+
+func (f encryptedKeyFile) read() ([]byte, error) {
+	exist, err := util.FileExists(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exist {
+		return nil, fmt.Errorf("encrypted secret key file %q not found: the file should be created with `werf helm secret encrypt-secret-key` command", f.path)
+	}
+
+	return ioutil.ReadFile(f.path)
+}
+
+func (f encryptedKeyFile) write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(f.path, data, 0644)
+}
@@ -0,0 +1,80 @@
+package secrets_manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// ageSecretKeyProvider stores the secret key encrypted for the age X25519 recipients
+type ageSecretKeyProvider struct {
+	encryptedKeyFile
+	recipients []string
+}
+
+func (p *ageSecretKeyProvider) GetSecretKey(_ context.Context) ([]byte, error) {
+	data, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := getAgeIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := secret.AgeDecrypt(data, identities)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt %s: %s", p.path, err)
+	}
+
+	return bytes.TrimSpace(key), nil
+}
+
+func (p *ageSecretKeyProvider) EncryptSecretKey(_ context.Context, key []byte) error {
+	data, err := secret.AgeEncrypt(key, p.recipients)
+	if err != nil {
+		return err
+	}
+
+	return p.write(data)
+}
+
+func (p *ageSecretKeyProvider) String() string {
+	return config.SecretKeyProviderAge
+}
+
+func getAgeIdentities() ([]string, error) {
+	if identities := os.Getenv("WERF_SECRET_KEY_AGE_IDENTITY"); identities != "" {
+		return secret.ParseAgeIdentities([]byte(identities)), nil
+	}
+
+	identitiesFilePath := os.Getenv("WERF_SECRET_KEY_AGE_IDENTITY_FILE")
+	if identitiesFilePath == "" {
+		identitiesFilePath = filepath.Join(werf.GetHomeDir(), "age_identities")
+	}
+
+	exist, err := util.FileExists(identitiesFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exist {
+		return nil, fmt.Errorf("age identity required in $WERF_SECRET_KEY_AGE_IDENTITY or %q file ($WERF_SECRET_KEY_AGE_IDENTITY_FILE)", identitiesFilePath)
+	}
+
+	data, err := ioutil.ReadFile(identitiesFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.ParseAgeIdentities(data), nil
+}
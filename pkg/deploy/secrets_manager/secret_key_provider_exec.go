@@ -0,0 +1,93 @@
+package secrets_manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/werf/werf/pkg/config"
+)
+
+const (
+	execSecretKeyActionDecrypt = "decrypt"
+	execSecretKeyActionEncrypt = "encrypt"
+)
+
+// execSecretKeyProvider gets the secret key from the helper program output.
+// The helper gets the action in $WERF_SECRET_KEY_ACTION:
+//   - decrypt: the encrypted key file data (if the file is configured) is passed to stdin, the secret key is expected in stdout;
+//   - encrypt: the secret key is passed to stdin, the encrypted key file data is expected in stdout.
+//
+// The werf secret keys variables ($WERF_SECRET_KEY*) are not passed to the helper.
+// The provider should be allowed by the giterminism config (config.secretKey.allowExecProvider).
+type execSecretKeyProvider struct {
+	encryptedKeyFile
+	workingDir string
+	command    []string
+}
+
+func (p *execSecretKeyProvider) GetSecretKey(ctx context.Context) ([]byte, error) {
+	var input []byte
+	if p.path != "" {
+		data, err := p.read()
+		if err != nil {
+			return nil, err
+		}
+		input = data
+	}
+
+	key, err := p.run(ctx, execSecretKeyActionDecrypt, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSpace(key), nil
+}
+
+func (p *execSecretKeyProvider) EncryptSecretKey(ctx context.Context, key []byte) error {
+	if p.path == "" {
+		return fmt.Errorf("`encryptedKeyFile: PATH` required to store the secret key encrypted by the exec secret key provider")
+	}
+
+	data, err := p.run(ctx, execSecretKeyActionEncrypt, key)
+	if err != nil {
+		return err
+	}
+
+	return p.write(data)
+}
+
+func (p *execSecretKeyProvider) String() string {
+	return config.SecretKeyProviderExec
+}
+
+func (p *execSecretKeyProvider) run(ctx context.Context, action string, input []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	cmd.Dir = p.workingDir
+	cmd.Env = append(execSecretKeyHelperEnviron(os.Environ()), fmt.Sprintf("WERF_SECRET_KEY_ACTION=%s", action))
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("secret key helper %q failed to %s the secret key: %s", strings.Join(p.command, " "), action, err)
+	}
+
+	return output, nil
+}
+
+func execSecretKeyHelperEnviron(environ []string) []string {
+	var res []string
+	for _, keyValue := range environ {
+		if strings.HasPrefix(keyValue, "WERF_SECRET_KEY") {
+			continue
+		}
+
+		res = append(res, keyValue)
+	}
+
+	return res
+}
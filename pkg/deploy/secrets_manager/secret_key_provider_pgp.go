@@ -0,0 +1,88 @@
+package secrets_manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/openpgp"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/secret"
+)
+
+// pgpSecretKeyProvider stores the secret key encrypted for the PGP keys,
+// the keys are looked up in the exported keyring files because the gpg keybox format is not supported
+type pgpSecretKeyProvider struct {
+	encryptedKeyFile
+	recipients []string
+}
+
+func (p *pgpSecretKeyProvider) GetSecretKey(_ context.Context) ([]byte, error) {
+	data, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	keyring, err := readPGPKeyRing("WERF_SECRET_KEY_PGP_SECRET_KEYRING", "secring.gpg")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := secret.PGPDecrypt(data, keyring, []byte(os.Getenv("WERF_SECRET_KEY_PGP_PASSPHRASE")))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt %s: %s", p.path, err)
+	}
+
+	return bytes.TrimSpace(key), nil
+}
+
+func (p *pgpSecretKeyProvider) EncryptSecretKey(_ context.Context, key []byte) error {
+	keyring, err := readPGPKeyRing("WERF_SECRET_KEY_PGP_PUBLIC_KEYRING", "pubring.gpg")
+	if err != nil {
+		return err
+	}
+
+	recipients, err := secret.SelectPGPRecipients(keyring, p.recipients)
+	if err != nil {
+		return err
+	}
+
+	data, err := secret.PGPEncrypt(key, recipients)
+	if err != nil {
+		return err
+	}
+
+	return p.write(data)
+}
+
+func (p *pgpSecretKeyProvider) String() string {
+	return config.SecretKeyProviderPGP
+}
+
+func readPGPKeyRing(pathEnvName, defaultGnupgFileName string) (openpgp.EntityList, error) {
+	keyringPath := os.Getenv(pathEnvName)
+	if keyringPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+
+		keyringPath = filepath.Join(homeDir, ".gnupg", defaultGnupgFileName)
+	}
+
+	data, err := ioutil.ReadFile(keyringPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read pgp keyring (the path can be specified with $%s): %s", pathEnvName, err)
+	}
+
+	keyring, err := secret.ReadPGPKeyRing(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read pgp keyring %s: %s", keyringPath, err)
+	}
+
+	return keyring, nil
+}
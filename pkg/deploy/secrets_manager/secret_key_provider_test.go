@@ -0,0 +1,265 @@
+package secrets_manager
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/openpgp"
+
+	"github.com/werf/werf/pkg/config"
+)
+
+var testSecretKey = []byte("11ac8312520b5ff037bae386ea2e8a07")
+
+func newTestDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "werf-secret-key-provider-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func setTestEnv(t *testing.T, name, value string) func() {
+	oldValue, isSet := os.LookupEnv(name)
+	if err := os.Setenv(name, value); err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		if isSet {
+			os.Setenv(name, oldValue)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestNewSecretKeyProvider(t *testing.T) {
+	for _, test := range []struct {
+		secretKeyConfig          config.MetaSecretKey
+		expectedProvider         string
+		expectedEncryptedKeyFile string
+	}{
+		{config.MetaSecretKey{}, config.SecretKeyProviderStatic, ""},
+		{config.MetaSecretKey{Provider: config.SecretKeyProviderAge, Recipients: []string{"age1"}}, config.SecretKeyProviderAge, ".werf_secret_key.age"},
+		{config.MetaSecretKey{Name: "prod", Provider: config.SecretKeyProviderPGP, Recipients: []string{"ABCD"}}, config.SecretKeyProviderPGP, ".werf_secret_key_prod.asc"},
+		{config.MetaSecretKey{Provider: config.SecretKeyProviderExec, Command: []string{"helper"}, EncryptedKeyFile: ".werf/key.enc"}, config.SecretKeyProviderExec, ".werf/key.enc"},
+		{config.MetaSecretKey{Provider: config.SecretKeyProviderExec, Command: []string{"helper"}}, config.SecretKeyProviderExec, ""},
+	} {
+		provider, err := NewSecretKeyProvider("/project", test.secretKeyConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if provider.String() != test.expectedProvider {
+			t.Errorf("expected %s provider, got %s", test.expectedProvider, provider.String())
+		}
+
+		if envelopeProvider, ok := provider.(EnvelopeSecretKeyProvider); ok {
+			expectedPath := ""
+			if test.expectedEncryptedKeyFile != "" {
+				expectedPath = filepath.Join("/project", test.expectedEncryptedKeyFile)
+			}
+
+			if envelopeProvider.EncryptedKeyFilePath() != expectedPath {
+				t.Errorf("expected %s provider encrypted key file %q, got %q", test.expectedProvider, expectedPath, envelopeProvider.EncryptedKeyFilePath())
+			}
+		} else if test.expectedEncryptedKeyFile != "" {
+			t.Errorf("expected %s provider to store the encrypted key file", test.expectedProvider)
+		}
+	}
+
+	if _, err := NewSecretKeyProvider("/project", config.MetaSecretKey{Provider: "unknown"}); err == nil {
+		t.Errorf("expected error for the unsupported provider")
+	}
+}
+
+func TestStaticSecretKeyProvider(t *testing.T) {
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+
+	defer setTestEnv(t, "WERF_SECRET_KEY_PROD", string(testSecretKey))()
+
+	provider, err := NewSecretKeyProvider(dir, config.MetaSecretKey{Name: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := provider.GetSecretKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(key, testSecretKey) {
+		t.Errorf("expected key %q, got %q", testSecretKey, key)
+	}
+}
+
+func TestAgeSecretKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewSecretKeyProvider(dir, config.MetaSecretKey{Provider: config.SecretKeyProviderAge, Recipients: []string{identity.Recipient().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.GetSecretKey(ctx); err == nil {
+		t.Errorf("expected error when the encrypted key file does not exist")
+	}
+
+	if err := provider.(EnvelopeSecretKeyProvider).EncryptSecretKey(ctx, testSecretKey); err != nil {
+		t.Fatal(err)
+	}
+
+	identityFile := filepath.Join(dir, "age_identities")
+	if err := ioutil.WriteFile(identityFile, []byte("# test identity\n"+identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer setTestEnv(t, "WERF_SECRET_KEY_AGE_IDENTITY_FILE", identityFile)()
+
+	key, err := provider.GetSecretKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(key, testSecretKey) {
+		t.Errorf("expected key %q, got %q", testSecretKey, key)
+	}
+
+	otherIdentity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer setTestEnv(t, "WERF_SECRET_KEY_AGE_IDENTITY", otherIdentity.String())()
+
+	if _, err := provider.GetSecretKey(ctx); err == nil {
+		t.Errorf("expected error when the identity is not the recipient")
+	}
+}
+
+func TestPGPSecretKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+
+	entity, err := openpgp.NewEntity("werf", "", "werf@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKeyring, secretKeyring := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := entity.Serialize(publicKeyring); err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(secretKeyring, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	publicKeyringPath, secretKeyringPath := filepath.Join(dir, "pubring.gpg"), filepath.Join(dir, "secring.gpg")
+	if err := ioutil.WriteFile(publicKeyringPath, publicKeyring.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(secretKeyringPath, secretKeyring.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer setTestEnv(t, "WERF_SECRET_KEY_PGP_PUBLIC_KEYRING", publicKeyringPath)()
+	defer setTestEnv(t, "WERF_SECRET_KEY_PGP_SECRET_KEYRING", secretKeyringPath)()
+
+	keyID := []string{"0x" + entity.PrimaryKey.KeyIdString()}
+	provider, err := NewSecretKeyProvider(dir, config.MetaSecretKey{Provider: config.SecretKeyProviderPGP, Recipients: keyID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.(EnvelopeSecretKeyProvider).EncryptSecretKey(ctx, testSecretKey); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := provider.GetSecretKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(key, testSecretKey) {
+		t.Errorf("expected key %q, got %q", testSecretKey, key)
+	}
+
+	unknownProvider, err := NewSecretKeyProvider(dir, config.MetaSecretKey{Name: "unknown", Provider: config.SecretKeyProviderPGP, Recipients: []string{"DEADBEEFDEADBEEF"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := unknownProvider.(EnvelopeSecretKeyProvider).EncryptSecretKey(ctx, testSecretKey); err == nil {
+		t.Errorf("expected error for the recipient which is not in the keyring")
+	}
+}
+
+func TestExecSecretKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+
+	// the helper reverses the data for both actions and fails if the werf secret key is passed in the environment
+	command := []string{"sh", "-c", `test -z "$WERF_SECRET_KEY" && test -n "$WERF_SECRET_KEY_ACTION" && rev`}
+	defer setTestEnv(t, "WERF_SECRET_KEY", "secret")()
+
+	provider, err := NewSecretKeyProvider(dir, config.MetaSecretKey{Provider: config.SecretKeyProviderExec, EncryptedKeyFile: "key.enc", Command: command})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.(EnvelopeSecretKeyProvider).EncryptSecretKey(ctx, append(testSecretKey, '\n')); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "key.enc")); err != nil {
+		t.Fatal(err)
+	} else if bytes.Equal(bytes.TrimSpace(data), testSecretKey) {
+		t.Errorf("expected the key file to contain the helper output")
+	}
+
+	key, err := provider.GetSecretKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(key, testSecretKey) {
+		t.Errorf("expected key %q, got %q", testSecretKey, key)
+	}
+
+	failingProvider, err := NewSecretKeyProvider(dir, config.MetaSecretKey{Provider: config.SecretKeyProviderExec, Command: []string{"sh", "-c", "exit 1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := failingProvider.GetSecretKey(ctx); err == nil {
+		t.Errorf("expected error when the helper fails")
+	}
+
+	if err := failingProvider.(EnvelopeSecretKeyProvider).EncryptSecretKey(ctx, testSecretKey); err == nil {
+		t.Errorf("expected error when the encrypted key file is not configured")
+	}
+}
+
+func TestExecSecretKeyHelperEnviron(t *testing.T) {
+	environ := []string{"PATH=/bin", "WERF_SECRET_KEY=key", "WERF_SECRET_KEY_PROD=key", "WERF_SECRET_KEY_PGP_PASSPHRASE=passphrase", "VAULT_ADDR=http://vault"}
+	expected := []string{"PATH=/bin", "VAULT_ADDR=http://vault"}
+
+	if res := execSecretKeyHelperEnviron(environ); !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+}
@@ -3,49 +3,211 @@ package secrets_manager
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/werf/logboek"
 
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/secret"
 )
 
 type SecretsManager struct {
 	WorkingDir               string
 	DisableSecretsDecryption bool
+	AllowLegacyFormat        bool
+	UseVersionedFormat       bool
+	SecretKey                config.MetaSecretKey
 }
 
 type SecretsManagerOptions struct {
 	DisableSecretsDecryption bool
+	// AllowLegacyFormat enables decryption of the legacy secret format regardless of the secret key configuration
+	AllowLegacyFormat bool
+	// UseVersionedFormat enables encryption in the versioned secret format regardless of the secret key configuration
+	UseVersionedFormat bool
+	SecretKey          config.MetaSecretKey
 }
 
 func NewSecretsManager(workingDir string, opts SecretsManagerOptions) *SecretsManager {
 	return &SecretsManager{
 		WorkingDir:               workingDir,
 		DisableSecretsDecryption: opts.DisableSecretsDecryption,
+		AllowLegacyFormat:        opts.AllowLegacyFormat,
+		UseVersionedFormat:       opts.UseVersionedFormat,
+		SecretKey:                opts.SecretKey,
 	}
 }
 
+func (manager *SecretsManager) GetSecretKeyProvider() (SecretKeyProvider, error) {
+	return NewSecretKeyProvider(manager.WorkingDir, manager.SecretKey)
+}
+
+func (manager *SecretsManager) GetSecretKey(ctx context.Context) ([]byte, error) {
+	provider, err := manager.GetSecretKeyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.GetSecretKey(ctx)
+}
+
+// GetNamedSecretKeyProvider returns the provider of the named secret key configured in the secretKey.keys werf.yaml directive
+func (manager *SecretsManager) GetNamedSecretKeyProvider(name string) (SecretKeyProvider, error) {
+	secretKeyConfig, ok := manager.SecretKey.Keys[name]
+	if !ok {
+		return nil, fmt.Errorf("secret key %q is not defined: the named secret keys should be specified with secretKey.keys directive in werf.yaml", name)
+	}
+
+	return NewSecretKeyProvider(manager.WorkingDir, secretKeyConfig)
+}
+
 func (manager *SecretsManager) GetYamlEncoder(ctx context.Context) (*secret.YamlEncoder, error) {
 	if manager.DisableSecretsDecryption {
 		logboek.Context(ctx).Default().LogLnDetails("Secrets decryption disabled")
 		return secret.NewYamlEncoder(nil), nil
 	}
 
-	if key, err := GetRequiredSecretKey(manager.WorkingDir); err != nil {
+	if len(manager.SecretKey.Keys) == 0 {
+		enc, err := manager.getEncoder(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return secret.NewYamlEncoder(enc), nil
+	}
+
+	// the default key is not required when all secrets are encrypted with the named keys
+	yamlEncoder := secret.NewYamlEncoder(&lazyEncoder{newEncoderFunc: func() (secret.Encoder, error) {
+		return manager.getEncoder(ctx)
+	}})
+
+	namedKeys := map[string]secret.NamedKey{}
+	for _, name := range manager.SecretKey.GetKeyNames() {
+		name := name
+		namedKeys[name] = secret.NamedKey{
+			NewEncoderFunc: func() (secret.Encoder, error) {
+				return manager.getNamedEncoder(ctx, name)
+			},
+			Optional: manager.SecretKey.Keys[name].Optional,
+		}
+	}
+	yamlEncoder.SetNamedKeys(namedKeys)
+	yamlEncoder.SetWarningFunc(logboek.Context(ctx).Warn().LogF)
+
+	return yamlEncoder, nil
+}
+
+func (manager *SecretsManager) getEncoder(ctx context.Context) (secret.Encoder, error) {
+	if key, err := manager.GetSecretKey(ctx); err != nil {
 		return nil, fmt.Errorf("unable to load secret key: %s", err)
 	} else if enc, err := secret.NewAesEncoder(key); err != nil {
 		return nil, fmt.Errorf("check encryption key: %s", err)
 	} else {
-		return secret.NewYamlEncoder(enc), nil
+		enc.UseVersionedFormat = manager.SecretKey.UseVersionedFormat || manager.UseVersionedFormat
+		enc.RejectLegacyFormat = manager.SecretKey.RejectLegacyFormat && !manager.AllowLegacyFormat
+		return enc, nil
 	}
 }
 
-func (manager *SecretsManager) GetYamlEncoderForOldKey(ctx context.Context) (*secret.YamlEncoder, error) {
-	if key, err := GetRequiredOldSecretKey(); err != nil {
-		return nil, fmt.Errorf("unable to load old secret key: %s", err)
+func (manager *SecretsManager) getNamedEncoder(ctx context.Context, name string) (secret.Encoder, error) {
+	provider, err := manager.GetNamedSecretKeyProvider(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := provider.GetSecretKey(ctx); err != nil {
+		return nil, fmt.Errorf("unable to load secret key: %s", err)
 	} else if enc, err := secret.NewAesEncoder(key); err != nil {
-		return nil, fmt.Errorf("check old encryption key: %s", err)
+		return nil, fmt.Errorf("check encryption key: %s", err)
 	} else {
-		return secret.NewYamlEncoder(enc), nil
+		enc.UseVersionedFormat = manager.SecretKey.Keys[name].UseVersionedFormat || manager.UseVersionedFormat
+		enc.RejectLegacyFormat = manager.SecretKey.Keys[name].RejectLegacyFormat && !manager.AllowLegacyFormat
+		return enc, nil
 	}
 }
+
+// lazyEncoder creates the encoder on the first usage
+type lazyEncoder struct {
+	newEncoderFunc func() (secret.Encoder, error)
+
+	encoder secret.Encoder
+	err     error
+	created bool
+}
+
+func (e *lazyEncoder) getEncoder() (secret.Encoder, error) {
+	if !e.created {
+		e.encoder, e.err = e.newEncoderFunc()
+		e.created = true
+	}
+
+	return e.encoder, e.err
+}
+
+func (e *lazyEncoder) Encrypt(data []byte) ([]byte, error) {
+	encoder, err := e.getEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	return encoder.Encrypt(data)
+}
+
+func (e *lazyEncoder) Decrypt(data []byte) ([]byte, error) {
+	encoder, err := e.getEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	return encoder.Decrypt(data)
+}
+
+// GetYamlEncoderForOldKey returns the encoder of the old default secret key ($WERF_OLD_SECRET_KEY) or the old named secret key.
+// The old named key is taken from $WERF_OLD_SECRET_KEY_<NAME>, otherwise it is resolved with the named key provider
+// from oldEncryptedKeyFile, the encrypted key file of the envelope provider (age, pgp or exec) before the rotation
+func (manager *SecretsManager) GetYamlEncoderForOldKey(ctx context.Context, name, oldEncryptedKeyFile string) (*secret.YamlEncoder, error) {
+	var key []byte
+	if name == "" {
+		var err error
+		if key, err = GetRequiredOldSecretKey(); err != nil {
+			return nil, fmt.Errorf("unable to load old secret key: %s", err)
+		}
+	} else {
+		var err error
+		if key, err = manager.getOldNamedSecretKey(ctx, name, oldEncryptedKeyFile); err != nil {
+			return nil, fmt.Errorf("unable to load old secret key %q: %s", name, err)
+		}
+	}
+
+	enc, err := secret.NewAesEncoder(key)
+	if err != nil {
+		return nil, fmt.Errorf("check old encryption key: %s", err)
+	}
+
+	return secret.NewYamlEncoder(enc), nil
+}
+
+func (manager *SecretsManager) getOldNamedSecretKey(ctx context.Context, name, oldEncryptedKeyFile string) ([]byte, error) {
+	secretKeyConfig, ok := manager.SecretKey.Keys[name]
+	if !ok {
+		return nil, fmt.Errorf("secret key %q is not defined: the named secret keys should be specified with secretKey.keys directive in werf.yaml", name)
+	}
+	secretKeyConfig.Name = name
+
+	if key := os.Getenv(secretKeyConfig.GetOldStaticKeyEnvName()); key != "" {
+		return []byte(key), nil
+	}
+
+	if secretKeyConfig.GetProvider() == config.SecretKeyProviderStatic || oldEncryptedKeyFile == "" {
+		return nil, fmt.Errorf("$%s environment or the old encrypted key file of the %s secret key provider required", secretKeyConfig.GetOldStaticKeyEnvName(), secretKeyConfig.GetProvider())
+	}
+
+	oldSecretKeyConfig := secretKeyConfig
+	oldSecretKeyConfig.EncryptedKeyFile = oldEncryptedKeyFile
+	provider, err := NewSecretKeyProvider(manager.WorkingDir, oldSecretKeyConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.GetSecretKey(ctx)
+}
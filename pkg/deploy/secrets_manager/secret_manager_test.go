@@ -0,0 +1,77 @@
+package secrets_manager
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/secret"
+)
+
+func TestGetYamlEncoderForOldNamedKey(t *testing.T) {
+	ctx := context.Background()
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identityFile := filepath.Join(dir, "age_identities")
+	if err := ioutil.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer setTestEnv(t, "WERF_SECRET_KEY_AGE_IDENTITY_FILE", identityFile)()
+
+	secretKeyConfig := config.MetaSecretKey{Name: "prod", Provider: config.SecretKeyProviderAge, Recipients: []string{identity.Recipient().String()}, EncryptedKeyFile: "old_key.age"}
+	provider, err := NewSecretKeyProvider(dir, secretKeyConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.(EnvelopeSecretKeyProvider).EncryptSecretKey(ctx, testSecretKey); err != nil {
+		t.Fatal(err)
+	}
+
+	oldAesEncoder, err := secret.NewAesEncoder(testSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := oldAesEncoder.Encrypt([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretKeyConfig.EncryptedKeyFile = "new_key.age"
+	manager := NewSecretsManager(dir, SecretsManagerOptions{SecretKey: config.MetaSecretKey{Keys: map[string]config.MetaSecretKey{"prod": secretKeyConfig}}})
+
+	if _, err := manager.GetYamlEncoderForOldKey(ctx, "prod", ""); err == nil {
+		t.Errorf("expected error when neither the old key environment nor the old encrypted key file is specified")
+	}
+
+	if _, err := manager.GetYamlEncoderForOldKey(ctx, "unknown", "old_key.age"); err == nil {
+		t.Errorf("expected error for the undefined named key")
+	}
+
+	oldEncoder, err := manager.GetYamlEncoderForOldKey(ctx, "prod", "old_key.age")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := oldEncoder.Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != "password" {
+		t.Errorf("expected %q, got %q", "password", decrypted)
+	}
+
+	defer setTestEnv(t, "WERF_OLD_SECRET_KEY_PROD", "bad")()
+	if _, err := manager.GetYamlEncoderForOldKey(ctx, "prod", "old_key.age"); err == nil {
+		t.Errorf("expected $WERF_OLD_SECRET_KEY_PROD to take precedence over the old encrypted key file")
+	}
+}
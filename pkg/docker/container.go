@@ -6,6 +6,10 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"golang.org/x/net/context"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/util/secretvalues"
 )
 
 func Containers(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
@@ -67,6 +71,37 @@ func CliRun_LiveOutput(ctx context.Context, args ...string) error {
 	return doCliRun(cli(ctx), args...)
 }
 
+// CliRun_LiveOutputWithMaskedValues runs the container with the live output where the secret values are replaced with asterisks
+func CliRun_LiveOutputWithMaskedValues(ctx context.Context, valuesToMask []string, args ...string) error {
+	if len(valuesToMask) == 0 {
+		return CliRun_LiveOutput(ctx, args...)
+	}
+
+	outStream := secretvalues.NewMaskWriter(logboek.Context(ctx).ProxyOutStream(), valuesToMask)
+	errStream := secretvalues.NewMaskWriter(logboek.Context(ctx).ProxyErrStream(), valuesToMask)
+
+	err := cliWithCustomOptions(
+		ctx,
+		[]command.DockerCliOption{
+			command.WithOutputStream(outStream),
+			command.WithErrorStream(errStream),
+		},
+		func(c command.Cli) error {
+			return doCliRun(c, args...)
+		},
+	)
+
+	if flushErr := outStream.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+
+	if flushErr := errStream.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+
+	return err
+}
+
 func CliRun_RecordedOutput(ctx context.Context, args ...string) (string, error) {
 	return callCliWithRecordedOutput(ctx, func(c command.Cli) error {
 		return doCliRun(c, args...)
@@ -3,6 +3,7 @@ package docker
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"github.com/docker/cli/cli/streams"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"golang.org/x/net/context"
 
 	"github.com/werf/logboek"
@@ -208,3 +210,17 @@ func CliBuild_LiveOutputWithCustomIn(ctx context.Context, rc io.ReadCloser, args
 func CliBuild_LiveOutput(ctx context.Context, args ...string) error {
 	return doCliBuild(cli(ctx), args...)
 }
+
+func ImageLoad(ctx context.Context, r io.Reader) error {
+	resp, err := apiCli(ctx).ImageLoad(ctx, r, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return jsonmessage.DisplayJSONMessagesStream(resp.Body, ioutil.Discard, 0, false, nil)
+}
+
+func ImageSave(ctx context.Context, refs ...string) (io.ReadCloser, error) {
+	return apiCli(ctx).ImageSave(ctx, refs)
+}
@@ -12,12 +12,16 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 
 	"github.com/werf/logboek"
 
 	"github.com/werf/werf/pkg/docker_registry/container_registry_extensions"
 	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/tracing"
 )
 
 type api struct {
@@ -132,6 +136,85 @@ func (api *api) GetRepoImage(_ context.Context, reference string) (*image.Info,
 	return repoImage, nil
 }
 
+func (api *api) GetRepoV1Image(_ context.Context, reference string) (v1.Image, error) {
+	img, _, err := api.image(reference)
+	return img, err
+}
+
+func (api *api) WriteRepoV1Image(_ context.Context, reference string, img v1.Image) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	if err := remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport())); err != nil {
+		return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
+	}
+
+	return nil
+}
+
+func (api *api) PushImageIndex(_ context.Context, reference string, manifests []ImageIndexManifest) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	var mediaType types.MediaType = types.OCIImageIndex
+	var adds []mutate.IndexAddendum
+	for _, manifest := range manifests {
+		manifestRef, err := name.ParseReference(manifest.Reference, api.parseReferenceOptions()...)
+		if err != nil {
+			return fmt.Errorf("parsing reference %q: %v", manifest.Reference, err)
+		}
+
+		img, err := remote.Image(manifestRef, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport()))
+		if err != nil {
+			return fmt.Errorf("reading image %q: %v", manifestRef, err)
+		}
+
+		imgMediaType, err := img.MediaType()
+		if err != nil {
+			return fmt.Errorf("getting media type of image %q: %v", manifestRef, err)
+		}
+
+		if imgMediaType == types.DockerManifestSchema2 {
+			mediaType = types.DockerManifestList
+		}
+
+		platform, err := parsePlatform(manifest.Platform)
+		if err != nil {
+			return err
+		}
+
+		adds = append(adds, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: platform},
+		})
+	}
+
+	idx := mutate.IndexMediaType(mutate.AppendManifests(empty.Index, adds...), mediaType)
+	if err := remote.WriteIndex(ref, idx, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport())); err != nil {
+		return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
+	}
+
+	return nil
+}
+
+func parsePlatform(platform string) (*v1.Platform, error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("invalid platform %q: expected format OS/ARCH[/VARIANT]", platform)
+	}
+
+	res := &v1.Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		res.Variant = parts[2]
+	}
+
+	return res, nil
+}
+
 func (api *api) list(reference string) ([]string, error) {
 	repo, err := name.NewRepository(reference, api.newRepositoryOptions()...)
 	if err != nil {
@@ -251,11 +334,14 @@ func (api *api) parseReferenceOptions() []name.Option {
 	return options
 }
 
+// defaultHttpTransport is saved because http.DefaultTransport is temporarily replaced for the go-containerregistry library calls
+var defaultHttpTransport = http.DefaultTransport.(*http.Transport)
+
 func (api *api) getHttpTransport() (transport http.RoundTripper) {
-	transport = http.DefaultTransport
+	transport = defaultHttpTransport
 
 	if api.SkipTlsVerifyRegistry {
-		defaultTransport := http.DefaultTransport.(*http.Transport)
+		defaultTransport := defaultHttpTransport
 
 		newTransport := &http.Transport{
 			Proxy:                 defaultTransport.Proxy,
@@ -271,5 +357,5 @@ func (api *api) getHttpTransport() (transport http.RoundTripper) {
 		transport = newTransport
 	}
 
-	return
+	return tracing.NewTransport(transport)
 }
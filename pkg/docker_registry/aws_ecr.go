@@ -16,6 +16,9 @@ import (
 
 const AwsEcrImplementationName = "ecr"
 
+// awsEcrBatchDeleteImageLimit is the maximum number of images deleted by a single BatchDeleteImage request
+const awsEcrBatchDeleteImageLimit = 100
+
 var (
 	awsEcrPatternRegexp = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(-fips)?\.([a-zA-Z0-9][a-zA-Z0-9-_]*)\.amazonaws\.com(\.cn)?$`)
 	awsEcrPatterns      = []string{awsEcrPatternRegexp.String()}
@@ -60,6 +63,61 @@ func (r *awsEcr) DeleteRepoImage(_ context.Context, repoImage *image.Info) error
 	return err
 }
 
+// DeleteRepoImages deletes the images by digests with the BatchDeleteImage requests
+func (r *awsEcr) DeleteRepoImages(ctx context.Context, repoImages []*image.Info, _ DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	for _, repositoryGroup := range groupRepoImages(repoImages, repoImageRepository) {
+		if err := r.batchDeleteRepoImages(ctx, repositoryGroup.key, repositoryGroup.repoImages, f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *awsEcr) batchDeleteRepoImages(ctx context.Context, reference string, repoImages []*image.Info, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	_, region, repository, err := r.parseReference(reference)
+	if err != nil {
+		return err
+	}
+
+	mySession := session.Must(session.NewSession())
+	service := ecr.New(mySession, aws.NewConfig().WithRegion(region))
+
+	for _, digestGroups := range chunkRepoImagesGroups(groupRepoImages(repoImages, repoImageDigest), awsEcrBatchDeleteImageLimit) {
+		var imageIds []*ecr.ImageIdentifier
+		for _, digestGroup := range digestGroups {
+			imageIds = append(imageIds, &ecr.ImageIdentifier{ImageDigest: aws.String(digestGroup.key)})
+		}
+
+		output, err := service.BatchDeleteImageWithContext(ctx, &ecr.BatchDeleteImageInput{
+			ImageIds:       imageIds,
+			RepositoryName: &repository,
+		})
+
+		failureByDigest := map[string]error{}
+		if err == nil {
+			for _, failure := range output.Failures {
+				if failure.ImageId != nil && failure.ImageId.ImageDigest != nil {
+					failureByDigest[*failure.ImageId.ImageDigest] = fmt.Errorf("%s: %s", aws.StringValue(failure.FailureCode), aws.StringValue(failure.FailureReason))
+				}
+			}
+		}
+
+		for _, digestGroup := range digestGroups {
+			deletionErr := err
+			if deletionErr == nil {
+				deletionErr = failureByDigest[digestGroup.key]
+			}
+
+			if err := reportRepoImagesGroupDeletion(ctx, digestGroup, deletionErr, f); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (r *awsEcr) CreateRepo(_ context.Context, reference string) error {
 	_, region, repository, err := r.parseReference(reference)
 	if err != nil {
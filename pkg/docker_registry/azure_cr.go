@@ -53,6 +53,10 @@ func (r *azureCr) DeleteRepoImage(ctx context.Context, repoImage *image.Info) er
 	)
 }
 
+func (r *azureCr) DeleteRepoImages(ctx context.Context, repoImages []*image.Info, opts DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	return deleteRepoImagesInParallel(ctx, repoImages, opts, r.DeleteRepoImage, f)
+}
+
 func (r *azureCr) DeleteRepo(ctx context.Context, reference string) error {
 	registryName, repository, err := r.parseReference(reference)
 	if err != nil {
@@ -0,0 +1,91 @@
+package docker_registry
+
+import (
+	"context"
+	"errors"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/util/parallel"
+)
+
+// ErrDeletionScheduled is reported for the image which deletion is accepted by the registry API and performed asynchronously,
+// the image can still be available in the registry for some time
+var ErrDeletionScheduled = errors.New("deletion scheduled")
+
+func IsDeletionScheduledError(err error) bool {
+	return err == ErrDeletionScheduled
+}
+
+// repoImagesGroup is the images with the same key, e.g. the images of the same repository or with the same manifest digest
+type repoImagesGroup struct {
+	key        string
+	repoImages []*image.Info
+}
+
+// groupRepoImages groups the images by the key keeping the order of the images
+func groupRepoImages(repoImages []*image.Info, keyFunc func(repoImage *image.Info) string) []*repoImagesGroup {
+	var res []*repoImagesGroup
+	groupByKey := map[string]*repoImagesGroup{}
+	for _, repoImage := range repoImages {
+		key := keyFunc(repoImage)
+
+		group, ok := groupByKey[key]
+		if !ok {
+			group = &repoImagesGroup{key: key}
+			groupByKey[key] = group
+			res = append(res, group)
+		}
+
+		group.repoImages = append(group.repoImages, repoImage)
+	}
+
+	return res
+}
+
+func repoImageRepository(repoImage *image.Info) string {
+	return repoImage.Repository
+}
+
+func repoImageDigest(repoImage *image.Info) string {
+	return repoImage.RepoDigest
+}
+
+func repoImageTag(repoImage *image.Info) string {
+	return repoImage.Tag
+}
+
+// chunkRepoImagesGroups splits the groups into the chunks of at most size groups
+func chunkRepoImagesGroups(groups []*repoImagesGroup, size int) [][]*repoImagesGroup {
+	var res [][]*repoImagesGroup
+	for len(groups) > size {
+		res = append(res, groups[:size])
+		groups = groups[size:]
+	}
+
+	if len(groups) != 0 {
+		res = append(res, groups)
+	}
+
+	return res
+}
+
+// reportRepoImagesGroupDeletion calls f with the same deletion result for each image of the group
+func reportRepoImagesGroupDeletion(ctx context.Context, group *repoImagesGroup, err error, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	for _, repoImage := range group.repoImages {
+		if err := f(ctx, repoImage, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteRepoImagesInParallel deletes the images one by one with deleteFunc, it is used by the registries without the bulk deletion API
+func deleteRepoImagesInParallel(ctx context.Context, repoImages []*image.Info, opts DeleteRepoImagesOptions, deleteFunc func(ctx context.Context, repoImage *image.Info) error, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	return parallel.DoTasks(ctx, len(repoImages), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: opts.MaxNumberOfWorkers,
+	}, func(ctx context.Context, taskId int) error {
+		repoImage := repoImages[taskId]
+		return f(ctx, repoImage, deleteFunc(ctx, repoImage))
+	})
+}
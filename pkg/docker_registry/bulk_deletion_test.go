@@ -0,0 +1,305 @@
+package docker_registry_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+)
+
+const (
+	digestA = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB = "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	digestC = "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+)
+
+// registryStandIn records the requests of the docker registry client and responds with the handler
+type registryStandIn struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	requests []string
+}
+
+func newRegistryStandIn(isTLS bool, handler func(w http.ResponseWriter, r *http.Request, body string)) *registryStandIn {
+	standIn := &registryStandIn{}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		standIn.mutex.Lock()
+		standIn.requests = append(standIn.requests, fmt.Sprintf("%s %s", r.Method, r.URL.RequestURI()))
+		standIn.mutex.Unlock()
+
+		handler(w, r, string(body))
+	})
+
+	if isTLS {
+		standIn.Server = httptest.NewTLSServer(h)
+	} else {
+		standIn.Server = httptest.NewServer(h)
+	}
+
+	return standIn
+}
+
+func (s *registryStandIn) Host() string {
+	return strings.TrimPrefix(strings.TrimPrefix(s.URL, "https://"), "http://")
+}
+
+func (s *registryStandIn) Requests() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string{}, s.requests...)
+}
+
+// useDefaultHTTPClient makes the vendor API requests trust the TLS certificate of the stand-in
+func useDefaultHTTPClient(client *http.Client) func() {
+	defaultClient := http.DefaultClient
+	http.DefaultClient = client
+
+	return func() {
+		http.DefaultClient = defaultClient
+	}
+}
+
+func deleteRepoImages(registry docker_registry.DockerRegistry, repoImages []*image.Info) map[string]error {
+	var mutex sync.Mutex
+	res := map[string]error{}
+
+	err := registry.DeleteRepoImages(context.Background(), repoImages, docker_registry.DeleteRepoImagesOptions{MaxNumberOfWorkers: 2}, func(_ context.Context, repoImage *image.Info, err error) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		res[repoImage.Tag] = err
+		return nil
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	return res
+}
+
+var _ = Describe("docker registry bulk deletion", func() {
+	It("default implementation deletes manifests one by one", func() {
+		registry := newRegistryStandIn(false, func(w http.ResponseWriter, r *http.Request, _ string) {
+			switch {
+			case r.URL.Path == "/v2/":
+				w.WriteHeader(http.StatusOK)
+			case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/manifests/"+digestC):
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+			case r.Method == http.MethodDelete:
+				w.WriteHeader(http.StatusAccepted)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		defer registry.Close()
+
+		dockerRegistry, err := docker_registry.NewDockerRegistry(registry.Host()+"/repo", docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{InsecureRegistry: true})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		repository := registry.Host() + "/repo"
+		res := deleteRepoImages(dockerRegistry, []*image.Info{
+			{Repository: repository, Tag: "a", RepoDigest: digestA},
+			{Repository: repository, Tag: "b", RepoDigest: digestB},
+			{Repository: repository, Tag: "c", RepoDigest: digestC},
+		})
+
+		Ω(res).Should(HaveLen(3))
+		Ω(res["a"]).ShouldNot(HaveOccurred())
+		Ω(res["b"]).ShouldNot(HaveOccurred())
+		Ω(docker_registry.IsManifestUnknownError(res["c"])).Should(BeTrue())
+
+		Ω(registry.Requests()).Should(ContainElement("DELETE /v2/repo/manifests/" + digestA))
+		Ω(registry.Requests()).Should(ContainElement("DELETE /v2/repo/manifests/" + digestB))
+	})
+
+	It("harbor deletes artifacts by digest and schedules garbage collection", func() {
+		registry := newRegistryStandIn(true, func(w http.ResponseWriter, r *http.Request, body string) {
+			user, password, _ := r.BasicAuth()
+			if user != "user" || password != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch {
+			case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/artifacts/"+digestB):
+				w.WriteHeader(http.StatusNotFound)
+			case r.Method == http.MethodDelete:
+				w.WriteHeader(http.StatusOK)
+			case r.Method == http.MethodPost && r.URL.Path == "/api/v2.0/system/gc/schedule" && body == `{"schedule":{"type":"Manual"}}`:
+				w.WriteHeader(http.StatusCreated)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		})
+		defer registry.Close()
+		defer useDefaultHTTPClient(registry.Client())()
+
+		repository := registry.Host() + "/project/group/repo"
+		dockerRegistry, err := docker_registry.NewDockerRegistry(repository, docker_registry.HarborImplementationName, docker_registry.DockerRegistryOptions{HarborUsername: "user", HarborPassword: "password"})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		res := deleteRepoImages(dockerRegistry, []*image.Info{
+			{Repository: repository, Tag: "a1", RepoDigest: digestA},
+			{Repository: repository, Tag: "a2", RepoDigest: digestA},
+			{Repository: repository, Tag: "b", RepoDigest: digestB},
+		})
+
+		Ω(res).Should(HaveLen(3))
+		Ω(res["a1"]).ShouldNot(HaveOccurred())
+		Ω(res["a2"]).ShouldNot(HaveOccurred())
+		Ω(res["b"]).Should(BeAssignableToTypeOf(docker_registry.HarborNotFoundError{}))
+
+		Ω(registry.Requests()).Should(ConsistOf(
+			"DELETE /api/v2.0/projects/project/repositories/group%252Frepo/artifacts/"+digestA,
+			"DELETE /api/v2.0/projects/project/repositories/group%252Frepo/artifacts/"+digestB,
+		))
+
+		garbageCollector, ok := dockerRegistry.(docker_registry.GarbageCollector)
+		Ω(ok).Should(BeTrue())
+		Ω(garbageCollector.TriggerGarbageCollection(context.Background(), repository)).Should(Succeed())
+	})
+
+	It("gitlab schedules tags deletion with a bulk deletion request", func() {
+		var nameRegexDelete string
+		var registry *registryStandIn
+		registry = newRegistryStandIn(true, func(w http.ResponseWriter, r *http.Request, body string) {
+			if r.Header.Get("PRIVATE-TOKEN") != "token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch r.URL.RequestURI() {
+			case "/api/v4/projects/group%2Fproject%2Frepo":
+				w.WriteHeader(http.StatusNotFound)
+			case "/api/v4/projects/group%2Fproject":
+				_, _ = w.Write([]byte(`{"id":1}`))
+			case "/api/v4/projects/1/registry/repositories?per_page=100&page=1":
+				_, _ = w.Write([]byte(fmt.Sprintf(`[{"id":2,"location":"%[1]s/group/project"},{"id":3,"location":"%[1]s/group/project/repo"}]`, registry.Host())))
+			case "/api/v4/projects/1/registry/repositories/3/tags":
+				var params map[string]string
+				if err := json.Unmarshal([]byte(body), &params); err != nil || r.Method != http.MethodDelete {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				nameRegexDelete = params["name_regex_delete"]
+				w.WriteHeader(http.StatusAccepted)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		})
+		defer registry.Close()
+		defer useDefaultHTTPClient(registry.Client())()
+
+		repository := registry.Host() + "/group/project/repo"
+		dockerRegistry, err := docker_registry.NewDockerRegistry(repository, docker_registry.GitLabRegistryImplementationName, docker_registry.DockerRegistryOptions{GitLabToken: "token", GitLabApiURL: registry.URL + "/api/v4/"})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		res := deleteRepoImages(dockerRegistry, []*image.Info{
+			{Repository: repository, Tag: "a-1", RepoDigest: digestA},
+			{Repository: repository, Tag: "b.1", RepoDigest: digestB},
+		})
+
+		Ω(res).Should(HaveLen(2))
+		Ω(docker_registry.IsDeletionScheduledError(res["a-1"])).Should(BeTrue())
+		Ω(docker_registry.IsDeletionScheduledError(res["b.1"])).Should(BeTrue())
+
+		regex := regexp.MustCompile(nameRegexDelete)
+		Ω(regex.MatchString("a-1")).Should(BeTrue())
+		Ω(regex.MatchString("b.1")).Should(BeTrue())
+		Ω(regex.MatchString("bx1")).Should(BeFalse())
+		Ω(regex.MatchString("a-10")).Should(BeFalse())
+
+		Ω(registry.Requests()).Should(HaveLen(4))
+	})
+
+	It("gitlab splits tags into chunks and deletes tags one by one when the bulk deletion is rate limited", func() {
+		var bulkDeletionRequests int
+		var registry *registryStandIn
+		registry = newRegistryStandIn(true, func(w http.ResponseWriter, r *http.Request, _ string) {
+			switch {
+			case r.URL.Path == "/v2/":
+				w.WriteHeader(http.StatusOK)
+			case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v2/group/project/tags/reference/"):
+				w.WriteHeader(http.StatusAccepted)
+			case r.URL.RequestURI() == "/api/v4/projects/group%2Fproject":
+				_, _ = w.Write([]byte(`{"id":1}`))
+			case r.URL.RequestURI() == "/api/v4/projects/1/registry/repositories?per_page=100&page=1":
+				_, _ = w.Write([]byte(fmt.Sprintf(`[{"id":2,"location":"%s/group/project"}]`, registry.Host())))
+			case r.URL.RequestURI() == "/api/v4/projects/1/registry/repositories/2/tags":
+				bulkDeletionRequests++
+				if bulkDeletionRequests > 1 {
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+
+				w.WriteHeader(http.StatusAccepted)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		})
+		defer registry.Close()
+		defer useDefaultHTTPClient(registry.Client())()
+
+		repository := registry.Host() + "/group/project"
+		dockerRegistry, err := docker_registry.NewDockerRegistry(repository, docker_registry.GitLabRegistryImplementationName, docker_registry.DockerRegistryOptions{GitLabToken: "token", GitLabApiURL: registry.URL + "/api/v4", InsecureRegistry: true, SkipTlsVerifyRegistry: true})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		var repoImages []*image.Info
+		for i := 0; i < 60; i++ {
+			repoImages = append(repoImages, &image.Info{Repository: repository, Tag: fmt.Sprintf("tag-%d", i), RepoDigest: digestA})
+		}
+
+		res := deleteRepoImages(dockerRegistry, repoImages)
+
+		Ω(res).Should(HaveLen(60))
+		Ω(bulkDeletionRequests).Should(Equal(2))
+		for i := 0; i < 50; i++ {
+			Ω(docker_registry.IsDeletionScheduledError(res[fmt.Sprintf("tag-%d", i)])).Should(BeTrue())
+		}
+		for i := 50; i < 60; i++ {
+			Ω(res[fmt.Sprintf("tag-%d", i)]).ShouldNot(HaveOccurred())
+			Ω(registry.Requests()).Should(ContainElement(fmt.Sprintf("DELETE /v2/group/project/tags/reference/tag-%d", i)))
+		}
+	})
+
+	It("gitlab deletes tags one by one when the GitLab API URL is not specified", func() {
+		registry := newRegistryStandIn(true, func(w http.ResponseWriter, r *http.Request, _ string) {
+			switch {
+			case r.URL.Path == "/v2/":
+				w.WriteHeader(http.StatusOK)
+			case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v2/group/project/tags/reference/"):
+				w.WriteHeader(http.StatusAccepted)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		})
+		defer registry.Close()
+
+		repository := registry.Host() + "/group/project"
+		dockerRegistry, err := docker_registry.NewDockerRegistry(repository, docker_registry.GitLabRegistryImplementationName, docker_registry.DockerRegistryOptions{GitLabToken: "token", InsecureRegistry: true, SkipTlsVerifyRegistry: true})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		res := deleteRepoImages(dockerRegistry, []*image.Info{{Repository: repository, Tag: "a", RepoDigest: digestA}})
+
+		Ω(res).Should(HaveLen(1))
+		Ω(res["a"]).ShouldNot(HaveOccurred())
+		Ω(registry.Requests()).Should(ContainElement("DELETE /v2/group/project/tags/reference/a"))
+	})
+})
@@ -37,6 +37,10 @@ func (r *defaultImplementation) DeleteRepoImage(_ context.Context, repoImage *im
 	return r.api.deleteImageByReference(reference)
 }
 
+func (r *defaultImplementation) DeleteRepoImages(ctx context.Context, repoImages []*image.Info, opts DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	return deleteRepoImagesInParallel(ctx, repoImages, opts, r.DeleteRepoImage, f)
+}
+
 func (r *defaultImplementation) String() string {
 	return DefaultImplementationName
 }
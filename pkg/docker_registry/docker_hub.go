@@ -81,6 +81,10 @@ func (r *dockerHub) DeleteRepoImage(ctx context.Context, repoImage *image.Info)
 	return nil
 }
 
+func (r *dockerHub) DeleteRepoImages(ctx context.Context, repoImages []*image.Info, opts DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	return deleteRepoImagesInParallel(ctx, repoImages, opts, r.DeleteRepoImage, f)
+}
+
 func (r *dockerHub) deleteRepo(ctx context.Context, reference string) error {
 	token, err := r.getToken(ctx)
 	if err != nil {
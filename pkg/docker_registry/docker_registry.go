@@ -20,15 +20,36 @@ type DockerRegistry interface {
 	TryGetRepoImage(ctx context.Context, reference string) (*image.Info, error)
 	IsRepoImageExists(ctx context.Context, reference string) (bool, error)
 	DeleteRepoImage(ctx context.Context, repoImage *image.Info) error
+	// DeleteRepoImages deletes the images using the bulk deletion API of the registry if available,
+	// otherwise the images are deleted one by one in parallel. The function f is called with the deletion result of each image
+	DeleteRepoImages(ctx context.Context, repoImages []*image.Info, opts DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error
 	PushImage(ctx context.Context, reference string, opts *PushImageOptions) error
+	PushImageIndex(ctx context.Context, reference string, manifests []ImageIndexManifest) error
 
 	String() string
 }
 
+// GarbageCollector is implemented by the registries which API allows to trigger the garbage collection,
+// the space of the deleted images is not freed until the garbage collection is performed
+type GarbageCollector interface {
+	TriggerGarbageCollection(ctx context.Context, reference string) error
+}
+
+type DeleteRepoImagesOptions struct {
+	// MaxNumberOfWorkers limits the number of parallel deletions when the images are deleted one by one
+	MaxNumberOfWorkers int
+}
+
 type PushImageOptions struct {
 	Labels map[string]string
 }
 
+// ImageIndexManifest is the image already pushed to the registry that should be referenced by the image index for the platform
+type ImageIndexManifest struct {
+	Reference string
+	Platform  string
+}
+
 type DockerRegistryOptions struct {
 	InsecureRegistry      bool
 	SkipTlsVerifyRegistry bool
@@ -36,6 +57,8 @@ type DockerRegistryOptions struct {
 	DockerHubUsername     string
 	DockerHubPassword     string
 	GitHubToken           string
+	GitLabToken           string
+	GitLabApiURL          string
 	HarborUsername        string
 	HarborPassword        string
 	QuayToken             string
@@ -82,6 +105,10 @@ func (o *DockerRegistryOptions) gitHubPackagesOptions() gitHubPackagesOptions {
 func (o *DockerRegistryOptions) gitLabRegistryOptions() gitLabRegistryOptions {
 	return gitLabRegistryOptions{
 		defaultImplementationOptions: o.defaultOptions(),
+		gitLabCredentials: gitLabCredentials{
+			token: o.GitLabToken,
+		},
+		apiURL: o.GitLabApiURL,
 	}
 }
 
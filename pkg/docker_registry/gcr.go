@@ -35,6 +35,10 @@ func (r *gcr) DeleteRepoImage(_ context.Context, repoImage *image.Info) error {
 	return r.api.deleteImageByReference(reference)
 }
 
+func (r *gcr) DeleteRepoImages(ctx context.Context, repoImages []*image.Info, opts DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	return deleteRepoImagesInParallel(ctx, repoImages, opts, r.DeleteRepoImage, f)
+}
+
 func (r *gcr) String() string {
 	return GcrImplementationName
 }
@@ -85,6 +85,10 @@ func (r *gitHubPackages) DeleteRepoImage(ctx context.Context, repoImage *image.I
 	return nil
 }
 
+func (r *gitHubPackages) DeleteRepoImages(ctx context.Context, repoImages []*image.Info, opts DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	return deleteRepoImagesInParallel(ctx, repoImages, opts, r.DeleteRepoImage, f)
+}
+
 func (r *gitHubPackages) deletePackageVersion(ctx context.Context, owner, project, packageName, packageVersion string) error {
 	processError := func(resp *http.Response, err error) error {
 		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
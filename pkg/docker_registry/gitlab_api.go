@@ -0,0 +1,101 @@
+package docker_registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+)
+
+// gitLabRegistryRepositoriesPerPage is the maximum page size of the GitLab API
+const gitLabRegistryRepositoriesPerPage = 100
+
+type gitLabApi struct{}
+
+func newGitLabApi() gitLabApi {
+	return gitLabApi{}
+}
+
+type gitLabProject struct {
+	ID int `json:"id"`
+}
+
+type gitLabRegistryRepository struct {
+	ID       int    `json:"id"`
+	Location string `json:"location"`
+}
+
+func (api *gitLabApi) getProject(ctx context.Context, apiURL, projectPath, token string) (*gitLabProject, *http.Response, error) {
+	url := fmt.Sprintf("%s/projects/%s", apiURL, neturl.PathEscape(projectPath))
+
+	resp, respBody, err := doRequest(ctx, http.MethodGet, url, nil, doRequestOptions{
+		Headers:       api.headers(token),
+		AcceptedCodes: []int{http.StatusOK},
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	project := &gitLabProject{}
+	if err := json.Unmarshal(respBody, project); err != nil {
+		return nil, resp, fmt.Errorf("unable to unmarshal project %s: %s", projectPath, err)
+	}
+
+	return project, resp, nil
+}
+
+func (api *gitLabApi) getRegistryRepositories(ctx context.Context, apiURL string, projectID int, token string) ([]*gitLabRegistryRepository, *http.Response, error) {
+	var res []*gitLabRegistryRepository
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/projects/%d/registry/repositories?per_page=%d&page=%d", apiURL, projectID, gitLabRegistryRepositoriesPerPage, page)
+
+		resp, respBody, err := doRequest(ctx, http.MethodGet, url, nil, doRequestOptions{
+			Headers:       api.headers(token),
+			AcceptedCodes: []int{http.StatusOK},
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+
+		var repositories []*gitLabRegistryRepository
+		if err := json.Unmarshal(respBody, &repositories); err != nil {
+			return nil, resp, fmt.Errorf("unable to unmarshal registry repositories of project %d: %s", projectID, err)
+		}
+
+		res = append(res, repositories...)
+		if len(repositories) < gitLabRegistryRepositoriesPerPage {
+			return res, resp, nil
+		}
+	}
+}
+
+// deleteRegistryRepositoryTagsInBulk schedules the deletion of the tags matching the regexp, the tags are deleted asynchronously.
+// The regexp is passed in the request body because the URL length is limited.
+// GitLab rejects the request with 400 if the regexp is not accepted and rate limits the requests for the same repository with 429
+func (api *gitLabApi) deleteRegistryRepositoryTagsInBulk(ctx context.Context, apiURL string, projectID, repositoryID int, nameRegexDelete, token string) (*http.Response, error) {
+	url := fmt.Sprintf("%s/projects/%d/registry/repositories/%d/tags", apiURL, projectID, repositoryID)
+
+	body, err := json.Marshal(map[string]string{"name_regex_delete": nameRegexDelete})
+	if err != nil {
+		return nil, err
+	}
+
+	headers := api.headers(token)
+	headers["Content-Type"] = "application/json"
+
+	resp, _, err := doRequest(ctx, http.MethodDelete, url, bytes.NewReader(body), doRequestOptions{
+		Headers:       headers,
+		AcceptedCodes: []int{http.StatusAccepted},
+	})
+
+	return resp, err
+}
+
+func (api *gitLabApi) headers(token string) map[string]string {
+	return map[string]string{
+		"Accept":        "application/json",
+		"PRIVATE-TOKEN": token,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/authn"
@@ -19,6 +20,15 @@ import (
 
 const GitLabRegistryImplementationName = "gitlab"
 
+const (
+	// gitLabComRegistryHostname is the registry of gitlab.com, the GitLab API address of other instances is specified explicitly
+	gitLabComRegistryHostname = "registry.gitlab.com"
+	gitLabComApiURL           = "https://gitlab.com/api/v4"
+
+	// gitLabBulkDeletionTagsPerRequest limits the number of tags in the regexp of the bulk deletion request
+	gitLabBulkDeletionTagsPerRequest = 50
+)
+
 var (
 	gitlabPatterns = []string{`^gitlab\.com`}
 
@@ -34,11 +44,20 @@ var (
 
 type gitLabRegistry struct {
 	*defaultImplementation
+	gitLabApi
+	gitLabCredentials
+	apiURL              string
 	deleteRepoImageFunc func(ctx context.Context, repoImage *image.Info) error
 }
 
 type gitLabRegistryOptions struct {
 	defaultImplementationOptions
+	gitLabCredentials
+	apiURL string
+}
+
+type gitLabCredentials struct {
+	token string
 }
 
 func newGitLabRegistry(options gitLabRegistryOptions) (*gitLabRegistry, error) {
@@ -47,7 +66,12 @@ func newGitLabRegistry(options gitLabRegistryOptions) (*gitLabRegistry, error) {
 		return nil, err
 	}
 
-	gitLab := &gitLabRegistry{defaultImplementation: d}
+	gitLab := &gitLabRegistry{
+		defaultImplementation: d,
+		gitLabApi:             newGitLabApi(),
+		gitLabCredentials:     options.gitLabCredentials,
+		apiURL:                strings.TrimSuffix(options.apiURL, "/"),
+	}
 
 	return gitLab, nil
 }
@@ -107,6 +131,123 @@ func (r *gitLabRegistry) DeleteRepoImage(ctx context.Context, repoImage *image.I
 	return nil
 }
 
+// DeleteRepoImages deletes the tags with the GitLab bulk tags deletion API if the GitLab token is specified.
+// GitLab deletes the tags asynchronously, so the tags are reported with ErrDeletionScheduled.
+// The bulk deletion is rate limited by GitLab, the tags are deleted one by one if the bulk deletion is not available or rejected
+func (r *gitLabRegistry) DeleteRepoImages(ctx context.Context, repoImages []*image.Info, opts DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	if r.gitLabCredentials.token == "" {
+		return deleteRepoImagesInParallel(ctx, repoImages, opts, r.DeleteRepoImage, f)
+	}
+
+	for _, repositoryGroup := range groupRepoImages(repoImages, repoImageRepository) {
+		if err := r.deleteRepoImagesInBulk(ctx, repositoryGroup.key, repositoryGroup.repoImages, opts, f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *gitLabRegistry) deleteRepoImagesInBulk(ctx context.Context, reference string, repoImages []*image.Info, opts DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	apiURL, projectID, repositoryID, err := r.getRegistryRepositoryID(ctx, reference)
+	if err != nil {
+		logboek.Context(ctx).Info().LogF("Bulk tags deletion is not available for %s, deleting tags one by one: %s\n", reference, err)
+		return deleteRepoImagesInParallel(ctx, repoImages, opts, r.DeleteRepoImage, f)
+	}
+
+	tagsChunks := chunkRepoImagesGroups(groupRepoImages(repoImages, repoImageTag), gitLabBulkDeletionTagsPerRequest)
+	for ind, tagGroups := range tagsChunks {
+		var tagRegexps []string
+		for _, tagGroup := range tagGroups {
+			tagRegexps = append(tagRegexps, regexp.QuoteMeta(tagGroup.key))
+		}
+		nameRegexDelete := fmt.Sprintf("^(%s)$", strings.Join(tagRegexps, "|"))
+
+		deletionErr := ErrDeletionScheduled
+		if resp, err := r.gitLabApi.deleteRegistryRepositoryTagsInBulk(ctx, apiURL, projectID, repositoryID, nameRegexDelete, r.gitLabCredentials.token); err != nil {
+			if resp != nil && (resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusTooManyRequests) {
+				var restRepoImages []*image.Info
+				for _, restTagGroups := range tagsChunks[ind:] {
+					for _, tagGroup := range restTagGroups {
+						restRepoImages = append(restRepoImages, tagGroup.repoImages...)
+					}
+				}
+
+				logboek.Context(ctx).Info().LogF("Bulk tags deletion is rejected for %s, deleting %d tags one by one: %s\n", reference, len(restRepoImages), err)
+				return deleteRepoImagesInParallel(ctx, restRepoImages, opts, r.DeleteRepoImage, f)
+			}
+
+			deletionErr = err
+		}
+
+		for _, tagGroup := range tagGroups {
+			if err := reportRepoImagesGroupDeletion(ctx, tagGroup, deletionErr, f); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// getApiURL returns the GitLab API address specified by the user, the address is known only for the registry of gitlab.com
+func (r *gitLabRegistry) getApiURL(registryHostname string) (string, error) {
+	if r.apiURL != "" {
+		return r.apiURL, nil
+	}
+
+	if registryHostname == gitLabComRegistryHostname {
+		return gitLabComApiURL, nil
+	}
+
+	return "", fmt.Errorf("GitLab API address of registry %s is not specified", registryHostname)
+}
+
+// getRegistryRepositoryID returns the GitLab API address, the project ID and the registry repository ID by the registry repository reference,
+// the project path is the longest existing prefix of the repository path
+func (r *gitLabRegistry) getRegistryRepositoryID(ctx context.Context, reference string) (string, int, int, error) {
+	repository, err := name.NewRepository(reference, r.api.parseReferenceOptions()...)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	apiURL, err := r.getApiURL(repository.RegistryStr())
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	location := strings.Join([]string{repository.RegistryStr(), repository.RepositoryStr()}, "/")
+
+	pathParts := strings.Split(repository.RepositoryStr(), "/")
+	for i := len(pathParts); i > 0; i-- {
+		projectPath := strings.Join(pathParts[:i], "/")
+
+		project, resp, err := r.gitLabApi.getProject(ctx, apiURL, projectPath, r.gitLabCredentials.token)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				continue
+			}
+
+			return "", 0, 0, err
+		}
+
+		registryRepositories, _, err := r.gitLabApi.getRegistryRepositories(ctx, apiURL, project.ID, r.gitLabCredentials.token)
+		if err != nil {
+			return "", 0, 0, err
+		}
+
+		for _, registryRepository := range registryRepositories {
+			if registryRepository.Location == location {
+				return apiURL, project.ID, registryRepository.ID, nil
+			}
+		}
+
+		return "", 0, 0, fmt.Errorf("registry repository %s not found in project %s", location, projectPath)
+	}
+
+	return "", 0, 0, fmt.Errorf("project of registry repository %s not found", location)
+}
+
 func (r *gitLabRegistry) deleteRepoImageTagWithUniversalScope(_ context.Context, repoImage *image.Info) error {
 	return r.deleteRepoImageTagWithCustomScope(repoImage, universalScopeFunc)
 }
@@ -7,7 +7,10 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/name"
 
+	"github.com/werf/logboek"
+
 	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/util/parallel"
 )
 
 const HarborImplementationName = "harbor"
@@ -104,6 +107,66 @@ func (r *harbor) deleteRepo(ctx context.Context, reference string) error {
 	return nil
 }
 
+// DeleteRepoImages deletes the artifacts by digests with the Harbor API, the artifact is deleted with all its tags.
+// The images are deleted with the docker registry API if the Harbor credentials are not specified
+func (r *harbor) DeleteRepoImages(ctx context.Context, repoImages []*image.Info, opts DeleteRepoImagesOptions, f func(ctx context.Context, repoImage *image.Info, err error) error) error {
+	if r.harborCredentials.username == "" || r.harborCredentials.password == "" {
+		return deleteRepoImagesInParallel(ctx, repoImages, opts, r.DeleteRepoImage, f)
+	}
+
+	artifactGroups := groupRepoImages(repoImages, func(repoImage *image.Info) string {
+		return strings.Join([]string{repoImage.Repository, repoImage.RepoDigest}, "@")
+	})
+
+	return parallel.DoTasks(ctx, len(artifactGroups), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: opts.MaxNumberOfWorkers,
+	}, func(ctx context.Context, taskId int) error {
+		artifactGroup := artifactGroups[taskId]
+		err := r.deleteArtifact(ctx, artifactGroup.repoImages[0])
+		return reportRepoImagesGroupDeletion(ctx, artifactGroup, err, f)
+	})
+}
+
+func (r *harbor) deleteArtifact(ctx context.Context, repoImage *image.Info) error {
+	hostname, repository, err := r.parseReference(repoImage.Repository)
+	if err != nil {
+		return err
+	}
+
+	// the repository of the project root cannot be addressed by the artifact API
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) != 2 {
+		return r.DeleteRepoImage(ctx, repoImage)
+	}
+
+	resp, err := r.harborApi.DeleteArtifact(ctx, hostname, parts[0], parts[1], repoImage.RepoDigest, r.harborCredentials.username, r.harborCredentials.password)
+	if resp != nil {
+		if resp.StatusCode == http.StatusNotFound {
+			return HarborNotFoundError{error: err}
+		}
+	}
+
+	return err
+}
+
+// TriggerGarbageCollection schedules the manual garbage collection of the Harbor instance, it requires the system admin permissions
+func (r *harbor) TriggerGarbageCollection(ctx context.Context, reference string) error {
+	hostname, _, err := r.parseReference(reference)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.harborApi.ScheduleGarbageCollection(ctx, hostname, r.harborCredentials.username, r.harborCredentials.password)
+	if resp != nil {
+		if resp.StatusCode == http.StatusConflict {
+			logboek.Context(ctx).Info().LogF("Garbage collection is already running in %s\n", hostname)
+			return nil
+		}
+	}
+
+	return err
+}
+
 func (r *harbor) String() string {
 	return HarborImplementationName
 }
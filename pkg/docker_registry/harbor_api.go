@@ -2,9 +2,11 @@ package docker_registry
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	neturl "net/url"
 	"path"
+	"strings"
 )
 
 type harborApi struct{}
@@ -35,3 +37,46 @@ func (api *harborApi) DeleteRepository(ctx context.Context, hostname, repository
 
 	return resp, err
 }
+
+func (api *harborApi) DeleteArtifact(ctx context.Context, hostname, project, repository, reference, username, password string) (*http.Response, error) {
+	url := fmt.Sprintf(
+		"https://%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s",
+		hostname,
+		neturl.PathEscape(project),
+		// the slashes of the repository name are encoded twice according to the Harbor API
+		neturl.PathEscape(neturl.PathEscape(repository)),
+		reference,
+	)
+
+	resp, _, err := doRequest(ctx, http.MethodDelete, url, nil, doRequestOptions{
+		Headers: map[string]string{
+			"Accept": "application/json",
+		},
+		BasicAuth: doRequestBasicAuth{
+			username: username,
+			password: password,
+		},
+		AcceptedCodes: []int{http.StatusOK},
+	})
+
+	return resp, err
+}
+
+func (api *harborApi) ScheduleGarbageCollection(ctx context.Context, hostname, username, password string) (*http.Response, error) {
+	url := fmt.Sprintf("https://%s/api/v2.0/system/gc/schedule", hostname)
+	body := strings.NewReader(`{"schedule":{"type":"Manual"}}`)
+
+	resp, _, err := doRequest(ctx, http.MethodPost, url, body, doRequestOptions{
+		Headers: map[string]string{
+			"Accept":       "application/json",
+			"Content-Type": "application/json",
+		},
+		BasicAuth: doRequestBasicAuth{
+			username: username,
+			password: password,
+		},
+		AcceptedCodes: []int{http.StatusOK, http.StatusCreated},
+	})
+
+	return resp, err
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 
 	"github.com/werf/logboek"
 
@@ -33,29 +34,38 @@ type Local struct {
 
 type OpenLocalRepoOptions struct {
 	Dev bool
+	// Revision is used as the head commit of the repo instead of the work tree HEAD, Dev option is ignored in this case
+	Revision string
 }
 
-func OpenLocalRepo(name, workTreeDir string, opts OpenLocalRepoOptions) (l Local, err error) {
-	_, err = git.PlainOpenWithOptions(workTreeDir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+func OpenLocalRepo(name, workTreeDir string, opts OpenLocalRepoOptions) (*Local, error) {
+	_, err := git.PlainOpenWithOptions(workTreeDir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
 	if err != nil {
 		if err == git.ErrRepositoryNotExists {
-			return l, ErrLocalRepositoryNotExists
+			return nil, ErrLocalRepositoryNotExists
 		}
 
-		return l, err
+		return nil, err
 	}
 
 	gitDir, err := true_git.ResolveRepoDir(filepath.Join(workTreeDir, git.GitDirName))
 	if err != nil {
-		return l, fmt.Errorf("unable to resolve git repo dir for %s: %s", workTreeDir, err)
+		return nil, fmt.Errorf("unable to resolve git repo dir for %s: %s", workTreeDir, err)
 	}
 
-	l, err = newLocal(name, workTreeDir, gitDir)
+	l, err := newLocal(name, workTreeDir, gitDir)
 	if err != nil {
-		return l, err
+		return nil, err
 	}
 
-	if opts.Dev {
+	if opts.Revision != "" {
+		commit, err := l.resolveRevision(opts.Revision)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve revision %q: %s", opts.Revision, err)
+		}
+
+		l.headCommit = commit
+	} else if opts.Dev {
 		devHeadCommit, err := true_git.SyncDevBranchWithStagedFiles(
 			context.Background(),
 			l.GitDir,
@@ -63,7 +73,7 @@ func OpenLocalRepo(name, workTreeDir string, opts OpenLocalRepoOptions) (l Local
 			l.headCommit,
 		)
 		if err != nil {
-			return l, err
+			return nil, err
 		}
 
 		l.headCommit = devHeadCommit
@@ -72,13 +82,13 @@ func OpenLocalRepo(name, workTreeDir string, opts OpenLocalRepoOptions) (l Local
 	return l, nil
 }
 
-func newLocal(name, workTreeDir, gitDir string) (l Local, err error) {
+func newLocal(name, workTreeDir, gitDir string) (*Local, error) {
 	headCommit, err := getHeadCommit(workTreeDir)
 	if err != nil {
-		return l, fmt.Errorf("unable to get git repo head commit: %s", err)
+		return nil, fmt.Errorf("unable to get git repo head commit: %s", err)
 	}
 
-	l = Local{
+	l := &Local{
 		Base:        Base{Name: name},
 		WorkTreeDir: workTreeDir,
 		GitDir:      gitDir,
@@ -88,6 +98,20 @@ func newLocal(name, workTreeDir, gitDir string) (l Local, err error) {
 	return l, nil
 }
 
+func (repo *Local) resolveRevision(revision string) (string, error) {
+	repository, err := repo.PlainOpen()
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := repository.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
 func (repo *Local) PlainOpen() (*git.Repository, error) {
 	return git.PlainOpen(repo.WorkTreeDir)
 }
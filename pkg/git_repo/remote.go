@@ -16,6 +16,7 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 
@@ -298,6 +299,77 @@ func (repo *Remote) IsCommitExists(ctx context.Context, commit string) (bool, er
 	return repo.isCommitExists(ctx, repo.GetClonePath(), repo.GetClonePath(), commit)
 }
 
+func (repo *Remote) IsCommitFileExists(_ context.Context, commit, path string) (bool, error) {
+	commitObj, err := repo.getCommitObject(commit)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := commitObj.File(filepath.ToSlash(path)); err == object.ErrFileNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (repo *Remote) GetCommitFilePathList(_ context.Context, commit string) ([]string, error) {
+	commitObj, err := repo.getCommitObject(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	filesIter, err := commitObj.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	if err := filesIter.ForEach(func(f *object.File) error {
+		res = append(res, filepath.FromSlash(f.Name))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ReadCommitFile reads the file from the commit tree of the bare clone, the symlinks are not resolved
+func (repo *Remote) ReadCommitFile(_ context.Context, commit, path string) ([]byte, error) {
+	commitObj, err := repo.getCommitObject(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := commitObj.File(filepath.ToSlash(path))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get file %q of commit %s: %s", path, commit, err)
+	}
+
+	data, err := f.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %q of commit %s: %s", path, commit, err)
+	}
+
+	return []byte(data), nil
+}
+
+func (repo *Remote) getCommitObject(commit string) (*object.Commit, error) {
+	rawRepo, err := git.PlainOpenWithOptions(repo.GetClonePath(), &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open repo: %s", err)
+	}
+
+	commitObj, err := rawRepo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s commit info: %s", commit, err)
+	}
+
+	return commitObj, nil
+}
+
 func (repo *Remote) getRepoID() string {
 	return util.Sha256Hash(repo.getFilesystemRelativePathByEndpoint())
 }
@@ -81,6 +81,10 @@ func (c Config) IsConfigStapelMountFromPathAccepted(fromPath string) (bool, erro
 	return c.Config.Stapel.Mount.IsFromPathAccepted(fromPath)
 }
 
+func (c Config) IsConfigSecretKeyExecProviderAccepted() bool {
+	return c.Config.SecretKey.AllowExecProvider
+}
+
 func (c Config) IsConfigDockerfileContextAddFileAccepted(relPath string) (bool, error) {
 	return c.Config.Dockerfile.IsContextAddFileAccepted(relPath)
 }
@@ -103,6 +107,7 @@ type config struct {
 	GoTemplateRendering       goTemplateRendering `json:"goTemplateRendering"`
 	Stapel                    stapel              `json:"stapel"`
 	Dockerfile                dockerfile          `json:"dockerfile"`
+	SecretKey                 secretKey           `json:"secretKey"`
 }
 
 func (c config) IsUncommittedTemplateFileAccepted(path string) (bool, error) {
@@ -123,9 +128,11 @@ func (r goTemplateRendering) IsEnvNameAccepted(name string) (bool, error) {
 				return false, err
 			}
 
-			return r.MatchString(name), nil
-		} else {
-			return pattern == name, nil
+			if r.MatchString(name) {
+				return true, nil
+			}
+		} else if pattern == name {
+			return true, nil
 		}
 	}
 
@@ -155,6 +162,10 @@ func (m mount) IsFromPathAccepted(path string) (bool, error) {
 	return isPathMatched(m.AllowFromPaths, path)
 }
 
+type secretKey struct {
+	AllowExecProvider bool `json:"allowExecProvider"`
+}
+
 type dockerfile struct {
 	AllowUncommitted                  []string `json:"allowUncommitted"`
 	AllowUncommittedDockerignoreFiles []string `json:"allowUncommittedDockerignoreFiles"`
@@ -43,6 +43,8 @@ definitions:
         $ref: '#/definitions/ConfigStapel'
       dockerfile:
         $ref: '#/definitions/ConfigDockerfile'
+      secretKey:
+        $ref: '#/definitions/ConfigSecretKey'
   ConfigGoTemplateRendering:
     type: object
     additionalProperties: {}
@@ -81,6 +83,12 @@ definitions:
         type: array
         items:
           type: string
+  ConfigSecretKey:
+    type: object
+    additionalProperties: {}
+    properties:
+      allowExecProvider:
+        type: boolean
   ConfigDockerfile:
     type: object
     additionalProperties: {}
@@ -36,7 +36,7 @@ func (r FileReader) ReadConfig(ctx context.Context, customRelPath string) ([]byt
 }
 
 func (r FileReader) isConfigExist(ctx context.Context, relPath string) (bool, error) {
-	return r.isConfigurationFileExist(ctx, relPath, func(_ string) (bool, error) {
+	return r.isConfigurationFileExist(ctx, configErrorConfigType, relPath, func(_ string) (bool, error) {
 		return r.giterminismConfig.IsUncommittedConfigAccepted(), nil
 	})
 }
@@ -0,0 +1,22 @@
+package file_reader
+
+import (
+	"context"
+)
+
+func (r FileReader) IsConfigParamsFileExistAnywhere(ctx context.Context, relPath string) (bool, error) {
+	return r.isConfigurationFileExistAnywhere(ctx, relPath)
+}
+
+// ReadConfigParamsFile reads the werf config parameters file, the file is accepted uncommitted along with the werf config
+func (r FileReader) ReadConfigParamsFile(ctx context.Context, relPath string) ([]byte, error) {
+	if err := r.checkConfigurationFileExistence(ctx, configParamsErrorConfigType, relPath, r.isUncommittedConfigParamsFileAccepted); err != nil {
+		return nil, err
+	}
+
+	return r.readConfigurationFile(ctx, configParamsErrorConfigType, relPath, r.isUncommittedConfigParamsFileAccepted)
+}
+
+func (r FileReader) isUncommittedConfigParamsFileAccepted(_ string) (bool, error) {
+	return r.giterminismConfig.IsUncommittedConfigAccepted(), nil
+}
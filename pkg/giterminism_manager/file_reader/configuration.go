@@ -2,10 +2,13 @@ package file_reader
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 )
 
 func (r FileReader) configurationFilesGlob(ctx context.Context, configType configType, pattern string, isFileAcceptedFunc func(relPath string) (bool, error), readCommitFileFunc func(ctx context.Context, relPath string) ([]byte, error), handleFileFunc func(relPath string, data []byte, err error) error) error {
+	isFileAcceptedFunc = r.reportAcceptedFunc(ctx, configType, isFileAcceptedFunc)
+
 	processedFiles := map[string]bool{}
 
 	isFileProcessedFunc := func(relPath string) bool {
@@ -99,6 +102,8 @@ func (r FileReader) configurationFilesGlob(ctx context.Context, configType confi
 }
 
 func (r FileReader) readConfigurationFile(ctx context.Context, configType configType, relPath string, isFileAcceptedFunc func(relPath string) (bool, error)) ([]byte, error) {
+	isFileAcceptedFunc = r.reportAcceptedFunc(ctx, configType, isFileAcceptedFunc)
+
 	accepted, err := isFileAcceptedFunc(relPath)
 	if err != nil {
 		return nil, err
@@ -114,6 +119,8 @@ func (r FileReader) readConfigurationFile(ctx context.Context, configType config
 }
 
 func (r FileReader) checkConfigurationDirectoryExistence(ctx context.Context, configType configType, relPath string, isFileAcceptedFunc func(relPath string) (bool, error)) error {
+	isFileAcceptedFunc = r.reportAcceptedFunc(ctx, configType, isFileAcceptedFunc)
+
 	accepted, err := isFileAcceptedFunc(relPath)
 	if err != nil {
 		return err
@@ -149,6 +156,8 @@ func (r FileReader) checkConfigurationDirectoryExistence(ctx context.Context, co
 }
 
 func (r FileReader) checkConfigurationFileExistence(ctx context.Context, configType configType, relPath string, isFileAcceptedFunc func(relPath string) (bool, error)) error {
+	isFileAcceptedFunc = r.reportAcceptedFunc(ctx, configType, isFileAcceptedFunc)
+
 	accepted, err := isFileAcceptedFunc(relPath)
 	if err != nil {
 		return err
@@ -193,7 +202,9 @@ func (r FileReader) isConfigurationFileExistAnywhere(ctx context.Context, relPat
 	}
 }
 
-func (r FileReader) isConfigurationDirectoryExist(ctx context.Context, relPath string, isFileAcceptedFunc func(relPath string) (bool, error)) (bool, error) {
+func (r FileReader) isConfigurationDirectoryExist(ctx context.Context, configType configType, relPath string, isFileAcceptedFunc func(relPath string) (bool, error)) (bool, error) {
+	isFileAcceptedFunc = r.reportAcceptedFunc(ctx, configType, isFileAcceptedFunc)
+
 	accepted, err := isFileAcceptedFunc(relPath)
 	if err != nil {
 		return false, err
@@ -206,7 +217,9 @@ func (r FileReader) isConfigurationDirectoryExist(ctx context.Context, relPath s
 	return r.isCommitDirectoryExist(ctx, relPath)
 }
 
-func (r FileReader) isConfigurationFileExist(ctx context.Context, relPath string, isFileAcceptedFunc func(relPath string) (bool, error)) (bool, error) {
+func (r FileReader) isConfigurationFileExist(ctx context.Context, configType configType, relPath string, isFileAcceptedFunc func(relPath string) (bool, error)) (bool, error) {
+	isFileAcceptedFunc = r.reportAcceptedFunc(ctx, configType, isFileAcceptedFunc)
+
 	accepted, err := isFileAcceptedFunc(relPath)
 	if err != nil {
 		return false, err
@@ -218,3 +231,57 @@ func (r FileReader) isConfigurationFileExist(ctx context.Context, relPath string
 
 	return r.isCommitFileExist(ctx, relPath)
 }
+
+// reportAcceptedFunc wraps the isFileAcceptedFunc in the report mode: the uncommitted file is registered as the violation
+// with the werf-giterminism.yaml rule that allows it and then is read from the project directory as the accepted one
+func (r FileReader) reportAcceptedFunc(ctx context.Context, configType configType, isFileAcceptedFunc func(relPath string) (bool, error)) func(relPath string) (bool, error) {
+	rep := r.sharedOptions.Report()
+	if rep == nil || r.sharedOptions.LooseGiterminism() {
+		return isFileAcceptedFunc
+	}
+
+	return func(relPath string) (bool, error) {
+		if accepted, err := isFileAcceptedFunc(relPath); err != nil || accepted {
+			return accepted, err
+		}
+
+		if exist, err := r.isDirectoryExist(relPath); err != nil {
+			return false, err
+		} else if exist {
+			return false, nil
+		}
+
+		existInCommit, err := r.isCommitFileExist(ctx, relPath)
+		if err != nil {
+			return false, err
+		}
+
+		var msg string
+		if existInCommit {
+			repoData, err := r.readCommitFile(ctx, relPath, nil)
+			if err != nil {
+				return false, err
+			}
+
+			if isDataIdentical, err := r.compareFileData(relPath, repoData); err != nil {
+				return false, fmt.Errorf("unable to compare commit file %q with the local project file: %s", relPath, err)
+			} else if isDataIdentical {
+				return false, nil
+			}
+
+			msg = fmt.Sprintf("the %s %q changes must be committed", configType, filepath.ToSlash(relPath))
+		} else {
+			if exist, err := r.isFileExist(relPath); err != nil {
+				return false, err
+			} else if !exist {
+				return false, nil
+			}
+
+			msg = fmt.Sprintf("the %s %q must be committed", configType, filepath.ToSlash(relPath))
+		}
+
+		rep.Add(msg, configType.allowUncommittedRule(relPath))
+
+		return true, nil
+	}
+}
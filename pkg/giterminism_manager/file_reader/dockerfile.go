@@ -37,7 +37,7 @@ func (r FileReader) checkDockerignoreExistence(ctx context.Context, relPath stri
 }
 
 func (r FileReader) isDockerignoreExist(ctx context.Context, relPath string) (bool, error) {
-	return r.isConfigurationFileExist(ctx, relPath, r.giterminismConfig.IsUncommittedDockerignoreAccepted)
+	return r.isConfigurationFileExist(ctx, dockerignoreErrorConfigType, relPath, r.giterminismConfig.IsUncommittedDockerignoreAccepted)
 }
 
 func (r FileReader) readDockerignore(ctx context.Context, relPath string) ([]byte, error) {
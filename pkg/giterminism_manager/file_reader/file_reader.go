@@ -1,6 +1,11 @@
 package file_reader
 
-import "github.com/werf/werf/pkg/git_repo"
+import (
+	"path/filepath"
+
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/giterminism_manager/report"
+)
 
 type configType string
 
@@ -8,6 +13,7 @@ const (
 	giterminismConfigErrorConfigType configType = "giterminism config"
 	configErrorConfigType            configType = "werf config"
 	configTemplateErrorConfigType    configType = "werf config template"
+	configParamsErrorConfigType      configType = "werf config params file"
 	configGoTemplateErrorConfigType  configType = "file"
 	dockerfileErrorConfigType        configType = "dockerfile"
 	dockerignoreErrorConfigType      configType = "dockerignore file"
@@ -15,6 +21,28 @@ const (
 	chartDirectoryErrorConfigType    configType = "chart directory"
 )
 
+// allowUncommittedRule returns the werf-giterminism.yaml rule that allows the uncommitted file of the config type
+func (t configType) allowUncommittedRule(relPath string) *report.Rule {
+	relPath = filepath.ToSlash(relPath)
+
+	switch t {
+	case configErrorConfigType, configParamsErrorConfigType:
+		return &report.Rule{Path: []string{"config", "allowUncommitted"}}
+	case configTemplateErrorConfigType:
+		return &report.Rule{Path: []string{"config", "allowUncommittedTemplates"}, Value: relPath}
+	case configGoTemplateErrorConfigType:
+		return &report.Rule{Path: []string{"config", "goTemplateRendering", "allowUncommittedFiles"}, Value: relPath}
+	case dockerfileErrorConfigType:
+		return &report.Rule{Path: []string{"config", "dockerfile", "allowUncommitted"}, Value: relPath}
+	case dockerignoreErrorConfigType:
+		return &report.Rule{Path: []string{"config", "dockerfile", "allowUncommittedDockerignoreFiles"}, Value: relPath}
+	case chartFileErrorConfigType, chartDirectoryErrorConfigType:
+		return &report.Rule{Path: []string{"helm", "allowUncommittedFiles"}, Value: relPath}
+	default:
+		return nil
+	}
+}
+
 type FileReader struct {
 	sharedOptions     sharedOptions
 	giterminismConfig giterminismConfig
@@ -42,4 +70,5 @@ type sharedOptions interface {
 	LocalGitRepo() *git_repo.Local
 	HeadCommit() string
 	LooseGiterminism() bool
+	Report() *report.Report
 }
@@ -2,6 +2,8 @@ package inspector
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 
 	"github.com/werf/logboek"
 
@@ -25,6 +27,14 @@ func (i Inspector) InspectBuildContextFiles(ctx context.Context, matcher path_ma
 	}
 
 	filePathList := result.FilePathList(status.FilterOptions{WorktreeOnly: i.sharedOptions.Dev()})
+	if r := i.sharedOptions.Report(); r != nil {
+		for _, filePath := range filePathList {
+			r.Add(fmt.Sprintf("the build context file %q changes must be committed", filepath.ToSlash(filePath)), nil)
+		}
+
+		return nil
+	}
+
 	if len(filePathList) != 0 {
 		return NewUncommittedFilesChangesError(filePathList...)
 	}
@@ -3,6 +3,8 @@ package inspector
 import (
 	"context"
 	"fmt"
+
+	"github.com/werf/werf/pkg/giterminism_manager/report"
 )
 
 func (i Inspector) InspectConfigGoTemplateRenderingEnv(ctx context.Context, envName string) error {
@@ -16,5 +18,5 @@ func (i Inspector) InspectConfigGoTemplateRenderingEnv(ctx context.Context, envN
 		return nil
 	}
 
-	return NewExternalDependencyFoundError(fmt.Sprintf(`env name %q not allowed`, envName))
+	return i.externalDependencyFound(fmt.Sprintf(`env name %q not allowed`, envName), &report.Rule{Path: []string{"config", "goTemplateRendering", "allowEnvVariables"}, Value: envName})
 }
@@ -3,6 +3,8 @@ package inspector
 import (
 	"fmt"
 	"path/filepath"
+
+	"github.com/werf/werf/pkg/giterminism_manager/report"
 )
 
 func (i Inspector) InspectConfigDockerfileContextAddFile(relPath string) error {
@@ -16,5 +18,5 @@ func (i Inspector) InspectConfigDockerfileContextAddFile(relPath string) error {
 		return nil
 	}
 
-	return NewExternalDependencyFoundError(fmt.Sprintf("contextAddFile %q not allowed", filepath.ToSlash(relPath)))
+	return i.externalDependencyFound(fmt.Sprintf("contextAddFile %q not allowed", filepath.ToSlash(relPath)), &report.Rule{Path: []string{"config", "dockerfile", "allowContextAddFiles"}, Value: filepath.ToSlash(relPath)})
 }
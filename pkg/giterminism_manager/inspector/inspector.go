@@ -1,6 +1,11 @@
 package inspector
 
-import "github.com/werf/werf/pkg/git_repo"
+import (
+	"strings"
+
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/giterminism_manager/report"
+)
 
 type Inspector struct {
 	giterminismConfig giterminismConfig
@@ -18,6 +23,7 @@ type giterminismConfig interface {
 	IsConfigStapelMountBuildDirAccepted() bool
 	IsConfigStapelMountFromPathAccepted(fromPath string) (bool, error)
 	IsConfigDockerfileContextAddFileAccepted(relPath string) (bool, error)
+	IsConfigSecretKeyExecProviderAccepted() bool
 }
 
 type sharedOptions interface {
@@ -25,4 +31,15 @@ type sharedOptions interface {
 	HeadCommit() string
 	LooseGiterminism() bool
 	Dev() bool
+	Report() *report.Report
+}
+
+// externalDependencyFound registers the violation with the first line of the message in the report mode or returns the error
+func (i Inspector) externalDependencyFound(msg string, rule *report.Rule) error {
+	if r := i.sharedOptions.Report(); r != nil {
+		r.Add(strings.SplitN(msg, "\n", 2)[0], rule)
+		return nil
+	}
+
+	return NewExternalDependencyFoundError(msg)
 }
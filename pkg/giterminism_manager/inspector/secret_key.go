@@ -0,0 +1,15 @@
+package inspector
+
+import (
+	"github.com/werf/werf/pkg/giterminism_manager/report"
+)
+
+func (i Inspector) InspectConfigSecretKeyExecProvider() error {
+	if i.sharedOptions.LooseGiterminism() || i.giterminismConfig.IsConfigSecretKeyExecProviderAccepted() {
+		return nil
+	}
+
+	return i.externalDependencyFound(`exec secret key provider not allowed
+
+The secret key helper command is run on the host with the user environment, thus the secret key and the rendered configuration depend on the external program which is not the part of the project git repository.`, &report.Rule{Path: []string{"config", "secretKey", "allowExecProvider"}})
+}
@@ -2,6 +2,8 @@ package inspector
 
 import (
 	"fmt"
+
+	"github.com/werf/werf/pkg/giterminism_manager/report"
 )
 
 func (i Inspector) InspectConfigStapelFromLatest() error {
@@ -9,14 +11,14 @@ func (i Inspector) InspectConfigStapelFromLatest() error {
 		return nil
 	}
 
-	return NewExternalDependencyFoundError(`fromLatest directive not allowed
+	return i.externalDependencyFound(`fromLatest directive not allowed
 
 Pay attention, werf uses actual base image digest in stage digest if 'fromLatest' is specified. Thus, the usage of this directive might break the reproducibility of previous builds. If the base image is changed in the registry, all previously built stages become not usable.
 
  * Previous pipeline jobs (e.g. deploy) cannot be retried without the image rebuild after changing base image in the registry.
  * If base image is modified unexpectedly it might lead to the inexplicably failed pipeline. For instance, the modification occurs after successful build and the following jobs will be failed due to changing of stages digests alongside base image digest.
 
-We recommend a particular unchangeable tag or periodically change 'fromCacheVersion' value to provide controllable and predictable lifecycle of software.`)
+We recommend a particular unchangeable tag or periodically change 'fromCacheVersion' value to provide controllable and predictable lifecycle of software.`, &report.Rule{Path: []string{"config", "stapel", "allowFromLatest"}})
 }
 
 func (i Inspector) InspectConfigStapelGitBranch() error {
@@ -24,7 +26,7 @@ func (i Inspector) InspectConfigStapelGitBranch() error {
 		return nil
 	}
 
-	return NewExternalDependencyFoundError("git branch directive not allowed")
+	return i.externalDependencyFound("git branch directive not allowed", &report.Rule{Path: []string{"config", "stapel", "git", "allowBranch"}})
 }
 
 func (i Inspector) InspectConfigStapelMountBuildDir() error {
@@ -32,7 +34,7 @@ func (i Inspector) InspectConfigStapelMountBuildDir() error {
 		return nil
 	}
 
-	return NewExternalDependencyFoundError(`"mount { from: build_dir, ... }" not allowed`)
+	return i.externalDependencyFound(`"mount { from: build_dir, ... }" not allowed`, &report.Rule{Path: []string{"config", "stapel", "mount", "allowBuildDir"}})
 }
 
 func (i Inspector) InspectConfigStapelMountFromPath(fromPath string) error {
@@ -46,5 +48,5 @@ func (i Inspector) InspectConfigStapelMountFromPath(fromPath string) error {
 		return nil
 	}
 
-	return NewExternalDependencyFoundError(fmt.Sprintf(`"mount { fromPath: %s, ... }" not allowed`, fromPath))
+	return i.externalDependencyFound(fmt.Sprintf(`"mount { fromPath: %s, ... }" not allowed`, fromPath), &report.Rule{Path: []string{"config", "stapel", "mount", "allowFromPaths"}, Value: fromPath})
 }
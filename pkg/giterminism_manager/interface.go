@@ -24,6 +24,8 @@ type FileReader interface {
 	IsConfigExistAnywhere(ctx context.Context, relPath string) (bool, error)
 	ReadConfig(ctx context.Context, customRelPath string) ([]byte, error)
 	ReadConfigTemplateFiles(ctx context.Context, customRelDirPath string, tmplFunc func(templatePathInsideDir string, data []byte, err error) error) error
+	IsConfigParamsFileExistAnywhere(ctx context.Context, relPath string) (bool, error)
+	ReadConfigParamsFile(ctx context.Context, relPath string) ([]byte, error)
 	ConfigGoTemplateFilesGet(ctx context.Context, relPath string) ([]byte, error)
 	ConfigGoTemplateFilesGlob(ctx context.Context, pattern string) (map[string]interface{}, error)
 	ReadDockerfile(ctx context.Context, relPath string) ([]byte, error)
@@ -46,5 +48,6 @@ type Inspector interface {
 	InspectConfigStapelMountBuildDir() error
 	InspectConfigStapelMountFromPath(fromPath string) error
 	InspectConfigDockerfileContextAddFile(relPath string) error
+	InspectConfigSecretKeyExecProvider() error
 	InspectBuildContextFiles(ctx context.Context, matcher path_matcher.PathMatcher) error
 }
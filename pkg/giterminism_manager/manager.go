@@ -9,20 +9,25 @@ import (
 	"github.com/werf/werf/pkg/giterminism_manager/errors"
 	"github.com/werf/werf/pkg/giterminism_manager/file_reader"
 	"github.com/werf/werf/pkg/giterminism_manager/inspector"
+	"github.com/werf/werf/pkg/giterminism_manager/report"
 )
 
 type NewManagerOptions struct {
 	LooseGiterminism bool
 	Dev              bool
+
+	// Report enables the report mode: giterminism violations are registered in the report instead of failing
+	Report *report.Report
 }
 
-func NewManager(ctx context.Context, projectDir string, localGitRepo git_repo.Local, headCommit string, options NewManagerOptions) (Interface, error) {
+func NewManager(ctx context.Context, projectDir string, localGitRepo *git_repo.Local, headCommit string, options NewManagerOptions) (Interface, error) {
 	sharedOptions := &sharedOptions{
 		projectDir:       projectDir,
 		localGitRepo:     localGitRepo,
 		headCommit:       headCommit,
 		looseGiterminism: options.LooseGiterminism,
 		dev:              options.Dev,
+		report:           options.Report,
 	}
 
 	if options.LooseGiterminism {
@@ -71,9 +76,10 @@ func (m Manager) Inspector() Inspector {
 type sharedOptions struct {
 	projectDir       string
 	headCommit       string
-	localGitRepo     git_repo.Local
+	localGitRepo     *git_repo.Local
 	looseGiterminism bool
 	dev              bool
+	report           *report.Report
 }
 
 func (s *sharedOptions) ProjectDir() string {
@@ -85,7 +91,7 @@ func (s *sharedOptions) HeadCommit() string {
 }
 
 func (s *sharedOptions) LocalGitRepo() *git_repo.Local {
-	return &s.localGitRepo
+	return s.localGitRepo
 }
 
 func (s *sharedOptions) LooseGiterminism() bool {
@@ -95,3 +101,7 @@ func (s *sharedOptions) LooseGiterminism() bool {
 func (s *sharedOptions) Dev() bool {
 	return s.dev
 }
+
+func (s *sharedOptions) Report() *report.Report {
+	return s.report
+}
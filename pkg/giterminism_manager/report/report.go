@@ -0,0 +1,187 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+const giterminismConfigVersion = "1"
+
+// Rule is the werf-giterminism.yaml directive that allows the violation.
+// The boolean directive is enabled if the Value is empty, otherwise the Value is added to the list directive.
+type Rule struct {
+	Path  []string
+	Value string
+}
+
+func (r Rule) IsBool() bool {
+	return r.Value == ""
+}
+
+func (r Rule) String() string {
+	if r.IsBool() {
+		return fmt.Sprintf("%s: true", strings.Join(r.Path, "."))
+	}
+
+	return fmt.Sprintf("%s: [%q]", strings.Join(r.Path, "."), r.Value)
+}
+
+// Violation is the giterminism check failure, the Rule is nil if the werf-giterminism.yaml cannot allow the violation.
+type Violation struct {
+	Message string
+	Rule    *Rule
+}
+
+// Report collects giterminism violations instead of failing on the first one
+type Report struct {
+	violations []Violation
+	mutex      sync.Mutex
+}
+
+func NewReport() *Report {
+	return &Report{}
+}
+
+func (r *Report) Add(message string, rule *Rule) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, v := range r.violations {
+		if v.Message == message {
+			return
+		}
+	}
+
+	r.violations = append(r.violations, Violation{Message: message, Rule: rule})
+}
+
+func (r *Report) Violations() []Violation {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Violation{}, r.violations...)
+}
+
+// Rules returns the unique rules required to allow all violations
+func (r *Report) Rules() []Rule {
+	var rules []Rule
+	keys := map[string]bool{}
+	for _, v := range r.Violations() {
+		if v.Rule == nil {
+			continue
+		}
+
+		key := v.Rule.String()
+		if keys[key] {
+			continue
+		}
+		keys[key] = true
+
+		rules = append(rules, *v.Rule)
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].String() < rules[j].String()
+	})
+
+	return rules
+}
+
+// PatchGiterminismConfig adds the rules into the werf-giterminism.yaml data keeping the existing directives (comments are not preserved).
+// The new config is created if the data is empty.
+func PatchGiterminismConfig(data []byte, rules []Rule) ([]byte, error) {
+	var cfg yaml.MapSlice
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to parse giterminism config: %s", err)
+	}
+
+	if _, ok := getMapSliceValue(cfg, "giterminismConfigVersion"); !ok {
+		cfg = append(yaml.MapSlice{{Key: "giterminismConfigVersion", Value: giterminismConfigVersion}}, cfg...)
+	}
+
+	for _, rule := range rules {
+		var err error
+		cfg, err = patchMapSlice(cfg, rule.Path, rule)
+		if err != nil {
+			return nil, fmt.Errorf("unable to add rule %s: %s", rule, err)
+		}
+	}
+
+	return yaml.Marshal(cfg)
+}
+
+func patchMapSlice(m yaml.MapSlice, path []string, rule Rule) (yaml.MapSlice, error) {
+	key := path[0]
+	value, exist := getMapSliceValue(m, key)
+
+	var newValue interface{}
+	switch {
+	case len(path) > 1:
+		var child yaml.MapSlice
+		if exist && value != nil {
+			var ok bool
+			child, ok = value.(yaml.MapSlice)
+			if !ok {
+				return nil, fmt.Errorf("%q must be a map", key)
+			}
+		}
+
+		var err error
+		newValue, err = patchMapSlice(child, path[1:], rule)
+		if err != nil {
+			return nil, err
+		}
+	case rule.IsBool():
+		newValue = true
+	default:
+		var list []interface{}
+		if exist && value != nil {
+			var ok bool
+			list, ok = value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%q must be an array", key)
+			}
+		}
+
+		newValue = list
+		if !isListContainValue(list, rule.Value) {
+			newValue = append(list, rule.Value)
+		}
+	}
+
+	if exist {
+		for i := range m {
+			if m[i].Key == key {
+				m[i].Value = newValue
+			}
+		}
+
+		return m, nil
+	}
+
+	return append(m, yaml.MapItem{Key: key, Value: newValue}), nil
+}
+
+func getMapSliceValue(m yaml.MapSlice, key string) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+func isListContainValue(list []interface{}, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"testing"
+)
+
+func TestPatchGiterminismConfig(t *testing.T) {
+	rules := []Rule{
+		{Path: []string{"config", "stapel", "allowFromLatest"}},
+		{Path: []string{"config", "goTemplateRendering", "allowEnvVariables"}, Value: "FOO"},
+		{Path: []string{"config", "goTemplateRendering", "allowEnvVariables"}, Value: "BAR"},
+		{Path: []string{"helm", "allowUncommittedFiles"}, Value: ".helm/values.yaml"},
+	}
+
+	data, err := PatchGiterminismConfig(nil, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `giterminismConfigVersion: "1"
+config:
+  stapel:
+    allowFromLatest: true
+  goTemplateRendering:
+    allowEnvVariables:
+    - FOO
+    - BAR
+helm:
+  allowUncommittedFiles:
+  - .helm/values.yaml
+`
+	if string(data) != expected {
+		t.Fatalf("unexpected new config:\n%s", data)
+	}
+
+	existing := `giterminismConfigVersion: 1
+config:
+  allowUncommitted: false
+  goTemplateRendering:
+    allowEnvVariables: [FOO, /CI_.*/]
+`
+	data, err = PatchGiterminismConfig([]byte(existing), []Rule{
+		{Path: []string{"config", "allowUncommitted"}},
+		{Path: []string{"config", "goTemplateRendering", "allowEnvVariables"}, Value: "FOO"},
+		{Path: []string{"config", "goTemplateRendering", "allowEnvVariables"}, Value: "BAR"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `giterminismConfigVersion: 1
+config:
+  allowUncommitted: true
+  goTemplateRendering:
+    allowEnvVariables:
+    - FOO
+    - /CI_.*/
+    - BAR
+`
+	if string(data) != expected {
+		t.Fatalf("unexpected patched config:\n%s", data)
+	}
+
+	if _, err := PatchGiterminismConfig([]byte("config: [a]\n"), rules[:1]); err == nil {
+		t.Fatal("expected error for the invalid config structure")
+	}
+}
+
+func TestReport_Rules(t *testing.T) {
+	r := NewReport()
+	r.Add("env name \"FOO\" not allowed", &Rule{Path: []string{"config", "goTemplateRendering", "allowEnvVariables"}, Value: "FOO"})
+	r.Add("env name \"FOO\" not allowed", &Rule{Path: []string{"config", "goTemplateRendering", "allowEnvVariables"}, Value: "FOO"})
+	r.Add("the build context file \"a\" changes must be committed", nil)
+	r.Add("the werf config \"werf.yaml\" changes must be committed", &Rule{Path: []string{"config", "allowUncommitted"}})
+
+	if len(r.Violations()) != 3 {
+		t.Fatalf("expected 3 violations, got %d", len(r.Violations()))
+	}
+
+	rules := r.Rules()
+	if len(rules) != 2 || rules[0].String() != "config.allowUncommitted: true" || rules[1].String() != `config.goTemplateRendering.allowEnvVariables: ["FOO"]` {
+		t.Fatalf("unexpected rules: %v", rules)
+	}
+}
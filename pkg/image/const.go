@@ -11,6 +11,7 @@ const (
 	WerfStageContentDigestLabel   = "werf-stage-content-digest"
 	WerfProjectRepoCommitLabel    = "werf-project-repo-commit"
 	WerfImportChecksumLabelPrefix = "werf-import-checksum-"
+	WerfStageDigestInputsLabel    = "werf-stage-digest-inputs"
 
 	WerfImportMetadataChecksumLabel       = "checksum"
 	WerfImportMetadataSourceImageIDLabel  = "source-image-id"
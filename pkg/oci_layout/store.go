@@ -0,0 +1,429 @@
+package oci_layout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/werf/lockgate"
+
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+const RefNameAnnotation = "org.opencontainers.image.ref.name"
+
+// Store keeps images in the OCI image layout directory.
+// Image names are saved in the index.json descriptors annotations, the same image could be referenced by multiple names.
+type Store struct {
+	Dir string
+
+	path  layout.Path
+	mutex sync.Mutex
+}
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create dir %s: %s", dir, err)
+	}
+
+	store := &Store{Dir: dir}
+
+	if exists, err := util.RegularFileExists(filepath.Join(dir, "index.json")); err != nil {
+		return nil, err
+	} else if exists {
+		store.path = layout.Path(dir)
+	} else if p, err := layout.Write(dir, empty.Index); err != nil {
+		return nil, fmt.Errorf("unable to init oci layout in %s: %s", dir, err)
+	} else {
+		store.path = p
+	}
+
+	return store, nil
+}
+
+// GetImage finds image by the name, image ID (config digest) or manifest digest, returns nil if image not found.
+// The manifest and config are read under the lock, the layers could be removed by the concurrent GarbageCollect, use UseImage to read them.
+func (s *Store) GetImage(ctx context.Context, ref string) (v1.Image, error) {
+	var img v1.Image
+	err := s.withLock(ctx, func(index *v1.IndexManifest) (bool, error) {
+		var err error
+		img, err = s.readImage(index, ref)
+		return false, err
+	})
+
+	return img, err
+}
+
+// UseImage passes the image to the callback, GarbageCollect does not remove the image blobs until the callback returns
+func (s *Store) UseImage(ctx context.Context, ref string, f func(img v1.Image) error) error {
+	lockName := s.blobsLockName()
+	if _, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{Shared: true}); err != nil {
+		return fmt.Errorf("unable to acquire lock %s: %s", lockName, err)
+	} else {
+		defer werf.ReleaseHostLock(lock)
+	}
+
+	img, err := s.GetImage(ctx, ref)
+	if err != nil {
+		return err
+	} else if img == nil {
+		return fmt.Errorf("image %s not found", ref)
+	}
+
+	return f(img)
+}
+
+// GetImageRefs returns all names of the image with the specified manifest digest
+func (s *Store) GetImageRefs(ctx context.Context, manifestDigest v1.Hash) ([]string, error) {
+	var refs []string
+	err := s.withLock(ctx, func(index *v1.IndexManifest) (bool, error) {
+		for _, desc := range index.Manifests {
+			if desc.Digest == manifestDigest && desc.Annotations[RefNameAnnotation] != "" {
+				refs = append(refs, desc.Annotations[RefNameAnnotation])
+			}
+		}
+
+		return false, nil
+	})
+
+	return refs, err
+}
+
+// GetRefs returns all image names in the store
+func (s *Store) GetRefs(ctx context.Context) ([]string, error) {
+	var refs []string
+	err := s.withLock(ctx, func(index *v1.IndexManifest) (bool, error) {
+		for _, desc := range index.Manifests {
+			if ref := desc.Annotations[RefNameAnnotation]; ref != "" {
+				refs = append(refs, ref)
+			}
+		}
+
+		return false, nil
+	})
+
+	sort.Strings(refs)
+	return refs, err
+}
+
+// WriteImage writes image blobs into the store and references the image by the specified names.
+// The image without names is still available by the image ID until the next GarbageCollect.
+func (s *Store) WriteImage(ctx context.Context, img v1.Image, refs ...string) error {
+	desc, err := imageDescriptor(img)
+	if err != nil {
+		return err
+	}
+
+	// blobs are written under the lock, so the concurrent GarbageCollect does not remove them before the image is referenced
+	return s.withLock(ctx, func(index *v1.IndexManifest) (bool, error) {
+		if err := s.path.WriteImage(img); err != nil {
+			return false, fmt.Errorf("unable to write image blobs: %s", err)
+		}
+
+		if len(refs) == 0 {
+			for _, d := range index.Manifests {
+				if d.Digest == desc.Digest {
+					return false, nil
+				}
+			}
+
+			index.Manifests = append(index.Manifests, *desc)
+			return true, nil
+		}
+
+		for _, ref := range refs {
+			index.Manifests = withoutRef(index.Manifests, ref)
+			index.Manifests = append(index.Manifests, withRef(*desc, ref))
+		}
+
+		return true, nil
+	})
+}
+
+// Tag references the existing image by the new name, the previous image with the new name loses it
+func (s *Store) Tag(ctx context.Context, ref, newRef string) error {
+	return s.withLock(ctx, func(index *v1.IndexManifest) (bool, error) {
+		desc, err := s.findDescriptor(index, ref)
+		if err != nil {
+			return false, err
+		} else if desc == nil {
+			return false, fmt.Errorf("image %s not found", ref)
+		}
+
+		newDesc := withRef(*desc, newRef)
+		index.Manifests = withoutRef(index.Manifests, newRef)
+		index.Manifests = append(index.Manifests, newDesc)
+
+		return true, nil
+	})
+}
+
+// Untag removes the image name, or all references to the image if the image ID specified
+func (s *Store) Untag(ctx context.Context, ref string) error {
+	return s.withLock(ctx, func(index *v1.IndexManifest) (bool, error) {
+		var result []v1.Descriptor
+		for _, desc := range index.Manifests {
+			if desc.Annotations[RefNameAnnotation] == ref || desc.Digest.String() == ref {
+				continue
+			}
+
+			if configName, err := s.configName(desc); err != nil {
+				return false, err
+			} else if configName.String() == ref {
+				continue
+			}
+
+			result = append(result, desc)
+		}
+
+		changed := len(result) != len(index.Manifests)
+		index.Manifests = result
+
+		return changed, nil
+	})
+}
+
+// GarbageCollect removes blobs which are not reachable from the index, waits for the images in use to be released
+func (s *Store) GarbageCollect(ctx context.Context) error {
+	lockName := s.blobsLockName()
+	if _, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{}); err != nil {
+		return fmt.Errorf("unable to acquire lock %s: %s", lockName, err)
+	} else {
+		defer werf.ReleaseHostLock(lock)
+	}
+
+	return s.withLock(ctx, func(index *v1.IndexManifest) (bool, error) {
+		reachable := map[string]bool{}
+		for _, desc := range index.Manifests {
+			reachable[desc.Digest.Hex] = true
+
+			img, err := s.path.Image(desc.Digest)
+			if err != nil {
+				return false, err
+			}
+
+			manifest, err := img.Manifest()
+			if err != nil {
+				return false, err
+			}
+
+			reachable[manifest.Config.Digest.Hex] = true
+			for _, l := range manifest.Layers {
+				reachable[l.Digest.Hex] = true
+			}
+		}
+
+		blobsDir := filepath.Join(s.Dir, "blobs", "sha256")
+		infos, err := ioutil.ReadDir(blobsDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		for _, info := range infos {
+			if !reachable[info.Name()] {
+				if err := os.Remove(filepath.Join(blobsDir, info.Name())); err != nil {
+					return false, err
+				}
+			}
+		}
+
+		return false, nil
+	})
+}
+
+func (s *Store) withLock(ctx context.Context, f func(index *v1.IndexManifest) (bool, error)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	lockName := s.indexLockName()
+	if _, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{}); err != nil {
+		return fmt.Errorf("unable to acquire lock %s: %s", lockName, err)
+	} else {
+		defer werf.ReleaseHostLock(lock)
+	}
+
+	ii, err := s.path.ImageIndex()
+	if err != nil {
+		return fmt.Errorf("unable to read oci layout index: %s", err)
+	}
+
+	index, err := ii.IndexManifest()
+	if err != nil {
+		return fmt.Errorf("unable to read oci layout index: %s", err)
+	}
+
+	if changed, err := f(index); err != nil {
+		return err
+	} else if !changed {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return s.writeIndex(data)
+}
+
+// writeIndex replaces index.json atomically, so the concurrent readers never see the partially written index
+func (s *Store) writeIndex(data []byte) error {
+	f, err := ioutil.TempFile(s.Dir, "index.json.")
+	if err != nil {
+		return fmt.Errorf("unable to create tmp index file: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write tmp index file %s: %s", f.Name(), err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write tmp index file %s: %s", f.Name(), err)
+	}
+
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), filepath.Join(s.Dir, "index.json")); err != nil {
+		return fmt.Errorf("unable to replace index.json: %s", err)
+	}
+
+	return nil
+}
+
+func (s *Store) indexLockName() string {
+	return fmt.Sprintf("oci_layout.%s", util.Sha256Hash(s.Dir))
+}
+
+func (s *Store) blobsLockName() string {
+	return fmt.Sprintf("oci_layout.%s.blobs", util.Sha256Hash(s.Dir))
+}
+
+// readImage reads the image manifest and config, so they stay available after the lock is released
+func (s *Store) readImage(index *v1.IndexManifest, ref string) (v1.Image, error) {
+	desc, err := s.findDescriptor(index, ref)
+	if err != nil || desc == nil {
+		return nil, err
+	}
+
+	img, err := s.path.Image(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := img.RawManifest(); err != nil {
+		return nil, err
+	}
+
+	rawConfig, err := img.RawConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	configName, err := img.ConfigName()
+	if err != nil {
+		return nil, err
+	}
+
+	return &storedImage{Image: img, rawConfig: rawConfig, configName: configName}, nil
+}
+
+func (s *Store) findDescriptor(index *v1.IndexManifest, ref string) (*v1.Descriptor, error) {
+	for _, desc := range index.Manifests {
+		if desc.Annotations[RefNameAnnotation] == ref || desc.Digest.String() == ref {
+			desc := desc
+			return &desc, nil
+		}
+	}
+
+	for _, desc := range index.Manifests {
+		if configName, err := s.configName(desc); err != nil {
+			return nil, err
+		} else if configName.String() == ref {
+			desc := desc
+			return &desc, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *Store) configName(desc v1.Descriptor) (v1.Hash, error) {
+	img, err := s.path.Image(desc.Digest)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	return img.ConfigName()
+}
+
+func imageDescriptor(img v1.Image) (*v1.Descriptor, error) {
+	mediaType, err := img.MediaType()
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := img.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.Descriptor{MediaType: mediaType, Digest: digest, Size: size}, nil
+}
+
+func withRef(desc v1.Descriptor, ref string) v1.Descriptor {
+	desc.Annotations = map[string]string{RefNameAnnotation: ref}
+	return desc
+}
+
+func withoutRef(descs []v1.Descriptor, ref string) []v1.Descriptor {
+	var result []v1.Descriptor
+	for _, desc := range descs {
+		if desc.Annotations[RefNameAnnotation] != ref {
+			result = append(result, desc)
+		}
+	}
+
+	return result
+}
+
+// storedImage keeps the config read under the store lock, the layout image reads the config blob on each call
+type storedImage struct {
+	v1.Image
+
+	rawConfig  []byte
+	configName v1.Hash
+}
+
+func (img *storedImage) RawConfigFile() ([]byte, error) {
+	return img.rawConfig, nil
+}
+
+func (img *storedImage) ConfigFile() (*v1.ConfigFile, error) {
+	return v1.ParseConfigFile(bytes.NewReader(img.rawConfig))
+}
+
+func (img *storedImage) ConfigName() (v1.Hash, error) {
+	return img.configName, nil
+}
@@ -0,0 +1,329 @@
+package oci_layout
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+func newTestStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "werf-oci-layout-store-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	store, err := NewStore(filepath.Join(dir, "layout"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func newTestImage(t *testing.T) v1.Image {
+	img, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+func imageBlobs(t *testing.T, img v1.Image) []string {
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobs := []string{digest.Hex, manifest.Config.Digest.Hex}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, layer.Digest.Hex)
+	}
+
+	return blobs
+}
+
+func blobExists(t *testing.T, store *Store, hex string) bool {
+	_, err := os.Stat(filepath.Join(store.Dir, "blobs", "sha256", hex))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	return err == nil
+}
+
+func TestStoreTagUntag(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	img := newTestImage(t)
+	if err := store.WriteImage(ctx, img, "app:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Tag(ctx, "app:1", "app:latest"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Tag(ctx, "app:unknown", "app:2"); err == nil {
+		t.Errorf("expected error when tagging the unknown image")
+	}
+
+	if refs, err := store.GetRefs(ctx); err != nil {
+		t.Fatal(err)
+	} else if expected := []string{"app:1", "app:latest"}; !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected refs %v, got %v", expected, refs)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configName, err := img.ConfigName()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{"app:latest", digest.String(), configName.String()} {
+		got, err := store.GetImage(ctx, ref)
+		if err != nil {
+			t.Fatal(err)
+		} else if got == nil {
+			t.Fatalf("expected image by %s", ref)
+		}
+
+		if gotDigest, err := got.Digest(); err != nil {
+			t.Fatal(err)
+		} else if gotDigest != digest {
+			t.Errorf("expected image %s by %s, got %s", digest, ref, gotDigest)
+		}
+	}
+
+	if err := store.Untag(ctx, "app:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if refs, err := store.GetImageRefs(ctx, digest); err != nil {
+		t.Fatal(err)
+	} else if expected := []string{"app:latest"}; !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected refs %v, got %v", expected, refs)
+	}
+
+	if err := store.Untag(ctx, configName.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := store.GetImage(ctx, "app:latest"); err != nil {
+		t.Fatal(err)
+	} else if got != nil {
+		t.Errorf("expected all references to be removed by the image ID")
+	}
+}
+
+func TestStoreTagMovesRef(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	img1, img2 := newTestImage(t), newTestImage(t)
+	if err := store.WriteImage(ctx, img1, "app:latest"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.WriteImage(ctx, img2, "app:2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Tag(ctx, "app:2", "app:latest"); err != nil {
+		t.Fatal(err)
+	}
+
+	digest1, _ := img1.Digest()
+	digest2, _ := img2.Digest()
+
+	if refs, err := store.GetImageRefs(ctx, digest1); err != nil {
+		t.Fatal(err)
+	} else if len(refs) != 0 {
+		t.Errorf("expected the previous image to lose the ref, got %v", refs)
+	}
+
+	if refs, err := store.GetImageRefs(ctx, digest2); err != nil {
+		t.Fatal(err)
+	} else if expected := []string{"app:2", "app:latest"}; !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected refs %v, got %v", expected, refs)
+	}
+}
+
+func TestStoreGarbageCollect(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	kept, removed := newTestImage(t), newTestImage(t)
+	if err := store.WriteImage(ctx, kept, "app:kept"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.WriteImage(ctx, removed, "app:removed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Untag(ctx, "app:removed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.GarbageCollect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hex := range imageBlobs(t, kept) {
+		if !blobExists(t, store, hex) {
+			t.Errorf("expected reachable blob %s to be kept", hex)
+		}
+	}
+
+	for _, hex := range imageBlobs(t, removed) {
+		if blobExists(t, store, hex) {
+			t.Errorf("expected unreachable blob %s to be removed", hex)
+		}
+	}
+
+	if got, err := store.GetImage(ctx, "app:kept"); err != nil {
+		t.Fatal(err)
+	} else if _, err := got.RawManifest(); err != nil {
+		t.Errorf("expected the kept image to be readable: %s", err)
+	}
+}
+
+func TestStoreIndexReadBack(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	img := newTestImage(t)
+	if err := store.WriteImage(ctx, img, "app:1", "app:2"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewStore(store.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if refs, err := reopened.GetRefs(ctx); err != nil {
+		t.Fatal(err)
+	} else if expected := []string{"app:1", "app:2"}; !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected refs %v read from index.json, got %v", expected, refs)
+	}
+
+	got, err := reopened.GetImage(ctx, "app:2")
+	if err != nil {
+		t.Fatal(err)
+	} else if got == nil {
+		t.Fatalf("expected image app:2")
+	}
+
+	expectedDigest, _ := img.Digest()
+	if digest, err := got.Digest(); err != nil {
+		t.Fatal(err)
+	} else if digest != expectedDigest {
+		t.Errorf("expected image %s, got %s", expectedDigest, digest)
+	}
+}
+
+func TestStoreGarbageCollectWaitsForImagesInUse(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	img := newTestImage(t)
+	if err := store.WriteImage(ctx, img, "app:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	gcDone := make(chan error, 1)
+	if err := store.UseImage(ctx, "app:1", func(img v1.Image) error {
+		if err := store.Untag(ctx, "app:1"); err != nil {
+			return err
+		}
+
+		go func() { gcDone <- store.GarbageCollect(ctx) }()
+		time.Sleep(100 * time.Millisecond)
+
+		layers, err := img.Layers()
+		if err != nil {
+			return err
+		}
+
+		for _, layer := range layers {
+			rc, err := layer.Compressed()
+			if err != nil {
+				return err
+			}
+
+			_, err = ioutil.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatalf("expected image in use to stay readable: %s", err)
+	}
+
+	if err := <-gcDone; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hex := range imageBlobs(t, img) {
+		if blobExists(t, store, hex) {
+			t.Errorf("expected unreachable blob %s to be removed after the image is released", hex)
+		}
+	}
+}
+
+func TestStoreIndexFileMode(t *testing.T) {
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	if err := store.WriteImage(context.Background(), newTestImage(t), "app:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(store.Dir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("expected index.json mode 0644, got %s", info.Mode().Perm())
+	}
+
+	if matches, err := filepath.Glob(filepath.Join(store.Dir, "index.json.*")); err != nil {
+		t.Fatal(err)
+	} else if len(matches) != 0 {
+		t.Errorf("expected no tmp index files, got %v", matches)
+	}
+}
@@ -5,15 +5,37 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// The versioned format of the encrypted data is v2:HEX(NONCE || CIPHERTEXT || TAG),
+// the data is encrypted with AES-256-GCM using the key derived from the secret key with HKDF-SHA256.
+// The additional authenticated data is only the constant "v2:" prefix: the versioned format provides the integrity
+// of each value separately, the value is not bound to its path in the values file or to the secret key name,
+// so the encrypted values can be moved, swapped or removed within the file and between the files encrypted with the same key undetected.
+// The legacy format is HEX(IV_SIZE || IV || CIPHERTEXT), the data is encrypted with AES-CBC without authentication.
+// Both formats are decrypted, but the versioned format cannot be decrypted by the previous werf versions,
+// so the data is encrypted in the legacy format unless the versioned format is enabled explicitly.
+const (
+	versionedFormatPrefix = "v2:"
+	versionedFormatInfo   = "werf secret v2"
 )
 
 type AesEncoder struct {
 	CipherBlock cipher.Block
+	AEAD        cipher.AEAD
+
+	// UseVersionedFormat enables encryption in the versioned format
+	UseVersionedFormat bool
+	// RejectLegacyFormat disables decryption of the unauthenticated legacy format and enables encryption in the versioned format
+	RejectLegacyFormat bool
 }
 
 func GenerateAesSecretKey() ([]byte, error) {
@@ -38,11 +60,61 @@ func NewAesEncoder(key []byte) (*AesEncoder, error) {
 		return nil, err
 	}
 
-	secret := &AesEncoder{c}
+	aead, err := newVersionedFormatAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &AesEncoder{CipherBlock: c, AEAD: aead}
 	return secret, nil
 }
 
+func newVersionedFormatAEAD(key []byte) (cipher.AEAD, error) {
+	derivedKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(versionedFormatInfo)), derivedKey); err != nil {
+		return nil, err
+	}
+
+	c, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(c)
+}
+
+// IsLegacyFormat reports whether the encrypted data has the legacy unauthenticated format
+func IsLegacyFormat(data []byte) bool {
+	return len(data) != 0 && !bytes.HasPrefix(data, []byte(versionedFormatPrefix))
+}
+
 func (s *AesEncoder) Encrypt(data []byte) ([]byte, error) {
+	if s.UseVersionedFormat || s.RejectLegacyFormat {
+		return s.EncryptVersioned(data)
+	}
+
+	return s.EncryptLegacy(data)
+}
+
+// EncryptVersioned encrypts the data in the versioned authenticated format which is not readable by the previous werf versions.
+// Only the value itself is authenticated, not its location in the secret values (see the format description above)
+func (s *AesEncoder) EncryptVersioned(data []byte) ([]byte, error) {
+	nonce := make([]byte, s.AEAD.NonceSize(), s.AEAD.NonceSize()+len(data)+s.AEAD.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	cipherData := s.AEAD.Seal(nonce, nonce, data, []byte(versionedFormatPrefix))
+
+	result := make([]byte, len(versionedFormatPrefix)+hex.EncodedLen(len(cipherData)))
+	copy(result, versionedFormatPrefix)
+	hex.Encode(result[len(versionedFormatPrefix):], cipherData)
+
+	return result, nil
+}
+
+// EncryptLegacy encrypts the data in the legacy format which is readable by the previous werf versions
+func (s *AesEncoder) EncryptLegacy(data []byte) ([]byte, error) {
 	dataToEncrypt := pad(data)
 
 	cipherData := make([]byte, aes.BlockSize+len(dataToEncrypt))
@@ -72,6 +144,38 @@ func (s *AesEncoder) Decrypt(data []byte) ([]byte, error) {
 		return data, nil
 	}
 
+	if bytes.HasPrefix(data, []byte(versionedFormatPrefix)) {
+		return s.decryptVersioned(data[len(versionedFormatPrefix):])
+	}
+
+	if s.RejectLegacyFormat {
+		return nil, fmt.Errorf("legacy secret format is not allowed: migrate secrets with `werf helm secret migrate` command")
+	}
+
+	return s.decryptLegacy(data)
+}
+
+func (s *AesEncoder) decryptVersioned(data []byte) ([]byte, error) {
+	dataToExtract, err := hexToBinary(data)
+	if err != nil {
+		return nil, err
+	}
+
+	minimalDataBinarySize := s.AEAD.NonceSize() + s.AEAD.Overhead()
+	if len(dataToExtract) < minimalDataBinarySize {
+		return nil, fmt.Errorf("minimum required data length: '%v'", len(versionedFormatPrefix)+minimalDataBinarySize*2)
+	}
+
+	nonce := dataToExtract[:s.AEAD.NonceSize()]
+	result, err := s.AEAD.Open(nil, nonce, dataToExtract[s.AEAD.NonceSize():], []byte(versionedFormatPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("message authentication failed: data is corrupted or encrypted with another key")
+	}
+
+	return result, nil
+}
+
+func (s *AesEncoder) decryptLegacy(data []byte) ([]byte, error) {
 	dataToExtract, err := hexToBinary(data)
 	if err != nil {
 		return nil, err
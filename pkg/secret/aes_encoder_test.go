@@ -11,8 +11,8 @@ import (
 var AesSecretKey = []byte("11ac8312520b5ff037bae386ea2e8a07")
 var supportedKeySizes = []int{16, 24, 32}
 
-func TestGenerateAexSecretKey(t *testing.T) {
-	key, err := GenerateAexSecretKey()
+func TestGenerateAesSecretKey(t *testing.T) {
+	key, err := GenerateAesSecretKey()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -160,24 +160,98 @@ func TestAesSecret(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		t.Run(fmt.Sprintf("%v|%v", size, string(key)), func(t *testing.T) {
-			for _, test := range tests {
-				t.Run(test, func(t *testing.T) {
-					encodedData, err := s.Encrypt([]byte(test))
-					if err != nil {
-						t.Fatal(err)
-					}
-
-					result, err := s.Decrypt(encodedData)
-					if err != nil {
-						t.Fatal(err)
-					}
-
-					if test != string(result) {
-						t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test, result)
-					}
-				})
-			}
-		})
+		for _, useVersionedFormat := range []bool{false, true} {
+			s.UseVersionedFormat = useVersionedFormat
+
+			t.Run(fmt.Sprintf("%v|%v|%v", size, string(key), useVersionedFormat), func(t *testing.T) {
+				for _, test := range tests {
+					t.Run(test, func(t *testing.T) {
+						encodedData, err := s.Encrypt([]byte(test))
+						if err != nil {
+							t.Fatal(err)
+						}
+
+						result, err := s.Decrypt(encodedData)
+						if err != nil {
+							t.Fatal(err)
+						}
+
+						if test != string(result) {
+							t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test, result)
+						}
+					})
+				}
+			})
+		}
+	}
+}
+
+func TestAesSecret_legacyFormatByDefault(t *testing.T) {
+	s, err := NewAesEncoder(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := s.Encrypt([]byte("flant"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsLegacyFormat(encodedData) {
+		t.Errorf("Expected legacy format, got %q", encodedData)
+	}
+
+	s.RejectLegacyFormat = true
+
+	encodedData, err = s.Encrypt([]byte("flant"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if IsLegacyFormat(encodedData) {
+		t.Errorf("Expected versioned format when the legacy format is rejected, got %q", encodedData)
+	}
+}
+
+func TestAesSecret_versionedFormat(t *testing.T) {
+	s, err := NewAesEncoder(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.UseVersionedFormat = true
+
+	encodedData, err := s.Encrypt([]byte("flant"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if IsLegacyFormat(encodedData) {
+		t.Fatalf("Got unexpected legacy format %q", encodedData)
+	}
+
+	tamperedData := append([]byte{}, encodedData...)
+	if tamperedData[len(tamperedData)-1] == '0' {
+		tamperedData[len(tamperedData)-1] = '1'
+	} else {
+		tamperedData[len(tamperedData)-1] = '0'
+	}
+
+	if _, err := s.Decrypt(tamperedData); err == nil {
+		t.Error("Expected message authentication error")
+	}
+
+	s.RejectLegacyFormat = true
+
+	result, err := s.Decrypt(encodedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "flant" {
+		t.Errorf("\n[EXPECTED]: flant\n[GOT]: %s", result)
+	}
+
+	if _, err := s.Decrypt([]byte("10000f13a718d019612ab8ad30d9bec8e2c09df0f2d168c179bef954e78371bf6a5a")); err == nil {
+		t.Error("Expected legacy format error")
 	}
 }
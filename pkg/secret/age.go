@@ -0,0 +1,92 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// The age v1 format (https://age-encryption.org/v1) with the X25519 recipients,
+// the data encrypted by werf can be decrypted by the age tool and vice versa.
+
+// ParseAgeIdentities returns the identities from the age identities file data, the empty lines and # comments are skipped
+func ParseAgeIdentities(data []byte) []string {
+	var res []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		res = append(res, line)
+	}
+
+	return res
+}
+
+// AgeEncrypt encrypts the data for the X25519 recipients (age1...) in the armored age format
+func AgeEncrypt(data []byte, recipients []string) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no age recipients specified")
+	}
+
+	var ageRecipients []age.Recipient
+	for _, recipient := range recipients {
+		r, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("malformed age recipient %q: %s", recipient, err)
+		}
+		ageRecipients = append(ageRecipients, r)
+	}
+
+	res := &bytes.Buffer{}
+	armorWriter := armor.NewWriter(res)
+
+	w, err := age.Encrypt(armorWriter, ageRecipients...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := armorWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return res.Bytes(), nil
+}
+
+// AgeDecrypt decrypts the armored or binary age data with one of the X25519 identities (AGE-SECRET-KEY-1...)
+func AgeDecrypt(data []byte, identities []string) ([]byte, error) {
+	var ageIdentities []age.Identity
+	for _, identity := range identities {
+		i, err := age.ParseX25519Identity(identity)
+		if err != nil {
+			return nil, fmt.Errorf("malformed age identity: %s", err)
+		}
+		ageIdentities = append(ageIdentities, i)
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		r = armor.NewReader(bytes.NewReader(bytes.TrimSpace(data)))
+	}
+
+	decrypted, err := age.Decrypt(r, ageIdentities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(decrypted)
+}
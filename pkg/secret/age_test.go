@@ -0,0 +1,148 @@
+package secret
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// the file is encrypted by the age v1.0.0 for the ageTestIdentity
+const (
+	ageTestIdentity      = "AGE-SECRET-KEY-1SW4ST634V3WN9N07MH7525VWKUZ88HTTEJCZYKPSVFYTRL5DAEFSNH97T4"
+	ageTestRecipient     = "age1j4kgffjf9djtghvn6ng58kn3ewzww2y3vfclprhyfvng0pg4xeqswnx803"
+	ageTestEncryptedFile = `-----BEGIN AGE ENCRYPTED FILE-----
+YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBRQVh2bUVSV0FLZEVJOFoy
+SmgyN2JTNmlwcG5KbnZCQnZQa0YwRTFRMWw0Ck13TmdZR1JibDNIZEFIWHd2RldB
+dW95bi9DcHVVYmFVTUZsZmNHNzdMNTQKLS0tIHdicjZJT0RDdnJZVzdBR3dJajly
+MUQwUHVVRkNwYTNoK0FrWXRUaUIvYnMK8AM1KFh7ew6F4JyXG0T9WusK363QwSkw
+1oiQMSCpMUgzwW76pSqh6Hp4PHtKNHZe
+-----END AGE ENCRYPTED FILE-----
+`
+)
+
+func newTestAgeIdentity(t *testing.T) *age.X25519Identity {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return identity
+}
+
+func TestParseAgeIdentities(t *testing.T) {
+	data := []byte("# created: 2021-08-17\n# public key: " + ageTestRecipient + "\n" + ageTestIdentity + "\n\n  AGE-SECRET-KEY-2  \n")
+
+	identities := ParseAgeIdentities(data)
+	if len(identities) != 2 || identities[0] != ageTestIdentity || identities[1] != "AGE-SECRET-KEY-2" {
+		t.Errorf("unexpected identities %q", identities)
+	}
+}
+
+func TestAgeDecrypt_interop(t *testing.T) {
+	data, err := AgeDecrypt([]byte(ageTestEncryptedFile), []string{ageTestIdentity})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "werf secret key\n" {
+		t.Errorf("unexpected decrypted data %q", data)
+	}
+}
+
+func TestAgeEncrypt_interop(t *testing.T) {
+	data, err := AgeEncrypt([]byte("werf secret key"), []string{ageTestRecipient})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(string(data), armor.Header) {
+		t.Fatalf("expected armored age file, got %q", data)
+	}
+
+	identity, err := age.ParseX25519Identity(ageTestIdentity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := age.Decrypt(armor.NewReader(bytes.NewReader(data)), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted := &bytes.Buffer{}
+	if _, err := decrypted.ReadFrom(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if decrypted.String() != "werf secret key" {
+		t.Errorf("unexpected decrypted data %q", decrypted.String())
+	}
+}
+
+func TestAgeEncryptDecrypt(t *testing.T) {
+	identity1, identity2, otherIdentity := newTestAgeIdentity(t), newTestAgeIdentity(t), newTestAgeIdentity(t)
+	data := []byte("11ac8312520b5ff037bae386ea2e8a07")
+
+	encrypted, err := AgeEncrypt(data, []string{identity1.Recipient().String(), identity2.Recipient().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, identity := range []*age.X25519Identity{identity1, identity2} {
+		decrypted, err := AgeDecrypt(encrypted, []string{otherIdentity.String(), identity.String()})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(decrypted, data) {
+			t.Errorf("expected %q, got %q", data, decrypted)
+		}
+	}
+
+	if _, err := AgeDecrypt(encrypted, []string{otherIdentity.String()}); err == nil {
+		t.Errorf("expected error when decrypting with the identity which is not the recipient")
+	}
+}
+
+func TestAgeDecrypt_binary(t *testing.T) {
+	identity := newTestAgeIdentity(t)
+	data := []byte("11ac8312520b5ff037bae386ea2e8a07")
+
+	encrypted := &bytes.Buffer{}
+	w, err := age.Encrypt(encrypted, identity.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := AgeDecrypt(encrypted.Bytes(), []string{identity.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decrypted, data) {
+		t.Errorf("expected %q, got %q", data, decrypted)
+	}
+}
+
+func TestAgeEncrypt_negative(t *testing.T) {
+	if _, err := AgeEncrypt([]byte("data"), nil); err == nil {
+		t.Errorf("expected error without recipients")
+	}
+
+	if _, err := AgeEncrypt([]byte("data"), []string{"age1invalid"}); err == nil {
+		t.Errorf("expected error for the malformed recipient")
+	}
+
+	if _, err := AgeDecrypt([]byte(ageTestEncryptedFile), []string{"AGE-SECRET-KEY-1INVALID"}); err == nil {
+		t.Errorf("expected error for the malformed identity")
+	}
+}
@@ -0,0 +1,131 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	// RIPEMD160 is the fallback hash for the keys without preferred hashes
+	_ "golang.org/x/crypto/ripemd160"
+)
+
+const pgpMessageType = "PGP MESSAGE"
+
+// ReadPGPKeyRing reads the armored or binary OpenPGP keyring
+func ReadPGPKeyRing(data []byte) (openpgp.EntityList, error) {
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "-----BEGIN") {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	}
+
+	return openpgp.ReadKeyRing(bytes.NewReader(data))
+}
+
+// SelectPGPRecipients returns the keyring entities by the recipients key fingerprints or key ids
+func SelectPGPRecipients(keyring openpgp.EntityList, recipients []string) (openpgp.EntityList, error) {
+	var res openpgp.EntityList
+
+RecipientsLoop:
+	for _, recipient := range recipients {
+		id := strings.ToUpper(strings.Replace(strings.TrimPrefix(recipient, "0x"), " ", "", -1))
+
+		for _, entity := range keyring {
+			fingerprint := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+			if id != "" && strings.HasSuffix(fingerprint, id) {
+				res = append(res, entity)
+				continue RecipientsLoop
+			}
+		}
+
+		return nil, fmt.Errorf("pgp key %q not found in the keyring", recipient)
+	}
+
+	return res, nil
+}
+
+// PGPEncrypt encrypts the data for the recipients in the armored OpenPGP message format
+func PGPEncrypt(data []byte, recipients openpgp.EntityList) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no pgp recipients specified")
+	}
+
+	res := &bytes.Buffer{}
+	armorWriter, err := armor.Encode(res, pgpMessageType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := openpgp.Encrypt(armorWriter, recipients, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encrypt pgp message: %s", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := armorWriter.Close(); err != nil {
+		return nil, err
+	}
+	res.WriteString("\n")
+
+	return res.Bytes(), nil
+}
+
+// PGPDecrypt decrypts the armored or binary OpenPGP message with the private keys of the keyring,
+// the passphrase is used for the encrypted private keys
+func PGPDecrypt(data []byte, keyring openpgp.EntityList, passphrase []byte) ([]byte, error) {
+	r := bytes.NewReader(data)
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "-----BEGIN") {
+		block, err := armor.Decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("malformed pgp message armor: %s", err)
+		}
+
+		if block.Type != pgpMessageType {
+			return nil, fmt.Errorf("unexpected pgp armor type %q", block.Type)
+		}
+
+		armoredData, err := ioutil.ReadAll(block.Body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(armoredData)
+	}
+
+	var prompted bool
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if prompted || len(passphrase) == 0 || symmetric {
+			return nil, fmt.Errorf("unable to decrypt pgp private key: valid passphrase required")
+		}
+		prompted = true
+
+		for _, key := range keys {
+			if key.PrivateKey != nil && key.PrivateKey.Encrypted {
+				if err := key.PrivateKey.Decrypt(passphrase); err != nil {
+					return nil, fmt.Errorf("unable to decrypt pgp private key: %s", err)
+				}
+			}
+		}
+
+		return nil, nil
+	}
+
+	md, err := openpgp.ReadMessage(r, keyring, prompt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read pgp message: %s", err)
+	}
+
+	res, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt pgp message: %s", err)
+	}
+
+	return res, nil
+}
@@ -6,12 +6,17 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-// YamlEncoder is an Encoder compatible object with additional helpers to work with yaml data: EncryptYamlData and DecryptYamlData
+// YamlEncoder is an Encoder compatible object with additional helpers to work with yaml data: EncryptYamlData and DecryptYamlData.
+// The yaml values can be encrypted with the named keys (see SetNamedKeys), such values are prefixed with @NAME: marker.
 type YamlEncoder struct {
 	Encoder Encoder
 
 	generateFunc func([]byte) ([]byte, error)
 	extractFunc  func([]byte) ([]byte, error)
+
+	namedKeys     map[string]NamedKey
+	namedEncoders map[string]*namedEncoder
+	warningFunc   func(format string, a ...interface{})
 }
 
 func NewYamlEncoder(encoder Encoder) *YamlEncoder {
@@ -38,7 +43,9 @@ func (s *YamlEncoder) Encrypt(data []byte) ([]byte, error) {
 }
 
 func (s *YamlEncoder) EncryptYamlData(data []byte) ([]byte, error) {
-	resultData, err := doYamlData(s.generateFunc, data)
+	resultData, err := doYamlData(func(config yaml.MapSlice) (interface{}, error) {
+		return s.encryptYamlValue(config, "", false)
+	}, data)
 	if err != nil {
 		return nil, fmt.Errorf("encryption failed: check encryption key and data: %s", err)
 	}
@@ -60,7 +67,10 @@ func (s *YamlEncoder) Decrypt(data []byte) ([]byte, error) {
 }
 
 func (s *YamlEncoder) DecryptYamlData(data []byte) ([]byte, error) {
-	resultData, err := doYamlData(s.extractFunc, data)
+	resultData, err := doYamlData(func(config yaml.MapSlice) (interface{}, error) {
+		result, _, err := s.decryptYamlValue(config, "")
+		return result, err
+	}, data)
 	if err != nil {
 		if IsExtractDataError(err) {
 			return nil, fmt.Errorf("decryption failed: check data `%s`: %s", string(data), err)
@@ -72,14 +82,58 @@ func (s *YamlEncoder) DecryptYamlData(data []byte) ([]byte, error) {
 	return resultData, nil
 }
 
-func doYamlData(doFunc func([]byte) ([]byte, error), data []byte) ([]byte, error) {
+// MigrateYamlData re-encrypts the values in the legacy format with the versioned format and returns the number of migrated values,
+// the values encrypted with the unavailable named keys are left untouched.
+// The encoders should encrypt in the versioned format (see AesEncoder.UseVersionedFormat).
+func (s *YamlEncoder) MigrateYamlData(data []byte) ([]byte, int, error) {
+	var migratedValues int
+	resultData, err := doYamlData(func(config yaml.MapSlice) (interface{}, error) {
+		return mapYamlScalars(config, func(value interface{}) (interface{}, error) {
+			valueStr := fmt.Sprintf("%v", value)
+
+			keyName, encryptedValue := parseNamedKeyValue(valueStr)
+			if !IsLegacyFormat([]byte(encryptedValue)) {
+				return value, nil
+			}
+
+			decryptedValue, _, err := s.decryptScalar(valueStr)
+			if err != nil {
+				if _, ok := err.(*namedKeyNotAvailableError); ok {
+					return value, nil
+				}
+
+				return nil, err
+			}
+
+			result, err := s.encryptScalar([]byte(decryptedValue), keyName)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, encryptedValue := parseNamedKeyValue(fmt.Sprintf("%v", result)); IsLegacyFormat([]byte(encryptedValue)) {
+				return nil, fmt.Errorf("the encoder does not use the versioned format")
+			}
+
+			migratedValues++
+
+			return result, nil
+		})
+	}, data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("migration failed: check encryption key and data: %s", err)
+	}
+
+	return resultData, migratedValues, nil
+}
+
+func doYamlData(doFunc func(yaml.MapSlice) (interface{}, error), data []byte) ([]byte, error) {
 	config := make(yaml.MapSlice, 0)
 	err := yaml.UnmarshalStrict(data, &config)
 	if err != nil {
 		return nil, err
 	}
 
-	resultConfig, err := doYamlValueSecret(doFunc, config)
+	resultConfig, err := doFunc(config)
 	if err != nil {
 		return nil, err
 	}
@@ -92,54 +146,4 @@ func doYamlData(doFunc func([]byte) ([]byte, error), data []byte) ([]byte, error
 	return resultData, nil
 }
 
-func doYamlValueSecret(doFunc func([]byte) ([]byte, error), data interface{}) (interface{}, error) {
-	switch data.(type) {
-	case yaml.MapSlice:
-		result := make(yaml.MapSlice, len(data.(yaml.MapSlice)))
-		for ind, elm := range data.(yaml.MapSlice) {
-			result[ind].Key = elm.Key
-			resultValue, err := doYamlValueSecret(doFunc, elm.Value)
-			if err != nil {
-				return nil, err
-			}
-
-			result[ind].Value = resultValue
-		}
-
-		return result, nil
-	case yaml.MapItem:
-		var result yaml.MapItem
-
-		result.Key = data.(yaml.MapItem).Key
-
-		resultValue, err := doYamlValueSecret(doFunc, data.(yaml.MapItem).Value)
-		if err != nil {
-			return nil, err
-		}
-
-		result.Value = resultValue
-
-		return result, nil
-	case []interface{}:
-		var result []interface{}
-		for _, elm := range data.([]interface{}) {
-			resultElm, err := doYamlValueSecret(doFunc, elm)
-			if err != nil {
-				return nil, err
-			}
-
-			result = append(result, resultElm)
-		}
-
-		return result, nil
-	default:
-		result, err := doFunc([]byte(fmt.Sprintf("%v", data)))
-		if err != nil {
-			return nil, err
-		}
-
-		return string(result), nil
-	}
-}
-
 func doNothing(data []byte) ([]byte, error) { return data, nil }
@@ -0,0 +1,156 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+type YamlValueChangeType string
+
+const (
+	YamlValueAdded   YamlValueChangeType = "added"
+	YamlValueRemoved YamlValueChangeType = "removed"
+	YamlValueChanged YamlValueChangeType = "changed"
+)
+
+// YamlValueChange describes the change of the secret value between two versions of the secret values yaml data.
+// The old and new decrypted values are nil if the value is absent or cannot be decrypted with the available keys.
+type YamlValueChange struct {
+	Path     string
+	Type     YamlValueChangeType
+	OldValue *string
+	NewValue *string
+}
+
+type encryptedYamlValue struct {
+	Path  string
+	Value string
+}
+
+// DiffYamlData compares the encrypted secret values yaml data and returns the changes sorted by the value path.
+// The values are compared by the decrypted data, so the value re-encrypted without changes is not reported,
+// the values encrypted with the unavailable named keys are compared by the encrypted data.
+func (s *YamlEncoder) DiffYamlData(oldData, newData []byte) ([]*YamlValueChange, error) {
+	oldValues, err := flattenEncryptedYamlData(oldData)
+	if err != nil {
+		return nil, err
+	}
+
+	newValues, err := flattenEncryptedYamlData(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	oldValueByPath := map[string]string{}
+	for _, value := range oldValues {
+		oldValueByPath[value.Path] = value.Value
+	}
+
+	newValueByPath := map[string]string{}
+	for _, value := range newValues {
+		newValueByPath[value.Path] = value.Value
+	}
+
+	var changes []*YamlValueChange
+	for _, value := range newValues {
+		oldValue, exist := oldValueByPath[value.Path]
+		if !exist {
+			newDecryptedValue, err := s.decryptValueForDiff(value.Value)
+			if err != nil {
+				return nil, fmt.Errorf("unable to decrypt value %q: %s", value.Path, err)
+			}
+
+			changes = append(changes, &YamlValueChange{Path: value.Path, Type: YamlValueAdded, NewValue: newDecryptedValue})
+			continue
+		}
+
+		if oldValue == value.Value {
+			continue
+		}
+
+		oldDecryptedValue, err := s.decryptValueForDiff(oldValue)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt old value %q: %s", value.Path, err)
+		}
+
+		newDecryptedValue, err := s.decryptValueForDiff(value.Value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt value %q: %s", value.Path, err)
+		}
+
+		oldKeyName, _ := parseNamedKeyValue(oldValue)
+		newKeyName, _ := parseNamedKeyValue(value.Value)
+		if oldDecryptedValue != nil && newDecryptedValue != nil && *oldDecryptedValue == *newDecryptedValue && oldKeyName == newKeyName {
+			continue
+		}
+
+		changes = append(changes, &YamlValueChange{Path: value.Path, Type: YamlValueChanged, OldValue: oldDecryptedValue, NewValue: newDecryptedValue})
+	}
+
+	for _, value := range oldValues {
+		if _, exist := newValueByPath[value.Path]; exist {
+			continue
+		}
+
+		oldDecryptedValue, err := s.decryptValueForDiff(value.Value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt old value %q: %s", value.Path, err)
+		}
+
+		changes = append(changes, &YamlValueChange{Path: value.Path, Type: YamlValueRemoved, OldValue: oldDecryptedValue})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// decryptValueForDiff returns nil if the value is encrypted with the unavailable named key
+func (s *YamlEncoder) decryptValueForDiff(value string) (*string, error) {
+	result, _, err := s.decryptScalar(value)
+	if err != nil {
+		if _, ok := err.(*namedKeyNotAvailableError); ok {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func flattenEncryptedYamlData(data []byte) ([]*encryptedYamlValue, error) {
+	config := make(yaml.MapSlice, 0)
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, err
+	}
+
+	var result []*encryptedYamlValue
+	var flatten func(path string, data interface{})
+	flatten = func(path string, data interface{}) {
+		switch value := data.(type) {
+		case yaml.MapSlice:
+			for _, elm := range value {
+				elmPath := fmt.Sprintf("%v", elm.Key)
+				if path != "" {
+					elmPath = fmt.Sprintf("%s.%v", path, elm.Key)
+				}
+
+				flatten(elmPath, elm.Value)
+			}
+		case []interface{}:
+			for ind, elm := range value {
+				flatten(fmt.Sprintf("%s[%d]", path, ind), elm)
+			}
+		default:
+			result = append(result, &encryptedYamlValue{Path: path, Value: fmt.Sprintf("%v", value)})
+		}
+	}
+	flatten("", config)
+
+	return result, nil
+}
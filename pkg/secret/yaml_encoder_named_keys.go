@@ -0,0 +1,436 @@
+package secret
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The value encrypted with the named key has the format @NAME:DATA.
+// In the yaml data decrypted by DecryptYamlDataWithKeyNames and encrypted by EncryptYamlDataWithKeyNames
+// the named key is specified with the @NAME suffix of the map key, all values of the map item are encrypted with the key:
+//
+//	password@production: s3cr3t
+//	database@staging:
+//	  user: app
+//	  password: s3cr3t
+var namedKeyValueRegexp = regexp.MustCompile(`^@([a-zA-Z0-9][a-zA-Z0-9_-]*):(.*)$`)
+
+// NamedKey describes the named secret key
+type NamedKey struct {
+	// NewEncoderFunc returns the encoder of the named key, the function is called once when the named key is required
+	NewEncoderFunc func() (Encoder, error)
+	// Optional key values are skipped by DecryptYamlData with the warning if the key is not available
+	Optional bool
+}
+
+type namedEncoder struct {
+	Encoder Encoder
+	Err     error
+}
+
+// SetNamedKeys sets the named keys which are used to encrypt and decrypt the @NAME: marked values.
+// DecryptYamlData fails if the named key is not available unless the key is optional,
+// DecryptYamlDataWithKeyNames returns the values of unavailable keys as is and EncryptYamlDataWithKeyNames leaves them untouched.
+func (s *YamlEncoder) SetNamedKeys(namedKeys map[string]NamedKey) {
+	s.namedKeys = namedKeys
+	s.namedEncoders = map[string]*namedEncoder{}
+}
+
+// SetWarningFunc sets the function which logs the warnings, e.g. about the skipped values of the optional named keys
+func (s *YamlEncoder) SetWarningFunc(warningFunc func(format string, a ...interface{})) {
+	s.warningFunc = warningFunc
+}
+
+// DecryptYamlDataWithKeyNames decrypts yaml data and marks the values encrypted with the named keys with the @NAME map key suffix,
+// the result can be encrypted back with EncryptYamlDataWithKeyNames
+func (s *YamlEncoder) DecryptYamlDataWithKeyNames(data []byte) ([]byte, error) {
+	resultData, err := doYamlData(func(config yaml.MapSlice) (interface{}, error) {
+		result, names, err := s.decryptMapSliceWithKeyNames(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return markMapSliceKeyNames(result, names), nil
+	}, data)
+	if err != nil {
+		if IsExtractDataError(err) {
+			return nil, fmt.Errorf("decryption failed: check data `%s`: %s", string(data), err)
+		}
+
+		return nil, fmt.Errorf("decryption failed: check encryption key and data: %s", err)
+	}
+
+	return resultData, nil
+}
+
+// EncryptYamlDataWithKeyNames encrypts yaml data, the map items with the @NAME key suffix of the defined named key
+// are encrypted with the named key and the suffix is removed.
+// EncryptYamlData keeps the map keys as is, so that the plain map keys containing @ are not treated as the named key markers
+func (s *YamlEncoder) EncryptYamlDataWithKeyNames(data []byte) ([]byte, error) {
+	resultData, err := doYamlData(func(config yaml.MapSlice) (interface{}, error) {
+		return s.encryptYamlValue(config, "", true)
+	}, data)
+	if err != nil {
+		return nil, fmt.Errorf("encryption failed: check encryption key and data: %s", err)
+	}
+
+	return resultData, nil
+}
+
+// ReencryptYamlData decrypts the values encrypted with the keyName key (the default key if keyName is empty) using oldEncoder
+// and encrypts them with the current key, the values encrypted with other keys are left untouched
+func (s *YamlEncoder) ReencryptYamlData(data []byte, keyName string, oldEncoder *YamlEncoder) ([]byte, error) {
+	return doYamlData(func(config yaml.MapSlice) (interface{}, error) {
+		return mapYamlScalars(config, func(value interface{}) (interface{}, error) {
+			valueKeyName, encryptedValue := parseNamedKeyValue(fmt.Sprintf("%v", value))
+			if valueKeyName != keyName {
+				return value, nil
+			}
+
+			decryptedValue, err := oldEncoder.extractFunc([]byte(encryptedValue))
+			if err != nil {
+				return nil, err
+			}
+
+			return s.encryptScalar(decryptedValue, keyName)
+		})
+	}, data)
+}
+
+func (s *YamlEncoder) isNamedKeyDefined(name string) bool {
+	_, ok := s.namedKeys[name]
+	return ok
+}
+
+func (s *YamlEncoder) getNamedEncoder(name string) (Encoder, error) {
+	if _, ok := s.namedEncoders[name]; !ok {
+		encoder, err := s.namedKeys[name].NewEncoderFunc()
+		if err != nil {
+			err = fmt.Errorf("secret key %q is not available: %s", name, err)
+		}
+
+		s.namedEncoders[name] = &namedEncoder{Encoder: encoder, Err: err}
+	}
+
+	return s.namedEncoders[name].Encoder, s.namedEncoders[name].Err
+}
+
+func (s *YamlEncoder) splitKeyName(key interface{}, inheritedName string) (interface{}, string) {
+	if keyStr, ok := key.(string); ok {
+		if ind := strings.LastIndex(keyStr, "@"); ind > 0 && s.isNamedKeyDefined(keyStr[ind+1:]) {
+			return keyStr[:ind], keyStr[ind+1:]
+		}
+	}
+
+	return key, inheritedName
+}
+
+func (s *YamlEncoder) encryptYamlValue(data interface{}, keyName string, withKeyNames bool) (interface{}, error) {
+	switch value := data.(type) {
+	case yaml.MapSlice:
+		result := make(yaml.MapSlice, len(value))
+		for ind, elm := range value {
+			key, name := elm.Key, keyName
+			if withKeyNames {
+				key, name = s.splitKeyName(elm.Key, keyName)
+			}
+
+			resultValue, err := s.encryptYamlValue(elm.Value, name, withKeyNames)
+			if err != nil {
+				return nil, err
+			}
+
+			result[ind] = yaml.MapItem{Key: key, Value: resultValue}
+		}
+
+		return result, nil
+	case []interface{}:
+		var result []interface{}
+		for _, elm := range value {
+			resultElm, err := s.encryptYamlValue(elm, keyName, withKeyNames)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, resultElm)
+		}
+
+		return result, nil
+	default:
+		valueStr := fmt.Sprintf("%v", value)
+
+		if keyName != "" {
+			if _, err := s.getNamedEncoder(keyName); err != nil {
+				// the value of the unavailable key is kept encrypted
+				if name, _ := parseNamedKeyValue(valueStr); name == keyName {
+					return valueStr, nil
+				}
+
+				return nil, err
+			}
+		}
+
+		return s.encryptScalar([]byte(valueStr), keyName)
+	}
+}
+
+func (s *YamlEncoder) encryptScalar(data []byte, keyName string) (interface{}, error) {
+	if keyName == "" {
+		result, err := s.generateFunc(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(result), nil
+	}
+
+	encoder, err := s.getNamedEncoder(keyName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := encoder.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatNamedKeyValue(keyName, string(result)), nil
+}
+
+// decryptYamlValue returns the decrypted value and reports whether the value should be skipped because of the unavailable optional named key
+func (s *YamlEncoder) decryptYamlValue(data interface{}, path string) (interface{}, bool, error) {
+	switch value := data.(type) {
+	case yaml.MapSlice:
+		result := make(yaml.MapSlice, 0, len(value))
+		for _, elm := range value {
+			elmPath := fmt.Sprintf("%v", elm.Key)
+			if path != "" {
+				elmPath = fmt.Sprintf("%s.%v", path, elm.Key)
+			}
+
+			resultValue, skip, err := s.decryptYamlValue(elm.Value, elmPath)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if !skip {
+				result = append(result, yaml.MapItem{Key: elm.Key, Value: resultValue})
+			}
+		}
+
+		return result, false, nil
+	case []interface{}:
+		var result []interface{}
+		var skipList bool
+		for ind, elm := range value {
+			resultElm, skip, err := s.decryptYamlValue(elm, fmt.Sprintf("%s[%d]", path, ind))
+			if err != nil {
+				return nil, false, err
+			}
+
+			// the list with the skipped elements is skipped entirely to not shift the indexes
+			if skip {
+				skipList = true
+				continue
+			}
+
+			result = append(result, resultElm)
+		}
+
+		if skipList {
+			return nil, true, nil
+		}
+
+		return result, false, nil
+	default:
+		result, name, err := s.decryptScalar(fmt.Sprintf("%v", value))
+		if err != nil {
+			if _, ok := err.(*namedKeyNotAvailableError); ok && s.namedKeys[name].Optional {
+				s.warn("WARNING: secret value %q skipped: %s\n", path, err)
+				return nil, true, nil
+			}
+
+			return nil, false, err
+		}
+
+		return result, false, nil
+	}
+}
+
+func (s *YamlEncoder) warn(format string, a ...interface{}) {
+	if s.warningFunc != nil {
+		s.warningFunc(format, a...)
+	}
+}
+
+func (s *YamlEncoder) decryptYamlValueWithKeyName(data interface{}) (interface{}, string, error) {
+	switch value := data.(type) {
+	case yaml.MapSlice:
+		result, names, err := s.decryptMapSliceWithKeyNames(value)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if name := commonKeyName(names); name != "" {
+			return result, name, nil
+		}
+
+		return markMapSliceKeyNames(result, names), "", nil
+	case []interface{}:
+		var result []interface{}
+		var names []string
+		for _, elm := range value {
+			resultElm, name, err := s.decryptYamlValueWithKeyName(elm)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if len(names) != 0 && names[0] != name {
+				return nil, "", fmt.Errorf("list values encrypted with different secret keys %q and %q are not supported", names[0], name)
+			}
+
+			result = append(result, resultElm)
+			names = append(names, name)
+		}
+
+		return result, commonKeyName(names), nil
+	default:
+		result, name, err := s.decryptScalar(fmt.Sprintf("%v", value))
+		if err != nil {
+			if _, ok := err.(*namedKeyNotAvailableError); ok {
+				return fmt.Sprintf("%v", value), name, nil
+			}
+
+			return nil, "", err
+		}
+
+		return result, name, nil
+	}
+}
+
+func (s *YamlEncoder) decryptMapSliceWithKeyNames(data yaml.MapSlice) (yaml.MapSlice, []string, error) {
+	result := make(yaml.MapSlice, len(data))
+	names := make([]string, len(data))
+	for ind, elm := range data {
+		resultValue, name, err := s.decryptYamlValueWithKeyName(elm.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result[ind] = yaml.MapItem{Key: elm.Key, Value: resultValue}
+		names[ind] = name
+	}
+
+	return result, names, nil
+}
+
+func (s *YamlEncoder) decryptScalar(value string) (string, string, error) {
+	if s.Encoder == nil {
+		return value, "", nil
+	}
+
+	name, encryptedValue := parseNamedKeyValue(value)
+	if name == "" {
+		result, err := s.extractFunc([]byte(value))
+		if err != nil {
+			return "", "", err
+		}
+
+		return string(result), "", nil
+	}
+
+	if !s.isNamedKeyDefined(name) {
+		return "", "", fmt.Errorf("unknown secret key %q", name)
+	}
+
+	encoder, err := s.getNamedEncoder(name)
+	if err != nil {
+		return "", name, &namedKeyNotAvailableError{err: err}
+	}
+
+	result, err := encoder.Decrypt([]byte(encryptedValue))
+	if err != nil {
+		return "", "", fmt.Errorf("secret key %q: %s", name, err)
+	}
+
+	return string(result), name, nil
+}
+
+type namedKeyNotAvailableError struct {
+	err error
+}
+
+func (e *namedKeyNotAvailableError) Error() string {
+	return e.err.Error()
+}
+
+func mapYamlScalars(data interface{}, mapFunc func(interface{}) (interface{}, error)) (interface{}, error) {
+	switch value := data.(type) {
+	case yaml.MapSlice:
+		result := make(yaml.MapSlice, len(value))
+		for ind, elm := range value {
+			resultValue, err := mapYamlScalars(elm.Value, mapFunc)
+			if err != nil {
+				return nil, err
+			}
+
+			result[ind] = yaml.MapItem{Key: elm.Key, Value: resultValue}
+		}
+
+		return result, nil
+	case []interface{}:
+		var result []interface{}
+		for _, elm := range value {
+			resultElm, err := mapYamlScalars(elm, mapFunc)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, resultElm)
+		}
+
+		return result, nil
+	default:
+		return mapFunc(value)
+	}
+}
+
+func markMapSliceKeyNames(data yaml.MapSlice, names []string) yaml.MapSlice {
+	for ind, name := range names {
+		if name != "" {
+			data[ind].Key = fmt.Sprintf("%v@%s", data[ind].Key, name)
+		}
+	}
+
+	return data
+}
+
+// commonKeyName returns the key name if all values are encrypted with the same named key
+func commonKeyName(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	for _, name := range names[1:] {
+		if name != names[0] {
+			return ""
+		}
+	}
+
+	return names[0]
+}
+
+func parseNamedKeyValue(value string) (string, string) {
+	if match := namedKeyValueRegexp.FindStringSubmatch(value); match != nil {
+		return match[1], match[2]
+	}
+
+	return "", value
+}
+
+func formatNamedKeyValue(name, data string) string {
+	return fmt.Sprintf("@%s:%s", name, data)
+}
@@ -0,0 +1,152 @@
+package secret
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func newTestNamedKeysYamlEncoder(t *testing.T, optional bool) *YamlEncoder {
+	aesEncoder, err := NewAesEncoder(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := NewYamlEncoder(aesEncoder)
+	enc.SetNamedKeys(map[string]NamedKey{
+		"staging": {NewEncoderFunc: func() (Encoder, error) { return aesEncoder, nil }},
+		"production": {
+			NewEncoderFunc: func() (Encoder, error) { return nil, fmt.Errorf("no production key") },
+			Optional:       optional,
+		},
+	})
+
+	return enc
+}
+
+func newTestNamedKeysYamlData(t *testing.T) []byte {
+	aesEncoder, err := NewAesEncoder(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypt := func(value string) string {
+		data, err := aesEncoder.Encrypt([]byte(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(data)
+	}
+
+	return []byte(fmt.Sprintf(`a: %s
+b: "@staging:%s"
+c: "@production:%s"
+d:
+  e: "@production:%s"
+f:
+- "@staging:%s"
+- "@production:%s"
+`, encrypt("a"), encrypt("b"), encrypt("c"), encrypt("e"), encrypt("f0"), encrypt("f1")))
+}
+
+func TestYamlEncoder_DecryptYamlData_namedKeyNotAvailable(t *testing.T) {
+	enc := newTestNamedKeysYamlEncoder(t, false)
+
+	var warnings []string
+	enc.SetWarningFunc(func(format string, a ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, a...))
+	})
+
+	_, err := enc.DecryptYamlData(newTestNamedKeysYamlData(t))
+	if err == nil || !strings.Contains(err.Error(), "no production key") {
+		t.Errorf("expected error about the unavailable production key, got %v", err)
+	}
+
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %q", warnings)
+	}
+}
+
+func TestYamlEncoder_DecryptYamlData_optionalNamedKey(t *testing.T) {
+	enc := newTestNamedKeysYamlEncoder(t, true)
+
+	var warnings []string
+	enc.SetWarningFunc(func(format string, a ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, a...))
+	})
+
+	resultData, err := enc.DecryptYamlData(newTestNamedKeysYamlData(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedData := "a: a\nb: b\nd: {}\n"
+	if string(resultData) != expectedData {
+		t.Errorf("\n[EXPECTED]\n%s\n[GOT]\n%s\n", expectedData, resultData)
+	}
+
+	expectedWarnings := []string{
+		"WARNING: secret value \"c\" skipped: secret key \"production\" is not available: no production key\n",
+		"WARNING: secret value \"d.e\" skipped: secret key \"production\" is not available: no production key\n",
+		"WARNING: secret value \"f[1]\" skipped: secret key \"production\" is not available: no production key\n",
+	}
+	if fmt.Sprintf("%q", warnings) != fmt.Sprintf("%q", expectedWarnings) {
+		t.Errorf("\n[EXPECTED]\n%q\n[GOT]\n%q\n", expectedWarnings, warnings)
+	}
+}
+
+func TestYamlEncoder_EncryptYamlData_keyNames(t *testing.T) {
+	enc := newTestNamedKeysYamlEncoder(t, false)
+	data := []byte("admin@staging: a\nb@staging:\n  c: c\n")
+
+	encryptedData, err := enc.EncryptYamlData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var encrypted yaml.MapSlice
+	if err := yaml.Unmarshal(encryptedData, &encrypted); err != nil {
+		t.Fatal(err)
+	}
+	if encrypted[0].Key != "admin@staging" {
+		t.Errorf("expected the plain map key containing @ to be kept as is, got %q", encrypted[0].Key)
+	}
+	if name, _ := parseNamedKeyValue(fmt.Sprintf("%v", encrypted[0].Value)); name != "" {
+		t.Errorf("expected the value to be encrypted with the default key, got %q key", name)
+	}
+
+	decryptedData, err := enc.DecryptYamlData(encryptedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptedData) != string(data) {
+		t.Errorf("\n[EXPECTED]\n%s\n[GOT]\n%s\n", data, decryptedData)
+	}
+
+	encryptedData, err = enc.EncryptYamlDataWithKeyNames(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted = nil
+	if err := yaml.Unmarshal(encryptedData, &encrypted); err != nil {
+		t.Fatal(err)
+	}
+	if encrypted[0].Key != "admin" {
+		t.Errorf("expected the @staging key suffix to be removed, got %q", encrypted[0].Key)
+	}
+	if name, _ := parseNamedKeyValue(fmt.Sprintf("%v", encrypted[0].Value)); name != "staging" {
+		t.Errorf("expected the value to be encrypted with the staging key, got %q key", name)
+	}
+
+	decryptedData, err = enc.DecryptYamlDataWithKeyNames(encryptedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptedData) != string(data) {
+		t.Errorf("\n[EXPECTED]\n%s\n[GOT]\n%s\n", data, decryptedData)
+	}
+}
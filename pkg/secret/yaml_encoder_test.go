@@ -2,6 +2,7 @@ package secret
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 )
 
@@ -52,10 +53,7 @@ func TestYamlEncoder_doYamlData(t *testing.T) {
   affinity: {}
 `)
 
-	enc, err := NewYamlEncoder(&EncoderMock{})
-	if err != nil {
-		t.Fatal(err)
-	}
+	enc := NewYamlEncoder(&EncoderMock{})
 
 	encodedData, err := enc.EncryptYamlData(valuesData)
 	if err != nil {
@@ -71,3 +69,102 @@ func TestYamlEncoder_doYamlData(t *testing.T) {
 		t.Errorf("\n[EXPECTED]\n%s\n[GOT]\n%s\n", string(valuesData), string(resultData))
 	}
 }
+
+func TestYamlEncoder_MigrateYamlData(t *testing.T) {
+	aesEncoder, err := NewAesEncoder(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyValue, err := aesEncoder.EncryptLegacy([]byte("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versionedValue, err := aesEncoder.EncryptVersioned([]byte("versioned"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := NewYamlEncoder(aesEncoder)
+
+	if _, _, err := enc.MigrateYamlData([]byte(fmt.Sprintf("a: %s\n", legacyValue))); err == nil {
+		t.Errorf("Expected error when the encoder does not use the versioned format")
+	}
+
+	aesEncoder.UseVersionedFormat = true
+
+	resultData, migratedValues, err := enc.MigrateYamlData([]byte(fmt.Sprintf("a: %s\nb:\n- %s\n", legacyValue, versionedValue)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if migratedValues != 1 {
+		t.Errorf("Expected 1 migrated value, got %d", migratedValues)
+	}
+
+	if !bytes.Contains(resultData, versionedValue) || bytes.Contains(resultData, legacyValue) {
+		t.Errorf("Got unexpected migrated data:\n%s", resultData)
+	}
+
+	aesEncoder.RejectLegacyFormat = true
+
+	decodedData, err := enc.DecryptYamlData(resultData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedData := "a: legacy\nb:\n- versioned\n"
+	if string(decodedData) != expectedData {
+		t.Errorf("\n[EXPECTED]\n%s\n[GOT]\n%s\n", expectedData, decodedData)
+	}
+}
+
+func TestYamlEncoder_DiffYamlData(t *testing.T) {
+	aesEncoder, err := NewAesEncoder(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := NewYamlEncoder(aesEncoder)
+
+	oldData, err := enc.EncryptYamlData([]byte("a: 1\nb:\n  c: x\n  d: [p, q]\nf: removed\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// all values are re-encrypted with the new nonces
+	newData, err := enc.EncryptYamlData([]byte("a: 1\nb:\n  c: z\n  d: [p, r]\ne: added\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := enc.DiffYamlData(oldData, newData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	for _, change := range changes {
+		var oldValue, newValue string
+		if change.OldValue != nil {
+			oldValue = *change.OldValue
+		}
+		if change.NewValue != nil {
+			newValue = *change.NewValue
+		}
+
+		result = append(result, fmt.Sprintf("%s %s %s->%s", change.Type, change.Path, oldValue, newValue))
+	}
+
+	expected := []string{
+		"changed b.c x->z",
+		"changed b.d[1] q->r",
+		"added e ->added",
+		"removed f removed->",
+	}
+
+	if fmt.Sprintf("%q", result) != fmt.Sprintf("%q", expected) {
+		t.Errorf("\n[EXPECTED]\n%q\n[GOT]\n%q\n", expected, result)
+	}
+}
@@ -34,6 +34,14 @@ func getContainer() container {
 	}
 }
 
+func ContainerName() string {
+	return getContainer().Name
+}
+
+func VolumePath() string {
+	return getContainer().Volume
+}
+
 func GetOrCreateContainer(ctx context.Context) (string, error) {
 	container := getContainer()
 
@@ -78,6 +86,10 @@ func Base64BinPath() string {
 	return embeddedBinPath("base64")
 }
 
+func CatBinPath() string {
+	return embeddedBinPath("cat")
+}
+
 func LsBinPath() string {
 	return embeddedBinPath("ls")
 }
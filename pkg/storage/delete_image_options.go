@@ -4,6 +4,11 @@ type DeleteImageOptions struct {
 	RmiForce bool
 }
 
+type DeleteStagesOptions struct {
+	DeleteImageOptions
+	MaxNumberOfWorkers int
+}
+
 type FilterStagesAndProcessRelatedDataOptions struct {
 	SkipUsedImage            bool
 	RmForce                  bool
@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/util"
+)
+
+const (
+	LayersCacheRecordParentLayerLabel = "werf-layers-cache-parent-layer"
+	LayersCacheRecordConfigLabel      = "werf-layers-cache-config"
+)
+
+// LayersCache is a content-addressable cache of the stage layers.
+// Record is addressed by the stage dependencies digest and the diff id of the top layer of the parent image,
+// so the stage image can be restored on top of the locally available parent image by fetching a single layer blob.
+type LayersCache interface {
+	// FetchStageLayer restores stage image from the cache on top of the local parent image, returns false if there is no suitable record
+	FetchStageLayer(ctx context.Context, dependenciesDigest, parentImageName string, img container_runtime.Image) (bool, error)
+	// StoreStageLayer stores the top layer of the local stage image into the cache,
+	// the stage is skipped unless it adds exactly one layer on top of the local parent image
+	StoreStageLayer(ctx context.Context, dependenciesDigest, parentImageName string, img container_runtime.Image) error
+
+	String() string
+}
+
+type RepoLayersCache struct {
+	RepoAddress      string
+	ContainerRuntime container_runtime.ContainerRuntime
+}
+
+func NewRepoLayersCache(repoAddress string, containerRuntime container_runtime.ContainerRuntime) *RepoLayersCache {
+	return &RepoLayersCache{
+		RepoAddress:      repoAddress,
+		ContainerRuntime: containerRuntime,
+	}
+}
+
+func (cache *RepoLayersCache) FetchStageLayer(ctx context.Context, dependenciesDigest, parentImageName string, img container_runtime.Image) (bool, error) {
+	dockerImage := img.(*container_runtime.DockerImage)
+	imageName := dockerImage.Image.Name()
+
+	parentInspect, err := cache.ContainerRuntime.GetImageInspect(ctx, parentImageName)
+	if err != nil {
+		return false, fmt.Errorf("unable to get image %s inspect: %s", parentImageName, err)
+	} else if parentInspect == nil || len(parentInspect.RootFS.Layers) == 0 {
+		return false, nil
+	}
+
+	recordName := cache.recordName(dependenciesDigest, parentInspect.RootFS.Layers[len(parentInspect.RootFS.Layers)-1])
+	logboek.Context(ctx).Debug().LogF("-- RepoLayersCache.FetchStageLayer %s record %s\n", imageName, recordName)
+
+	record, err := docker_registry.API().GetRepoV1Image(ctx, recordName)
+	if err != nil {
+		if docker_registry.IsManifestUnknownError(err) || docker_registry.IsNameUnknownError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to get record %s: %s", recordName, err)
+	}
+
+	layer, rawConfig, err := parseLayersCacheRecord(record)
+	if err != nil {
+		return false, fmt.Errorf("invalid record %s: %s", recordName, err)
+	}
+
+	layerDiffID, err := layer.DiffID()
+	if err != nil {
+		return false, err
+	}
+
+	configFile, err := v1.ParseConfigFile(bytes.NewReader(rawConfig))
+	if err != nil {
+		return false, fmt.Errorf("invalid record %s config: %s", recordName, err)
+	}
+
+	expectedDiffIDs := append(append([]string{}, parentInspect.RootFS.Layers...), layerDiffID.String())
+	if !reflect.DeepEqual(expectedDiffIDs, diffIDsToStrings(configFile.RootFS.DiffIDs)) {
+		logboek.Context(ctx).Info().LogF("Record %s does not match parent image %s layers\n", recordName, parentImageName)
+		return false, nil
+	}
+
+	if err := logboek.Context(ctx).Info().LogProcess("Restoring %s from %s", imageName, recordName).DoError(func() error {
+		return withRuntimeImage(ctx, cache.ContainerRuntime, parentImageName, func(parentImg v1.Image) error {
+			appendedImg, err := mutate.AppendLayers(parentImg, layer)
+			if err != nil {
+				return err
+			}
+
+			img, err := newRawConfigImage(appendedImg, rawConfig)
+			if err != nil {
+				return err
+			}
+			return writeRuntimeImage(ctx, cache.ContainerRuntime, imageName, img)
+		})
+	}); err != nil {
+		return false, err
+	}
+
+	if err := cache.ContainerRuntime.RefreshImageObject(ctx, img); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (cache *RepoLayersCache) StoreStageLayer(ctx context.Context, dependenciesDigest, parentImageName string, img container_runtime.Image) error {
+	dockerImage := img.(*container_runtime.DockerImage)
+	imageName := dockerImage.Image.Name()
+
+	inspect, err := cache.ContainerRuntime.GetImageInspect(ctx, imageName)
+	if err != nil {
+		return fmt.Errorf("unable to get image %s inspect: %s", imageName, err)
+	} else if inspect == nil {
+		return fmt.Errorf("image %s not found", imageName)
+	}
+
+	parentInspect, err := cache.ContainerRuntime.GetImageInspect(ctx, parentImageName)
+	if err != nil {
+		return fmt.Errorf("unable to get image %s inspect: %s", parentImageName, err)
+	} else if parentInspect == nil {
+		logboek.Context(ctx).Info().LogF("Parent image %s of %s is not available locally, skipping\n", parentImageName, imageName)
+		return nil
+	}
+
+	if !isSingleLayerAdded(parentInspect.RootFS.Layers, inspect.RootFS.Layers) {
+		logboek.Context(ctx).Info().LogF("Image %s does not add a single layer on top of parent image %s (%d and %d layers), skipping\n", imageName, parentImageName, len(inspect.RootFS.Layers), len(parentInspect.RootFS.Layers))
+		return nil
+	}
+
+	recordName := cache.recordName(dependenciesDigest, inspect.RootFS.Layers[len(inspect.RootFS.Layers)-2])
+	logboek.Context(ctx).Debug().LogF("-- RepoLayersCache.StoreStageLayer %s record %s\n", imageName, recordName)
+
+	if exists, err := docker_registry.API().IsRepoImageExists(ctx, recordName); err != nil {
+		return fmt.Errorf("unable to check record %s existence: %s", recordName, err)
+	} else if exists {
+		return nil
+	}
+
+	return logboek.Context(ctx).Info().LogProcess("Storing %s top layer into %s", imageName, recordName).DoError(func() error {
+		return withRuntimeImage(ctx, cache.ContainerRuntime, imageName, func(localImg v1.Image) error {
+			layers, err := localImg.Layers()
+			if err != nil {
+				return err
+			}
+
+			rawConfig, err := localImg.RawConfigFile()
+			if err != nil {
+				return err
+			}
+
+			record, err := newLayersCacheRecord(layers[len(layers)-1], rawConfig, inspect.RootFS.Layers[len(inspect.RootFS.Layers)-2])
+			if err != nil {
+				return err
+			}
+
+			return docker_registry.API().WriteRepoV1Image(ctx, recordName, record)
+		})
+	})
+}
+
+func (cache *RepoLayersCache) String() string {
+	return cache.RepoAddress
+}
+
+func (cache *RepoLayersCache) recordName(dependenciesDigest, parentLayerDiffID string) string {
+	return fmt.Sprintf("%s:%s", cache.RepoAddress, util.Sha3_224Hash(image.BuildCacheVersion, dependenciesDigest, parentLayerDiffID))
+}
+
+// newLayersCacheRecord creates an image with the single stage layer, the original stage config is kept in the label
+// to restore the stage image with the same id
+func newLayersCacheRecord(layer v1.Layer, rawConfig []byte, parentLayerDiffID string) (v1.Image, error) {
+	record, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		return nil, err
+	}
+
+	return mutate.Config(record, v1.Config{
+		Labels: map[string]string{
+			LayersCacheRecordParentLayerLabel: parentLayerDiffID,
+			LayersCacheRecordConfigLabel:      base64.StdEncoding.EncodeToString(rawConfig),
+		},
+	})
+}
+
+func parseLayersCacheRecord(record v1.Image) (v1.Layer, []byte, error) {
+	layers, err := record.Layers()
+	if err != nil {
+		return nil, nil, err
+	} else if len(layers) != 1 {
+		return nil, nil, fmt.Errorf("expected single layer, got %d", len(layers))
+	}
+
+	configFile, err := record.ConfigFile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rawConfig, err := base64.StdEncoding.DecodeString(configFile.Config.Labels[LayersCacheRecordConfigLabel])
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to decode %s label: %s", LayersCacheRecordConfigLabel, err)
+	} else if len(rawConfig) == 0 {
+		return nil, nil, fmt.Errorf("%s label not found", LayersCacheRecordConfigLabel)
+	}
+
+	return layers[0], rawConfig, nil
+}
+
+// isSingleLayerAdded reports whether the image layers are the parent layers with exactly one layer on top,
+// the stage without changes or with the squashed layers could not be restored from the single cached layer
+func isSingleLayerAdded(parentLayers, layers []string) bool {
+	return len(parentLayers) != 0 && len(layers) == len(parentLayers)+1 && reflect.DeepEqual(parentLayers, layers[:len(parentLayers)])
+}
+
+func diffIDsToStrings(diffIDs []v1.Hash) []string {
+	var res []string
+	for _, diffID := range diffIDs {
+		res = append(res, diffID.String())
+	}
+	return res
+}
+
+// rawConfigImage overrides the config of the image with the raw config preserving it byte-to-byte,
+// so the image id (config digest) stays the same as the original one
+type rawConfigImage struct {
+	v1.Image
+	rawConfig   []byte
+	rawManifest []byte
+}
+
+func newRawConfigImage(img v1.Image, rawConfig []byte) (*rawConfigImage, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	manifest = manifest.DeepCopy()
+
+	configDigest, configSize, err := v1.SHA256(bytes.NewReader(rawConfig))
+	if err != nil {
+		return nil, err
+	}
+	manifest.Config.Digest = configDigest
+	manifest.Config.Size = configSize
+
+	rawManifest, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rawConfigImage{Image: img, rawConfig: rawConfig, rawManifest: rawManifest}, nil
+}
+
+func (i *rawConfigImage) RawConfigFile() ([]byte, error) {
+	return i.rawConfig, nil
+}
+
+func (i *rawConfigImage) ConfigFile() (*v1.ConfigFile, error) {
+	return v1.ParseConfigFile(bytes.NewReader(i.rawConfig))
+}
+
+func (i *rawConfigImage) ConfigName() (v1.Hash, error) {
+	hash, _, err := v1.SHA256(bytes.NewReader(i.rawConfig))
+	return hash, err
+}
+
+func (i *rawConfigImage) RawManifest() ([]byte, error) {
+	return i.rawManifest, nil
+}
+
+func (i *rawConfigImage) Manifest() (*v1.Manifest, error) {
+	return v1.ParseManifest(bytes.NewReader(i.rawManifest))
+}
+
+func (i *rawConfigImage) Digest() (v1.Hash, error) {
+	hash, _, err := v1.SHA256(bytes.NewReader(i.rawManifest))
+	return hash, err
+}
+
+func (i *rawConfigImage) Size() (int64, error) {
+	return int64(len(i.rawManifest)), nil
+}
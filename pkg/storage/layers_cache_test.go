@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestLayersCacheRecordRestoresImageWithSameID(t *testing.T) {
+	parentImg, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageLayer, err := random.Layer(1024, "application/vnd.docker.image.rootfs.diff.tar.gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageImg, err := mutate.AppendLayers(parentImg, stageLayer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageConfigFile, err := stageImg.ConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageConfigFile.Config.Labels = map[string]string{"werf-stage-digest": "digest"}
+	stageImg, err = mutate.ConfigFile(stageImg, stageConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawConfig, err := stageImg.RawConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := newLayersCacheRecord(stageLayer, rawConfig, "sha256:parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer, recordRawConfig, err := parseLayersCacheRecord(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(recordRawConfig) != string(rawConfig) {
+		t.Fatalf("unexpected record config %s", recordRawConfig)
+	}
+
+	appendedImg, err := mutate.AppendLayers(parentImg, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restoredImg, err := newRawConfigImage(appendedImg, recordRawConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedID, err := stageImg.ConfigName()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := restoredImg.ConfigName(); err != nil {
+		t.Fatal(err)
+	} else if id != expectedID {
+		t.Errorf("expected restored image id %s, got %s", expectedID, id)
+	}
+
+	expectedDigest, err := stageImg.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest, err := restoredImg.Digest(); err != nil {
+		t.Fatal(err)
+	} else if digest != expectedDigest {
+		t.Errorf("expected restored image digest %s, got %s", expectedDigest, digest)
+	}
+}
+
+func TestIsSingleLayerAdded(t *testing.T) {
+	for _, test := range []struct {
+		parentLayers, layers []string
+		expected             bool
+	}{
+		{parentLayers: []string{"a", "b"}, layers: []string{"a", "b", "c"}, expected: true},
+		{parentLayers: []string{"a", "b"}, layers: []string{"a", "b"}},
+		{parentLayers: []string{"a", "b"}, layers: []string{"a", "b", "c", "d"}},
+		{parentLayers: []string{"a", "b"}, layers: []string{"a", "x", "c"}},
+		{parentLayers: nil, layers: []string{"a"}},
+	} {
+		if got := isSingleLayerAdded(test.parentLayers, test.layers); got != test.expected {
+			t.Errorf("expected %v for parent layers %v and layers %v, got %v", test.expected, test.parentLayers, test.layers, got)
+		}
+	}
+}
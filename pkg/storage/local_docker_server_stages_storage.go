@@ -32,6 +32,9 @@ const (
 
 	LocalClientIDRecord_ImageNameFormat = "werf-client-id/%s"
 	LocalClientIDRecord_ImageFormat     = "werf-client-id/%s:%s-%d"
+
+	LocalStageLastUseRecord_ImageNameFormat = "werf-stage-last-use/%s"
+	LocalStageLastUseRecord_ImageFormat     = "werf-stage-last-use/%s:%s-%d"
 )
 
 const ImageDeletionFailedDueToUsedByContainerErrorTip = "Use --force option to remove all containers that are based on deleting werf docker images"
@@ -62,6 +65,14 @@ func (storage *LocalDockerServerStagesStorage) ConstructStageImageName(projectNa
 	return fmt.Sprintf(LocalStage_ImageFormat, projectName, digest, uniqueID)
 }
 
+func (storage *LocalDockerServerStagesStorage) ConstructImageIndexName(projectName, digest string) string {
+	return ""
+}
+
+func (storage *LocalDockerServerStagesStorage) StoreImageIndex(_ context.Context, _, _ string, _ []PlatformImage) error {
+	return fmt.Errorf("multi-platform images are not supported by the %s stages storage: specify --repo=ADDRESS", storage.String())
+}
+
 func (storage *LocalDockerServerStagesStorage) GetStagesIDs(ctx context.Context, projectName string) ([]image.StageID, error) {
 	filterSet := localStagesStorageFilterSetBase(projectName)
 	images, err := docker.Images(ctx, types.ImageListOptions{Filters: filterSet})
@@ -76,6 +87,10 @@ func (storage *LocalDockerServerStagesStorage) DeleteStage(ctx context.Context,
 	return deleteRepoImageListInLocalDockerServerStagesStorage(ctx, stageDescription, options.RmiForce)
 }
 
+func (storage *LocalDockerServerStagesStorage) DeleteStages(ctx context.Context, stageDescriptions []*image.StageDescription, options DeleteStagesOptions, f func(ctx context.Context, stageDescription *image.StageDescription, err error) error) error {
+	return deleteStagesOneByOne(ctx, storage, stageDescriptions, options, f)
+}
+
 func (storage *LocalDockerServerStagesStorage) FilterStagesAndProcessRelatedData(ctx context.Context, stageDescriptions []*image.StageDescription, options FilterStagesAndProcessRelatedDataOptions) ([]*image.StageDescription, error) {
 	return processRelatedContainers(ctx, stageDescriptions, processRelatedContainersOptions{
 		skipUsedImages:           options.SkipUsedImage,
@@ -464,6 +479,58 @@ func (storage *LocalDockerServerStagesStorage) PostClientIDRecord(ctx context.Co
 	return nil
 }
 
+func (storage *LocalDockerServerStagesStorage) GetStageLastUseRecords(ctx context.Context, projectName string) ([]*StageLastUseRecord, error) {
+	logboek.Context(ctx).Debug().LogF("-- LocalDockerServerStagesStorage.GetStageLastUseRecords for project %s\n", projectName)
+
+	filterSet := filters.NewArgs()
+	filterSet.Add("reference", fmt.Sprintf(LocalStageLastUseRecord_ImageNameFormat, projectName))
+
+	images, err := docker.Images(ctx, types.ImageListOptions{Filters: filterSet})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get docker images: %s", err)
+	}
+
+	var res []*StageLastUseRecord
+	for _, img := range images {
+		for _, repoTag := range img.RepoTags {
+			_, tag := image.ParseRepositoryAndTag(repoTag)
+			if rec := parseStageLastUseRecordTag(tag); rec != nil {
+				res = append(res, rec)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func (storage *LocalDockerServerStagesStorage) PostStageLastUseRecord(ctx context.Context, projectName string, rec *StageLastUseRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- LocalDockerServerStagesStorage.PostStageLastUseRecord %s for project %s\n", rec, projectName)
+
+	fullImageName := fmt.Sprintf(LocalStageLastUseRecord_ImageFormat, projectName, rec.StageID, rec.TimestampMillisec)
+	if err := docker.CreateImage(ctx, fullImageName, map[string]string{}); err != nil {
+		return fmt.Errorf("unable to create image %q: %s", fullImageName, err)
+	}
+
+	return nil
+}
+
+func (storage *LocalDockerServerStagesStorage) RmStageLastUseRecord(ctx context.Context, projectName string, rec *StageLastUseRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- LocalDockerServerStagesStorage.RmStageLastUseRecord %s for project %s\n", rec, projectName)
+
+	fullImageName := fmt.Sprintf(LocalStageLastUseRecord_ImageFormat, projectName, rec.StageID, rec.TimestampMillisec)
+	if exists, err := docker.ImageExist(ctx, fullImageName); err != nil {
+		return fmt.Errorf("unable to check existence of image %s: %s", fullImageName, err)
+	} else if !exists {
+		return nil
+	}
+
+	if err := docker.CliRmi(ctx, "--force", fullImageName); err != nil {
+		return fmt.Errorf("unable to remove image %s: %s", fullImageName, err)
+	}
+
+	return nil
+}
+
 type processRelatedContainersOptions struct {
 	skipUsedImages           bool
 	rmContainersThatUseImage bool
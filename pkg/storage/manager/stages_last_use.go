@@ -0,0 +1,193 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/util/parallel"
+)
+
+// StageLastUseRecordingPeriod is the minimal interval between the last use records of the same stage,
+// the stage last use is not recorded again until the previous record becomes older than the period
+const StageLastUseRecordingPeriod = time.Hour
+
+// stagesLastUseFlushInterval is the interval between the background flushes of the stages last use
+var stagesLastUseFlushInterval = time.Minute
+
+// StagesLastUse maps the stage ID to the last use records of the stage
+type StagesLastUse map[string][]*storage.StageLastUseRecord
+
+func NewStagesLastUse(records []*storage.StageLastUseRecord) StagesLastUse {
+	res := StagesLastUse{}
+	for _, rec := range records {
+		res[rec.StageID] = append(res[rec.StageID], rec)
+	}
+
+	return res
+}
+
+// LastUsedAt returns the latest recorded use of the stage or zero time if the stage use has not been recorded
+func (l StagesLastUse) LastUsedAt(stageID string) time.Time {
+	var res time.Time
+	for _, rec := range l[stageID] {
+		if t := rec.Time(); t.After(res) {
+			res = t
+		}
+	}
+
+	return res
+}
+
+type stagesUsage struct {
+	mutex        sync.Mutex
+	usedStageIDs map[string]bool
+
+	// flushMutex serializes the flushes and is never held by the stages selection,
+	// lastUse caches the records of the stages storage read by the first flush
+	flushMutex sync.Mutex
+	lastUse    StagesLastUse
+}
+
+func (u *stagesUsage) add(stageID image.StageID) {
+	u.addString(stageID.String())
+}
+
+func (u *stagesUsage) addString(stageID string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.usedStageIDs == nil {
+		u.usedStageIDs = map[string]bool{}
+	}
+
+	u.usedStageIDs[stageID] = true
+}
+
+func (u *stagesUsage) pop() []string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	var res []string
+	for stageID := range u.usedStageIDs {
+		res = append(res, stageID)
+	}
+	u.usedStageIDs = nil
+
+	return res
+}
+
+// GetStagesLastUse returns the last use records of all stages from the stages storage
+func (m *StagesStorageManager) GetStagesLastUse(ctx context.Context) (StagesLastUse, error) {
+	records, err := m.StagesStorage.GetStageLastUseRecords(ctx, m.ProjectName)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStagesLastUse(records), nil
+}
+
+// StartStagesLastUseFlushing flushes the last use of the selected or fetched stages in batches in the background
+// every stagesLastUseFlushInterval, so that the selection and fetching of the stages does not wait for the stages storage.
+// The returned function stops the flushing, the remaining stages should be flushed with FlushStagesLastUse
+func (m *StagesStorageManager) StartStagesLastUseFlushing(ctx context.Context) func() {
+	ticker := time.NewTicker(stagesLastUseFlushInterval)
+	doneCh := make(chan struct{})
+	stoppedCh := make(chan struct{})
+
+	go func() {
+		defer close(stoppedCh)
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.FlushStagesLastUse(ctx); err != nil {
+					logboek.Context(ctx).Warn().LogF("WARNING: Unable to record stages last use: %s\n", err)
+				}
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(doneCh)
+		<-stoppedCh
+	}
+}
+
+// FlushStagesLastUse records the last use of the stages selected or fetched by the manager into the stages storage.
+// The records are posted at most once per StageLastUseRecordingPeriod for each stage, outdated records of the stage are deleted.
+// The stages storage records are fetched once and cached by the manager for the subsequent flushes
+func (m *StagesStorageManager) FlushStagesLastUse(ctx context.Context) error {
+	m.stagesUsage.flushMutex.Lock()
+	defer m.stagesUsage.flushMutex.Unlock()
+
+	usedStageIDs := m.stagesUsage.pop()
+	if len(usedStageIDs) == 0 {
+		return nil
+	}
+
+	if m.stagesUsage.lastUse == nil {
+		stagesLastUse, err := m.GetStagesLastUse(ctx)
+		if err != nil {
+			for _, stageID := range usedStageIDs {
+				m.stagesUsage.addString(stageID)
+			}
+			return err
+		}
+
+		m.stagesUsage.lastUse = stagesLastUse
+	}
+	stagesLastUse := m.stagesUsage.lastUse
+
+	now := time.Now()
+	var recordsToPost, recordsToRm []*storage.StageLastUseRecord
+	for _, stageID := range usedStageIDs {
+		if lastUsedAt := stagesLastUse.LastUsedAt(stageID); now.Sub(lastUsedAt) < StageLastUseRecordingPeriod {
+			continue
+		}
+
+		recordsToPost = append(recordsToPost, &storage.StageLastUseRecord{StageID: stageID, TimestampMillisec: now.UnixNano() / int64(time.Millisecond)})
+		recordsToRm = append(recordsToRm, stagesLastUse[stageID]...)
+	}
+
+	if len(recordsToPost) == 0 {
+		return nil
+	}
+
+	logboek.Context(ctx).Info().LogF("Recording last use of %d stages into %s\n", len(recordsToPost), m.StagesStorage.String())
+
+	if err := parallel.DoTasks(ctx, len(recordsToPost), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, func(ctx context.Context, taskId int) error {
+		return m.StagesStorage.PostStageLastUseRecord(ctx, m.ProjectName, recordsToPost[taskId])
+	}); err != nil {
+		// the posted records are unknown, the records are fetched again by the next flush
+		m.stagesUsage.lastUse = nil
+		return err
+	}
+
+	for _, rec := range recordsToPost {
+		stagesLastUse[rec.StageID] = []*storage.StageLastUseRecord{rec}
+	}
+
+	return m.ForEachRmStageLastUseRecord(ctx, recordsToRm, func(ctx context.Context, rec *storage.StageLastUseRecord, err error) error {
+		return err
+	})
+}
+
+func (m *StagesStorageManager) ForEachRmStageLastUseRecord(ctx context.Context, records []*storage.StageLastUseRecord, f func(ctx context.Context, rec *storage.StageLastUseRecord, err error) error) error {
+	return parallel.DoTasks(ctx, len(records), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, func(ctx context.Context, taskId int) error {
+		rec := records[taskId]
+		err := m.StagesStorage.RmStageLastUseRecord(ctx, m.ProjectName, rec)
+		return f(ctx, rec, err)
+	})
+}
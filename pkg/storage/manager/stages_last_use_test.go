@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func TestFlushStagesLastUse(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "werf-stages-last-use-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	stagesStorage, err := storage.NewStagesStorage(storage.OCILayoutStorageAddressPrefix+filepath.Join(dir, "repo"), &container_runtime.LocalDockerServerRuntime{}, storage.StagesStorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outdatedTimestamp := time.Now().Add(-2*StageLastUseRecordingPeriod).UnixNano() / int64(time.Millisecond)
+	recentTimestamp := time.Now().Add(-time.Minute).UnixNano() / int64(time.Millisecond)
+	for _, rec := range []*storage.StageLastUseRecord{
+		{StageID: "outdated-1", TimestampMillisec: outdatedTimestamp},
+		{StageID: "recent-2", TimestampMillisec: recentTimestamp},
+	} {
+		if err := stagesStorage.PostStageLastUseRecord(ctx, "myproject", rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewStorageManager("myproject", stagesStorage, nil, nil, nil)
+	for _, stageID := range []image.StageID{{Digest: "outdated", UniqueID: 1}, {Digest: "recent", UniqueID: 2}, {Digest: "new", UniqueID: 3}} {
+		m.stagesUsage.add(stageID)
+	}
+
+	if err := m.FlushStagesLastUse(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stagesLastUse, err := m.GetStagesLastUse(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for stageID, expectedRecordsCount := range map[string]int{"outdated-1": 1, "recent-2": 1, "new-3": 1} {
+		if len(stagesLastUse[stageID]) != expectedRecordsCount {
+			t.Errorf("expected %d last use records of stage %s, got %v", expectedRecordsCount, stageID, stagesLastUse[stageID])
+		}
+	}
+
+	if lastUsedAt := stagesLastUse.LastUsedAt("outdated-1"); time.Since(lastUsedAt) > time.Minute {
+		t.Errorf("expected the last use of stage outdated-1 to be updated, got %s", lastUsedAt)
+	}
+
+	if lastUsedAt := stagesLastUse.LastUsedAt("recent-2"); lastUsedAt.UnixNano()/int64(time.Millisecond) != recentTimestamp {
+		t.Errorf("expected the last use of stage recent-2 not to be updated, got %s", lastUsedAt)
+	}
+
+	if err := m.FlushStagesLastUse(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer func(interval time.Duration) { stagesLastUseFlushInterval = interval }(stagesLastUseFlushInterval)
+	stagesLastUseFlushInterval = 10 * time.Millisecond
+
+	stopFlushing := m.StartStagesLastUseFlushing(ctx)
+	m.stagesUsage.add(image.StageID{Digest: "selected", UniqueID: 4})
+
+	for i := 0; i < 100; i++ {
+		stagesLastUse, err = m.GetStagesLastUse(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(stagesLastUse["selected-4"]) != 0 {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+	stopFlushing()
+
+	if len(stagesLastUse["selected-4"]) != 1 {
+		t.Errorf("expected the last use of stage selected-4 to be recorded in the background, got %v", stagesLastUse["selected-4"])
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/tracing"
 	"github.com/werf/werf/pkg/util/parallel"
 )
 
@@ -40,6 +41,8 @@ type StagesStorageManager struct {
 	StagesStorageCache storage.StagesStorageCache
 
 	SecondaryStagesStorageList []storage.StagesStorage
+
+	stagesUsage stagesUsage
 }
 
 func newStagesStorageManager(projectName string, stagesStorage storage.StagesStorage, secondaryStagesStorageList []storage.StagesStorage, storageLockManager storage.LockManager, stagesStorageCache storage.StagesStorageCache) *StagesStorageManager {
@@ -110,17 +113,16 @@ func (m *StagesStorageManager) ForEachDeleteStage(ctx context.Context, options F
 		}
 	}
 
-	return parallel.DoTasks(ctx, len(stagesDescriptions), parallel.DoTasksOptions{
-		MaxNumberOfWorkers:         m.MaxNumberOfWorkers(),
-		InitDockerCLIForEachWorker: true,
-	}, func(ctx context.Context, taskId int) error {
-		stageDescription := stagesDescriptions[taskId]
-		err := m.StagesStorage.DeleteStage(ctx, stageDescription, options.DeleteImageOptions)
-		return f(ctx, stageDescription, err)
-	})
+	return m.StagesStorage.DeleteStages(ctx, stagesDescriptions, storage.DeleteStagesOptions{
+		DeleteImageOptions: options.DeleteImageOptions,
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, f)
 }
 
 func (m *StagesStorageManager) FetchStage(ctx context.Context, stg stage.Interface) error {
+	ctx, span := tracing.StartSpan(ctx, "storage fetch stage", tracing.Attr("werf.stage", stg.LogDetailedName()), tracing.Attr("werf.stages_storage", m.StagesStorage.String()))
+	defer span.End()
+
 	logboek.Context(ctx).Debug().LogF("-- StagesManager.FetchStage %s\n", stg.LogDetailedName())
 	if freshStageDescription, err := m.StagesStorage.GetStageDescription(ctx, m.ProjectName, stg.GetImage().GetStageDescription().StageID.Digest, stg.GetImage().GetStageDescription().StageID.UniqueID); err != nil {
 		return err
@@ -129,6 +131,8 @@ func (m *StagesStorageManager) FetchStage(ctx context.Context, stg stage.Interfa
 		return ErrShouldResetStagesStorageCache
 	}
 
+	m.stagesUsage.add(*stg.GetImage().GetStageDescription().StageID)
+
 	if shouldFetch, err := m.StagesStorage.ShouldFetchImage(ctx, &container_runtime.DockerImage{Image: stg.GetImage()}); err == nil && shouldFetch {
 		if err := logboek.Context(ctx).Default().LogProcess("Fetching stage %s from storage", stg.LogDetailedName()).
 			Options(func(options types.LogProcessOptionsInterface) {
@@ -168,6 +172,8 @@ func (m *StagesStorageManager) SelectSuitableStage(ctx context.Context, c stage.
 		return nil, nil
 	}
 
+	m.stagesUsage.add(*stageDesc.StageID)
+
 	imgInfoData, err := yaml.Marshal(stageDesc)
 	if err != nil {
 		panic(err)
@@ -201,6 +207,9 @@ func (m *StagesStorageManager) AtomicStoreStagesByDigestToCache(ctx context.Cont
 }
 
 func (m *StagesStorageManager) GetStagesByDigest(ctx context.Context, stageName, stageDigest string) ([]*image.StageDescription, error) {
+	ctx, span := tracing.StartSpan(ctx, "storage get stages by digest", tracing.Attr("werf.stage.digest", stageDigest), tracing.Attr("werf.stages_storage", m.StagesStorage.String()))
+	defer span.End()
+
 	cacheExists, cacheStages, err := m.getStagesByDigestFromCache(ctx, stageName, stageDigest)
 	if err != nil {
 		return nil, err
@@ -217,6 +226,9 @@ func (m *StagesStorageManager) GetStagesByDigest(ctx context.Context, stageName,
 }
 
 func (m *StagesStorageManager) GetStagesByDigestFromStagesStorage(ctx context.Context, stageName, stageDigest string, stagesStorage storage.StagesStorage) ([]*image.StageDescription, error) {
+	ctx, span := tracing.StartSpan(ctx, "storage get stages by digest", tracing.Attr("werf.stage.digest", stageDigest), tracing.Attr("werf.stages_storage", stagesStorage.String()))
+	defer span.End()
+
 	stageIDs, err := m.getStagesIDsByDigestFromStagesStorage(ctx, stageName, stageDigest, stagesStorage)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get stages ids from %s by digest %s for stage %s: %s", stagesStorage.String(), stageDigest, stageName, err)
@@ -231,7 +243,10 @@ func (m *StagesStorageManager) GetStagesByDigestFromStagesStorage(ctx context.Co
 }
 
 func (m *StagesStorageManager) CopySuitableByDigestStage(ctx context.Context, stageDesc *image.StageDescription, sourceStagesStorage, destinationStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime) (*image.StageDescription, error) {
-	img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime.(*container_runtime.LocalDockerServerRuntime))
+	ctx, span := tracing.StartSpan(ctx, "storage copy stage", tracing.Attr("werf.stage.id", stageDesc.StageID.String()), tracing.Attr("werf.stages_storage", destinationStagesStorage.String()))
+	defer span.End()
+
+	img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime)
 
 	logboek.Context(ctx).Info().LogF("Fetching %s\n", img.Name())
 	if err := sourceStagesStorage.FetchImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
@@ -257,7 +272,7 @@ func (m *StagesStorageManager) CopySuitableByDigestStage(ctx context.Context, st
 }
 
 func (m *StagesStorageManager) getWithManifestCacheOption() bool {
-	return m.StagesStorage.Address() != storage.LocalStorageAddress
+	return m.StagesStorage.Address() != storage.LocalStorageAddress && !storage.IsOCILayoutStorageAddress(m.StagesStorage.Address())
 }
 
 func (m *StagesStorageManager) getStagesByDigestFromCache(ctx context.Context, stageName, stageDigest string) (bool, []*image.StageDescription, error) {
@@ -0,0 +1,332 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/util/parallel"
+)
+
+type SyncStagesOptions struct {
+	// ImageNameList is used to find image metadata records in the source storage (werf.yaml images and managed images)
+	ImageNameList []string
+	DryRun        bool
+}
+
+// SyncStages copies all stages, stages last use, import metadata, image metadata and managed images of the project
+// from the source stages storage into the manager stages storage.
+// Records which already exist in the destination are skipped, so an interrupted sync can be resumed by running it again.
+// Multi-platform image indexes are not copied, they reference the source repo manifests and are published again by the next build.
+func (m *StagesStorageManager) SyncStages(ctx context.Context, fromStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, opts SyncStagesOptions) error {
+	toStagesStorage := m.StagesStorage
+
+	if fromStagesStorage.Address() == toStagesStorage.Address() {
+		return fmt.Errorf("source and destination stages storages should differ: %s", toStagesStorage.String())
+	}
+
+	var copiedStages int
+	if err := logboek.Context(ctx).Default().LogProcess("Syncing stages").DoError(func() error {
+		var err error
+		copiedStages, err = m.syncStages(ctx, fromStagesStorage, toStagesStorage, containerRuntime, opts)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Syncing stages last use").DoError(func() error {
+		return m.syncStagesLastUse(ctx, fromStagesStorage, toStagesStorage, opts)
+	}); err != nil {
+		return err
+	}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Syncing import metadata").DoError(func() error {
+		return m.syncImportMetadata(ctx, fromStagesStorage, toStagesStorage, opts)
+	}); err != nil {
+		return err
+	}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Syncing image metadata").DoError(func() error {
+		return m.syncImageMetadata(ctx, fromStagesStorage, toStagesStorage, opts)
+	}); err != nil {
+		return err
+	}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Syncing managed images").DoError(func() error {
+		return m.syncManagedImages(ctx, fromStagesStorage, toStagesStorage, opts)
+	}); err != nil {
+		return err
+	}
+
+	if copiedStages > 0 && !opts.DryRun {
+		if err := m.ResetStagesStorageCache(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *StagesStorageManager) syncStages(ctx context.Context, fromStagesStorage, toStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, opts SyncStagesOptions) (int, error) {
+	fromStageIDs, err := fromStagesStorage.GetStagesIDs(ctx, m.ProjectName)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get stages from %s: %s", fromStagesStorage.String(), err)
+	}
+
+	toStageIDs, err := toStagesStorage.GetStagesIDs(ctx, m.ProjectName)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get stages from %s: %s", toStagesStorage.String(), err)
+	}
+
+	existingStageIDs := map[string]bool{}
+	for _, stageID := range toStageIDs {
+		existingStageIDs[stageID.String()] = true
+	}
+
+	var stageIDs []image.StageID
+	for _, stageID := range fromStageIDs {
+		if !existingStageIDs[stageID.String()] {
+			stageIDs = append(stageIDs, stageID)
+		}
+	}
+
+	logboek.Context(ctx).Default().LogF("Stages to copy: %d (%d already exist in %s)\n", len(stageIDs), len(fromStageIDs)-len(stageIDs), toStagesStorage.String())
+
+	if opts.DryRun {
+		for _, stageID := range stageIDs {
+			logboek.Context(ctx).Default().LogF("Copy stage %s\n", stageID.String())
+		}
+		return len(stageIDs), nil
+	}
+
+	return len(stageIDs), parallel.DoTasks(ctx, len(stageIDs), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, func(ctx context.Context, taskId int) error {
+		stageID := stageIDs[taskId]
+
+		stageDesc, err := getStageDescription(ctx, m.ProjectName, stageID, fromStagesStorage, getStageDescriptionOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get stage %s description from %s: %s", stageID.String(), fromStagesStorage.String(), err)
+		} else if stageDesc == nil {
+			logboek.Context(ctx).Warn().LogF("Ignoring stage %s: cannot get stage description from %s\n", stageID.String(), fromStagesStorage.String())
+			return nil
+		}
+
+		if _, err := m.CopySuitableByDigestStage(ctx, stageDesc, fromStagesStorage, toStagesStorage, containerRuntime); err != nil {
+			return err
+		}
+		logboek.Context(ctx).Default().LogF("Copied stage %s\n", stageID.String())
+
+		return m.cleanupSyncedStageImages(ctx, stageDesc, fromStagesStorage, toStagesStorage, containerRuntime)
+	})
+}
+
+// cleanupSyncedStageImages removes the local images left after the stage copying unless they belong to the local stages storage
+func (m *StagesStorageManager) cleanupSyncedStageImages(ctx context.Context, stageDesc *image.StageDescription, fromStagesStorage, toStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime) error {
+	var imageNames []string
+	if fromStagesStorage.Address() != storage.LocalStorageAddress {
+		imageNames = append(imageNames, stageDesc.Info.Name)
+	}
+	if toStagesStorage.Address() != storage.LocalStorageAddress {
+		imageNames = append(imageNames, toStagesStorage.ConstructStageImageName(m.ProjectName, stageDesc.StageID.Digest, stageDesc.StageID.UniqueID))
+	}
+
+	for _, imageName := range imageNames {
+		img := container_runtime.NewStageImage(nil, imageName, containerRuntime)
+		if err := containerRuntime.RemoveImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
+			return fmt.Errorf("unable to remove local image %s: %s", imageName, err)
+		}
+	}
+
+	return nil
+}
+
+// syncStagesLastUse copies the latest last use record of each stage unless the destination has a more recent use of the stage
+func (m *StagesStorageManager) syncStagesLastUse(ctx context.Context, fromStagesStorage, toStagesStorage storage.StagesStorage, opts SyncStagesOptions) error {
+	fromRecords, err := fromStagesStorage.GetStageLastUseRecords(ctx, m.ProjectName)
+	if err != nil {
+		return fmt.Errorf("unable to get stages last use from %s: %s", fromStagesStorage.String(), err)
+	}
+
+	toRecords, err := toStagesStorage.GetStageLastUseRecords(ctx, m.ProjectName)
+	if err != nil {
+		return fmt.Errorf("unable to get stages last use from %s: %s", toStagesStorage.String(), err)
+	}
+
+	fromStagesLastUse, toStagesLastUse := NewStagesLastUse(fromRecords), NewStagesLastUse(toRecords)
+
+	var records []*storage.StageLastUseRecord
+	for stageID := range fromStagesLastUse {
+		if lastUsedAt := fromStagesLastUse.LastUsedAt(stageID); lastUsedAt.After(toStagesLastUse.LastUsedAt(stageID)) {
+			records = append(records, &storage.StageLastUseRecord{StageID: stageID, TimestampMillisec: lastUsedAt.UnixNano() / int64(time.Millisecond)})
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].StageID < records[j].StageID })
+
+	logboek.Context(ctx).Default().LogF("Stages last use to copy: %d\n", len(records))
+
+	return parallel.DoTasks(ctx, len(records), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, func(ctx context.Context, taskId int) error {
+		rec := records[taskId]
+
+		if opts.DryRun {
+			logboek.Context(ctx).Default().LogF("Copy stage %s last use\n", rec.StageID)
+			return nil
+		}
+
+		if err := toStagesStorage.PostStageLastUseRecord(ctx, m.ProjectName, rec); err != nil {
+			return fmt.Errorf("unable to put stage %s last use into %s: %s", rec.StageID, toStagesStorage.String(), err)
+		}
+		logboek.Context(ctx).Default().LogF("Copied stage %s last use\n", rec.StageID)
+
+		return nil
+	})
+}
+
+func (m *StagesStorageManager) syncImportMetadata(ctx context.Context, fromStagesStorage, toStagesStorage storage.StagesStorage, opts SyncStagesOptions) error {
+	fromIDs, err := fromStagesStorage.GetImportMetadataIDs(ctx, m.ProjectName)
+	if err != nil {
+		return fmt.Errorf("unable to get import metadata ids from %s: %s", fromStagesStorage.String(), err)
+	}
+
+	toIDs, err := toStagesStorage.GetImportMetadataIDs(ctx, m.ProjectName)
+	if err != nil {
+		return fmt.Errorf("unable to get import metadata ids from %s: %s", toStagesStorage.String(), err)
+	}
+
+	ids := subtractStrings(fromIDs, toIDs)
+	logboek.Context(ctx).Default().LogF("Import metadata to copy: %d\n", len(ids))
+
+	return parallel.DoTasks(ctx, len(ids), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, func(ctx context.Context, taskId int) error {
+		id := ids[taskId]
+
+		if opts.DryRun {
+			logboek.Context(ctx).Default().LogF("Copy import metadata %s\n", id)
+			return nil
+		}
+
+		metadata, err := fromStagesStorage.GetImportMetadata(ctx, m.ProjectName, id)
+		if err != nil {
+			return fmt.Errorf("unable to get import metadata %s from %s: %s", id, fromStagesStorage.String(), err)
+		} else if metadata == nil {
+			return nil
+		}
+
+		if err := toStagesStorage.PutImportMetadata(ctx, m.ProjectName, metadata); err != nil {
+			return fmt.Errorf("unable to put import metadata %s into %s: %s", id, toStagesStorage.String(), err)
+		}
+		logboek.Context(ctx).Default().LogF("Copied import metadata %s\n", id)
+
+		return nil
+	})
+}
+
+type syncImageMetadataTask struct {
+	imageName string
+	commit    string
+	stageID   string
+}
+
+func (m *StagesStorageManager) syncImageMetadata(ctx context.Context, fromStagesStorage, toStagesStorage storage.StagesStorage, opts SyncStagesOptions) error {
+	fromImageMetadata, fromNotManagedImageMetadata, err := fromStagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, m.ProjectName, opts.ImageNameList)
+	if err != nil {
+		return fmt.Errorf("unable to get image metadata from %s: %s", fromStagesStorage.String(), err)
+	}
+
+	toImageMetadata, _, err := toStagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, m.ProjectName, opts.ImageNameList)
+	if err != nil {
+		return fmt.Errorf("unable to get image metadata from %s: %s", toStagesStorage.String(), err)
+	}
+
+	for imageNameID := range fromNotManagedImageMetadata {
+		logboek.Context(ctx).Warn().LogF("Ignoring image metadata of unknown image %s: image name should be defined in werf.yaml or be a managed image\n", imageNameID)
+	}
+
+	var tasks []syncImageMetadataTask
+	for imageName, stageIDCommitList := range fromImageMetadata {
+		for stageID, commitList := range stageIDCommitList {
+			for _, commit := range subtractStrings(commitList, toImageMetadata[imageName][stageID]) {
+				tasks = append(tasks, syncImageMetadataTask{imageName: imageName, commit: commit, stageID: stageID})
+			}
+		}
+	}
+
+	logboek.Context(ctx).Default().LogF("Image metadata to copy: %d\n", len(tasks))
+
+	return parallel.DoTasks(ctx, len(tasks), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, func(ctx context.Context, taskId int) error {
+		task := tasks[taskId]
+
+		if opts.DryRun {
+			logboek.Context(ctx).Default().LogF("Copy image %s commit %s stage ID %s metadata\n", task.imageName, task.commit, task.stageID)
+			return nil
+		}
+
+		if err := toStagesStorage.PutImageMetadata(ctx, m.ProjectName, task.imageName, task.commit, task.stageID); err != nil {
+			return fmt.Errorf("unable to put image %s metadata into %s: %s", task.imageName, toStagesStorage.String(), err)
+		}
+		logboek.Context(ctx).Default().LogF("Copied image %s commit %s stage ID %s metadata\n", task.imageName, task.commit, task.stageID)
+
+		return nil
+	})
+}
+
+func (m *StagesStorageManager) syncManagedImages(ctx context.Context, fromStagesStorage, toStagesStorage storage.StagesStorage, opts SyncStagesOptions) error {
+	fromManagedImages, err := fromStagesStorage.GetManagedImages(ctx, m.ProjectName)
+	if err != nil {
+		return fmt.Errorf("unable to get managed images from %s: %s", fromStagesStorage.String(), err)
+	}
+
+	toManagedImages, err := toStagesStorage.GetManagedImages(ctx, m.ProjectName)
+	if err != nil {
+		return fmt.Errorf("unable to get managed images from %s: %s", toStagesStorage.String(), err)
+	}
+
+	managedImages := subtractStrings(fromManagedImages, toManagedImages)
+	logboek.Context(ctx).Default().LogF("Managed images to copy: %d\n", len(managedImages))
+
+	return parallel.DoTasks(ctx, len(managedImages), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, func(ctx context.Context, taskId int) error {
+		managedImage := managedImages[taskId]
+
+		if opts.DryRun {
+			logboek.Context(ctx).Default().LogF("Copy managed image %s\n", managedImage)
+			return nil
+		}
+
+		if err := toStagesStorage.AddManagedImage(ctx, m.ProjectName, managedImage); err != nil {
+			return fmt.Errorf("unable to add managed image %s into %s: %s", managedImage, toStagesStorage.String(), err)
+		}
+		logboek.Context(ctx).Default().LogF("Copied managed image %s\n", managedImage)
+
+		return nil
+	})
+}
+
+func subtractStrings(list, other []string) []string {
+	otherSet := map[string]bool{}
+	for _, s := range other {
+		otherSet[s] = true
+	}
+
+	var res []string
+	for _, s := range list {
+		if !otherSet[s] {
+			res = append(res, s)
+			otherSet[s] = true
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func TestSyncStagesMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "werf-sync-stages-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	containerRuntime := &container_runtime.LocalDockerServerRuntime{}
+
+	from, err := storage.NewStagesStorage(storage.OCILayoutStorageAddressPrefix+filepath.Join(dir, "from"), containerRuntime, storage.StagesStorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := storage.NewStagesStorage(storage.OCILayoutStorageAddressPrefix+filepath.Join(dir, "to"), containerRuntime, storage.StagesStorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := from.AddManagedImage(ctx, "myproject", "app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := from.PutImageMetadata(ctx, "myproject", "app", "commit-1", "stage-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := from.PutImageMetadata(ctx, "myproject", "app", "commit-2", "stage-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := from.PutImportMetadata(ctx, "myproject", &storage.ImportMetadata{ImportSourceID: "source-id", SourceImageID: "sha256:123", Checksum: "checksum"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rec := range []*storage.StageLastUseRecord{
+		{StageID: "digest-1-1", TimestampMillisec: 2000},
+		{StageID: "digest-1-1", TimestampMillisec: 3000},
+		{StageID: "digest-2-2", TimestampMillisec: 1000},
+	} {
+		if err := from.PostStageLastUseRecord(ctx, "myproject", rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// already synced records should be skipped
+	if err := to.PutImageMetadata(ctx, "myproject", "app", "commit-1", "stage-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := to.PostStageLastUseRecord(ctx, "myproject", &storage.StageLastUseRecord{StageID: "digest-2-2", TimestampMillisec: 5000}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewStorageManager("myproject", to, nil, nil, nil)
+	opts := SyncStagesOptions{ImageNameList: []string{"app"}}
+
+	opts.DryRun = true
+	if err := m.SyncStages(ctx, from, containerRuntime, opts); err != nil {
+		t.Fatal(err)
+	}
+	if managedImages, err := to.GetManagedImages(ctx, "myproject"); err != nil {
+		t.Fatal(err)
+	} else if len(managedImages) != 0 {
+		t.Errorf("expected no managed images after dry run, got %v", managedImages)
+	}
+
+	opts.DryRun = false
+	if err := m.SyncStages(ctx, from, containerRuntime, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if managedImages, err := to.GetManagedImages(ctx, "myproject"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(managedImages, []string{"app"}) {
+		t.Errorf("unexpected managed images: %v", managedImages)
+	}
+
+	for _, commit := range []string{"commit-1", "commit-2"} {
+		if exists, err := to.IsImageMetadataExist(ctx, "myproject", "app", commit, "stage-1"); err != nil {
+			t.Fatal(err)
+		} else if !exists {
+			t.Errorf("expected image metadata for %s to be synced", commit)
+		}
+	}
+
+	if metadata, err := to.GetImportMetadata(ctx, "myproject", "source-id"); err != nil {
+		t.Fatal(err)
+	} else if metadata == nil || metadata.Checksum != "checksum" {
+		t.Errorf("unexpected import metadata: %#v", metadata)
+	}
+
+	if stagesLastUse, err := m.GetStagesLastUse(ctx); err != nil {
+		t.Fatal(err)
+	} else {
+		if got := stagesLastUse.LastUsedAt("digest-1-1"); got.UnixNano() != 3000*int64(time.Millisecond) {
+			t.Errorf("expected the latest stage last use to be synced, got %s", got)
+		}
+
+		if len(stagesLastUse["digest-1-1"]) != 1 || len(stagesLastUse["digest-2-2"]) != 1 {
+			t.Errorf("expected only the latest last use records to be copied, got %v", stagesLastUse)
+		}
+	}
+
+	if err := m.SyncStages(ctx, from, containerRuntime, opts); err != nil {
+		t.Fatal(err)
+	}
+}
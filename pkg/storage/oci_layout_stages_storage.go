@@ -0,0 +1,524 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/oci_layout"
+	"github.com/werf/werf/pkg/util"
+)
+
+const OCILayoutStorageAddressPrefix = "oci:"
+
+func IsOCILayoutStorageAddress(address string) bool {
+	return strings.HasPrefix(address, OCILayoutStorageAddressPrefix)
+}
+
+// OCILayoutStagesStorage keeps stages and werf records in the OCI image layout directory.
+// Images are named the same way as in the RepoStagesStorage, but the project name is used instead of the repo address.
+type OCILayoutStagesStorage struct {
+	Dir              string
+	Store            *oci_layout.Store
+	ContainerRuntime container_runtime.ContainerRuntime
+}
+
+func NewOCILayoutStagesStorage(address string, containerRuntime container_runtime.ContainerRuntime) (*OCILayoutStagesStorage, error) {
+	dir := strings.TrimPrefix(address, OCILayoutStorageAddressPrefix)
+	if dir == "" {
+		return nil, fmt.Errorf("bad stages storage address %q: expected %sPATH", address, OCILayoutStorageAddressPrefix)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get absolute path for %s: %s", dir, err)
+	}
+
+	store, err := oci_layout.NewStore(absDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OCILayoutStagesStorage{Dir: absDir, Store: store, ContainerRuntime: containerRuntime}, nil
+}
+
+func (storage *OCILayoutStagesStorage) ConstructStageImageName(projectName, digest string, uniqueID int64) string {
+	return fmt.Sprintf(RepoStage_ImageFormat, projectName, digest, uniqueID)
+}
+
+func (storage *OCILayoutStagesStorage) ConstructImageIndexName(projectName, digest string) string {
+	return ""
+}
+
+func (storage *OCILayoutStagesStorage) StoreImageIndex(_ context.Context, _, _ string, _ []PlatformImage) error {
+	return fmt.Errorf("multi-platform images are not supported by the %s stages storage: specify --repo=ADDRESS", storage.String())
+}
+
+func (storage *OCILayoutStagesStorage) GetStagesIDs(ctx context.Context, projectName string) ([]image.StageID, error) {
+	return storage.getStagesIDsByTagPrefix(ctx, projectName, "")
+}
+
+func (storage *OCILayoutStagesStorage) GetStagesIDsByDigest(ctx context.Context, projectName, digest string) ([]image.StageID, error) {
+	return storage.getStagesIDsByTagPrefix(ctx, projectName, digest+"-")
+}
+
+func (storage *OCILayoutStagesStorage) getStagesIDsByTagPrefix(ctx context.Context, projectName, prefix string) ([]image.StageID, error) {
+	tags, err := storage.getTags(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []image.StageID
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, prefix) || isOCILayoutRecordTag(tag) {
+			continue
+		}
+
+		if digest, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(tag); err != nil {
+			if isUnexpectedTagFormatError(err) {
+				logboek.Context(ctx).Debug().LogLn(err.Error())
+				continue
+			}
+			return nil, err
+		} else {
+			res = append(res, image.StageID{Digest: digest, UniqueID: uniqueID})
+		}
+	}
+
+	return res, nil
+}
+
+func isOCILayoutRecordTag(tag string) bool {
+	for _, prefix := range []string{
+		RepoManagedImageRecord_ImageTagPrefix,
+		RepoImageMetadataByCommitRecord_ImageTagPrefix,
+		RepoImportMetadata_ImageTagPrefix,
+		RepoClientIDRecrod_ImageTagPrefix,
+		RepoStageLastUseRecord_ImageTagPrefix,
+	} {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (storage *OCILayoutStagesStorage) GetStageDescription(ctx context.Context, projectName, digest string, uniqueID int64) (*image.StageDescription, error) {
+	stageImageName := storage.ConstructStageImageName(projectName, digest, uniqueID)
+
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetStageDescription %s %s %d\n", projectName, digest, uniqueID)
+
+	if info, err := storage.getImageInfo(ctx, stageImageName); err != nil {
+		return nil, err
+	} else if info != nil {
+		return &image.StageDescription{
+			StageID: &image.StageID{Digest: digest, UniqueID: uniqueID},
+			Info:    info,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (storage *OCILayoutStagesStorage) DeleteStage(ctx context.Context, stageDescription *image.StageDescription, _ DeleteImageOptions) error {
+	if err := storage.untagStage(ctx, stageDescription); err != nil {
+		return err
+	}
+
+	return storage.Store.GarbageCollect(ctx)
+}
+
+// DeleteStages removes the stages names and then collects the unreachable blobs once, GC walks the whole store
+func (storage *OCILayoutStagesStorage) DeleteStages(ctx context.Context, stageDescriptions []*image.StageDescription, _ DeleteStagesOptions, f func(ctx context.Context, stageDescription *image.StageDescription, err error) error) error {
+	for _, stageDescription := range stageDescriptions {
+		if err := f(ctx, stageDescription, storage.untagStage(ctx, stageDescription)); err != nil {
+			return err
+		}
+	}
+
+	if err := storage.Store.GarbageCollect(ctx); err != nil {
+		return fmt.Errorf("unable to remove unreachable blobs: %s", err)
+	}
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) untagStage(ctx context.Context, stageDescription *image.StageDescription) error {
+	if err := storage.Store.Untag(ctx, stageDescription.Info.Name); err != nil {
+		return fmt.Errorf("unable to remove image %s: %s", stageDescription.Info.Name, err)
+	}
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) FilterStagesAndProcessRelatedData(_ context.Context, stageDescriptions []*image.StageDescription, _ FilterStagesAndProcessRelatedDataOptions) ([]*image.StageDescription, error) {
+	return stageDescriptions, nil
+}
+
+func (storage *OCILayoutStagesStorage) CreateRepo(_ context.Context) error {
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) DeleteRepo(_ context.Context) error {
+	return os.RemoveAll(storage.Dir)
+}
+
+func (storage *OCILayoutStagesStorage) FetchImage(ctx context.Context, img container_runtime.Image) error {
+	dockerImage := img.(*container_runtime.DockerImage)
+	imageName := dockerImage.Image.Name()
+
+	if err := storage.Store.UseImage(ctx, imageName, func(storedImg v1.Image) error {
+		return writeRuntimeImage(ctx, storage.ContainerRuntime, imageName, storedImg)
+	}); err != nil {
+		return fmt.Errorf("unable to fetch image %s from %s: %s", imageName, storage.String(), err)
+	}
+
+	return storage.ContainerRuntime.RefreshImageObject(ctx, img)
+}
+
+func (storage *OCILayoutStagesStorage) StoreImage(ctx context.Context, img container_runtime.Image) error {
+	dockerImage := img.(*container_runtime.DockerImage)
+	imageName := dockerImage.Image.Name()
+
+	if dockerImage.Image.GetBuiltId() != "" {
+		if err := dockerImage.Image.TagBuiltImage(ctx, imageName); err != nil {
+			return fmt.Errorf("unable to tag built image by name %s: %s", imageName, err)
+		}
+	}
+
+	return logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Saving %s into %s", imageName, storage.String())).DoError(func() error {
+		return withRuntimeImage(ctx, storage.ContainerRuntime, imageName, func(localImg v1.Image) error {
+			return storage.Store.WriteImage(ctx, localImg, imageName)
+		})
+	})
+}
+
+func (storage *OCILayoutStagesStorage) ShouldFetchImage(_ context.Context, img container_runtime.Image) (bool, error) {
+	switch storage.ContainerRuntime.(type) {
+	case *container_runtime.LocalDockerServerRuntime, *container_runtime.LocalHostRuntime:
+		dockerImage := img.(*container_runtime.DockerImage)
+		return !dockerImage.Image.IsExistsLocally(), nil
+	default:
+		panic("not implemented")
+	}
+}
+
+func (storage *OCILayoutStagesStorage) AddManagedImage(ctx context.Context, projectName, imageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.AddManagedImage %s %s\n", projectName, imageName)
+
+	if validateImageName(imageName) != nil {
+		return nil
+	}
+
+	return storage.putRecord(ctx, makeRepoManagedImageRecord(projectName, imageName), nil)
+}
+
+func (storage *OCILayoutStagesStorage) RmManagedImage(ctx context.Context, projectName, imageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmManagedImage %s %s\n", projectName, imageName)
+
+	return storage.rmRecord(ctx, makeRepoManagedImageRecord(projectName, imageName))
+}
+
+func (storage *OCILayoutStagesStorage) GetManagedImages(ctx context.Context, projectName string) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetManagedImages %s\n", projectName)
+
+	tags, err := storage.getTags(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) {
+			continue
+		}
+
+		managedImageName := unslugDockerImageTagAsImageName(strings.TrimPrefix(tag, RepoManagedImageRecord_ImageTagPrefix))
+		if validateImageName(managedImageName) != nil {
+			continue
+		}
+
+		res = append(res, managedImageName)
+	}
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) PutImageMetadata(ctx context.Context, projectName, imageName, commit, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PutImageMetadata %s %s %s %s\n", projectName, imageName, commit, stageID)
+
+	if err := storage.putRecord(ctx, makeRepoImageMetadataName(projectName, imageName, commit, stageID), nil); err != nil {
+		return err
+	}
+
+	logboek.Context(ctx).Info().LogF("Put image %s commit %s stage ID %s\n", imageName, commit, stageID)
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) RmImageMetadata(ctx context.Context, projectName, imageNameOrID, commit, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmImageMetadata %s %s %s %s\n", projectName, imageNameOrID, commit, stageID)
+
+	for _, fullImageName := range []string{
+		makeRepoImageMetadataName(projectName, imageNameOrID, commit, stageID),
+		makeRepoImageMetadataNameByImageID(projectName, imageNameOrID, commit, stageID),
+	} {
+		if err := storage.rmRecord(ctx, fullImageName); err != nil {
+			return err
+		}
+	}
+
+	logboek.Context(ctx).Info().LogF("Removed image %s commit %s stage ID %s\n", imageNameOrID, commit, stageID)
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) IsImageMetadataExist(ctx context.Context, projectName, imageName, commit, stageID string) (bool, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.IsImageMetadataExist %s %s %s %s\n", projectName, imageName, commit, stageID)
+
+	img, err := storage.Store.GetImage(ctx, makeRepoImageMetadataName(projectName, imageName, commit, stageID))
+	return img != nil, err
+}
+
+func (storage *OCILayoutStagesStorage) GetAllAndGroupImageMetadataByImageName(ctx context.Context, projectName string, imageNameList []string) (map[string]map[string][]string, map[string]map[string][]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetAllAndGroupImageMetadataByImageName %s\n", projectName)
+
+	tags, err := storage.getTags(ctx, projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return groupImageMetadataTagsByImageName(ctx, imageNameList, tags, RepoImageMetadataByCommitRecord_ImageTagPrefix)
+}
+
+func (storage *OCILayoutStagesStorage) GetImportMetadata(ctx context.Context, projectName, id string) (*ImportMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetImportMetadata %s %s\n", projectName, id)
+
+	fullImageName := makeRepoImportMetadataName(projectName, id)
+
+	info, err := storage.getImageInfo(ctx, fullImageName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image %s: %s", fullImageName, err)
+	} else if info != nil {
+		return newImportMetadataFromLabels(info.Labels), nil
+	}
+
+	return nil, nil
+}
+
+func (storage *OCILayoutStagesStorage) PutImportMetadata(ctx context.Context, projectName string, metadata *ImportMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PutImportMetadata %s %v\n", projectName, metadata)
+
+	return storage.putRecord(ctx, makeRepoImportMetadataName(projectName, metadata.ImportSourceID), metadata.ToLabels())
+}
+
+func (storage *OCILayoutStagesStorage) RmImportMetadata(ctx context.Context, projectName, id string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmImportMetadata %s %s\n", projectName, id)
+
+	return storage.rmRecord(ctx, makeRepoImportMetadataName(projectName, id))
+}
+
+func (storage *OCILayoutStagesStorage) GetImportMetadataIDs(ctx context.Context, projectName string) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetImportMetadataIDs %s\n", projectName)
+
+	tags, err := storage.getTags(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, RepoImportMetadata_ImageTagPrefix) {
+			ids = append(ids, getImportMetadataIDFromRepoTag(tag))
+		}
+	}
+
+	return ids, nil
+}
+
+func (storage *OCILayoutStagesStorage) GetClientIDRecords(ctx context.Context, projectName string) ([]*ClientIDRecord, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetClientIDRecords for project %s\n", projectName)
+
+	tags, err := storage.getTags(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*ClientIDRecord
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoClientIDRecrod_ImageTagPrefix) {
+			continue
+		}
+
+		dataParts := strings.SplitN(util.Reverse(strings.TrimPrefix(tag, RepoClientIDRecrod_ImageTagPrefix)), "-", 2)
+		if len(dataParts) != 2 {
+			continue
+		}
+
+		clientID, timestampMillisecStr := util.Reverse(dataParts[1]), util.Reverse(dataParts[0])
+
+		timestampMillisec, err := strconv.ParseInt(timestampMillisecStr, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		res = append(res, &ClientIDRecord{ClientID: clientID, TimestampMillisec: timestampMillisec})
+	}
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) PostClientIDRecord(ctx context.Context, projectName string, rec *ClientIDRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PostClientID %s for project %s\n", rec.ClientID, projectName)
+
+	if err := storage.putRecord(ctx, fmt.Sprintf(RepoClientIDRecrod_ImageNameFormat, projectName, rec.ClientID, rec.TimestampMillisec), nil); err != nil {
+		return err
+	}
+
+	logboek.Context(ctx).Info().LogF("Posted new clientID %q for project %s\n", rec.ClientID, projectName)
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) GetStageLastUseRecords(ctx context.Context, projectName string) ([]*StageLastUseRecord, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetStageLastUseRecords for project %s\n", projectName)
+
+	tags, err := storage.getTags(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*StageLastUseRecord
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoStageLastUseRecord_ImageTagPrefix) {
+			continue
+		}
+
+		if rec := parseStageLastUseRecordTag(strings.TrimPrefix(tag, RepoStageLastUseRecord_ImageTagPrefix)); rec != nil {
+			res = append(res, rec)
+		}
+	}
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) PostStageLastUseRecord(ctx context.Context, projectName string, rec *StageLastUseRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PostStageLastUseRecord %s for project %s\n", rec, projectName)
+
+	return storage.putRecord(ctx, fmt.Sprintf(RepoStageLastUseRecord_ImageNameFormat, projectName, rec.StageID, rec.TimestampMillisec), nil)
+}
+
+func (storage *OCILayoutStagesStorage) RmStageLastUseRecord(ctx context.Context, projectName string, rec *StageLastUseRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmStageLastUseRecord %s for project %s\n", rec, projectName)
+
+	return storage.rmRecord(ctx, fmt.Sprintf(RepoStageLastUseRecord_ImageNameFormat, projectName, rec.StageID, rec.TimestampMillisec))
+}
+
+func (storage *OCILayoutStagesStorage) String() string {
+	return storage.Address()
+}
+
+func (storage *OCILayoutStagesStorage) Address() string {
+	return OCILayoutStorageAddressPrefix + storage.Dir
+}
+
+// getTags returns tags of all images of the project, the project name is used as the image repository
+func (storage *OCILayoutStagesStorage) getTags(ctx context.Context, projectName string) ([]string, error) {
+	refs, err := storage.Store.GetRefs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s images: %s", storage.String(), err)
+	}
+
+	var tags []string
+	for _, ref := range refs {
+		if repository, tag := image.ParseRepositoryAndTag(ref); repository == projectName && tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, nil
+}
+
+// putRecord saves the empty image with labels, records are the same as the RepoStagesStorage records
+func (storage *OCILayoutStagesStorage) putRecord(ctx context.Context, fullImageName string, labels map[string]string) error {
+	img, err := mutate.Config(empty.Image, v1.Config{Labels: labels})
+	if err != nil {
+		return err
+	}
+
+	if err := storage.Store.WriteImage(ctx, img, fullImageName); err != nil {
+		return fmt.Errorf("unable to write image %s: %s", fullImageName, err)
+	}
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) rmRecord(ctx context.Context, fullImageName string) error {
+	if err := storage.Store.Untag(ctx, fullImageName); err != nil {
+		return fmt.Errorf("unable to remove image %s: %s", fullImageName, err)
+	}
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) getImageInfo(ctx context.Context, ref string) (*image.Info, error) {
+	img, err := storage.Store.GetImage(ctx, ref)
+	if err != nil || img == nil {
+		return nil, err
+	}
+
+	configName, err := img.ConfigName()
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	manifestDigest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	for _, l := range layers {
+		if lSize, err := l.Size(); err != nil {
+			return nil, err
+		} else {
+			size += lSize
+		}
+	}
+
+	repository, tag := image.ParseRepositoryAndTag(ref)
+
+	return &image.Info{
+		Name:              ref,
+		Repository:        repository,
+		Tag:               tag,
+		RepoDigest:        fmt.Sprintf("%s@%s", repository, manifestDigest),
+		ID:                configName.String(),
+		ParentID:          configFile.Config.Image,
+		Labels:            configFile.Config.Labels,
+		Size:              size,
+		CreatedAtUnixNano: configFile.Created.UnixNano(),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func TestOCILayoutStagesStorageRecords(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "werf-oci-layout-stages-storage-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewStagesStorage(OCILayoutStorageAddressPrefix+filepath.Join(dir, "stages"), &container_runtime.LocalDockerServerRuntime{}, StagesStorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.(*OCILayoutStagesStorage); !ok {
+		t.Fatalf("expected OCILayoutStagesStorage, got %T", s)
+	}
+
+	if err := s.AddManagedImage(ctx, "myproject", "backend/app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddManagedImage(ctx, "otherproject", "frontend"); err != nil {
+		t.Fatal(err)
+	}
+
+	if images, err := s.GetManagedImages(ctx, "myproject"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(images, []string{"backend/app"}) {
+		t.Errorf("unexpected managed images: %v", images)
+	}
+
+	metadata := &ImportMetadata{ImportSourceID: "source-id", SourceImageID: "sha256:123", Checksum: "checksum"}
+	if err := s.PutImportMetadata(ctx, "myproject", metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := s.GetImportMetadata(ctx, "myproject", "source-id"); err != nil {
+		t.Fatal(err)
+	} else if got == nil || !reflect.DeepEqual(*got, *metadata) {
+		t.Errorf("unexpected import metadata: %#v", got)
+	}
+
+	if err := s.PutImageMetadata(ctx, "myproject", "backend/app", "commit", "stage-id"); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := s.IsImageMetadataExist(ctx, "myproject", "backend/app", "commit", "stage-id"); err != nil {
+		t.Fatal(err)
+	} else if !exists {
+		t.Errorf("expected image metadata to exist")
+	}
+
+	if err := s.RmImageMetadata(ctx, "myproject", "backend/app", "commit", "stage-id"); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := s.IsImageMetadataExist(ctx, "myproject", "backend/app", "commit", "stage-id"); err != nil {
+		t.Fatal(err)
+	} else if exists {
+		t.Errorf("expected image metadata to be removed")
+	}
+
+	if err := s.PostClientIDRecord(ctx, "myproject", &ClientIDRecord{ClientID: "client-1", TimestampMillisec: 42}); err != nil {
+		t.Fatal(err)
+	}
+
+	if records, err := s.GetClientIDRecords(ctx, "myproject"); err != nil {
+		t.Fatal(err)
+	} else if len(records) != 1 || records[0].ClientID != "client-1" || records[0].TimestampMillisec != 42 {
+		t.Errorf("unexpected client id records: %v", records)
+	}
+
+	if ids, err := s.GetStagesIDs(ctx, "myproject"); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 0 {
+		t.Errorf("expected no stages, got %v", ids)
+	}
+}
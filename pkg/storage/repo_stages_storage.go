@@ -26,9 +26,15 @@ const (
 	RepoImportMetadata_ImageTagPrefix  = "import-metadata-"
 	RepoImportMetadata_ImageNameFormat = "%s:import-metadata-%s"
 
+	RepoImageIndex_ImageTagPrefix  = "index-"
+	RepoImageIndex_ImageNameFormat = "%s:index-%s"
+
 	RepoClientIDRecrod_ImageTagPrefix  = "client-id-"
 	RepoClientIDRecrod_ImageNameFormat = "%s:client-id-%s-%d"
 
+	RepoStageLastUseRecord_ImageTagPrefix  = "last-use-"
+	RepoStageLastUseRecord_ImageNameFormat = "%s:last-use-%s-%d"
+
 	UnexpectedTagFormatErrorPrefix = "unexpected tag format"
 )
 
@@ -80,6 +86,29 @@ func (storage *RepoStagesStorage) ConstructStageImageName(_, digest string, uniq
 	return fmt.Sprintf(RepoStage_ImageFormat, storage.RepoAddress, digest, uniqueID)
 }
 
+func (storage *RepoStagesStorage) ConstructImageIndexName(_, digest string) string {
+	return fmt.Sprintf(RepoImageIndex_ImageNameFormat, storage.RepoAddress, digest)
+}
+
+func (storage *RepoStagesStorage) StoreImageIndex(ctx context.Context, projectName, digest string, platformImages []PlatformImage) error {
+	fullImageName := storage.ConstructImageIndexName(projectName, digest)
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.StoreImageIndex full image name: %s\n", fullImageName)
+
+	var manifests []docker_registry.ImageIndexManifest
+	for _, platformImage := range platformImages {
+		manifests = append(manifests, docker_registry.ImageIndexManifest{
+			Reference: platformImage.ImageName,
+			Platform:  platformImage.Platform,
+		})
+	}
+
+	if err := storage.DockerRegistry.PushImageIndex(ctx, fullImageName, manifests); err != nil {
+		return fmt.Errorf("unable to push image index %s: %s", fullImageName, err)
+	}
+
+	return nil
+}
+
 func (storage *RepoStagesStorage) GetStagesIDs(ctx context.Context, projectName string) ([]image.StageID, error) {
 	var res []image.StageID
 
@@ -89,7 +118,7 @@ func (storage *RepoStagesStorage) GetStagesIDs(ctx context.Context, projectName
 		logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetRepoImagesByDigest fetched tags for %q: %#v\n", storage.RepoAddress, tags)
 
 		for _, tag := range tags {
-			if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoImageMetadataByCommitRecord_ImageTagPrefix) {
+			if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoImageMetadataByCommitRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoImageIndex_ImageTagPrefix) || strings.HasPrefix(tag, RepoStageLastUseRecord_ImageTagPrefix) {
 				continue
 			}
 
@@ -114,6 +143,22 @@ func (storage *RepoStagesStorage) DeleteStage(ctx context.Context, stageDescript
 	return storage.DockerRegistry.DeleteRepoImage(ctx, stageDescription.Info)
 }
 
+// DeleteStages deletes the stages with the bulk deletion API of the docker registry if available
+func (storage *RepoStagesStorage) DeleteStages(ctx context.Context, stageDescriptions []*image.StageDescription, options DeleteStagesOptions, f func(ctx context.Context, stageDescription *image.StageDescription, err error) error) error {
+	var repoImages []*image.Info
+	stageDescriptionByRepoImage := map[*image.Info]*image.StageDescription{}
+	for _, stageDescription := range stageDescriptions {
+		repoImages = append(repoImages, stageDescription.Info)
+		stageDescriptionByRepoImage[stageDescription.Info] = stageDescription
+	}
+
+	return storage.DockerRegistry.DeleteRepoImages(ctx, repoImages, docker_registry.DeleteRepoImagesOptions{
+		MaxNumberOfWorkers: options.MaxNumberOfWorkers,
+	}, func(ctx context.Context, repoImage *image.Info, err error) error {
+		return f(ctx, stageDescriptionByRepoImage[repoImage], err)
+	})
+}
+
 func (storage *RepoStagesStorage) FilterStagesAndProcessRelatedData(_ context.Context, stageDescriptions []*image.StageDescription, _ FilterStagesAndProcessRelatedDataOptions) ([]*image.StageDescription, error) {
 	return stageDescriptions, nil
 }
@@ -248,8 +293,9 @@ func (storage *RepoStagesStorage) FetchImage(ctx context.Context, img container_
 	switch containerRuntime := storage.ContainerRuntime.(type) {
 	case *container_runtime.LocalDockerServerRuntime:
 		return containerRuntime.PullImageFromRegistry(ctx, img)
+	case *container_runtime.LocalHostRuntime:
+		return containerRuntime.PullImageFromRegistry(ctx, img)
 	default:
-		// TODO: case *container_runtime.LocalHostRuntime:
 		panic("not implemented")
 	}
 }
@@ -265,15 +311,23 @@ func (storage *RepoStagesStorage) StoreImage(ctx context.Context, img container_
 			return containerRuntime.PushImage(ctx, img)
 		}
 
+	case *container_runtime.LocalHostRuntime:
+		dockerImage := img.(*container_runtime.DockerImage)
+
+		if dockerImage.Image.GetBuiltId() != "" {
+			return containerRuntime.PushBuiltImage(ctx, img)
+		} else {
+			return containerRuntime.PushImage(ctx, img)
+		}
+
 	default:
-		// TODO: case *container_runtime.LocalHostRuntime:
 		panic("not implemented")
 	}
 }
 
 func (storage *RepoStagesStorage) ShouldFetchImage(_ context.Context, img container_runtime.Image) (bool, error) {
 	switch storage.ContainerRuntime.(type) {
-	case *container_runtime.LocalDockerServerRuntime:
+	case *container_runtime.LocalDockerServerRuntime, *container_runtime.LocalHostRuntime:
 		dockerImage := img.(*container_runtime.DockerImage)
 		return !dockerImage.Image.IsExistsLocally(), nil
 	default:
@@ -603,3 +657,54 @@ func (storage *RepoStagesStorage) PostClientIDRecord(ctx context.Context, projec
 
 	return nil
 }
+
+func (storage *RepoStagesStorage) GetStageLastUseRecords(ctx context.Context, projectName string) ([]*StageLastUseRecord, error) {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetStageLastUseRecords for project %s\n", projectName)
+
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get repo %s tags: %s", storage.RepoAddress, err)
+	}
+
+	var res []*StageLastUseRecord
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoStageLastUseRecord_ImageTagPrefix) {
+			continue
+		}
+
+		if rec := parseStageLastUseRecordTag(strings.TrimPrefix(tag, RepoStageLastUseRecord_ImageTagPrefix)); rec != nil {
+			res = append(res, rec)
+		}
+	}
+
+	return res, nil
+}
+
+func (storage *RepoStagesStorage) PostStageLastUseRecord(ctx context.Context, projectName string, rec *StageLastUseRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.PostStageLastUseRecord %s for project %s\n", rec, projectName)
+
+	fullImageName := fmt.Sprintf(RepoStageLastUseRecord_ImageNameFormat, storage.RepoAddress, rec.StageID, rec.TimestampMillisec)
+	if err := storage.DockerRegistry.PushImage(ctx, fullImageName, nil); err != nil {
+		return fmt.Errorf("unable to push image %s: %s", fullImageName, err)
+	}
+
+	return nil
+}
+
+func (storage *RepoStagesStorage) RmStageLastUseRecord(ctx context.Context, projectName string, rec *StageLastUseRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.RmStageLastUseRecord %s for project %s\n", rec, projectName)
+
+	fullImageName := fmt.Sprintf(RepoStageLastUseRecord_ImageNameFormat, storage.RepoAddress, rec.StageID, rec.TimestampMillisec)
+	img, err := storage.DockerRegistry.TryGetRepoImage(ctx, fullImageName)
+	if err != nil {
+		return fmt.Errorf("unable to get repo image %s: %s", fullImageName, err)
+	} else if img == nil {
+		return nil
+	}
+
+	if err := storage.DockerRegistry.DeleteRepoImage(ctx, img); err != nil {
+		return fmt.Errorf("unable to remove repo image %s: %s", img.Tag, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// withRuntimeImage passes the container runtime image as v1.Image to the callback.
+// The image is only valid during the callback.
+func withRuntimeImage(ctx context.Context, containerRuntime container_runtime.ContainerRuntime, imageName string, f func(img v1.Image) error) error {
+	switch containerRuntime := containerRuntime.(type) {
+	case *container_runtime.LocalDockerServerRuntime:
+		return withDockerServerImage(ctx, imageName, f)
+	case *container_runtime.LocalHostRuntime:
+		return containerRuntime.Store.UseImage(ctx, imageName, f)
+	default:
+		panic("not implemented")
+	}
+}
+
+// writeRuntimeImage writes v1.Image into the container runtime by the specified name
+func writeRuntimeImage(ctx context.Context, containerRuntime container_runtime.ContainerRuntime, imageName string, img v1.Image) error {
+	switch containerRuntime := containerRuntime.(type) {
+	case *container_runtime.LocalDockerServerRuntime:
+		if err := loadImageIntoDockerServer(ctx, imageName, img); err != nil {
+			return fmt.Errorf("unable to load image %s into docker server: %s", imageName, err)
+		}
+		return nil
+	case *container_runtime.LocalHostRuntime:
+		return containerRuntime.Store.WriteImage(ctx, img, imageName)
+	default:
+		panic("not implemented")
+	}
+}
+
+func loadImageIntoDockerServer(ctx context.Context, imageName string, img v1.Image) error {
+	tag, err := name.NewTag(imageName, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		return pw.CloseWithError(tarball.Write(tag, img, pw))
+	})
+
+	eg.Go(func() error {
+		if err := docker.ImageLoad(egCtx, pr); err != nil {
+			_ = pr.CloseWithError(err)
+			return err
+		}
+		return nil
+	})
+
+	return eg.Wait()
+}
+
+func withDockerServerImage(ctx context.Context, imageName string, f func(img v1.Image) error) error {
+	tag, err := name.NewTag(imageName, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(werf.GetTmpDir(), "docker-server-image-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	rc, err := docker.ImageSave(ctx, imageName)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	defer rc.Close()
+
+	if _, err := io.Copy(file, rc); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	img, err := tarball.ImageFromPath(file.Name(), &tag)
+	if err != nil {
+		return fmt.Errorf("unable to read saved image %s: %s", imageName, err)
+	}
+
+	return f(img)
+}
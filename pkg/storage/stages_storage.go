@@ -3,9 +3,14 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/util/parallel"
 )
 
 const (
@@ -19,9 +24,12 @@ type StagesStorage interface {
 	GetStagesIDsByDigest(ctx context.Context, projectName, digest string) ([]image.StageID, error)
 	GetStageDescription(ctx context.Context, projectName, digest string, uniqueID int64) (*image.StageDescription, error)
 	DeleteStage(ctx context.Context, stageDescription *image.StageDescription, options DeleteImageOptions) error
+	// DeleteStages deletes the stages using the bulk deletion if the storage supports it, f is called with the deletion result of each stage
+	DeleteStages(ctx context.Context, stageDescriptions []*image.StageDescription, options DeleteStagesOptions, f func(ctx context.Context, stageDescription *image.StageDescription, err error) error) error
 	FilterStagesAndProcessRelatedData(ctx context.Context, stageDescriptions []*image.StageDescription, options FilterStagesAndProcessRelatedDataOptions) ([]*image.StageDescription, error)
 
 	ConstructStageImageName(projectName, digest string, uniqueID int64) string
+	ConstructImageIndexName(projectName, digest string) string
 
 	// FetchImage will create a local image in the container-runtime
 	FetchImage(ctx context.Context, img container_runtime.Image) error
@@ -29,6 +37,9 @@ type StagesStorage interface {
 	StoreImage(ctx context.Context, img container_runtime.Image) error
 	ShouldFetchImage(ctx context.Context, img container_runtime.Image) (bool, error)
 
+	// StoreImageIndex will store the image index referencing already stored stage images built for different platforms
+	StoreImageIndex(ctx context.Context, projectName, digest string, platformImages []PlatformImage) error
+
 	CreateRepo(ctx context.Context) error
 	DeleteRepo(ctx context.Context) error
 
@@ -49,10 +60,26 @@ type StagesStorage interface {
 	GetClientIDRecords(ctx context.Context, projectName string) ([]*ClientIDRecord, error)
 	PostClientIDRecord(ctx context.Context, projectName string, rec *ClientIDRecord) error
 
+	GetStageLastUseRecords(ctx context.Context, projectName string) ([]*StageLastUseRecord, error)
+	PostStageLastUseRecord(ctx context.Context, projectName string, rec *StageLastUseRecord) error
+	RmStageLastUseRecord(ctx context.Context, projectName string, rec *StageLastUseRecord) error
+
 	String() string
 	Address() string
 }
 
+// deleteStagesOneByOne deletes the stages in parallel with DeleteStage, it is used by the storages without the bulk deletion
+func deleteStagesOneByOne(ctx context.Context, stagesStorage StagesStorage, stageDescriptions []*image.StageDescription, options DeleteStagesOptions, f func(ctx context.Context, stageDescription *image.StageDescription, err error) error) error {
+	return parallel.DoTasks(ctx, len(stageDescriptions), parallel.DoTasksOptions{
+		MaxNumberOfWorkers:         options.MaxNumberOfWorkers,
+		InitDockerCLIForEachWorker: true,
+	}, func(ctx context.Context, taskId int) error {
+		stageDescription := stageDescriptions[taskId]
+		err := stagesStorage.DeleteStage(ctx, stageDescription, options.DeleteImageOptions)
+		return f(ctx, stageDescription, err)
+	})
+}
+
 type ClientIDRecord struct {
 	ClientID          string
 	TimestampMillisec int64
@@ -62,6 +89,42 @@ func (rec *ClientIDRecord) String() string {
 	return fmt.Sprintf("clientID:%s tsMillisec:%d", rec.ClientID, rec.TimestampMillisec)
 }
 
+// StageLastUseRecord is the time the stage was last selected or fetched from the stages storage
+type StageLastUseRecord struct {
+	StageID           string
+	TimestampMillisec int64
+}
+
+func (rec *StageLastUseRecord) String() string {
+	return fmt.Sprintf("stageID:%s tsMillisec:%d", rec.StageID, rec.TimestampMillisec)
+}
+
+func (rec *StageLastUseRecord) Time() time.Time {
+	return time.Unix(rec.TimestampMillisec/1000, (rec.TimestampMillisec%1000)*int64(time.Millisecond))
+}
+
+// parseStageLastUseRecordTag parses the STAGE_ID-TIMESTAMP_MILLISEC record tag without the prefix
+func parseStageLastUseRecordTag(tag string) *StageLastUseRecord {
+	dataParts := strings.SplitN(util.Reverse(tag), "-", 2)
+	if len(dataParts) != 2 {
+		return nil
+	}
+
+	stageID, timestampMillisecStr := util.Reverse(dataParts[1]), util.Reverse(dataParts[0])
+
+	timestampMillisec, err := strconv.ParseInt(timestampMillisecStr, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &StageLastUseRecord{StageID: stageID, TimestampMillisec: timestampMillisec}
+}
+
+type PlatformImage struct {
+	Platform  string
+	ImageName string
+}
+
 type ImageMetadata struct {
 	ContentDigest string
 }
@@ -72,7 +135,13 @@ type StagesStorageOptions struct {
 
 func NewStagesStorage(stagesStorageAddress string, containerRuntime container_runtime.ContainerRuntime, options StagesStorageOptions) (StagesStorage, error) {
 	if stagesStorageAddress == LocalStorageAddress {
-		return NewLocalDockerServerStagesStorage(containerRuntime.(*container_runtime.LocalDockerServerRuntime)), nil
+		localDockerServerRuntime, ok := containerRuntime.(*container_runtime.LocalDockerServerRuntime)
+		if !ok {
+			return nil, fmt.Errorf("stages storage %s is not supported by %s container runtime: specify --repo=ADDRESS", LocalStorageAddress, containerRuntime.String())
+		}
+		return NewLocalDockerServerStagesStorage(localDockerServerRuntime), nil
+	} else if IsOCILayoutStorageAddress(stagesStorageAddress) {
+		return NewOCILayoutStagesStorage(stagesStorageAddress, containerRuntime)
 	} else { // Docker registry based stages storage
 		return NewRepoStagesStorage(stagesStorageAddress, containerRuntime, options.RepoStagesStorageOptions)
 	}
@@ -0,0 +1,202 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	serviceName = "werf"
+
+	otlpSpanKindInternal = 1
+	otlpStatusCodeError  = 2
+)
+
+type Exporter interface {
+	Export(ctx context.Context, spans []*Span) error
+	String() string
+}
+
+// FileExporter appends spans to the file in the OTLP JSON format, one export request per line
+type FileExporter struct {
+	Path string
+
+	mux sync.Mutex
+}
+
+func NewFileExporter(path string) *FileExporter {
+	return &FileExporter{Path: path}
+}
+
+func (exporter *FileExporter) Export(_ context.Context, spans []*Span) error {
+	data, err := json.Marshal(newOTLPExportRequest(spans))
+	if err != nil {
+		return err
+	}
+
+	exporter.mux.Lock()
+	defer exporter.mux.Unlock()
+
+	f, err := os.OpenFile(exporter.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func (exporter *FileExporter) String() string {
+	return fmt.Sprintf("file %s", exporter.Path)
+}
+
+// OTLPHTTPExporter sends spans to the OTLP/HTTP collector using the JSON encoding
+type OTLPHTTPExporter struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewOTLPHTTPExporter(endpoint string) *OTLPHTTPExporter {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+
+	return &OTLPHTTPExporter{URL: url, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (exporter *OTLPHTTPExporter) Export(ctx context.Context, spans []*Span) error {
+	data, err := json.Marshal(newOTLPExportRequest(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, exporter.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := exporter.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+func (exporter *OTLPHTTPExporter) String() string {
+	return fmt.Sprintf("otlp collector %s", exporter.URL)
+}
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string             `json:"key"`
+	Value otlpAttributeValue `json:"value"`
+}
+
+type otlpAttributeValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func newOTLPExportRequest(spans []*Span) otlpExportRequest {
+	var otlpSpans []otlpSpan
+	for _, span := range spans {
+		otlpSpans = append(otlpSpans, newOTLPSpan(span))
+	}
+
+	return otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource:   otlpResource{Attributes: newOTLPAttributes([]Attribute{Attr("service.name", serviceName)})},
+				ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: serviceName}, Spans: otlpSpans}},
+			},
+		},
+	}
+}
+
+func newOTLPSpan(span *Span) otlpSpan {
+	span.mux.Lock()
+	defer span.mux.Unlock()
+
+	res := otlpSpan{
+		TraceID:           span.TraceID,
+		SpanID:            span.SpanID,
+		ParentSpanID:      span.ParentSpanID,
+		Name:              span.Name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+		Attributes:        newOTLPAttributes(span.Attributes),
+	}
+
+	if span.Error != "" {
+		res.Status = &otlpStatus{Code: otlpStatusCodeError, Message: span.Error}
+	}
+
+	return res
+}
+
+func newOTLPAttributes(attributes []Attribute) []otlpAttribute {
+	var res []otlpAttribute
+	for _, attr := range attributes {
+		res = append(res, otlpAttribute{Key: attr.Key, Value: otlpAttributeValue{StringValue: attr.Value}})
+	}
+
+	return res
+}
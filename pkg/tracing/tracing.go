@@ -0,0 +1,236 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OTLPEndpointEnv enables export of spans to the OTLP/HTTP collector, e.g. http://localhost:4318
+	OTLPEndpointEnv = "WERF_TRACING_OTLP_ENDPOINT"
+	// FileEnv enables export of spans to the file in the OTLP JSON format (one export request per line)
+	FileEnv = "WERF_TRACING_FILE"
+
+	// exportBatchSize limits the number of finished spans kept in memory before export
+	exportBatchSize = 1000
+)
+
+var tracer *Tracer
+
+type Tracer struct {
+	exporters []Exporter
+	root      *Span
+
+	mux   sync.Mutex
+	spans []*Span
+}
+
+type spanContextKey struct{}
+
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+func Attr(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span describes a single timed operation, all methods of the nil span are no-op so the caller should not check whether tracing is enabled
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   []Attribute
+	Error        string
+
+	mux sync.Mutex
+}
+
+// Init enables tracing if one of the exporters is configured by the environment, rootSpanName is used for the span covering the whole werf invocation
+func Init(rootSpanName string) error {
+	var exporters []Exporter
+
+	if endpoint := os.Getenv(OTLPEndpointEnv); endpoint != "" {
+		exporters = append(exporters, NewOTLPHTTPExporter(endpoint))
+	}
+
+	if path := os.Getenv(FileEnv); path != "" {
+		exporters = append(exporters, NewFileExporter(path))
+	}
+
+	if len(exporters) == 0 {
+		return nil
+	}
+
+	traceID, err := newID(16)
+	if err != nil {
+		return fmt.Errorf("unable to generate trace id: %s", err)
+	}
+
+	spanID, err := newID(8)
+	if err != nil {
+		return fmt.Errorf("unable to generate span id: %s", err)
+	}
+
+	tracer = &Tracer{
+		exporters: exporters,
+		root:      &Span{TraceID: traceID, SpanID: spanID, Name: rootSpanName, StartTime: time.Now()},
+	}
+
+	return nil
+}
+
+func IsEnabled() bool {
+	return tracer != nil
+}
+
+// Shutdown ends the root span and exports all remaining spans
+func Shutdown(ctx context.Context) error {
+	if tracer == nil {
+		return nil
+	}
+
+	tracer.root.End()
+	err := tracer.flush(ctx)
+	tracer = nil
+
+	return err
+}
+
+// StartSpan starts the child span of the span from the context (or the root span) and returns the context with the new span
+func StartSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		parent = tracer.root
+	}
+
+	spanID, err := newID(8)
+	if err != nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		TraceID:      parent.TraceID,
+		SpanID:       spanID,
+		ParentSpanID: parent.SpanID,
+		Name:         name,
+		StartTime:    time.Now(),
+		Attributes:   attributes,
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	if span, ok := ctx.Value(spanContextKey{}).(*Span); ok {
+		return span
+	}
+
+	return nil
+}
+
+// Do runs f within the new span and records the returned error
+func Do(ctx context.Context, name string, attributes []Attribute, f func(ctx context.Context) error) error {
+	ctx, span := StartSpan(ctx, name, attributes...)
+	err := f(ctx)
+	span.SetError(err)
+	span.End()
+
+	return err
+}
+
+func (span *Span) SetAttribute(key, value string) {
+	if span == nil {
+		return
+	}
+
+	span.mux.Lock()
+	defer span.mux.Unlock()
+	span.Attributes = append(span.Attributes, Attr(key, value))
+}
+
+func (span *Span) SetError(err error) {
+	if span == nil || err == nil {
+		return
+	}
+
+	span.mux.Lock()
+	defer span.mux.Unlock()
+	span.Error = err.Error()
+}
+
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+
+	span.mux.Lock()
+	if !span.EndTime.IsZero() {
+		span.mux.Unlock()
+		return
+	}
+	span.EndTime = time.Now()
+	span.mux.Unlock()
+
+	if t := tracer; t != nil {
+		t.add(span)
+	}
+}
+
+func (t *Tracer) add(span *Span) {
+	t.mux.Lock()
+	t.spans = append(t.spans, span)
+	shouldFlush := len(t.spans) >= exportBatchSize
+	t.mux.Unlock()
+
+	if shouldFlush {
+		_ = t.flush(context.Background())
+	}
+}
+
+func (t *Tracer) flush(ctx context.Context) error {
+	t.mux.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mux.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	var errors []string
+	for _, exporter := range t.exporters {
+		if err := exporter.Export(ctx, spans); err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %s", exporter.String(), err))
+		}
+	}
+
+	if len(errors) != 0 {
+		return fmt.Errorf("unable to export spans: %s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+func newID(size int) (string, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(data), nil
+}
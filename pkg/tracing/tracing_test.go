@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTracingFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-tracing-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "trace.json")
+	os.Setenv(FileEnv, path)
+	defer os.Unsetenv(FileEnv)
+
+	if err := Init("werf build"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, phaseSpan := StartSpan(context.Background(), "phase build")
+	_ = Do(ctx, "stage from", []Attribute{Attr("image", "app")}, func(ctx context.Context) error {
+		return errors.New("failed")
+	})
+	phaseSpan.End()
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req otlpExportRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("unable to unmarshal %s: %s", data, err)
+	}
+
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d: %s", len(spans), data)
+	}
+
+	stageSpan, phase, root := spans[0], spans[1], spans[2]
+	if root.Name != "werf build" || root.ParentSpanID != "" {
+		t.Errorf("unexpected root span %#v", root)
+	}
+	if phase.ParentSpanID != root.SpanID || stageSpan.ParentSpanID != phase.SpanID {
+		t.Errorf("unexpected spans hierarchy: %s", data)
+	}
+	if stageSpan.Status == nil || stageSpan.Status.Message != "failed" {
+		t.Errorf("expected stage span error status: %s", data)
+	}
+	if stageSpan.TraceID != root.TraceID {
+		t.Errorf("expected the same trace id: %s", data)
+	}
+}
+
+func TestTracingOTLPHTTPExporter(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPHTTPExporter(server.URL)
+	if err := exporter.Export(context.Background(), []*Span{{TraceID: "t", SpanID: "s", Name: "lock wait"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(received), `"name":"lock wait"`) {
+		t.Errorf("unexpected request body %s", received)
+	}
+}
+
+func TestDisabledTracing(t *testing.T) {
+	ctx, span := StartSpan(context.Background(), "noop")
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Errorf("expected no span when tracing is disabled")
+	}
+
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("err"))
+	span.End()
+}
@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// NewTransport wraps the http transport to record the span for each request
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !IsEnabled() {
+		return t.base.RoundTrip(req)
+	}
+
+	_, span := StartSpan(req.Context(), fmt.Sprintf("HTTP %s %s", req.Method, req.URL.Host),
+		Attr("http.method", req.Method),
+		Attr("http.url", fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, req.URL.Path)),
+	)
+	defer span.End()
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+
+	span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetError(fmt.Errorf("response status %s", resp.Status))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,67 @@
+package secretvalues
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+const maskedValue = "***"
+
+// MaskWriter replaces the secret values with asterisks in the data written to the underlying writer.
+// The data is passed through by lines, so the values split between several writes are masked too,
+// the rest of the data without line ending is written by Flush.
+type MaskWriter struct {
+	w      io.Writer
+	values [][]byte
+	buf    []byte
+}
+
+func NewMaskWriter(w io.Writer, valuesToMask []string) *MaskWriter {
+	var values [][]byte
+	for _, value := range valuesToMask {
+		if value != "" {
+			values = append(values, []byte(value))
+		}
+	}
+
+	// the longest values are masked first to mask the values containing others completely
+	sort.SliceStable(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	return &MaskWriter{w: w, values: values}
+}
+
+func (m *MaskWriter) Write(p []byte) (int, error) {
+	m.buf = append(m.buf, p...)
+
+	if ind := bytes.LastIndexByte(m.buf, '\n'); ind != -1 {
+		if _, err := m.w.Write(m.mask(m.buf[:ind+1])); err != nil {
+			return 0, err
+		}
+
+		m.buf = append([]byte{}, m.buf[ind+1:]...)
+	}
+
+	return len(p), nil
+}
+
+func (m *MaskWriter) Flush() error {
+	if len(m.buf) == 0 {
+		return nil
+	}
+
+	_, err := m.w.Write(m.mask(m.buf))
+	m.buf = nil
+
+	return err
+}
+
+func (m *MaskWriter) mask(data []byte) []byte {
+	for _, value := range m.values {
+		data = bytes.ReplaceAll(data, value, []byte(maskedValue))
+	}
+
+	return data
+}
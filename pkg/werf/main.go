@@ -12,6 +12,8 @@ import (
 	"github.com/werf/logboek"
 
 	"github.com/werf/lockgate"
+
+	"github.com/werf/werf/pkg/tracing"
 )
 
 var (
@@ -102,7 +104,9 @@ func ReleaseHostLock(lock lockgate.LockHandle) error {
 func DefaultLockerOnWait(ctx context.Context) func(lockName string, doWait func() error) error {
 	return func(lockName string, doWait func() error) error {
 		logProcessMsg := fmt.Sprintf("Waiting for locked %q", lockName)
-		return logboek.Context(ctx).LogProcessInline(logProcessMsg).DoError(doWait)
+		return tracing.Do(ctx, "lock wait", []tracing.Attribute{tracing.Attr("werf.lock.name", lockName)}, func(_ context.Context) error {
+			return logboek.Context(ctx).LogProcessInline(logProcessMsg).DoError(doWait)
+		})
 	}
 }
 